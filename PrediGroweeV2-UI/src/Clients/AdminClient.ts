@@ -235,7 +235,9 @@ class AdminClient extends BaseClient {
 
   async getAllActivity() {
     try {
-      const res = await this.axiosInstance.get('/stats/activity');
+      const res = await this.axiosInstance.get('/stats/activity', {
+        params: { tz: Intl.DateTimeFormat().resolvedOptions().timeZone },
+      });
       return res.data;
     } catch (err) {
       throw new Error("Couldn't fetch activity: " + err);
@@ -75,6 +75,7 @@ class QuizClient extends BaseClient {
     const res = await this.axiosInstance.post(`/sessions/${sessionId}/answer`, {
       answer,
       screen_size: screenWidth + 'x' + screenHeight,
+      time_zone: Intl.DateTimeFormat().resolvedOptions().timeZone,
     });
     return res.data;
   }
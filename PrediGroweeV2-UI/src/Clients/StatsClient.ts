@@ -1,5 +1,5 @@
 import BaseClient from '@/Clients/BaseClient';
-import { UserSurvey } from '@/types';
+import { LeaderboardStatus, UserSurvey } from '@/types';
 import { AxiosError } from 'axios';
 
 class StatsClient extends BaseClient {
@@ -64,6 +64,38 @@ class StatsClient extends BaseClient {
     }
   }
 
+  async getLeaderboardStatus(): Promise<LeaderboardStatus> {
+    try {
+      const res = await this.axiosInstance.get('/leaderboard/me');
+      return res.data;
+    } catch (err) {
+      throw new Error("Couldn't get leaderboard status: " + err);
+    }
+  }
+
+  async joinLeaderboard(displayName: string) {
+    try {
+      await this.axiosInstance.put('/leaderboard/me', { displayName });
+    } catch (err) {
+      const axiosError = err as AxiosError;
+      if (axiosError.response?.status === 409) {
+        throw new Error('This display name is already taken.');
+      } else if (axiosError.response?.status === 400) {
+        throw new Error(String(axiosError.response.data).trim());
+      } else {
+        throw new Error('An error occurred while joining the leaderboard.');
+      }
+    }
+  }
+
+  async leaveLeaderboard() {
+    try {
+      await this.axiosInstance.delete('/leaderboard/me');
+    } catch (err) {
+      throw new Error("Couldn't leave the leaderboard: " + err);
+    }
+  }
+
   async getSurveyResponse(): Promise<UserSurvey> {
     try {
       const res = await this.axiosInstance.get('/survey');
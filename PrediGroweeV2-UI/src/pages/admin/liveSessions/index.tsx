@@ -47,8 +47,79 @@ type ActiveSession = {
   total_answers?: number;
 };
 
+// SessionEvent is what the stream sends when a session starts, answers,
+// finishes or goes idle.
+type SessionEvent = {
+  id: number;
+  user_id: number;
+  status: string;
+  mode: string;
+  current_question: number;
+  test_id?: number;
+  test_code?: string;
+  last_seen: string;
+  question_id?: number;
+  correct?: boolean;
+};
+
 const FIVE_MIN_MS = 5 * 60 * 1000;
 const COMPLETED_KEEP_MS = 60 * 1000;
+const STREAM_RETRY_MS = 15 * 1000;
+
+type Ghosts = Map<number, { row: ActiveSession; expiresAt: number }>;
+
+// addGhost keeps a session that left the list on screen for a minute.
+const addGhost = (ghosts: Ghosts, row: ActiveSession, status: string, now: number) => {
+  if (ghosts.has(row.id)) return;
+  ghosts.set(row.id, {
+    row: { ...row, status, last_seen: new Date(now).toISOString() },
+    expiresAt: now + COMPLETED_KEEP_MS,
+  });
+};
+
+// readEventStream reads a server-sent event stream and passes each event with
+// its parsed data to onEvent. It uses fetch rather than EventSource so the
+// access token can go in the Authorization header. It resolves when the
+// stream ends and rejects when it cannot be opened.
+const readEventStream = async (
+  url: string,
+  token: string,
+  signal: AbortSignal,
+  onEvent: (name: string, data: unknown) => void
+) => {
+  const res = await fetch(url, {
+    headers: { Authorization: 'Bearer ' + token, Accept: 'text/event-stream' },
+    signal,
+  });
+  if (!res.ok || !res.body) throw new Error(`stream failed: ${res.status}`);
+
+  const reader = res.body.pipeThrough(new TextDecoderStream()).getReader();
+  let buf = '';
+  for (;;) {
+    const { value, done } = await reader.read();
+    if (done) return;
+    buf += value;
+    let end: number;
+    while ((end = buf.indexOf('\n\n')) >= 0) {
+      const frame = buf.slice(0, end);
+      buf = buf.slice(end + 2);
+      let name = 'message';
+      const data: string[] = [];
+      frame.split('\n').forEach((line) => {
+        if (line.startsWith('event:')) name = line.slice(6).trim();
+        else if (line.startsWith('data:')) data.push(line.slice(5).replace(/^ /, ''));
+      });
+      if (data.length === 0) continue;
+      let parsed: unknown;
+      try {
+        parsed = JSON.parse(data.join('\n'));
+      } catch {
+        continue; // skip events that are not JSON
+      }
+      onEvent(name, parsed);
+    }
+  }
+};
 
 const statusChipColor = (status: string): 'default' | 'success' | 'info' => {
   if (status === 'active') return 'success';
@@ -105,11 +176,12 @@ const LiveSessionsPage: React.FC = () => {
   const [selectedUserDetails, setSelectedUserDetails] = React.useState<UserDetails | null>(null);
   const adminClient = React.useMemo(() => new AdminClient(ADMIN_SERVICE_URL), []);
 
-  const ghostsRef = React.useRef<Map<number, { row: ActiveSession; expiresAt: number }>>(new Map());
+  const ghostsRef = React.useRef<Ghosts>(new Map());
+  const liveRef = React.useRef<Map<number, ActiveSession>>(new Map());
 
-  const lastRowsRef = React.useRef<ActiveSession[]>([]);
   const inFlightRef = React.useRef(false);
   const timerRef = React.useRef<number | null>(null);
+  const pollingRef = React.useRef(false);
 
   const dt = React.useMemo(
     () => new Intl.DateTimeFormat('pl-PL', { dateStyle: 'short', timeStyle: 'medium' }),
@@ -130,82 +202,187 @@ const LiveSessionsPage: React.FC = () => {
     return `${m}m ${s}s`;
   };
 
-  const fetchData = React.useCallback(async () => {
-    try {
-      if (inFlightRef.current) return;
-      inFlightRef.current = true;
+  // render drops stale sessions and expired ghosts and shows the rest,
+  // most recently seen first.
+  const render = React.useCallback(() => {
+    const now = Date.now();
+    liveRef.current.forEach((r, id) => {
+      if (now - new Date(r.last_seen).getTime() >= FIVE_MIN_MS) {
+        liveRef.current.delete(id);
+      }
+    });
+    ghostsRef.current.forEach((g, id) => {
+      if (liveRef.current.has(id) || g.expiresAt <= now) {
+        ghostsRef.current.delete(id);
+      }
+    });
 
-      setError(null);
-      const token = sessionStorage.getItem('accessToken') || '';
-      const res = await axios.get(`${ADMIN_SERVICE_URL}/live/sessions/active?cutoff=5`, {
-        headers: { Authorization: 'Bearer ' + token },
-      });
+    const ghosts: ActiveSession[] = [];
+    ghostsRef.current.forEach((g) => ghosts.push(g.row));
+    const merged = [...Array.from(liveRef.current.values()), ...ghosts];
+    merged.sort((a, b) => new Date(b.last_seen).getTime() - new Date(a.last_seen).getTime());
+    setRows(merged);
+  }, []);
 
-      const raw: ActiveSession[] = Array.isArray(res.data) ? res.data : [];
+  // applySnapshot replaces the live sessions with a full list, keeping the
+  // ones that dropped out for a while as completed.
+  const applySnapshot = React.useCallback(
+    (raw: ActiveSession[]) => {
       const now = Date.now();
-
       const fresh = raw.filter(
         (r) => now - new Date(r.last_seen).getTime() < FIVE_MIN_MS && !r.test_code?.includes(' ')
       );
-
       const freshIds = new Set(fresh.map((r) => r.id));
+      liveRef.current.forEach((prev) => {
+        if (!freshIds.has(prev.id)) addGhost(ghostsRef.current, prev, 'completed', now);
+      });
+      liveRef.current = new Map(fresh.map((r) => [r.id, r]));
+      render();
+    },
+    [render]
+  );
 
-      lastRowsRef.current.forEach((prevRow) => {
-        if (!freshIds.has(prevRow.id)) {
-          if (!ghostsRef.current.has(prevRow.id)) {
-            ghostsRef.current.set(prevRow.id, {
-              row: { ...prevRow, status: 'completed', last_seen: new Date().toISOString() },
-              expiresAt: now + COMPLETED_KEEP_MS,
-            });
+  const applyEvent = React.useCallback(
+    (name: string, e: SessionEvent) => {
+      if (e.test_code?.includes(' ')) return;
+      const now = Date.now();
+      const prev = liveRef.current.get(e.id) ?? ghostsRef.current.get(e.id)?.row;
+
+      switch (name) {
+        case 'session_started':
+        case 'question_answered': {
+          const row: ActiveSession = {
+            correct_answers: 0,
+            total_answers: 0,
+            current_group: 0,
+            created_at: e.last_seen,
+            ...prev,
+            id: e.id,
+            user_id: e.user_id,
+            status: e.status,
+            mode: e.mode,
+            current_question: e.current_question,
+            test_id: e.test_id,
+            test_code: e.test_code,
+            updated_at: e.last_seen,
+            last_seen: e.last_seen,
+          };
+          if (name === 'question_answered') {
+            row.total_answers = (row.total_answers ?? 0) + 1;
+            row.correct_answers = (row.correct_answers ?? 0) + (e.correct ? 1 : 0);
+            row.accuracy = row.correct_answers / row.total_answers;
           }
+          ghostsRef.current.delete(e.id);
+          liveRef.current.set(e.id, row);
+          break;
         }
-      });
-
-      ghostsRef.current.forEach((g, id) => {
-        if (freshIds.has(id) || g.expiresAt <= now) {
-          ghostsRef.current.delete(id);
+        case 'session_finished':
+        case 'session_idle': {
+          const row = liveRef.current.get(e.id);
+          liveRef.current.delete(e.id);
+          if (row) addGhost(ghostsRef.current, row, name === 'session_finished' ? 'completed' : 'idle', now);
+          break;
         }
-      });
-
-      const ghosts: ActiveSession[] = [];
-      ghostsRef.current.forEach((g) => ghosts.push(g.row));
-      const merged = [...fresh, ...ghosts];
+        default:
+          return;
+      }
+      render();
+    },
+    [render]
+  );
 
-      merged.sort((a, b) => new Date(b.last_seen).getTime() - new Date(a.last_seen).getTime());
+  const fetchData = React.useCallback(async () => {
+    try {
+      if (inFlightRef.current) return;
+      inFlightRef.current = true;
 
-      setRows(merged);
-      lastRowsRef.current = merged;
+      setError(null);
+      const token = sessionStorage.getItem('accessToken') || '';
+      const res = await axios.get(`${ADMIN_SERVICE_URL}/live/sessions/active?cutoff=5`, {
+        headers: { Authorization: 'Bearer ' + token },
+      });
+      applySnapshot(Array.isArray(res.data) ? res.data : []);
     } catch {
       setError('Nie udało się pobrać sesji.');
       setRows([]);
-      lastRowsRef.current = [];
+      liveRef.current.clear();
       ghostsRef.current.clear();
     } finally {
       setLoading(false);
       inFlightRef.current = false;
     }
-  }, []);
+  }, [applySnapshot]);
 
+  // The page listens to the stream of session events and polls the list
+  // every second only while the stream is down, trying to reconnect in the
+  // meantime.
   React.useEffect(() => {
     let alive = true;
+    let controller: AbortController | null = null;
+    let retryTimer: number | null = null;
 
     const tick = async () => {
-      if (!alive) return;
+      if (!alive || !pollingRef.current) return;
       await fetchData();
-      if (!alive) return;
+      if (!alive || !pollingRef.current) return;
       timerRef.current = window.setTimeout(tick, 1000);
     };
 
-    tick();
+    const startPolling = () => {
+      if (pollingRef.current) return;
+      pollingRef.current = true;
+      tick();
+    };
 
-    return () => {
-      alive = false;
+    const stopPolling = () => {
+      pollingRef.current = false;
       if (timerRef.current) {
         clearTimeout(timerRef.current);
         timerRef.current = null;
       }
     };
-  }, [fetchData]);
+
+    const connect = async () => {
+      controller = new AbortController();
+      const token = sessionStorage.getItem('accessToken') || '';
+      try {
+        await readEventStream(
+          `${ADMIN_SERVICE_URL}/live/sessions/events?cutoff=5`,
+          token,
+          controller.signal,
+          (name, data) => {
+            if (name === 'snapshot') {
+              stopPolling();
+              setError(null);
+              setLoading(false);
+              applySnapshot(Array.isArray(data) ? data : []);
+            } else {
+              applyEvent(name, data as SessionEvent);
+            }
+          }
+        );
+      } catch {
+        // fall through to polling
+      }
+      if (!alive) return;
+      startPolling();
+      retryTimer = window.setTimeout(connect, STREAM_RETRY_MS);
+    };
+
+    connect();
+    // keeps the idle times current and lets ghosts expire between events
+    const renderTimer = window.setInterval(() => {
+      if (!pollingRef.current) render();
+    }, 1000);
+
+    return () => {
+      alive = false;
+      controller?.abort();
+      stopPolling();
+      if (retryTimer) clearTimeout(retryTimer);
+      clearInterval(renderTimer);
+    };
+  }, [fetchData, applySnapshot, applyEvent, render]);
 
   const handleViewDetails = async (userId: number) => {
     try {
@@ -2,14 +2,17 @@ import type { NextApiRequest, NextApiResponse } from 'next';
 import axios, { AxiosError } from 'axios';
 
 type LeaderboardRow = {
-  user_id: number;
-  education: string | null;
-  country: string | null;
+  rank: number;
+  display_name: string;
   total_answers: number;
   correct_answers: number;
   accuracy: number; // 0..1
+  score: number; // Wilson lower bound, 0..1
 };
 
+// filters passed through to the stats service as they are
+const FORWARDED = ['limit', 'minAnswers', 'scope', 'country', 'testCode', 'window'];
+
 type ApiErrorBody = { error: string; detail?: unknown };
 
 export default async function handler(
@@ -17,15 +20,17 @@ export default async function handler(
   res: NextApiResponse<LeaderboardRow[] | ApiErrorBody>
 ) {
   try {
-    const { limit = '100', minAnswers = '10' } = req.query;
     const base = process.env.STATS_SERVICE_URL;
     if (!base) {
       return res.status(500).json({ error: 'STATS_SERVICE_URL is not set' });
     }
 
-    const url = `${base}/stats/leaderboard?limit=${encodeURIComponent(
-      String(limit)
-    )}&minAnswers=${encodeURIComponent(String(minAnswers))}`;
+    const params = new URLSearchParams();
+    for (const key of FORWARDED) {
+      const v = req.query[key];
+      if (typeof v === 'string' && v !== '') params.set(key, v);
+    }
+    const url = `${base}/stats/leaderboard?${params.toString()}`;
 
     const r = await axios.get<LeaderboardRow[]>(url, {
       // jeśli VerifyToken bazuje na cookie, przepuść je dalej:
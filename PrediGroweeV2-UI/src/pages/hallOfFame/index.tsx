@@ -2,12 +2,14 @@ import TopNavBar from '@/components/ui/TopNavBar/TopNavBar';
 import {
   Alert,
   Box,
+  Button,
   Card,
   CardContent,
   CardHeader,
   Chip,
   IconButton,
   LinearProgress,
+  MenuItem,
   Paper,
   Stack,
   Table,
@@ -24,18 +26,22 @@ import ArrowBackIcon from '@mui/icons-material/ArrowBack';
 import Link from 'next/link';
 import React from 'react';
 import axios from 'axios';
-import AuthClient from '@/Clients/AuthClient';
-import { AUTH_SERVICE_URL } from '@/Envs';
+import StatsClient from '@/Clients/StatsClient';
+import { STATS_SERVICE_URL } from '@/Envs';
+import { LeaderboardStatus } from '@/types';
 
 type Row = {
-  user_id: number;
-  education: string | null;
-  country: string | null;
+  rank: number;
+  display_name: string;
   total_answers: number;
   correct_answers: number;
   accuracy: number; // 0..1
+  score: number; // Wilson lower bound, 0..1
 };
 
+type Scope = 'global' | 'country' | 'test';
+type Period = 'all' | 'week' | 'month';
+
 function pct(n: number) {
   if (!Number.isFinite(n)) return '0.0%';
   return `${(n * 100).toFixed(1)}%`;
@@ -75,96 +81,180 @@ const AccuracyCell: React.FC<{ value: number }> = ({ value }) => {
   );
 };
 
+// JoinCard lets a signed-in user list themselves under a display name, or
+// leave the leaderboard. Nobody is listed without joining.
+const JoinCard: React.FC<{
+  status: LeaderboardStatus;
+  onChange: (status: LeaderboardStatus) => void;
+}> = ({ status, onChange }) => {
+  const [name, setName] = React.useState(status.participant?.displayName ?? '');
+  const [saving, setSaving] = React.useState(false);
+  const [error, setError] = React.useState<string | null>(null);
+
+  const join = async () => {
+    setSaving(true);
+    setError(null);
+    try {
+      const client = new StatsClient(STATS_SERVICE_URL);
+      await client.joinLeaderboard(name.trim());
+      onChange(await client.getLeaderboardStatus());
+    } catch (e) {
+      setError((e as Error).message);
+    } finally {
+      setSaving(false);
+    }
+  };
+
+  const leave = async () => {
+    setSaving(true);
+    setError(null);
+    try {
+      await new StatsClient(STATS_SERVICE_URL).leaveLeaderboard();
+      onChange({ optedIn: false, participant: null });
+    } catch (e) {
+      setError((e as Error).message);
+    } finally {
+      setSaving(false);
+    }
+  };
+
+  const current = status.participant?.displayName;
+  return (
+    <Card sx={{ mb: 2 }}>
+      <CardHeader
+        title={status.optedIn ? `You are listed as ${current}` : 'Join the Hall of Fame'}
+        subheader={
+          status.optedIn
+            ? 'Only your display name and answer counts are shown.'
+            : 'Only users who join are listed, under a display name of their choice.'
+        }
+      />
+      <CardContent>
+        {error && (
+          <Alert severity="error" sx={{ mb: 2 }}>
+            {error}
+          </Alert>
+        )}
+        <Stack direction={{ xs: 'column', sm: 'row' }} spacing={2} alignItems="center">
+          <TextField
+            label="Display name"
+            value={name}
+            onChange={(e) => setName(e.target.value)}
+            helperText="3-32 letters, digits, spaces or . _ -"
+            inputProps={{ maxLength: 32 }}
+            fullWidth
+          />
+          <Button
+            variant="contained"
+            onClick={join}
+            disabled={saving || name.trim().length < 3 || name.trim() === current}
+          >
+            {status.optedIn ? 'Rename' : 'Join'}
+          </Button>
+          {status.optedIn && (
+            <Button color="error" onClick={leave} disabled={saving}>
+              Leave
+            </Button>
+          )}
+        </Stack>
+      </CardContent>
+    </Card>
+  );
+};
+
 const HallOfFamePage = () => {
   const [rows, setRows] = React.useState<Row[]>([]);
   const [error, setError] = React.useState<string | null>(null);
   const [loading, setLoading] = React.useState(true);
 
+  // filters sent to the stats service
+  const [scope, setScope] = React.useState<Scope>('global');
+  const [country, setCountry] = React.useState('');
+  const [testCode, setTestCode] = React.useState('');
+  const [period, setPeriod] = React.useState<Period>('all');
+  const [minAnswers, setMinAnswers] = React.useState<number>(10);
+
   // UI state
   const [q, setQ] = React.useState('');
-  const [minAnswers, setMinAnswers] = React.useState<number>(10);
-  const [sortKey, setSortKey] = React.useState<'rank' | 'answers' | 'accuracy'>('accuracy');
-  const [sortDir, setSortDir] = React.useState<'asc' | 'desc'>('desc');
+  const [sortKey, setSortKey] = React.useState<'rank' | 'answers' | 'accuracy'>('rank');
+  const [sortDir, setSortDir] = React.useState<'asc' | 'desc'>('asc');
 
-  const [myId, setMyId] = React.useState<number | null>(null);
+  // null until known, and for visitors who are not signed in
+  const [status, setStatus] = React.useState<LeaderboardStatus | null>(null);
   React.useEffect(() => {
-    const fetchMe = async () => {
-      try {
-        const auth = new AuthClient(AUTH_SERVICE_URL);
-        const me = await auth.getUser(); // { id, ... }
-        if (me?.id != null) setMyId(me.id as number);
-      } catch {
-        setMyId(null);
-      }
-    };
-    fetchMe();
+    if (!sessionStorage.getItem('accessToken')) return;
+    new StatsClient(STATS_SERVICE_URL)
+      .getLeaderboardStatus()
+      .then(setStatus)
+      .catch(() => setStatus(null));
   }, []);
+  const myName = status?.optedIn ? status.participant?.displayName ?? null : null;
 
-  const load = React.useCallback(async () => {
-    setLoading(true);
-    setError(null);
-    try {
-      const resp = await axios.get<Row[]>('/api/stats/leaderboard', {
-        params: { limit: 1000, minAnswers },
-      });
-      setRows(resp.data || []);
-    } catch {
-      setError('Failed to load leaderboard');
-    } finally {
+  const scopeValue = scope === 'country' ? country.trim() : scope === 'test' ? testCode.trim() : '';
+  const needsValue = scope !== 'global' && scopeValue === '';
+
+  React.useEffect(() => {
+    if (needsValue) {
+      setRows([]);
       setLoading(false);
+      return;
     }
-  }, [minAnswers]);
+    const params: Record<string, string | number> = {
+      limit: 1000,
+      minAnswers,
+      scope,
+      window: period,
+    };
+    if (scope === 'country') params.country = scopeValue;
+    if (scope === 'test') params.testCode = scopeValue;
 
-  React.useEffect(() => {
-    load();
-  }, [load]);
+    let cancelled = false;
+    // wait for the user to stop typing a country or test code
+    const timer = setTimeout(async () => {
+      setLoading(true);
+      setError(null);
+      try {
+        const resp = await axios.get<Row[]>('/api/stats/leaderboard', { params });
+        if (!cancelled) setRows(resp.data || []);
+      } catch {
+        if (!cancelled) setError('Failed to load leaderboard');
+      } finally {
+        if (!cancelled) setLoading(false);
+      }
+    }, 300);
+    return () => {
+      cancelled = true;
+      clearTimeout(timer);
+    };
+    // status is a dependency so that joining or leaving shows at once
+  }, [scope, scopeValue, needsValue, period, minAnswers, status]);
 
   const filtered = React.useMemo(() => {
     const s = q.trim().toLowerCase();
-    const base = rows.filter((r) => r.total_answers >= minAnswers);
-    if (!s) return base;
-    return base.filter((r) => {
-      const idMatch = r.user_id.toString().includes(s);
-      const eduMatch = (r.education || '').toLowerCase().includes(s);
-      const ctryMatch = (r.country || '').toLowerCase().includes(s);
-      return idMatch || eduMatch || ctryMatch;
-    });
-  }, [rows, q, minAnswers]);
+    if (!s) return rows;
+    return rows.filter((r) => r.display_name.toLowerCase().includes(s));
+  }, [rows, q]);
 
   const sorted = React.useMemo(() => {
     const arr = [...filtered];
+    const dir = sortDir === 'asc' ? 1 : -1;
     arr.sort((a, b) => {
       switch (sortKey) {
-        case 'answers': {
-          const av = a.total_answers;
-          const bv = b.total_answers;
-          if (av === bv) return 0;
-          const res = av < bv ? -1 : 1;
-          return sortDir === 'asc' ? res : -res;
-        }
-        case 'accuracy': {
-          const av = a.accuracy;
-          const bv = b.accuracy;
-          if (av === bv) {
-            // tie-breaker: total answers desc
-            const ta = a.total_answers - b.total_answers;
-            if (ta !== 0) return sortDir === 'asc' ? ta : -ta;
-            return 0;
-          }
-          const res = av < bv ? -1 : 1;
-          return sortDir === 'asc' ? res : -res;
-        }
+        case 'answers':
+          return dir * (a.total_answers - b.total_answers) || a.rank - b.rank;
+        case 'accuracy':
+          return dir * (a.accuracy - b.accuracy) || a.rank - b.rank;
         case 'rank':
         default:
-          return 0; // rank = pozycja po sortowaniu accuracy; nie sortujemy dodatkowo
+          return dir * (a.rank - b.rank);
       }
     });
     return arr;
   }, [filtered, sortKey, sortDir]);
 
   const myIndex = React.useMemo(
-    () => (myId ? sorted.findIndex((r) => r.user_id === myId) : -1),
-    [sorted, myId]
+    () => (myName ? sorted.findIndex((r) => r.display_name === myName) : -1),
+    [sorted, myName]
   );
 
   const top10 = React.useMemo(() => sorted.slice(0, 10), [sorted]);
@@ -183,22 +273,6 @@ const HallOfFamePage = () => {
     }
   };
 
-  const rankOf = (userId: number) => {
-    const idx = sorted.findIndex((r) => r.user_id === userId);
-    return idx >= 0 ? idx + 1 : null;
-  };
-
-  if (loading) {
-    return (
-      <Box>
-        <TopNavBar />
-        <Box display="flex" justifyContent="center" alignItems="center" minHeight="400px">
-          <Typography>Loading...</Typography>
-        </Box>
-      </Box>
-    );
-  }
-
   return (
     <Box>
       <TopNavBar />
@@ -216,19 +290,53 @@ const HallOfFamePage = () => {
           </Alert>
         )}
 
+        {status && <JoinCard status={status} onChange={setStatus} />}
+
         <Card>
-          <CardHeader title="Top users by accuracy" />
+          <CardHeader
+            title="Top users"
+            subheader="Ranked by accuracy, weighted so that more answers count for more"
+          />
           <CardContent>
             <Stack direction={{ xs: 'column', md: 'row' }} spacing={2} mb={2} alignItems="center">
               <TextField
-                label="Search (user id, education, country)"
-                value={q}
-                onChange={(e) => {
-                  setQ(e.target.value);
-                }}
-                fullWidth
-              />
-
+                select
+                label="Scope"
+                value={scope}
+                onChange={(e) => setScope(e.target.value as Scope)}
+                sx={{ minWidth: 150 }}
+              >
+                <MenuItem value="global">Everyone</MenuItem>
+                <MenuItem value="country">Country</MenuItem>
+                <MenuItem value="test">Test</MenuItem>
+              </TextField>
+              {scope === 'country' && (
+                <TextField
+                  label="Country"
+                  value={country}
+                  onChange={(e) => setCountry(e.target.value)}
+                  sx={{ minWidth: 180 }}
+                />
+              )}
+              {scope === 'test' && (
+                <TextField
+                  label="Test code"
+                  value={testCode}
+                  onChange={(e) => setTestCode(e.target.value.toUpperCase())}
+                  sx={{ minWidth: 180 }}
+                />
+              )}
+              <TextField
+                select
+                label="Period"
+                value={period}
+                onChange={(e) => setPeriod(e.target.value as Period)}
+                sx={{ minWidth: 150 }}
+              >
+                <MenuItem value="all">All time</MenuItem>
+                <MenuItem value="month">This month</MenuItem>
+                <MenuItem value="week">This week</MenuItem>
+              </TextField>
               <TextField
                 label="Min answers"
                 type="number"
@@ -242,10 +350,19 @@ const HallOfFamePage = () => {
                   const n = v === '' ? 0 : Number.parseInt(v, 10);
                   setMinAnswers(Number.isFinite(n) ? Math.max(0, n) : 0);
                 }}
-                sx={{ width: 180 }}
+                sx={{ width: 140 }}
+              />
+              <TextField
+                label="Search by name"
+                value={q}
+                onChange={(e) => {
+                  setQ(e.target.value);
+                }}
+                fullWidth
               />
             </Stack>
 
+            {loading && <LinearProgress sx={{ mb: 1 }} />}
             <TableContainer component={Paper} sx={{ borderRadius: 2 }}>
               <Table size="small">
                 <TableHead>
@@ -254,8 +371,6 @@ const HallOfFamePage = () => {
                       Rank {sortKey === 'rank' ? (sortDir === 'asc' ? '▲' : '▼') : ''}
                     </TableCell>
                     <TableCell>User</TableCell>
-                    <TableCell>Education</TableCell>
-                    <TableCell>Country</TableCell>
                     <TableCell
                       align="right"
                       onClick={() => toggleSort('answers')}
@@ -274,14 +389,13 @@ const HallOfFamePage = () => {
                   </TableRow>
                 </TableHead>
                 <TableBody>
-                  {rowsToShow.map((r, i) => {
-                    const rank = rankOf(r.user_id)!; // istnieje, bo r pochodzi z `sorted`
-                    const medal = medalForRank(rank);
-                    const isMe = myId && r.user_id === myId;
+                  {rowsToShow.map((r) => {
+                    const medal = medalForRank(r.rank);
+                    const isMe = myName !== null && r.display_name === myName;
 
                     return (
                       <TableRow
-                        key={`${r.user_id}-${i}`}
+                        key={r.display_name}
                         hover
                         sx={{
                           ...(isMe
@@ -292,17 +406,15 @@ const HallOfFamePage = () => {
                         <TableCell>
                           <Typography fontWeight={600}>
                             {medal ? `${medal} ` : ''}
-                            {rank}
+                            {r.rank}
                           </Typography>
                         </TableCell>
                         <TableCell>
                           <Typography variant="body2">
-                            <strong>#{r.user_id}</strong>
+                            <strong>{r.display_name}</strong>
                             {isMe ? ' (you)' : ''}
                           </Typography>
                         </TableCell>
-                        <TableCell>{r.education || '—'}</TableCell>
-                        <TableCell>{r.country || '—'}</TableCell>
                         <TableCell align="right">
                           <Tooltip title="Total answers">
                             <Chip
@@ -333,15 +445,17 @@ const HallOfFamePage = () => {
                   })}
                   {myIndex >= 10 && (
                     <TableRow>
-                      <TableCell colSpan={7} align="center" sx={{ opacity: 0.6, py: 1 }}>
+                      <TableCell colSpan={5} align="center" sx={{ opacity: 0.6, py: 1 }}>
                         …
                       </TableCell>
                     </TableRow>
                   )}
-                  {rowsToShow.length === 0 && (
+                  {!loading && rowsToShow.length === 0 && (
                     <TableRow>
-                      <TableCell colSpan={7} align="center">
-                        No data
+                      <TableCell colSpan={5} align="center">
+                        {needsValue
+                          ? `Enter a ${scope === 'country' ? 'country' : 'test code'} to see its leaderboard`
+                          : 'No data'}
                       </TableCell>
                     </TableRow>
                   )}
@@ -179,6 +179,11 @@ export type FavoriteCase = {
   note_updated_at?: string | null;
 };
 
+export type LeaderboardStatus = {
+  optedIn: boolean;
+  participant: { displayName: string; optedInAt: string } | null;
+};
+
 export type Settings = {
   name: string;
   value: string;
@@ -3,10 +3,14 @@ package clients
 import (
 	"admin/internal/models"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"go.uber.org/zap"
+	"io"
 	"net/http"
+	"net/url"
+	"strings"
 )
 
 type QuizClient interface {
@@ -29,11 +33,21 @@ type QuizClient interface {
 	UnapproveUser(userID int) error
 	ListApprovedUsers() ([]int, error)
 	ListActiveSessions(cutoff int) ([]ActiveSession, error)
+	StreamActiveSessions(ctx context.Context, cutoff int) (io.ReadCloser, error)
 	ListSessionsByTestCode(code string) ([]TestSession, error)
 	GetPendingReportsCount() (map[string]int, error)
 	GetReports() ([]models.CaseReport, error)
 	DeleteReport(id string) error
 	SetReportNote(id string, note string) error
+	GetDifficultySummaries() ([]models.QuestionDifficultySummary, error)
+	ListQuestionReviews(status string) ([]models.QuestionReview, error)
+	EnqueueQuestionReviews(candidates []models.QuestionReviewCandidate) (int, error)
+	UpdateQuestionReview(id string, update models.QuestionReviewUpdate) (models.QuestionReview, error)
+	ListLTIPlatforms() ([]models.LTIPlatform, error)
+	CreateLTIPlatform(platform models.LTIPlatform) (models.LTIPlatform, error)
+	DeleteLTIPlatform(id string) error
+	ExportQuestionsQTI(ctx context.Context, query url.Values) (io.ReadCloser, error)
+	ImportQuestionsQTI(ctx context.Context, query url.Values, contentType string, body io.Reader) (models.QTIImportResult, error)
 }
 
 type QuizRestClient struct {
@@ -444,6 +458,30 @@ func (c *QuizRestClient) ListApprovedUsers() ([]int, error) {
 }
 
 
+// StreamActiveSessions opens the quiz service's Server-Sent Events stream of
+// active sessions. The caller reads it until ctx is done and closes it.
+func (c *QuizRestClient) StreamActiveSessions(ctx context.Context, cutoff int) (io.ReadCloser, error) {
+	if cutoff <= 0 {
+		cutoff = 5
+	}
+	req, err := c.NewRequestWithAuth("GET", fmt.Sprintf("/sessions/active/events?cutoff=%d", cutoff), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "text/event-stream")
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
 func (c *QuizRestClient) ListActiveSessions(cutoff int) ([]ActiveSession, error) {
 	if cutoff <= 0 {
 		cutoff = 5
@@ -583,3 +621,234 @@ func (c *QuizRestClient) SetReportNote(id string, note string) error {
 	}
 	return nil
 }
+
+func (c *QuizRestClient) GetDifficultySummaries() ([]models.QuestionDifficultySummary, error) {
+	req, err := c.NewRequestWithAuth("GET", "/difficulty/summary", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var summaries []models.QuestionDifficultySummary
+	if err := json.NewDecoder(resp.Body).Decode(&summaries); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return summaries, nil
+}
+
+func (c *QuizRestClient) ListQuestionReviews(status string) ([]models.QuestionReview, error) {
+	req, err := c.NewRequestWithAuth("GET", "/reviews?status="+url.QueryEscape(status), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusBadRequest {
+		return nil, ErrInvalidParameter
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var reviews []models.QuestionReview
+	if err := json.NewDecoder(resp.Body).Decode(&reviews); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return reviews, nil
+}
+
+func (c *QuizRestClient) EnqueueQuestionReviews(candidates []models.QuestionReviewCandidate) (int, error) {
+	req, err := c.NewRequestWithAuth("POST", "/reviews", candidates)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var payload struct {
+		Opened int `json:"opened"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return payload.Opened, nil
+}
+
+func (c *QuizRestClient) UpdateQuestionReview(id string, update models.QuestionReviewUpdate) (models.QuestionReview, error) {
+	req, err := c.NewRequestWithAuth("PATCH", fmt.Sprintf("/reviews/%s", id), update)
+	if err != nil {
+		return models.QuestionReview{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return models.QuestionReview{}, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusBadRequest:
+		return models.QuestionReview{}, ErrInvalidParameter
+	case http.StatusNotFound:
+		return models.QuestionReview{}, ErrNotFound
+	case http.StatusConflict:
+		return models.QuestionReview{}, ErrConflict
+	default:
+		return models.QuestionReview{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var review models.QuestionReview
+	if err := json.NewDecoder(resp.Body).Decode(&review); err != nil {
+		return models.QuestionReview{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return review, nil
+}
+
+func (c *QuizRestClient) ListLTIPlatforms() ([]models.LTIPlatform, error) {
+	req, err := c.NewRequestWithAuth("GET", "/lti/platforms", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var platforms []models.LTIPlatform
+	if err := json.NewDecoder(resp.Body).Decode(&platforms); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return platforms, nil
+}
+
+func (c *QuizRestClient) CreateLTIPlatform(platform models.LTIPlatform) (models.LTIPlatform, error) {
+	req, err := c.NewRequestWithAuth("POST", "/lti/platforms", platform)
+	if err != nil {
+		return models.LTIPlatform{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return models.LTIPlatform{}, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusCreated:
+	case http.StatusBadRequest:
+		return models.LTIPlatform{}, ErrInvalidParameter
+	case http.StatusConflict:
+		return models.LTIPlatform{}, ErrConflict
+	default:
+		return models.LTIPlatform{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var created models.LTIPlatform
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return models.LTIPlatform{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return created, nil
+}
+
+func (c *QuizRestClient) DeleteLTIPlatform(id string) error {
+	req, err := c.NewRequestWithAuth("DELETE", fmt.Sprintf("/lti/platforms/%s", url.PathEscape(id)), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		return nil
+	case http.StatusBadRequest:
+		return ErrInvalidParameter
+	case http.StatusNotFound:
+		return ErrNotFound
+	default:
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+}
+
+// ExportQuestionsQTI opens the QTI package of the questions; query carries
+// the ids and version parameters. Errors wrap the quiz service's message.
+func (c *QuizRestClient) ExportQuestionsQTI(ctx context.Context, query url.Values) (io.ReadCloser, error) {
+	req, err := c.NewRequestWithAuth("GET", "/questions/qti?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req = req.WithContext(ctx)
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusBadRequest:
+		defer resp.Body.Close()
+		return nil, fmt.Errorf("%w: %s", ErrInvalidParameter, errorMessage(resp.Body))
+	case http.StatusNotFound:
+		defer resp.Body.Close()
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, errorMessage(resp.Body))
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+}
+
+// ImportQuestionsQTI sends a QTI package or item to the quiz service; query
+// carries the case_id, prediction_age and group defaults.
+func (c *QuizRestClient) ImportQuestionsQTI(ctx context.Context, query url.Values, contentType string, body io.Reader) (models.QTIImportResult, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", c.addr+"/questions/qti?"+query.Encode(), body)
+	if err != nil {
+		return models.QTIImportResult{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Api-Key", c.apiKey)
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return models.QTIImportResult{}, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge:
+		return models.QTIImportResult{}, fmt.Errorf("%w: %s", ErrInvalidParameter, errorMessage(resp.Body))
+	default:
+		return models.QTIImportResult{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var result models.QTIImportResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return models.QTIImportResult{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return result, nil
+}
+
+// errorMessage reads the text of an http.Error response.
+func errorMessage(body io.Reader) string {
+	b, _ := io.ReadAll(io.LimitReader(body, 1000))
+	return strings.TrimSpace(string(b))
+}
@@ -3,10 +3,14 @@ package clients
 import (
 	"admin/internal/models"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"go.uber.org/zap"
+	"io"
 	"net/http"
+	"net/url"
 	"strings"
 )
 
@@ -15,7 +19,11 @@ type StatsClient interface {
 	GetAllResponses() ([]models.QuestionResponse, error)
 	GetStatsForQuestion(id string) (models.QuestionStats, error)
 	GetStatsForAllQuestions() ([]models.QuestionStats, error)
-	GetActivityStats() ([]models.ActivityStats, error)
+	GetActivityStats(tz string) ([]models.ActivityStats, error)
+	GetActivityHeatmap(tz, country string) (models.ActivityHeatmap, error)
+	GetActivityByCountry(tz string) ([]models.CountryActivity, error)
+	GetDeviceStats() ([]models.DeviceStats, error)
+	GetResolutionStats(minAnswers string) ([]models.DeviceStats, error)
 	GetSummary() (models.StatsSummary, error)
 	GetSurvey(id string) (models.SurveyResponse, error)
 	GetAllSurveys() ([]models.SurveyResponse, error)
@@ -24,8 +32,30 @@ type StatsClient interface {
 	DeleteUserResponses(id string) error
 	GetAllUsersStats() ([]models.UserQuizStats, error)
 	GetSessionsAccuracy(sessionIDs []int) ([]models.SessionAccuracy, error)
+	EachResearchRow(ctx context.Context, fn func(models.ResearchRow) error) error
+	GetQuestionTimingStats() ([]models.TimingStats, error)
+	GetUserTimingStats() ([]models.TimingStats, error)
+	GetTimeBucketStats() ([]models.TimeBucketStats, error)
+	GetSuspiciousSessions(query string) ([]models.SuspiciousSession, error)
+	ExcludeSession(sessionID string, exclusion models.SessionExclusion) error
+	RestoreSession(sessionID string) error
+	GetExclusions(scope string) ([]models.Exclusion, error)
+	CreateExclusion(exclusion models.Exclusion) (models.Exclusion, error)
+	DeleteExclusion(id string) error
+	GetParticipation() (models.ParticipationReport, error)
+	GetIRTItemParams() ([]models.IRTItemParams, error)
+	GetIRTItemParamsForQuestion(id string) (models.IRTItemParams, error)
+	GetIRTAbilities() ([]models.IRTAbility, error)
+	GetIRTUserAbility(id string) (models.IRTAbility, error)
+	GetLatestIRTRun() (models.IRTRun, error)
+	TriggerIRTRun() (models.IRTRun, error)
+	GetQuestionDiscrimination() ([]models.QuestionDiscrimination, error)
 }
 
+var ErrNotFound = errors.New("not found")
+var ErrInvalidParameter = errors.New("invalid parameter")
+var ErrConflict = errors.New("conflict")
+
 type StatsRestClient struct {
 	addr   string
 	apiKey string
@@ -148,8 +178,8 @@ func (c *StatsRestClient) GetStatsForAllQuestions() ([]models.QuestionStats, err
 	return stats, nil
 }
 
-func (c *StatsRestClient) GetActivityStats() ([]models.ActivityStats, error) {
-	req, err := c.NewRequestWithAuth("GET", "/activity", nil)
+func (c *StatsRestClient) GetActivityStats(tz string) ([]models.ActivityStats, error) {
+	req, err := c.NewRequestWithAuth("GET", "/activity?tz="+url.QueryEscape(tz), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -158,6 +188,9 @@ func (c *StatsRestClient) GetActivityStats() ([]models.ActivityStats, error) {
 		return nil, fmt.Errorf("failed to make request: %w", err)
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusBadRequest {
+		return nil, ErrInvalidParameter
+	}
 	if resp.StatusCode != http.StatusOK {
 		return []models.ActivityStats{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
@@ -323,3 +356,286 @@ func (c *StatsRestClient) GetSessionsAccuracy(sessionIDs []int) ([]models.Sessio
 	}
 	return out, nil
 }
+
+// EachResearchRow calls fn with every row of the research dataset as stats
+// streams it, and stops at fn's first error. A stream cut short is an error.
+func (c *StatsRestClient) EachResearchRow(ctx context.Context, fn func(models.ResearchRow) error) error {
+	req, err := c.NewRequestWithAuth("GET", "/research/rows", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.MakeRequest(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var row models.ResearchRow
+		err := dec.Decode(&row)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to decode research row: %w", err)
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *StatsRestClient) GetQuestionTimingStats() ([]models.TimingStats, error) {
+	var stats []models.TimingStats
+	err := c.getJSON("/timing/questions", &stats)
+	return stats, err
+}
+
+func (c *StatsRestClient) GetUserTimingStats() ([]models.TimingStats, error) {
+	var stats []models.TimingStats
+	err := c.getJSON("/timing/users", &stats)
+	return stats, err
+}
+
+func (c *StatsRestClient) GetTimeBucketStats() ([]models.TimeBucketStats, error) {
+	var stats []models.TimeBucketStats
+	err := c.getJSON("/timing/buckets", &stats)
+	return stats, err
+}
+
+// GetSuspiciousSessions forwards the raw query string so detection thresholds
+// stay defined in one place, the stats service.
+func (c *StatsRestClient) GetSuspiciousSessions(query string) ([]models.SuspiciousSession, error) {
+	path := "/flags"
+	if query != "" {
+		path += "?" + query
+	}
+	var sessions []models.SuspiciousSession
+	err := c.getJSON(path, &sessions)
+	return sessions, err
+}
+
+func (c *StatsRestClient) ExcludeSession(sessionID string, exclusion models.SessionExclusion) error {
+	req, err := c.NewRequestWithAuth("POST", fmt.Sprintf("/sessions/%s/exclude", sessionID), exclusion)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.MakeRequest(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (c *StatsRestClient) RestoreSession(sessionID string) error {
+	req, err := c.NewRequestWithAuth("DELETE", fmt.Sprintf("/sessions/%s/exclude", sessionID), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.MakeRequest(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (c *StatsRestClient) GetExclusions(scope string) ([]models.Exclusion, error) {
+	path := "/exclusions"
+	if scope != "" {
+		path += "?scope=" + url.QueryEscape(scope)
+	}
+	var exclusions []models.Exclusion
+	err := c.getJSON(path, &exclusions)
+	return exclusions, err
+}
+
+func (c *StatsRestClient) CreateExclusion(exclusion models.Exclusion) (models.Exclusion, error) {
+	req, err := c.NewRequestWithAuth("POST", "/exclusions", exclusion)
+	if err != nil {
+		return models.Exclusion{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.MakeRequest(req)
+	if err != nil {
+		return models.Exclusion{}, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return models.Exclusion{}, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return models.Exclusion{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var created models.Exclusion
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return models.Exclusion{}, fmt.Errorf("failed to decode response body: %w", err)
+	}
+	return created, nil
+}
+
+func (c *StatsRestClient) DeleteExclusion(id string) error {
+	req, err := c.NewRequestWithAuth("DELETE", fmt.Sprintf("/exclusions/%s", id), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.MakeRequest(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (c *StatsRestClient) GetParticipation() (models.ParticipationReport, error) {
+	var report models.ParticipationReport
+	err := c.getJSON("/participation", &report)
+	return report, err
+}
+
+func (c *StatsRestClient) GetActivityHeatmap(tz, country string) (models.ActivityHeatmap, error) {
+	q := url.Values{}
+	q.Set("tz", tz)
+	if country != "" {
+		q.Set("country", country)
+	}
+	var heatmap models.ActivityHeatmap
+	err := c.getJSON("/activity/heatmap?"+q.Encode(), &heatmap)
+	return heatmap, err
+}
+
+func (c *StatsRestClient) GetActivityByCountry(tz string) ([]models.CountryActivity, error) {
+	var countries []models.CountryActivity
+	err := c.getJSON("/activity/countries?tz="+url.QueryEscape(tz), &countries)
+	return countries, err
+}
+
+func (c *StatsRestClient) GetDeviceStats() ([]models.DeviceStats, error) {
+	var stats []models.DeviceStats
+	err := c.getJSON("/devices", &stats)
+	return stats, err
+}
+
+func (c *StatsRestClient) GetResolutionStats(minAnswers string) ([]models.DeviceStats, error) {
+	path := "/devices/resolutions"
+	if minAnswers != "" {
+		path += "?minAnswers=" + url.QueryEscape(minAnswers)
+	}
+	var stats []models.DeviceStats
+	err := c.getJSON(path, &stats)
+	return stats, err
+}
+
+func (c *StatsRestClient) getJSON(path string, out interface{}) error {
+	req, err := c.NewRequestWithAuth("GET", path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.MakeRequest(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusBadRequest {
+		return ErrInvalidParameter
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response body: %w", err)
+	}
+	return nil
+}
+
+func (c *StatsRestClient) GetIRTItemParams() ([]models.IRTItemParams, error) {
+	var items []models.IRTItemParams
+	if err := c.getJSON("/irt/questions", &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (c *StatsRestClient) GetIRTItemParamsForQuestion(id string) (models.IRTItemParams, error) {
+	var item models.IRTItemParams
+	if err := c.getJSON(fmt.Sprintf("/irt/questions/%s", id), &item); err != nil {
+		return models.IRTItemParams{}, err
+	}
+	return item, nil
+}
+
+func (c *StatsRestClient) GetIRTAbilities() ([]models.IRTAbility, error) {
+	var abilities []models.IRTAbility
+	if err := c.getJSON("/irt/users", &abilities); err != nil {
+		return nil, err
+	}
+	return abilities, nil
+}
+
+func (c *StatsRestClient) GetIRTUserAbility(id string) (models.IRTAbility, error) {
+	var ability models.IRTAbility
+	if err := c.getJSON(fmt.Sprintf("/irt/users/%s", id), &ability); err != nil {
+		return models.IRTAbility{}, err
+	}
+	return ability, nil
+}
+
+func (c *StatsRestClient) GetLatestIRTRun() (models.IRTRun, error) {
+	var run models.IRTRun
+	if err := c.getJSON("/irt/runs/latest", &run); err != nil {
+		return models.IRTRun{}, err
+	}
+	return run, nil
+}
+
+func (c *StatsRestClient) TriggerIRTRun() (models.IRTRun, error) {
+	req, err := c.NewRequestWithAuth("POST", "/irt/runs", nil)
+	if err != nil {
+		return models.IRTRun{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.MakeRequest(req)
+	if err != nil {
+		return models.IRTRun{}, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusConflict {
+		return models.IRTRun{}, ErrConflict
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		return models.IRTRun{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var run models.IRTRun
+	if err := json.NewDecoder(resp.Body).Decode(&run); err != nil {
+		return models.IRTRun{}, fmt.Errorf("failed to decode response body: %w", err)
+	}
+	return run, nil
+}
+
+func (c *StatsRestClient) GetQuestionDiscrimination() ([]models.QuestionDiscrimination, error) {
+	var stats []models.QuestionDiscrimination
+	if err := c.getJSON("/questions/discrimination", &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
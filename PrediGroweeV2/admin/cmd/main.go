@@ -3,12 +3,17 @@ package main
 import (
 	"admin/clients"
 	"admin/internal/api"
+	"admin/internal/quality"
+	"context"
 	_ "github.com/lib/pq"
 	"go.uber.org/zap"
 	"log"
 	"os"
+	"time"
 )
 
+const defaultQualityReviewInterval = 24 * time.Hour
+
 func main() {
 	// Initialize logger
 	var err error
@@ -26,6 +31,25 @@ func main() {
 	authClient := clients.NewRestAuthClient("http://auth:8080/auth", os.Getenv("INTERNAL_API_KEY"), logger)
 	statsClient := clients.NewStatsRestClient("http://stats:8080/stats", os.Getenv("INTERNAL_API_KEY"), logger)
 	quizClient := clients.NewQuizRestClient("http://quiz:8080/quiz", os.Getenv("INTERNAL_API_KEY"), logger)
-	apiServer := api.NewApiServer(":8080", logger, authClient, statsClient, quizClient)
+	qualityService := quality.NewService(quizClient, statsClient, logger)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	qualityService.Start(ctx, qualityReviewInterval(logger))
+	apiServer := api.NewApiServer(":8080", logger, authClient, statsClient, quizClient, qualityService)
 	apiServer.Run()
 }
+
+// qualityReviewInterval reads QUALITY_REVIEW_INTERVAL, e.g. "6h". "0"
+// disables scheduled flagging; it can still be run from the admin panel.
+func qualityReviewInterval(logger *zap.Logger) time.Duration {
+	v := os.Getenv("QUALITY_REVIEW_INTERVAL")
+	if v == "" {
+		return defaultQualityReviewInterval
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		logger.Warn("invalid QUALITY_REVIEW_INTERVAL, using default", zap.String("value", v), zap.Error(err))
+		return defaultQualityReviewInterval
+	}
+	return d
+}
@@ -4,9 +4,11 @@ import (
 	"admin/clients"
 	"admin/internal/handlers"
 	"admin/internal/middleware"
+	"admin/internal/quality"
 	"context"
 	"github.com/rs/cors"
 	"go.uber.org/zap"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -20,15 +22,17 @@ type ApiServer struct {
 	authClient  clients.AuthClient
 	statsClient clients.StatsClient
 	quizClient  clients.QuizClient
+	quality     *quality.Service
 }
 
-func NewApiServer(addr string, logger *zap.Logger, authClient clients.AuthClient, statsClient clients.StatsClient, quizClient clients.QuizClient) *ApiServer {
+func NewApiServer(addr string, logger *zap.Logger, authClient clients.AuthClient, statsClient clients.StatsClient, quizClient clients.QuizClient, quality *quality.Service) *ApiServer {
 	return &ApiServer{
 		addr:        addr,
 		logger:      logger,
 		authClient:  authClient,
 		statsClient: statsClient,
 		quizClient:  quizClient,
+		quality:     quality,
 	}
 }
 
@@ -42,13 +46,19 @@ func (a *ApiServer) Run() {
 		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Authorization", "Content-Type"},
 	})
+	// the live sessions stream only ends when its request context does, so
+	// shutting down cancels the base context of every request
+	baseCtx, cancelBase := context.WithCancel(context.Background())
+	defer cancelBase()
 	srv := &http.Server{
 		Addr:         a.addr,
 		Handler:      corsMiddleware.Handler(mux),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
+		BaseContext:  func(net.Listener) context.Context { return baseCtx },
 	}
+	srv.RegisterOnShutdown(cancelBase)
 
 	go func() {
 		a.logger.Info("Starting server on " + a.addr)
@@ -87,6 +97,18 @@ func (a *ApiServer) registerRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /admin/questions/{id}", middleware.VerifyAdmin(quizHandler.GetQuestion, a.authClient))
 	mux.HandleFunc("PATCH /admin/questions/{id}", middleware.VerifyAdmin(quizHandler.UpdateQuestion, a.authClient))
 
+	// qti export and import
+	qtiHandler := handlers.NewQTIHandler(a.logger, a.quizClient)
+	mux.HandleFunc("GET /admin/questions/qti", middleware.VerifyAdmin(qtiHandler.Export, a.authClient))
+	mux.HandleFunc("POST /admin/questions/qti", middleware.VerifyAdmin(middleware.AdminOnly(qtiHandler.Import), a.authClient))
+
+	// question quality and review queue
+	qualityHandler := handlers.NewQualityHandler(a.logger, a.quizClient, a.quality)
+	mux.HandleFunc("GET /admin/questions/quality", middleware.VerifyAdmin(qualityHandler.GetReport, a.authClient))
+	mux.HandleFunc("POST /admin/questions/quality/sync", middleware.VerifyAdmin(middleware.AdminOnly(qualityHandler.Sync), a.authClient))
+	mux.HandleFunc("GET /admin/questions/reviews", middleware.VerifyAdmin(qualityHandler.ListReviews, a.authClient))
+	mux.HandleFunc("PATCH /admin/questions/reviews/{id}", middleware.VerifyAdmin(middleware.AdminOnly(qualityHandler.UpdateReview), a.authClient))
+
 	mux.HandleFunc("POST /admin/quiz/approve", middleware.VerifyAdmin(quizHandler.Approve, a.authClient))
     mux.HandleFunc("POST /admin/quiz/unapprove", middleware.VerifyAdmin(quizHandler.Unapprove, a.authClient))
 
@@ -114,14 +136,56 @@ func (a *ApiServer) registerRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /admin/stats/questions/{questionId}", middleware.VerifyAdmin(statsHandler.GetStatsForQuestion, a.authClient))
 	mux.HandleFunc("GET /admin/stats/questions", middleware.VerifyAdmin(statsHandler.GetStatsForAllQuestions, a.authClient))
 	mux.HandleFunc("GET /admin/stats/activity", middleware.VerifyAdmin(statsHandler.GetActivityStats, a.authClient))
+	mux.HandleFunc("GET /admin/stats/activity/heatmap", middleware.VerifyAdmin(statsHandler.GetActivityHeatmap, a.authClient))
+	mux.HandleFunc("GET /admin/stats/activity/countries", middleware.VerifyAdmin(statsHandler.GetActivityByCountry, a.authClient))
+	mux.HandleFunc("GET /admin/stats/devices", middleware.VerifyAdmin(statsHandler.GetDeviceStats, a.authClient))
+	mux.HandleFunc("GET /admin/stats/devices/resolutions", middleware.VerifyAdmin(statsHandler.GetResolutionStats, a.authClient))
 	mux.HandleFunc("GET /admin/stats/grouped", middleware.VerifyAdmin(statsHandler.GetStatsGroupedBySurvey, a.authClient))
 	mux.HandleFunc("GET /admin/stats/users", middleware.VerifyAdmin(statsHandler.GetStatsForUsers, a.authClient))
 
+	// response times and guessing detection
+	timingHandler := handlers.NewTimingHandler(a.logger, a.statsClient)
+	mux.HandleFunc("GET /admin/stats/timing/questions", middleware.VerifyAdmin(timingHandler.GetQuestions, a.authClient))
+	mux.HandleFunc("GET /admin/stats/timing/users", middleware.VerifyAdmin(timingHandler.GetUsers, a.authClient))
+	mux.HandleFunc("GET /admin/stats/timing/buckets", middleware.VerifyAdmin(timingHandler.GetBuckets, a.authClient))
+	mux.HandleFunc("GET /admin/stats/flags", middleware.VerifyAdmin(timingHandler.GetFlags, a.authClient))
+	mux.HandleFunc("POST /admin/stats/sessions/{id}/exclude", middleware.VerifyAdmin(middleware.AdminOnly(timingHandler.ExcludeSession), a.authClient))
+	mux.HandleFunc("DELETE /admin/stats/sessions/{id}/exclude", middleware.VerifyAdmin(middleware.AdminOnly(timingHandler.RestoreSession), a.authClient))
+
+	// item response theory
+	irtHandler := handlers.NewIRTHandler(a.logger, a.statsClient)
+	mux.HandleFunc("GET /admin/stats/irt/questions", middleware.VerifyAdmin(irtHandler.GetQuestions, a.authClient))
+	mux.HandleFunc("GET /admin/stats/irt/users", middleware.VerifyAdmin(irtHandler.GetUsers, a.authClient))
+	mux.HandleFunc("GET /admin/stats/irt/users/{id}", middleware.VerifyAdmin(irtHandler.GetUser, a.authClient))
+	mux.HandleFunc("GET /admin/stats/irt/runs/latest", middleware.VerifyAdmin(irtHandler.GetLatestRun, a.authClient))
+	mux.HandleFunc("POST /admin/stats/irt/runs", middleware.VerifyAdmin(middleware.AdminOnly(irtHandler.TriggerRun), a.authClient))
+
+	// exclusions
+	exclusionsHandler := handlers.NewExclusionsHandler(a.logger, a.statsClient)
+	mux.HandleFunc("GET /admin/exclusions", middleware.VerifyAdmin(middleware.AdminOnly(exclusionsHandler.List), a.authClient))
+	mux.HandleFunc("POST /admin/exclusions", middleware.VerifyAdmin(middleware.AdminOnly(exclusionsHandler.Create), a.authClient))
+	mux.HandleFunc("DELETE /admin/exclusions/{id}", middleware.VerifyAdmin(middleware.AdminOnly(exclusionsHandler.Restore), a.authClient))
+
+	// recruitment analytics
+	analyticsHandler := handlers.NewAnalyticsHandler(a.logger, a.authClient, a.statsClient)
+	mux.HandleFunc("GET /admin/analytics/participation", middleware.VerifyAdmin(analyticsHandler.GetParticipation, a.authClient))
+
+	// lti platforms
+	ltiHandler := handlers.NewLTIHandler(a.logger, a.quizClient)
+	mux.HandleFunc("GET /admin/lti/platforms", middleware.VerifyAdmin(middleware.AdminOnly(ltiHandler.ListPlatforms), a.authClient))
+	mux.HandleFunc("POST /admin/lti/platforms", middleware.VerifyAdmin(middleware.AdminOnly(ltiHandler.CreatePlatform), a.authClient))
+	mux.HandleFunc("DELETE /admin/lti/platforms/{id}", middleware.VerifyAdmin(middleware.AdminOnly(ltiHandler.DeletePlatform), a.authClient))
+
+	// research
+	researchHandler := handlers.NewResearchExportHandler(a.logger, a.statsClient, a.quizClient)
+	mux.HandleFunc("GET /admin/research/export", middleware.VerifyAdmin(middleware.AdminOnly(researchHandler.Export), a.authClient))
+
 	// dashboard
 	mux.HandleFunc("GET /admin/dashboard", middleware.VerifyAdmin(
 		handlers.NewSummaryHandler(a.logger, a.authClient, a.statsClient, a.quizClient).GetSummary, a.authClient))
 
 	mux.HandleFunc("GET /admin/live/sessions/active", middleware.VerifyAdmin(quizHandler.ListActiveSessions, a.authClient))
+	mux.HandleFunc("GET /admin/live/sessions/events", middleware.VerifyAdmin(quizHandler.StreamActiveSessions, a.authClient))
 
 	mux.HandleFunc("GET /admin/tests/{code}/progress", middleware.VerifyAdmin(handlers.NewTestsProgressHandler(a.quizClient, a.statsClient, a.logger).Get,a.authClient,))
 
@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"admin/clients"
+	"admin/internal/models"
 	"encoding/json"
+	"errors"
 	"go.uber.org/zap"
 	"net/http"
 )
@@ -43,6 +45,13 @@ func (h *AllStatsHandler) GetStatsForQuestion(w http.ResponseWriter, r *http.Req
 		http.Error(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
+	// IRT parameters are an optional extra; raw stats are still served when
+	// the question is not calibrated yet or the lookup fails.
+	if irt, err := h.statsClient.GetIRTItemParamsForQuestion(questionId); err == nil {
+		stats.IRT = &irt
+	} else if !errors.Is(err, clients.ErrNotFound) {
+		h.logger.Warn("failed to get irt parameters", zap.String("question_id", questionId), zap.Error(err))
+	}
 	w.Header().Set("Content-Type", "application/json")
 	statsJson, _ := json.Marshal(stats)
 	_, err = w.Write(statsJson)
@@ -60,6 +69,19 @@ func (h *AllStatsHandler) GetStatsForAllQuestions(w http.ResponseWriter, _ *http
 		http.Error(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
+	if items, err := h.statsClient.GetIRTItemParams(); err == nil {
+		byQuestion := make(map[int]models.IRTItemParams, len(items))
+		for _, it := range items {
+			byQuestion[it.QuestionID] = it
+		}
+		for i := range stats {
+			if it, ok := byQuestion[stats[i].QuestionID]; ok {
+				stats[i].IRT = &it
+			}
+		}
+	} else {
+		h.logger.Warn("failed to get irt parameters", zap.Error(err))
+	}
 	w.Header().Set("Content-Type", "application/json")
 	statsJson, _ := json.Marshal(stats)
 	_, err = w.Write(statsJson)
@@ -70,8 +92,13 @@ func (h *AllStatsHandler) GetStatsForAllQuestions(w http.ResponseWriter, _ *http
 	}
 }
 
+// GET /admin/stats/activity?tz=Europe/Warsaw
 func (h *AllStatsHandler) GetActivityStats(w http.ResponseWriter, r *http.Request) {
-	stats, err := h.statsClient.GetActivityStats()
+	stats, err := h.statsClient.GetActivityStats(r.URL.Query().Get("tz"))
+	if errors.Is(err, clients.ErrInvalidParameter) {
+		http.Error(w, "invalid tz", http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		h.logger.Error("failed to get stats", zap.Error(err))
 		http.Error(w, "internal server error", http.StatusInternalServerError)
@@ -127,3 +154,81 @@ func (h *AllStatsHandler) GetStatsForUsers(w http.ResponseWriter, _ *http.Reques
 	}
 	json.NewEncoder(w).Encode(stats)
 }
+
+// GET /admin/stats/activity/heatmap?tz=Europe/Warsaw|local&country=
+func (h *AllStatsHandler) GetActivityHeatmap(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	heatmap, err := h.statsClient.GetActivityHeatmap(q.Get("tz"), q.Get("country"))
+	if errors.Is(err, clients.ErrInvalidParameter) {
+		http.Error(w, "invalid tz", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to get activity heatmap", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(heatmap); err != nil {
+		h.logger.Error("failed to write response", zap.Error(err))
+	}
+}
+
+// GET /admin/stats/activity/countries?tz=Europe/Warsaw|local
+func (h *AllStatsHandler) GetActivityByCountry(w http.ResponseWriter, r *http.Request) {
+	countries, err := h.statsClient.GetActivityByCountry(r.URL.Query().Get("tz"))
+	if errors.Is(err, clients.ErrInvalidParameter) {
+		http.Error(w, "invalid tz", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to get activity by country", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(countries); err != nil {
+		h.logger.Error("failed to write response", zap.Error(err))
+	}
+}
+
+// GET /admin/stats/devices
+func (h *AllStatsHandler) GetDeviceStats(w http.ResponseWriter, _ *http.Request) {
+	stats, err := h.statsClient.GetDeviceStats()
+	if err != nil {
+		h.logger.Error("failed to get device stats", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		h.logger.Error("failed to write response", zap.Error(err))
+	}
+}
+
+// GET /admin/stats/devices/resolutions?minAnswers=20
+func (h *AllStatsHandler) GetResolutionStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.statsClient.GetResolutionStats(r.URL.Query().Get("minAnswers"))
+	if errors.Is(err, clients.ErrInvalidParameter) {
+		http.Error(w, "invalid minAnswers", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to get resolution stats", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		h.logger.Error("failed to write response", zap.Error(err))
+	}
+}
+
+// writeJSON sends v as the JSON body of a response with the given status.
+func writeJSON(w http.ResponseWriter, logger *zap.Logger, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Error("failed to write response", zap.Error(err))
+	}
+}
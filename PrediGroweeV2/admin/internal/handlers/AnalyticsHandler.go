@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"admin/clients"
+	"admin/internal/models"
+	"encoding/json"
+	"go.uber.org/zap"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	funnelStageRegistered   = "registered"
+	funnelStageVerified     = "verified"
+	funnelStageSurvey       = "survey_completed"
+	funnelStageQuizStarted  = "quiz_started"
+	funnelStageAnswers      = "answers_20"
+	funnelStageTestFinished = "test_finished"
+
+	funnelMinAnswers      = 20
+	defaultRetentionWeeks = 12
+	maxRetentionWeeks     = 52
+)
+
+type AnalyticsHandler struct {
+	logger      *zap.Logger
+	authClient  clients.AuthClient
+	statsClient clients.StatsClient
+}
+
+func NewAnalyticsHandler(logger *zap.Logger, authClient clients.AuthClient, statsClient clients.StatsClient) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		logger:      logger,
+		authClient:  authClient,
+		statsClient: statsClient,
+	}
+}
+
+// GET /admin/analytics/participation?weeks=12
+func (h *AnalyticsHandler) GetParticipation(w http.ResponseWriter, r *http.Request) {
+	weeks := defaultRetentionWeeks
+	if v := r.URL.Query().Get("weeks"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxRetentionWeeks {
+			http.Error(w, "weeks must be between 1 and 52", http.StatusBadRequest)
+			return
+		}
+		weeks = n
+	}
+
+	var (
+		wg                sync.WaitGroup
+		users             []models.User
+		report            models.ParticipationReport
+		usersErr, statErr error
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		users, usersErr = h.authClient.GetUsers()
+	}()
+	go func() {
+		defer wg.Done()
+		report, statErr = h.statsClient.GetParticipation()
+	}()
+	wg.Wait()
+	if usersErr != nil || statErr != nil {
+		h.logger.Error("failed to get participation data", zap.NamedError("auth", usersErr), zap.NamedError("stats", statErr))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now().UTC()
+	participants := studyParticipants(users, report.ExcludedUserIDs)
+	byUser := make(map[int]models.UserParticipation, len(report.Users))
+	for _, p := range report.Users {
+		byUser[p.UserID] = p
+	}
+
+	analytics := models.ParticipationAnalytics{
+		GeneratedAt: now,
+		Funnel:      buildFunnel(participants, byUser),
+		Cohorts:     buildCohorts(participants, byUser, weeks, now, h.logger),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(analytics); err != nil {
+		h.logger.Error("failed to write response", zap.Error(err))
+	}
+}
+
+// studyParticipants drops admins, guests and users excluded from statistics,
+// such as staff test accounts, so recruitment figures only count registered
+// participants.
+func studyParticipants(users []models.User, excluded []int) []models.User {
+	skip := make(map[int]bool, len(excluded))
+	for _, id := range excluded {
+		skip[id] = true
+	}
+	out := make([]models.User, 0, len(users))
+	for _, u := range users {
+		if u.Role == models.RoleAdmin || u.Role == models.RoleGuest || skip[u.ID] {
+			continue
+		}
+		out = append(out, u)
+	}
+	return out
+}
+
+func buildFunnel(users []models.User, byUser map[int]models.UserParticipation) []models.FunnelStage {
+	stages := []struct {
+		name    string
+		reached func(models.User, models.UserParticipation) bool
+	}{
+		{funnelStageRegistered, func(models.User, models.UserParticipation) bool { return true }},
+		{funnelStageVerified, func(u models.User, _ models.UserParticipation) bool { return u.Verified }},
+		{funnelStageSurvey, func(_ models.User, p models.UserParticipation) bool { return p.SurveyCompleted }},
+		{funnelStageQuizStarted, func(_ models.User, p models.UserParticipation) bool { return p.QuizStarted }},
+		{funnelStageAnswers, func(_ models.User, p models.UserParticipation) bool { return p.Answers >= funnelMinAnswers }},
+		{funnelStageTestFinished, func(_ models.User, p models.UserParticipation) bool { return p.FinishedTests > 0 }},
+	}
+
+	counts := make([]int, len(stages))
+	for _, u := range users {
+		p := byUser[u.ID]
+		for i, s := range stages {
+			if !s.reached(u, p) {
+				break
+			}
+			counts[i]++
+		}
+	}
+
+	out := make([]models.FunnelStage, len(stages))
+	for i, s := range stages {
+		out[i] = models.FunnelStage{
+			Stage:          s.name,
+			Users:          counts[i],
+			FromRegistered: share(counts[i], counts[0]),
+		}
+		if i == 0 {
+			out[i].FromPrevious = 1
+		} else {
+			out[i].FromPrevious = share(counts[i], counts[i-1])
+		}
+	}
+	return out
+}
+
+// buildCohorts groups users by registration week. A cohort only gets columns
+// for weeks that have already started, so recent cohorts are not reported as
+// having dropped out.
+func buildCohorts(users []models.User, byUser map[int]models.UserParticipation, weeks int, now time.Time, logger *zap.Logger) []models.RegistrationCohort {
+	currentWeek := weekStart(now)
+	cohorts := make(map[time.Time]*models.RegistrationCohort)
+	for _, u := range users {
+		registered, err := parseCreatedAt(u.CreatedAt)
+		if err != nil {
+			logger.Warn("skipping user with unparsable created_at", zap.Int("user_id", u.ID), zap.String("created_at", u.CreatedAt))
+			continue
+		}
+		week := weekStart(registered)
+		c, ok := cohorts[week]
+		if !ok {
+			span := int(currentWeek.Sub(week).Hours()/(24*7)) + 1
+			c = &models.RegistrationCohort{
+				Week:   week.Format(time.DateOnly),
+				Active: make([]int, min(span, weeks)),
+			}
+			cohorts[week] = c
+		}
+		c.Size++
+
+		seen := make(map[int]bool)
+		for _, aw := range byUser[u.ID].ActiveWeeks {
+			t, err := time.Parse(time.DateOnly, aw)
+			if err != nil {
+				continue
+			}
+			k := int(t.Sub(week).Hours() / (24 * 7))
+			if k < 0 || k >= len(c.Active) || seen[k] {
+				continue
+			}
+			seen[k] = true
+			c.Active[k]++
+		}
+	}
+
+	out := make([]models.RegistrationCohort, 0, len(cohorts))
+	for _, c := range cohorts {
+		c.Retention = make([]float64, len(c.Active))
+		for k, n := range c.Active {
+			c.Retention[k] = share(n, c.Size)
+		}
+		out = append(out, *c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Week < out[j].Week })
+	return out
+}
+
+// weekStart returns the Monday 00:00 UTC of the week containing t, matching
+// Postgres date_trunc('week', ...).
+func weekStart(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+func parseCreatedAt(s string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999", time.DateOnly} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func share(part, whole int) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return float64(part) / float64(whole)
+}
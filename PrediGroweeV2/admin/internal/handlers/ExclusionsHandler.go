@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"admin/clients"
+	"admin/internal/models"
+	"encoding/json"
+	"errors"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+)
+
+type ExclusionsHandler struct {
+	logger      *zap.Logger
+	statsClient clients.StatsClient
+}
+
+func NewExclusionsHandler(logger *zap.Logger, statsClient clients.StatsClient) *ExclusionsHandler {
+	return &ExclusionsHandler{
+		logger:      logger,
+		statsClient: statsClient,
+	}
+}
+
+// GET /admin/exclusions?scope=response|session|user
+func (h *ExclusionsHandler) List(w http.ResponseWriter, r *http.Request) {
+	scope := r.URL.Query().Get("scope")
+	if scope != "" && !validExclusionScope(scope) {
+		http.Error(w, "invalid scope", http.StatusBadRequest)
+		return
+	}
+	exclusions, err := h.statsClient.GetExclusions(scope)
+	if err != nil {
+		h.logger.Error("failed to get exclusions", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(exclusions); err != nil {
+		h.logger.Error("failed to write response", zap.Error(err))
+	}
+}
+
+// POST /admin/exclusions
+func (h *ExclusionsHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var exclusion models.Exclusion
+	if err := json.NewDecoder(r.Body).Decode(&exclusion); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	exclusion.Reason = strings.TrimSpace(exclusion.Reason)
+	switch {
+	case !validExclusionScope(exclusion.Scope):
+		http.Error(w, "scope must be response, session or user", http.StatusBadRequest)
+		return
+	case exclusion.TargetID <= 0:
+		http.Error(w, "target_id is required", http.StatusBadRequest)
+		return
+	case exclusion.Reason == "":
+		http.Error(w, "reason is required", http.StatusBadRequest)
+		return
+	}
+	exclusion.ExcludedBy, _ = r.Context().Value("user_id").(int)
+
+	created, err := h.statsClient.CreateExclusion(exclusion)
+	if err != nil {
+		if errors.Is(err, clients.ErrNotFound) {
+			http.Error(w, "target not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to create exclusion", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(created); err != nil {
+		h.logger.Error("failed to write response", zap.Error(err))
+	}
+}
+
+// DELETE /admin/exclusions/{id}
+func (h *ExclusionsHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	if err := h.statsClient.DeleteExclusion(r.PathValue("id")); err != nil {
+		if errors.Is(err, clients.ErrNotFound) {
+			http.Error(w, "exclusion not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to delete exclusion", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func validExclusionScope(scope string) bool {
+	switch scope {
+	case models.ExclusionScopeResponse, models.ExclusionScopeSession, models.ExclusionScopeUser:
+		return true
+	}
+	return false
+}
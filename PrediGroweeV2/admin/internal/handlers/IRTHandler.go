@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"admin/clients"
+	"errors"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+type IRTHandler struct {
+	logger      *zap.Logger
+	statsClient clients.StatsClient
+}
+
+func NewIRTHandler(logger *zap.Logger, statsClient clients.StatsClient) *IRTHandler {
+	return &IRTHandler{
+		logger:      logger,
+		statsClient: statsClient,
+	}
+}
+
+// GET /admin/stats/irt/questions
+func (h *IRTHandler) GetQuestions(w http.ResponseWriter, _ *http.Request) {
+	items, err := h.statsClient.GetIRTItemParams()
+	if err != nil {
+		h.logger.Error("failed to get irt item parameters", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, h.logger, http.StatusOK, items)
+}
+
+// GET /admin/stats/irt/users
+func (h *IRTHandler) GetUsers(w http.ResponseWriter, _ *http.Request) {
+	abilities, err := h.statsClient.GetIRTAbilities()
+	if err != nil {
+		h.logger.Error("failed to get irt abilities", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, h.logger, http.StatusOK, abilities)
+}
+
+// GET /admin/stats/irt/users/{id}
+func (h *IRTHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	ability, err := h.statsClient.GetIRTUserAbility(r.PathValue("id"))
+	if errors.Is(err, clients.ErrNotFound) {
+		http.Error(w, "user not calibrated", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, clients.ErrInvalidParameter) {
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to get irt ability", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, h.logger, http.StatusOK, ability)
+}
+
+// GET /admin/stats/irt/runs/latest
+func (h *IRTHandler) GetLatestRun(w http.ResponseWriter, _ *http.Request) {
+	run, err := h.statsClient.GetLatestIRTRun()
+	if errors.Is(err, clients.ErrNotFound) {
+		http.Error(w, "no calibration runs", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to get latest irt run", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, h.logger, http.StatusOK, run)
+}
+
+// POST /admin/stats/irt/runs
+func (h *IRTHandler) TriggerRun(w http.ResponseWriter, _ *http.Request) {
+	run, err := h.statsClient.TriggerIRTRun()
+	if errors.Is(err, clients.ErrConflict) {
+		http.Error(w, "calibration already running", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to start irt calibration", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, h.logger, http.StatusAccepted, run)
+}
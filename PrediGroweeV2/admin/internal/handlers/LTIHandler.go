@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"admin/clients"
+	"admin/internal/models"
+	"encoding/json"
+	"errors"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// LTIHandler manages the LMS platforms allowed to open tests over LTI 1.3.
+// The values come from the LMS's tool registration page; the LMS in turn
+// needs the tool's login, launch and JWKS URLs under /api/quiz/lti.
+type LTIHandler struct {
+	logger     *zap.Logger
+	quizClient clients.QuizClient
+}
+
+func NewLTIHandler(logger *zap.Logger, quizClient clients.QuizClient) *LTIHandler {
+	return &LTIHandler{logger: logger, quizClient: quizClient}
+}
+
+// GET /admin/lti/platforms
+func (h *LTIHandler) ListPlatforms(w http.ResponseWriter, _ *http.Request) {
+	platforms, err := h.quizClient.ListLTIPlatforms()
+	if err != nil {
+		h.logger.Error("failed to list lti platforms", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, h.logger, http.StatusOK, platforms)
+}
+
+// POST /admin/lti/platforms
+func (h *LTIHandler) CreatePlatform(w http.ResponseWriter, r *http.Request) {
+	var platform models.LTIPlatform
+	if err := json.NewDecoder(r.Body).Decode(&platform); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	created, err := h.quizClient.CreateLTIPlatform(platform)
+	switch {
+	case errors.Is(err, clients.ErrInvalidParameter):
+		http.Error(w, "issuer and client_id are required and the endpoints must be https URLs", http.StatusBadRequest)
+	case errors.Is(err, clients.ErrConflict):
+		http.Error(w, "platform already registered", http.StatusConflict)
+	case err != nil:
+		h.logger.Error("failed to create lti platform", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	default:
+		writeJSON(w, h.logger, http.StatusCreated, created)
+	}
+}
+
+// DELETE /admin/lti/platforms/{id}
+func (h *LTIHandler) DeletePlatform(w http.ResponseWriter, r *http.Request) {
+	err := h.quizClient.DeleteLTIPlatform(r.PathValue("id"))
+	switch {
+	case errors.Is(err, clients.ErrInvalidParameter):
+		http.Error(w, "invalid platform id", http.StatusBadRequest)
+	case errors.Is(err, clients.ErrNotFound):
+		http.Error(w, "platform not found", http.StatusNotFound)
+	case err != nil:
+		h.logger.Error("failed to delete lti platform", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
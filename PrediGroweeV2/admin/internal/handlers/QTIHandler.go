@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"admin/clients"
+	"encoding/json"
+	"errors"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	maxQTIPackageSize  = 50 << 20
+	qtiTransferTimeout = 5 * time.Minute
+)
+
+// QTIHandler relays question export to and import from QTI packages, for use
+// in other exam platforms; the work is done by the quiz service.
+type QTIHandler struct {
+	logger     *zap.Logger
+	quizClient clients.QuizClient
+}
+
+func NewQTIHandler(logger *zap.Logger, quizClient clients.QuizClient) *QTIHandler {
+	return &QTIHandler{logger: logger, quizClient: quizClient}
+}
+
+// GET /admin/questions/qti?ids=1,2,3&version=2.1|3.0 — a zip with the
+// given questions, or all of them, each item showing the case parameters and
+// images above the options
+func (h *QTIHandler) Export(w http.ResponseWriter, r *http.Request) {
+	query := url.Values{}
+	for _, name := range []string{"ids", "version"} {
+		if v := r.URL.Query().Get(name); v != "" {
+			query.Set(name, v)
+		}
+	}
+	extendDeadlines(w, qtiTransferTimeout)
+	body, err := h.quizClient.ExportQuestionsQTI(r.Context(), query)
+	switch {
+	case errors.Is(err, clients.ErrInvalidParameter):
+		http.Error(w, clientMessage(err, clients.ErrInvalidParameter), http.StatusBadRequest)
+		return
+	case errors.Is(err, clients.ErrNotFound):
+		http.Error(w, clientMessage(err, clients.ErrNotFound), http.StatusNotFound)
+		return
+	case err != nil:
+		h.logger.Error("failed to export qti package", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="questions-qti.zip"`)
+	if _, err := io.Copy(w, body); err != nil {
+		h.logger.Error("failed to relay qti package", zap.Error(err))
+	}
+}
+
+// POST /admin/questions/qti?case_id=&prediction_age=&group= — body: a QTI
+// 2.1 or 3.0 zip or item XML. Items exported by PrediGrowee keep their case
+// and age; others take them from the query. Responds with the created
+// questions and the skipped items with reasons.
+func (h *QTIHandler) Import(w http.ResponseWriter, r *http.Request) {
+	query := url.Values{}
+	for _, name := range []string{"case_id", "prediction_age", "group"} {
+		if v := r.URL.Query().Get(name); v != "" {
+			query.Set(name, v)
+		}
+	}
+	extendDeadlines(w, qtiTransferTimeout)
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/zip"
+	}
+	result, err := h.quizClient.ImportQuestionsQTI(r.Context(), query, contentType, http.MaxBytesReader(w, r.Body, maxQTIPackageSize))
+	switch {
+	case errors.Is(err, clients.ErrInvalidParameter):
+		http.Error(w, clientMessage(err, clients.ErrInvalidParameter), http.StatusBadRequest)
+	case err != nil:
+		h.logger.Error("failed to import qti package", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(result)
+	}
+}
+
+// clientMessage is the quiz service's message wrapped in err by the client.
+func clientMessage(err error, sentinel error) string {
+	return strings.TrimPrefix(err.Error(), sentinel.Error()+": ")
+}
+
+// extendDeadlines moves the connection's read and write deadlines d from
+// now, past the server's timeouts.
+func extendDeadlines(w http.ResponseWriter, d time.Duration) {
+	rc := http.NewResponseController(w)
+	deadline := time.Now().Add(d)
+	_ = rc.SetReadDeadline(deadline)
+	_ = rc.SetWriteDeadline(deadline)
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"admin/clients"
+	"admin/internal/models"
+	"admin/internal/quality"
+	"encoding/json"
+	"errors"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+type QualityHandler struct {
+	logger     *zap.Logger
+	quizClient clients.QuizClient
+	quality    *quality.Service
+}
+
+func NewQualityHandler(logger *zap.Logger, quizClient clients.QuizClient, quality *quality.Service) *QualityHandler {
+	return &QualityHandler{
+		logger:     logger,
+		quizClient: quizClient,
+		quality:    quality,
+	}
+}
+
+// GET /admin/questions/quality
+func (h *QualityHandler) GetReport(w http.ResponseWriter, _ *http.Request) {
+	report, err := h.quality.Report()
+	if err != nil {
+		h.logger.Error("failed to build question quality report", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, h.logger, http.StatusOK, report)
+}
+
+// POST /admin/questions/quality/sync
+func (h *QualityHandler) Sync(w http.ResponseWriter, _ *http.Request) {
+	report, opened, err := h.quality.Sync()
+	if err != nil {
+		h.logger.Error("failed to sync question review queue", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, h.logger, http.StatusOK, map[string]int{"flagged": report.Flagged, "opened": opened})
+}
+
+// GET /admin/questions/reviews?status=open
+func (h *QualityHandler) ListReviews(w http.ResponseWriter, r *http.Request) {
+	reviews, err := h.quizClient.ListQuestionReviews(r.URL.Query().Get("status"))
+	if errors.Is(err, clients.ErrInvalidParameter) {
+		http.Error(w, "invalid status", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to list question reviews", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	byQuestion := make(map[int]models.QuestionQuality)
+	if report, err := h.quality.Report(); err == nil {
+		for _, q := range report.Questions {
+			byQuestion[q.QuestionID] = q
+		}
+	} else {
+		h.logger.Warn("listing reviews without quality figures", zap.Error(err))
+	}
+	items := make([]models.ReviewQueueItem, len(reviews))
+	for i, rev := range reviews {
+		items[i] = models.ReviewQueueItem{QuestionReview: rev}
+		if q, ok := byQuestion[rev.QuestionID]; ok {
+			items[i].Quality = &q
+		}
+	}
+	writeJSON(w, h.logger, http.StatusOK, items)
+}
+
+// PATCH /admin/questions/reviews/{id}
+func (h *QualityHandler) UpdateReview(w http.ResponseWriter, r *http.Request) {
+	var update models.QuestionReviewUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	update.AdminID = nil
+	if adminID, ok := r.Context().Value("user_id").(int); ok {
+		update.AdminID = &adminID
+	}
+
+	review, err := h.quizClient.UpdateQuestionReview(r.PathValue("id"), update)
+	switch {
+	case errors.Is(err, clients.ErrInvalidParameter):
+		http.Error(w, "invalid status or id", http.StatusBadRequest)
+	case errors.Is(err, clients.ErrNotFound):
+		http.Error(w, "review not found", http.StatusNotFound)
+	case errors.Is(err, clients.ErrConflict):
+		http.Error(w, "question already has an open review", http.StatusConflict)
+	case err != nil:
+		h.logger.Error("failed to update question review", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	default:
+		writeJSON(w, h.logger, http.StatusOK, review)
+	}
+}
@@ -3,12 +3,21 @@ package handlers
 import (
 	"admin/clients"
 	"admin/internal/models"
+	"bufio"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"go.uber.org/zap"
 	"net/http"
     "strconv"
+	"strings"
+	"time"
 )
 
+// maxSessionEventSize bounds one event of the active sessions stream; the
+// snapshot that opens it lists up to 500 sessions.
+const maxSessionEventSize = 4 << 20
+
 type QuizHandler struct {
 	logger      *zap.Logger
 	quizClient  clients.QuizClient
@@ -303,29 +312,107 @@ func (h *QuizHandler) ListActiveSessions(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	h.addAccuracy(sessions)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(sessions)
+}
+
+// addAccuracy fills in the answer counts of the sessions from the stats
+// service; on failure the sessions are left without them.
+func (h *QuizHandler) addAccuracy(sessions []clients.ActiveSession) {
 	ids := make([]int, 0, len(sessions))
 	for _, s := range sessions { ids = append(ids, s.ID) }
 
 	accs, err := h.statsClient.GetSessionsAccuracy(ids)
 	if err != nil {
 		h.logger.Error("GetSessionsAccuracy failed", zap.Error(err))
-	} else {
-		m := make(map[int]models.SessionAccuracy, len(accs))
-		for _, a := range accs { m[a.SessionID] = a }
-		for i := range sessions {
-			if a, ok := m[sessions[i].ID]; ok {
-				acc := a.Accuracy
-				cor := a.Correct
-				tot := a.Total
-				sessions[i].Accuracy = &acc
-				sessions[i].CorrectAnswers = &cor
-				sessions[i].TotalAnswers = &tot
-			}
+		return
+	}
+	m := make(map[int]models.SessionAccuracy, len(accs))
+	for _, a := range accs { m[a.SessionID] = a }
+	for i := range sessions {
+		if a, ok := m[sessions[i].ID]; ok {
+			acc := a.Accuracy
+			cor := a.Correct
+			tot := a.Total
+			sessions[i].Accuracy = &acc
+			sessions[i].CorrectAnswers = &cor
+			sessions[i].TotalAnswers = &tot
 		}
 	}
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(sessions)
+// StreamActiveSessions relays the quiz service's stream of active sessions.
+// The "snapshot" event that opens it gets the same answer counts as
+// ListActiveSessions; the session_started, question_answered,
+// session_finished and session_idle events after it pass through unchanged.
+func (h *QuizHandler) StreamActiveSessions(w http.ResponseWriter, r *http.Request) {
+	cutoff := 5
+	if v := r.URL.Query().Get("cutoff"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil { cutoff = n }
+	}
+	body, err := h.quizClient.StreamActiveSessions(r.Context(), cutoff)
+	if err != nil {
+		h.logger.Error("Failed to open active sessions stream", zap.Error(err))
+		http.Error(w, "Failed to open active sessions stream", http.StatusBadGateway)
+		return
+	}
+	defer body.Close()
+
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		h.logger.Error("Failed to lift write deadline", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	sc := bufio.NewScanner(body)
+	sc.Buffer(make([]byte, 64*1024), maxSessionEventSize)
+	var frame []string
+	for sc.Scan() {
+		if line := sc.Text(); line != "" {
+			frame = append(frame, line)
+			continue
+		}
+		if len(frame) == 0 {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s\n\n", strings.Join(h.withSnapshotAccuracy(frame), "\n")); err != nil {
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+		frame = frame[:0]
+	}
+	if err := sc.Err(); err != nil && r.Context().Err() == nil {
+		h.logger.Info("active sessions stream ended", zap.Error(err))
+	}
+}
+
+// withSnapshotAccuracy adds answer counts to a snapshot event and returns
+// other events as they are.
+func (h *QuizHandler) withSnapshotAccuracy(frame []string) []string {
+	if len(frame) != 2 || frame[0] != "event: snapshot" || !strings.HasPrefix(frame[1], "data: ") {
+		return frame
+	}
+	var sessions []clients.ActiveSession
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(frame[1], "data: ")), &sessions); err != nil {
+		h.logger.Error("Failed to decode active sessions snapshot", zap.Error(err))
+		return frame
+	}
+	h.addAccuracy(sessions)
+	data, err := json.Marshal(sessions)
+	if err != nil {
+		return frame
+	}
+	return []string{frame[0], "data: " + string(data)}
 }
 
 // ... (przed ostatnim nawiasem '}')
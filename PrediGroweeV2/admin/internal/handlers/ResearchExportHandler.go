@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"admin/clients"
+	"admin/internal/models"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type ResearchExportHandler struct {
+	logger      *zap.Logger
+	statsClient clients.StatsClient
+	quizClient  clients.QuizClient
+}
+
+func NewResearchExportHandler(logger *zap.Logger, statsClient clients.StatsClient, quizClient clients.QuizClient) *ResearchExportHandler {
+	return &ResearchExportHandler{
+		logger:      logger,
+		statsClient: statsClient,
+		quizClient:  quizClient,
+	}
+}
+
+var researchCSVHeader = []string{
+	"participant_id", "session_id", "quiz_mode", "session_finished_at", "guest",
+	"question_id", "case_code", "case_gender", "case_age1", "case_age2",
+	"prediction_age", "question_group", "correct_option",
+	"answer", "is_correct", "answered_at", "time_spent", "screen_size",
+	"gender", "age", "vision_defect", "education", "experience", "country",
+}
+
+// GET /admin/research/export?format=csv|jsonl
+//
+// Rows are written as they arrive from stats, joined with the question bank
+// from quiz. Survey names and acknowledgments never reach this service:
+// stats strips them before the rows leave it. A failure after the first row
+// aborts the download rather than end it early.
+func (h *ResearchExportHandler) Export(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "jsonl" {
+		http.Error(w, "format must be csv or jsonl", http.StatusBadRequest)
+		return
+	}
+
+	questions, err := h.quizClient.GetAllQuestions()
+	if err != nil {
+		h.logger.Error("failed to get questions for research dataset", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	byID := make(map[int]models.Question, len(questions))
+	for _, q := range questions {
+		byID[q.ID] = q
+	}
+
+	// the download lasts as long as stats streams it: stats bounds the
+	// export, and the request's context ends it if the client goes away
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	filename := fmt.Sprintf("predigrowee_research_%s.%s", time.Now().UTC().Format("20060102"), format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	var write func(models.ResearchRow) error
+	var flush func() error
+	if format == "jsonl" {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		enc := json.NewEncoder(w)
+		write = func(row models.ResearchRow) error { return enc.Encode(row) }
+		flush = func() error { return nil }
+	} else {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		// buffered until the first rows, so a failure before them can still
+		// be reported with a status
+		cw := csv.NewWriter(w)
+		_ = cw.Write(researchCSVHeader)
+		write = func(row models.ResearchRow) error { return cw.Write(researchCSVRecord(row)) }
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	}
+
+	written := false
+	err = h.statsClient.EachResearchRow(r.Context(), func(row models.ResearchRow) error {
+		enrichResearchRow(&row, byID)
+		written = true
+		return write(row)
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		h.logger.Error("failed to export research dataset", zap.Error(err))
+		if !written {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func researchCSVRecord(row models.ResearchRow) []string {
+	return []string{
+		row.ParticipantID,
+		row.SessionID,
+		row.QuizMode,
+		formatTimePtr(row.SessionFinished),
+		strconv.FormatBool(row.Guest),
+		strconv.Itoa(row.QuestionID),
+		row.CaseCode,
+		row.CaseGender,
+		strconv.Itoa(row.CaseAge1),
+		strconv.Itoa(row.CaseAge2),
+		strconv.Itoa(row.PredictionAge),
+		strconv.Itoa(row.QuestionGroup),
+		row.CorrectOption,
+		row.Answer,
+		strconv.FormatBool(row.IsCorrect),
+		formatTimePtr(row.AnsweredAt),
+		strconv.Itoa(row.TimeSpent),
+		row.ScreenSize,
+		row.Gender,
+		row.Age,
+		row.VisionDefect,
+		row.Education,
+		row.Experience,
+		row.Country,
+	}
+}
+
+// enrichResearchRow adds the case and question metadata of the row's
+// question.
+func enrichResearchRow(row *models.ResearchRow, questions map[int]models.Question) {
+	q, ok := questions[row.QuestionID]
+	if !ok {
+		return
+	}
+	row.CaseGender = q.Case.Gender
+	row.CaseAge1 = q.Case.Age1
+	row.CaseAge2 = q.Case.Age2
+	row.PredictionAge = q.PredictionAge
+	row.QuestionGroup = q.Group
+	if q.Correct != nil {
+		row.CorrectOption = *q.Correct
+	}
+	if row.CaseCode == "" {
+		row.CaseCode = q.Case.Code
+	}
+}
+
+func formatTimePtr(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
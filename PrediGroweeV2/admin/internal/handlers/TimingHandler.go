@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"admin/clients"
+	"admin/internal/models"
+	"encoding/json"
+	"errors"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+)
+
+type TimingHandler struct {
+	logger      *zap.Logger
+	statsClient clients.StatsClient
+}
+
+func NewTimingHandler(logger *zap.Logger, statsClient clients.StatsClient) *TimingHandler {
+	return &TimingHandler{
+		logger:      logger,
+		statsClient: statsClient,
+	}
+}
+
+// GET /admin/stats/timing/questions
+func (h *TimingHandler) GetQuestions(w http.ResponseWriter, _ *http.Request) {
+	stats, err := h.statsClient.GetQuestionTimingStats()
+	if err != nil {
+		h.logger.Error("failed to get question timing stats", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, h.logger, http.StatusOK, stats)
+}
+
+// GET /admin/stats/timing/users
+func (h *TimingHandler) GetUsers(w http.ResponseWriter, _ *http.Request) {
+	stats, err := h.statsClient.GetUserTimingStats()
+	if err != nil {
+		h.logger.Error("failed to get user timing stats", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, h.logger, http.StatusOK, stats)
+}
+
+// GET /admin/stats/timing/buckets
+func (h *TimingHandler) GetBuckets(w http.ResponseWriter, _ *http.Request) {
+	stats, err := h.statsClient.GetTimeBucketStats()
+	if err != nil {
+		h.logger.Error("failed to get time bucket stats", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, h.logger, http.StatusOK, stats)
+}
+
+// GET /admin/stats/flags?fastSeconds=&fastShare=&minAnswers=
+func (h *TimingHandler) GetFlags(w http.ResponseWriter, r *http.Request) {
+	sessions, err := h.statsClient.GetSuspiciousSessions(r.URL.RawQuery)
+	if err != nil {
+		h.logger.Error("failed to get suspicious sessions", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, h.logger, http.StatusOK, sessions)
+}
+
+// POST /admin/stats/sessions/{id}/exclude
+func (h *TimingHandler) ExcludeSession(w http.ResponseWriter, r *http.Request) {
+	var exclusion models.SessionExclusion
+	if err := json.NewDecoder(r.Body).Decode(&exclusion); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	exclusion.Reason = strings.TrimSpace(exclusion.Reason)
+	if exclusion.Reason == "" {
+		http.Error(w, "reason is required", http.StatusBadRequest)
+		return
+	}
+	exclusion.ExcludedBy, _ = r.Context().Value("user_id").(int)
+	if err := h.statsClient.ExcludeSession(r.PathValue("id"), exclusion); err != nil {
+		if errors.Is(err, clients.ErrNotFound) {
+			http.Error(w, "session not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to exclude session", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DELETE /admin/stats/sessions/{id}/exclude
+func (h *TimingHandler) RestoreSession(w http.ResponseWriter, r *http.Request) {
+	if err := h.statsClient.RestoreSession(r.PathValue("id")); err != nil {
+		h.logger.Error("failed to restore session", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"admin/internal/models"
+	"net/http"
+)
+
+// AdminOnly narrows VerifyAdmin (which also lets teachers read) down to admins.
+// It must be wrapped by VerifyAdmin so the role is already in the context.
+func AdminOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		role, _ := r.Context().Value("user_role").(models.UserRole)
+		if role != models.RoleAdmin {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+type UserParticipation struct {
+	UserID          int        `json:"user_id"`
+	SurveyCompleted bool       `json:"survey_completed"`
+	QuizStarted     bool       `json:"quiz_started"`
+	FirstAnswerAt   *time.Time `json:"first_answer_at,omitempty"`
+	Answers         int        `json:"answers"`
+	FinishedTests   int        `json:"finished_tests"`
+	ActiveWeeks     []string   `json:"active_weeks"`
+}
+
+type ParticipationReport struct {
+	Users           []UserParticipation `json:"users"`
+	ExcludedUserIDs []int               `json:"excluded_user_ids"`
+}
+
+// FunnelStage counts users who reached a stage and every stage before it.
+type FunnelStage struct {
+	Stage          string  `json:"stage"`
+	Users          int     `json:"users"`
+	FromPrevious   float64 `json:"from_previous"`
+	FromRegistered float64 `json:"from_registered"`
+}
+
+// RegistrationCohort groups users by the week (starting Monday, UTC) they
+// registered. Active[k] is the number of them who answered at least one
+// question k weeks after registration; Retention[k] is the same as a share.
+type RegistrationCohort struct {
+	Week      string    `json:"week"`
+	Size      int       `json:"size"`
+	Active    []int     `json:"active"`
+	Retention []float64 `json:"retention"`
+}
+
+type ParticipationAnalytics struct {
+	GeneratedAt time.Time            `json:"generated_at"`
+	Funnel      []FunnelStage        `json:"funnel"`
+	Cohorts     []RegistrationCohort `json:"cohorts"`
+}
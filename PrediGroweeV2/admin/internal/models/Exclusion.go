@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+const (
+	ExclusionScopeResponse = "response"
+	ExclusionScopeSession  = "session"
+	ExclusionScopeUser     = "user"
+)
+
+type Exclusion struct {
+	ID         int       `json:"id"`
+	Scope      string    `json:"scope"`
+	TargetID   int       `json:"target_id"`
+	Reason     string    `json:"reason"`
+	ExcludedBy int       `json:"excluded_by"`
+	ExcludedAt time.Time `json:"excluded_at"`
+}
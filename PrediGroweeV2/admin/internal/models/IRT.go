@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+type IRTItemParams struct {
+	QuestionID       int     `json:"question_id"`
+	Difficulty       float64 `json:"difficulty"`
+	DifficultySE     float64 `json:"difficulty_se"`
+	Discrimination   float64 `json:"discrimination"`
+	DiscriminationSE float64 `json:"discrimination_se"`
+	Responses        int     `json:"responses"`
+	PValue           float64 `json:"p_value"`
+}
+
+type IRTAbility struct {
+	UserID    int     `json:"user_id"`
+	Ability   float64 `json:"ability"`
+	AbilitySE float64 `json:"ability_se"`
+	Responses int     `json:"responses"`
+	Correct   int     `json:"correct"`
+}
+
+type IRTRun struct {
+	ID            int        `json:"id"`
+	Status        string     `json:"status"`
+	StartedAt     time.Time  `json:"started_at"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`
+	Users         int        `json:"users"`
+	Items         int        `json:"items"`
+	Responses     int        `json:"responses"`
+	Iterations    int        `json:"iterations"`
+	Converged     bool       `json:"converged"`
+	LogLikelihood float64    `json:"log_likelihood"`
+	Error         string     `json:"error,omitempty"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// LTIPlatform is an LMS registered to open tests over LTI 1.3, as stored by
+// the quiz service.
+type LTIPlatform struct {
+	ID            int       `json:"id"`
+	Issuer        string    `json:"issuer"`
+	ClientID      string    `json:"client_id"`
+	DeploymentIDs []string  `json:"deployment_ids"`
+	AuthLoginURL  string    `json:"auth_login_url"`
+	AuthTokenURL  string    `json:"auth_token_url"`
+	JWKSURL       string    `json:"jwks_url"`
+	CreatedAt     time.Time `json:"created_at"`
+}
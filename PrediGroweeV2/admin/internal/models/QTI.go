@@ -0,0 +1,20 @@
+package models
+
+// QTIImportResult is the outcome of a QTI import in the quiz service: the
+// questions created and the items left out, with the reason.
+type QTIImportResult struct {
+	Imported []QTIImportedItem `json:"imported"`
+	Skipped  []QTISkippedItem  `json:"skipped"`
+}
+
+type QTIImportedItem struct {
+	Identifier string `json:"identifier"`
+	File       string `json:"file,omitempty"`
+	QuestionID int    `json:"question_id"`
+}
+
+type QTISkippedItem struct {
+	Identifier string `json:"identifier"`
+	File       string `json:"file,omitempty"`
+	Reason     string `json:"reason"`
+}
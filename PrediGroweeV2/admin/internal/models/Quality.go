@@ -0,0 +1,89 @@
+package models
+
+import "time"
+
+const (
+	QualityFlagBelowChance            = "below_chance"
+	QualityFlagNegativeDiscrimination = "negative_discrimination"
+	QualityFlagHeavilyReported        = "heavily_reported"
+	QualityFlagPolarising             = "polarising"
+)
+
+const (
+	ReviewStatusOpen      = "open"
+	ReviewStatusResolved  = "resolved"
+	ReviewStatusDismissed = "dismissed"
+)
+
+type QuestionDifficultySummary struct {
+	QuestionID int     `json:"question_id"`
+	TotalVotes int     `json:"total_votes"`
+	HardVotes  int     `json:"hard_votes"`
+	EasyVotes  int     `json:"easy_votes"`
+	HardPct    float64 `json:"hard_pct"`
+}
+
+type QuestionDiscrimination struct {
+	QuestionID    int      `json:"question_id"`
+	Responses     int      `json:"responses"`
+	PointBiserial *float64 `json:"point_biserial"`
+}
+
+// QuestionQuality combines everything known about a question's behaviour.
+// Accuracy and chance are shares; AccuracyUpper is the upper bound of the 95%
+// Wilson interval, used to decide whether a question is below chance.
+type QuestionQuality struct {
+	QuestionID    int            `json:"question_id"`
+	Question      string         `json:"question"`
+	CaseID        int            `json:"case_id"`
+	CaseCode      string         `json:"case_code"`
+	Options       int            `json:"options"`
+	Chance        float64        `json:"chance"`
+	Total         int            `json:"total"`
+	Correct       int            `json:"correct"`
+	Accuracy      float64        `json:"accuracy"`
+	AccuracyUpper float64        `json:"accuracy_upper"`
+	PointBiserial *float64       `json:"point_biserial"`
+	IRT           *IRTItemParams `json:"irt,omitempty"`
+	TotalVotes    int            `json:"total_votes"`
+	HardVotes     int            `json:"hard_votes"`
+	EasyVotes     int            `json:"easy_votes"`
+	OpenReports   int            `json:"open_reports"`
+	Flags         []string       `json:"flags"`
+}
+
+type QuestionQualityReport struct {
+	GeneratedAt time.Time         `json:"generated_at"`
+	Flagged     int               `json:"flagged"`
+	Questions   []QuestionQuality `json:"questions"`
+}
+
+type QuestionReview struct {
+	ID         int        `json:"id"`
+	QuestionID int        `json:"question_id"`
+	Status     string     `json:"status"`
+	Reasons    []string   `json:"reasons"`
+	Note       *string    `json:"note,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	ClosedAt   *time.Time `json:"closed_at,omitempty"`
+	ClosedBy   *int       `json:"closed_by,omitempty"`
+}
+
+type QuestionReviewCandidate struct {
+	QuestionID int      `json:"question_id"`
+	Reasons    []string `json:"reasons"`
+}
+
+type QuestionReviewUpdate struct {
+	Status  string  `json:"status"`
+	Note    *string `json:"note"`
+	AdminID *int    `json:"admin_id"`
+}
+
+// ReviewQueueItem is a review with the question's current quality figures,
+// which are missing when the question no longer exists or the report failed.
+type ReviewQueueItem struct {
+	QuestionReview
+	Quality *QuestionQuality `json:"quality,omitempty"`
+}
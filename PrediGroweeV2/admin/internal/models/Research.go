@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// ResearchRow is one answer of the anonymised research dataset. Rows come
+// from the stats service already pseudonymised, with the participant and the
+// session as pseudonyms, and are enriched here with case metadata from the
+// quiz service.
+type ResearchRow struct {
+	ParticipantID   string     `json:"participant_id"`
+	SessionID       string     `json:"session_id"`
+	QuizMode        string     `json:"quiz_mode"`
+	SessionFinished *time.Time `json:"session_finished_at,omitempty"`
+	Guest           bool       `json:"guest"`
+	QuestionID      int        `json:"question_id"`
+	CaseCode        string     `json:"case_code"`
+	CaseGender      string     `json:"case_gender"`
+	CaseAge1        int        `json:"case_age1"`
+	CaseAge2        int        `json:"case_age2"`
+	PredictionAge   int        `json:"prediction_age"`
+	QuestionGroup   int        `json:"question_group"`
+	CorrectOption   string     `json:"correct_option"`
+	Answer          string     `json:"answer"`
+	IsCorrect       bool       `json:"is_correct"`
+	AnsweredAt      *time.Time `json:"answered_at,omitempty"`
+	TimeSpent       int        `json:"time_spent"`
+	ScreenSize      string     `json:"screen_size"`
+	Gender          string     `json:"gender"`
+	Age             string     `json:"age"`
+	VisionDefect    string     `json:"vision_defect"`
+	Education       string     `json:"education"`
+	Experience      string     `json:"experience"`
+	Country         string     `json:"country"`
+}
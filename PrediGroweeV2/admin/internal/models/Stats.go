@@ -12,13 +12,15 @@ type QuestionResponse struct {
 	ScreenSize string     `json:"screen_size"`
 	TimeSpent  int        `json:"time_spent"`
 	CaseCode   string     `json:"case_code"`
+	Excluded   bool       `json:"excluded"`
 }
 
 type QuestionStats struct {
-	QuestionID int    `json:"question_id"`
-	CaseCode   string `json:"case_id"`
-	Total      int    `json:"total"`
-	Correct    int    `json:"correct"`
+	QuestionID int            `json:"question_id"`
+	CaseCode   string         `json:"case_id"`
+	Total      int            `json:"total"`
+	Correct    int            `json:"correct"`
+	IRT        *IRTItemParams `json:"irt,omitempty"`
 }
 
 type ActivityStats struct {
@@ -27,6 +29,28 @@ type ActivityStats struct {
 	Correct int       `json:"correct"`
 }
 
+type ActivityHeatmapCell struct {
+	Weekday int `json:"weekday"`
+	Hour    int `json:"hour"`
+	Total   int `json:"total"`
+	Correct int `json:"correct"`
+}
+
+type ActivityHeatmap struct {
+	TimeZone string                `json:"time_zone"`
+	Country  string                `json:"country,omitempty"`
+	Cells    []ActivityHeatmapCell `json:"cells"`
+}
+
+type CountryActivity struct {
+	Country  string  `json:"country"`
+	Users    int     `json:"users"`
+	Answers  int     `json:"answers"`
+	Correct  int     `json:"correct"`
+	Accuracy float64 `json:"accuracy"`
+	ByHour   []int   `json:"by_hour"`
+}
+
 type SurveyGroupedStats struct {
 	Group    string  `json:"group"`
 	Value    string  `json:"value"`
@@ -41,3 +65,16 @@ type UserQuizStats struct {
 	Experience     string `json:"experience"`
 	Education      string `json:"education"`
 }
+
+type DeviceStats struct {
+	DeviceClass     string  `json:"device_class"`
+	Resolution      string  `json:"resolution,omitempty"`
+	Width           int     `json:"width,omitempty"`
+	Height          int     `json:"height,omitempty"`
+	Users           int     `json:"users"`
+	Answers         int     `json:"answers"`
+	Correct         int     `json:"correct"`
+	Accuracy        float64 `json:"accuracy"`
+	MeanTimeSpent   float64 `json:"mean_time_spent"`
+	MedianTimeSpent float64 `json:"median_time_spent"`
+}
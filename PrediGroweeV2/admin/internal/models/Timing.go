@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+type TimingStats struct {
+	QuestionID *int    `json:"question_id,omitempty"`
+	UserID     *int    `json:"user_id,omitempty"`
+	Answers    int     `json:"answers"`
+	Correct    int     `json:"correct"`
+	Accuracy   float64 `json:"accuracy"`
+	Mean       float64 `json:"mean"`
+	Min        int     `json:"min"`
+	P25        float64 `json:"p25"`
+	Median     float64 `json:"median"`
+	P75        float64 `json:"p75"`
+	P90        float64 `json:"p90"`
+	Max        int     `json:"max"`
+}
+
+type TimeBucketStats struct {
+	Label    string  `json:"label"`
+	From     int     `json:"from"`
+	To       *int    `json:"to"`
+	Total    int     `json:"total"`
+	Correct  int     `json:"correct"`
+	Accuracy float64 `json:"accuracy"`
+}
+
+type SuspiciousSession struct {
+	SessionID   int        `json:"session_id"`
+	UserID      int        `json:"user_id"`
+	Answers     int        `json:"answers"`
+	FastAnswers int        `json:"fast_answers"`
+	FastShare   float64    `json:"fast_share"`
+	Accuracy    float64    `json:"accuracy"`
+	Reasons     []string   `json:"reasons"`
+	DuplicateOf []int      `json:"duplicate_of,omitempty"`
+	Excluded    bool       `json:"excluded"`
+	ExcludedAt  *time.Time `json:"excluded_at,omitempty"`
+}
+
+type SessionExclusion struct {
+	Reason     string `json:"reason"`
+	ExcludedBy int    `json:"excluded_by"`
+}
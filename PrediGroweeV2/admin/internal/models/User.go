@@ -13,6 +13,7 @@ type User struct {
 	Role      UserRole `json:"role"`
 	GoogleID  string   `json:"google_id"`
 	CreatedAt string   `json:"created_at"`
+	Verified  bool     `json:"verified"`
 }
 
 type UserPayload struct {
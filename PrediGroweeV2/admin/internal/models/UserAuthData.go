@@ -11,6 +11,8 @@ const (
 	RoleAdmin   UserRole = "admin"
 	RoleUser    UserRole = "user"
 	RoleTeacher UserRole = "teacher"
+	// RoleGuest is a user without an account who joined a single test.
+	RoleGuest UserRole = "guest"
 )
 
 type UserAuthData struct {
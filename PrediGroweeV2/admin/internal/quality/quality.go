@@ -0,0 +1,235 @@
+// Package quality builds the question-quality report and feeds flagged
+// questions into the review queue kept by the quiz service.
+package quality
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"admin/clients"
+	"admin/internal/models"
+	"go.uber.org/zap"
+)
+
+const (
+	// minResponses is the number of answers below which accuracy and
+	// discrimination are too noisy to flag a question.
+	minResponses = 30
+	// wilsonZ gives a two-sided 95% interval.
+	wilsonZ = 1.96
+	// minOpenReports is how many unanswered reports on a question's case make
+	// it heavily reported.
+	minOpenReports = 3
+	// A question is polarising when enough users voted on its difficulty and
+	// the smaller of the easy and hard camps is at least this share of votes.
+	minPolarisingVotes = 10
+	minPolarisingShare = 0.4
+)
+
+type Service struct {
+	quizClient  clients.QuizClient
+	statsClient clients.StatsClient
+	logger      *zap.Logger
+}
+
+func NewService(quizClient clients.QuizClient, statsClient clients.StatsClient, logger *zap.Logger) *Service {
+	return &Service{
+		quizClient:  quizClient,
+		statsClient: statsClient,
+		logger:      logger,
+	}
+}
+
+// Report gathers accuracy, discrimination, difficulty votes and open case
+// reports for every question and flags the suspicious ones. IRT parameters
+// are optional: without a calibration run the report still works.
+func (s *Service) Report() (models.QuestionQualityReport, error) {
+	var (
+		wg             sync.WaitGroup
+		questions      []models.Question
+		stats          []models.QuestionStats
+		discrimination []models.QuestionDiscrimination
+		votes          []models.QuestionDifficultySummary
+		reports        []models.CaseReport
+		irt            []models.IRTItemParams
+		errs           [6]error
+	)
+	fetch := func(i int, f func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = f()
+		}()
+	}
+	fetch(0, func() (err error) { questions, err = s.quizClient.GetAllQuestions(); return })
+	fetch(1, func() (err error) { stats, err = s.statsClient.GetStatsForAllQuestions(); return })
+	fetch(2, func() (err error) { discrimination, err = s.statsClient.GetQuestionDiscrimination(); return })
+	fetch(3, func() (err error) { votes, err = s.quizClient.GetDifficultySummaries(); return })
+	fetch(4, func() (err error) { reports, err = s.quizClient.GetReports(); return })
+	fetch(5, func() (err error) { irt, err = s.statsClient.GetIRTItemParams(); return })
+	wg.Wait()
+	if errs[5] != nil {
+		s.logger.Warn("quality report without irt parameters", zap.Error(errs[5]))
+	}
+	if err := errors.Join(errs[:5]...); err != nil {
+		return models.QuestionQualityReport{}, fmt.Errorf("failed to gather question data: %w", err)
+	}
+
+	statsByQuestion := make(map[int]models.QuestionStats, len(stats))
+	for _, st := range stats {
+		statsByQuestion[st.QuestionID] = st
+	}
+	discByQuestion := make(map[int]models.QuestionDiscrimination, len(discrimination))
+	for _, d := range discrimination {
+		discByQuestion[d.QuestionID] = d
+	}
+	votesByQuestion := make(map[int]models.QuestionDifficultySummary, len(votes))
+	for _, v := range votes {
+		votesByQuestion[v.QuestionID] = v
+	}
+	irtByQuestion := make(map[int]models.IRTItemParams, len(irt))
+	for _, it := range irt {
+		irtByQuestion[it.QuestionID] = it
+	}
+	openReports := make(map[int]int)
+	for _, r := range reports {
+		if r.AdminNote == nil || strings.TrimSpace(*r.AdminNote) == "" {
+			openReports[r.CaseID]++
+		}
+	}
+
+	report := models.QuestionQualityReport{
+		GeneratedAt: time.Now().UTC(),
+		Questions:   make([]models.QuestionQuality, 0, len(questions)),
+	}
+	for _, q := range questions {
+		st := statsByQuestion[q.ID]
+		v := votesByQuestion[q.ID]
+		qq := models.QuestionQuality{
+			QuestionID:    q.ID,
+			Question:      q.Question,
+			CaseID:        q.Case.ID,
+			CaseCode:      q.Case.Code,
+			Options:       len(q.Options),
+			Total:         st.Total,
+			Correct:       st.Correct,
+			PointBiserial: discByQuestion[q.ID].PointBiserial,
+			TotalVotes:    v.TotalVotes,
+			HardVotes:     v.HardVotes,
+			EasyVotes:     v.EasyVotes,
+			OpenReports:   openReports[q.Case.ID],
+		}
+		if qq.Options > 0 {
+			qq.Chance = 1 / float64(qq.Options)
+		}
+		if qq.Total > 0 {
+			qq.Accuracy = float64(qq.Correct) / float64(qq.Total)
+			qq.AccuracyUpper = wilsonUpper(qq.Correct, qq.Total)
+		}
+		if it, ok := irtByQuestion[q.ID]; ok {
+			qq.IRT = &it
+		}
+		qq.Flags = flags(qq, discByQuestion[q.ID].Responses)
+		if len(qq.Flags) > 0 {
+			report.Flagged++
+		}
+		report.Questions = append(report.Questions, qq)
+	}
+	sort.SliceStable(report.Questions, func(i, j int) bool {
+		a, b := report.Questions[i], report.Questions[j]
+		if len(a.Flags) != len(b.Flags) {
+			return len(a.Flags) > len(b.Flags)
+		}
+		return a.QuestionID < b.QuestionID
+	})
+	return report, nil
+}
+
+// flags applies the quality rules. The 2PL model constrains discrimination to
+// be positive, so the sign comes from the point-biserial correlation; a
+// negative one usually means a wrong answer key.
+func flags(q models.QuestionQuality, discResponses int) []string {
+	out := make([]string, 0)
+	if q.Chance > 0 && q.Total >= minResponses && q.AccuracyUpper < q.Chance {
+		out = append(out, models.QualityFlagBelowChance)
+	}
+	if q.PointBiserial != nil && *q.PointBiserial < 0 && discResponses >= minResponses {
+		out = append(out, models.QualityFlagNegativeDiscrimination)
+	}
+	if q.OpenReports >= minOpenReports {
+		out = append(out, models.QualityFlagHeavilyReported)
+	}
+	if q.TotalVotes >= minPolarisingVotes &&
+		float64(min(q.EasyVotes, q.HardVotes)) >= minPolarisingShare*float64(q.TotalVotes) {
+		out = append(out, models.QualityFlagPolarising)
+	}
+	return out
+}
+
+// wilsonUpper is the upper bound of the Wilson score interval for a share.
+func wilsonUpper(successes, n int) float64 {
+	p := float64(successes) / float64(n)
+	nf := float64(n)
+	z2 := wilsonZ * wilsonZ
+	centre := p + z2/(2*nf)
+	margin := wilsonZ * math.Sqrt(p*(1-p)/nf+z2/(4*nf*nf))
+	return math.Min(1, (centre+margin)/(1+z2/nf))
+}
+
+// Sync builds the report and opens reviews for flagged questions. It returns
+// the report and the number of newly opened reviews.
+func (s *Service) Sync() (models.QuestionQualityReport, int, error) {
+	report, err := s.Report()
+	if err != nil {
+		return report, 0, err
+	}
+	candidates := make([]models.QuestionReviewCandidate, 0, report.Flagged)
+	for _, q := range report.Questions {
+		if len(q.Flags) == 0 {
+			continue
+		}
+		reasons := append([]string(nil), q.Flags...)
+		sort.Strings(reasons)
+		candidates = append(candidates, models.QuestionReviewCandidate{QuestionID: q.QuestionID, Reasons: reasons})
+	}
+	if len(candidates) == 0 {
+		return report, 0, nil
+	}
+	opened, err := s.quizClient.EnqueueQuestionReviews(candidates)
+	if err != nil {
+		return report, 0, fmt.Errorf("failed to enqueue reviews: %w", err)
+	}
+	return report, opened, nil
+}
+
+// Start runs Sync every interval until ctx is cancelled. A zero interval
+// disables it.
+func (s *Service) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		s.logger.Info("scheduled question quality review disabled")
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				report, opened, err := s.Sync()
+				if err != nil {
+					s.logger.Error("question quality review failed", zap.Error(err))
+					continue
+				}
+				s.logger.Info("question quality review finished", zap.Int("flagged", report.Flagged), zap.Int("opened", opened))
+			}
+		}
+	}()
+}
@@ -99,6 +99,13 @@ func (a *ApiServer) registerRoutes(router *http.ServeMux) {
 	router.HandleFunc("PUT /auth/roles/{id}", middleware.InternalAuth(handlers.NewUpdateRoleHandler(a.storage, a.logger).Handle, a.logger, internalApiKey))
 	router.HandleFunc("DELETE /auth/roles/{id}", middleware.InternalAuth(handlers.NewDeleteRoleHandler(a.storage, a.logger).Handle, a.logger, internalApiKey))
 
+	guestHandler := handlers.NewGuestHandler(a.storage, a.logger)
+	router.HandleFunc("POST /auth/guests", middleware.InternalAuth(guestHandler.Create, a.logger, internalApiKey))
+	router.HandleFunc("POST /auth/guests/claim", middleware.InternalAuth(guestHandler.Claim, a.logger, internalApiKey))
+	ltiHandler := handlers.NewLTIHandler(a.storage, a.logger)
+	router.HandleFunc("POST /auth/lti/users", middleware.InternalAuth(ltiHandler.Provision, a.logger, internalApiKey))
+	router.HandleFunc("POST /auth/lti/links", middleware.InternalAuth(ltiHandler.Link, a.logger, internalApiKey))
+
 	router.HandleFunc("GET /auth/summary", middleware.InternalAuth(handlers.NewSummaryHandler(a.storage, a.logger).Handle, a.logger, internalApiKey))
 
 	router.HandleFunc("POST /auth/notify-approved",
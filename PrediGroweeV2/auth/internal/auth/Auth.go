@@ -31,6 +31,22 @@ func GenerateAccessToken(userID string) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
 }
+
+// GuestTokenTTL is how long a guest can use their test. Guests have no
+// session to refresh the token with.
+const GuestTokenTTL = 3 * time.Hour
+
+func GenerateGuestAccessToken(userID string) (string, time.Time, error) {
+	expiresAt := time.Now().Add(GuestTokenTTL)
+	claims := jwt.MapClaims{
+		"sub": userID,
+		"exp": expiresAt.Unix(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	return signed, expiresAt, err
+}
+
 func ExtractAccessTokenFromRequest(r *http.Request) (string, error) {
 	authHeader := r.Header.Get("Authorization")
 	if authHeader != "" {
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"auth/internal/auth"
+	"auth/internal/models"
+	"auth/internal/storage"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxGuestNicknameLength = 40
+
+// GuestHandler issues guest users for the quiz service. The quiz service
+// checks that the test accepts guests before calling Create, so both
+// endpoints are internal.
+type GuestHandler struct {
+	storage storage.Store
+	logger  *zap.Logger
+}
+
+func NewGuestHandler(store storage.Store, logger *zap.Logger) *GuestHandler {
+	return &GuestHandler{storage: store, logger: logger}
+}
+
+// POST /auth/guests  (internal)
+func (h *GuestHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var payload models.GuestPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "invalid request payload", http.StatusBadRequest)
+		return
+	}
+	payload.Nickname = strings.TrimSpace(payload.Nickname)
+	payload.TestCode = strings.ToUpper(strings.TrimSpace(payload.TestCode))
+	if payload.Nickname == "" || utf8.RuneCountInString(payload.Nickname) > maxGuestNicknameLength || payload.TestCode == "" {
+		http.Error(w, "nickname and test_code are required", http.StatusBadRequest)
+		return
+	}
+
+	claimCode, err := auth.GenerateSessionID(10)
+	if err != nil {
+		h.logger.Error("failed to generate claim code", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	guest, err := h.storage.CreateGuestUser(payload.Nickname, payload.TestCode, hashClaimCode(claimCode))
+	if err != nil {
+		h.logger.Error("failed to create guest user", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	accessToken, expiresAt, err := auth.GenerateGuestAccessToken(strconv.Itoa(guest.ID))
+	if err != nil {
+		h.logger.Error("failed to generate guest access token", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"user_id":      guest.ID,
+		"nickname":     guest.FirstName,
+		"test_code":    payload.TestCode,
+		"access_token": accessToken,
+		"claim_code":   claimCode,
+		"expires_at":   expiresAt.UTC(),
+	})
+}
+
+// POST /auth/guests/claim  (internal)
+func (h *GuestHandler) Claim(w http.ResponseWriter, r *http.Request) {
+	var payload models.GuestClaimPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "invalid request payload", http.StatusBadRequest)
+		return
+	}
+	claimCode := strings.ToLower(strings.TrimSpace(payload.ClaimCode))
+	if claimCode == "" || payload.UserID <= 0 {
+		http.Error(w, "claim_code and user_id are required", http.StatusBadRequest)
+		return
+	}
+	guestID, err := h.storage.ClaimGuestUser(hashClaimCode(claimCode), payload.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to claim guest user", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.logger.Info("guest claimed", zap.Int("guest_user_id", guestID), zap.Int("user_id", payload.UserID))
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]int{"guest_user_id": guestID})
+}
+
+// hashClaimCode keeps claim codes out of the database. The codes are random,
+// so unlike passwords they need no slow hash.
+func hashClaimCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
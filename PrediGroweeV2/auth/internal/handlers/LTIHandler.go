@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"auth/internal/auth"
+	"auth/internal/models"
+	"auth/internal/storage"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ltiSessionTTL matches the session of a password login.
+const ltiSessionTTL = 7 * 24 * time.Hour
+
+// LTIHandler signs in users who open a test from an LMS. The quiz service
+// validates the launch and calls Provision, so the endpoint is internal.
+type LTIHandler struct {
+	storage storage.Store
+	logger  *zap.Logger
+}
+
+func NewLTIHandler(store storage.Store, logger *zap.Logger) *LTIHandler {
+	return &LTIHandler{storage: store, logger: logger}
+}
+
+// POST /auth/lti/users  (internal)
+//
+// Returns the user linked to the LMS identity, created if needed, and a new
+// login session for them. The quiz service sets the session cookie. Until
+// the identity is linked to an account of the user's choice, a new
+// link_code is included for that.
+func (h *LTIHandler) Provision(w http.ResponseWriter, r *http.Request) {
+	var payload models.LTIUserPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "invalid request payload", http.StatusBadRequest)
+		return
+	}
+	payload.Issuer = strings.TrimSpace(payload.Issuer)
+	payload.Subject = strings.TrimSpace(payload.Subject)
+	payload.Email = strings.ToLower(strings.TrimSpace(payload.Email))
+	if payload.Issuer == "" || payload.Subject == "" {
+		http.Error(w, "issuer and subject are required", http.StatusBadRequest)
+		return
+	}
+
+	linkCode, err := auth.GenerateSessionID(32)
+	if err != nil {
+		h.logger.Error("failed to generate lti link code", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	userID, linkable, err := h.storage.ProvisionLTIUser(payload, hashClaimCode(linkCode))
+	if err != nil {
+		h.logger.Error("failed to provision lti user", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	sessionID, err := auth.GenerateSessionID(64)
+	if err != nil {
+		h.logger.Error("failed to generate session id", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	expiresAt := time.Now().Add(ltiSessionTTL)
+	if err := h.storage.SaveUserSession(models.UserSession{UserID: userID, SessionID: sessionID, Expiration: expiresAt}); err != nil {
+		h.logger.Error("failed to save lti user session", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := map[string]interface{}{
+		"user_id":    userID,
+		"session_id": sessionID,
+		"expires_at": expiresAt.UTC(),
+	}
+	if linkable {
+		resp["link_code"] = linkCode
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// POST /auth/lti/links  (internal)
+//
+// Links the LMS identity with the link code to user_id, whose user signed in
+// and confirmed it, and returns the account the identity had so that its
+// sessions can be moved.
+func (h *LTIHandler) Link(w http.ResponseWriter, r *http.Request) {
+	var payload models.LTILinkPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "invalid request payload", http.StatusBadRequest)
+		return
+	}
+	linkCode := strings.ToLower(strings.TrimSpace(payload.LinkCode))
+	if linkCode == "" || payload.UserID <= 0 {
+		http.Error(w, "link_code and user_id are required", http.StatusBadRequest)
+		return
+	}
+	fromUserID, err := h.storage.LinkLTIIdentity(hashClaimCode(linkCode), payload.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrLTILinkNotAllowed) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to link lti identity", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.logger.Info("lti identity linked", zap.Int("from_user_id", fromUserID), zap.Int("user_id", payload.UserID))
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]int{"from_user_id": fromUserID})
+}
@@ -20,6 +20,10 @@ func (h *VerifyTokenHandler) Handle(rw http.ResponseWriter, r *http.Request) {
 		"user_id": userID,
 		"role":    userRole,
 	}
+	// guests are restricted to the test they joined
+	if testCode, ok := r.Context().Value("guest_test_code").(string); ok {
+		resp["test_code"] = testCode
+	}
 
 	rw.Header().Set("Content-Type", "application/json")
 	rw.WriteHeader(http.StatusOK)
@@ -55,6 +55,9 @@ func ValidateAccessToken(next http.HandlerFunc, storage storage.Store) http.Hand
 		// Add user information to the request context
 		ctx := context.WithValue(r.Context(), "user_id", user.ID)
 		ctx = context.WithValue(ctx, "user_role", user.Role)
+		if user.GuestTestCode != nil {
+			ctx = context.WithValue(ctx, "guest_test_code", *user.GuestTestCode)
+		}
 		r = r.WithContext(ctx)
 
 		log.Println("Access token validated successfully")
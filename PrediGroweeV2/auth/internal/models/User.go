@@ -12,6 +12,9 @@ const (
 	RoleAdmin   UserRole = "admin"
 	RoleUser    UserRole = "user"
 	RoleTeacher UserRole = "teacher"
+	// RoleGuest is a user without an account who joined one test with a
+	// nickname. Guests cannot log in; they only hold a short-lived token.
+	RoleGuest UserRole = "guest"
 )
 
 type User struct {
@@ -24,6 +27,7 @@ type User struct {
 	Role            UserRole   `json:"role"`
 	CreatedAt       string     `json:"created_at"`
 	Verified        bool       `json:"verified"`
+	GuestTestCode   *string    `json:"guest_test_code,omitempty"`
 }
 
 func (u *User) Validate() error { return validator.New().Struct(u) }
@@ -57,3 +61,34 @@ type UserUpdatePayload struct {
 func (u *UserUpdatePayload) FromJSON(r io.Reader) error { return json.NewDecoder(r).Decode(u) }
 func (u *UserUpdatePayload) ToJSON(w io.Writer) error   { return json.NewEncoder(w).Encode(u) }
 
+
+// GuestPayload is sent by the quiz service when a guest joins a test.
+type GuestPayload struct {
+	Nickname string `json:"nickname"`
+	TestCode string `json:"test_code"`
+}
+
+// GuestClaimPayload is sent by the quiz service when a user claims the
+// sessions of a guest.
+type GuestClaimPayload struct {
+	ClaimCode string `json:"claim_code"`
+	UserID    int    `json:"user_id"`
+}
+
+// LTIUserPayload is sent by the quiz service when someone opens a test from
+// an LMS. Issuer and Subject identify them on the platform; the rest comes
+// from the launch and may be empty.
+type LTIUserPayload struct {
+	Issuer    string `json:"issuer"`
+	Subject   string `json:"subject"`
+	Email     string `json:"email"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+// LTILinkPayload is sent by the quiz service when a signed-in user confirms
+// linking the LMS identity of a launch to their account.
+type LTILinkPayload struct {
+	LinkCode string `json:"link_code"`
+	UserID   int    `json:"user_id"`
+}
@@ -3,10 +3,15 @@ package storage
 import (
 	"auth/internal/models"
 	"database/sql"
+	"errors"
 	"fmt"
 	"go.uber.org/zap"
 )
 
+// ErrLTILinkNotAllowed is returned when an LMS identity is to be linked to
+// an account that cannot have one.
+var ErrLTILinkNotAllowed = errors.New("account cannot be linked to an lms identity")
+
 type Store interface {
 	Ping() error
 	Close() error
@@ -29,6 +34,10 @@ type Store interface {
 	GetActiveUsersCount() int
 	GetLast24hRegisteredCount() int
 	UpdateUserPassword(userID int, hashedPassword string) error
+	CreateGuestUser(nickname, testCode, claimCodeHash string) (*models.User, error)
+	ClaimGuestUser(claimCodeHash string, userID int) (int, error)
+	ProvisionLTIUser(payload models.LTIUserPayload, linkCodeHash string) (userID int, linkable bool, err error)
+	LinkLTIIdentity(linkCodeHash string, userID int) (int, error)
 }
 type FirestoreStorage struct {
 	config string
@@ -61,12 +70,12 @@ func (p *PostgresStorage) CreateUser(user *models.User) (*models.User, error) {
 func (p *PostgresStorage) GetUserById(id int, withPwd bool) (*models.User, error) {
 	var user models.User
 	if withPwd {
-		err := p.db.QueryRow("SELECT id, first_name, last_name, email, pwd, role, google_id, verified, created_at FROM users WHERE id = $1", id).Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.Password, &user.Role, &user.GoogleID, &user.Verified, &user.CreatedAt)
+		err := p.db.QueryRow("SELECT id, first_name, last_name, email, pwd, role, COALESCE(google_id, ''), verified, created_at, guest_test_code FROM users WHERE id = $1", id).Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.Password, &user.Role, &user.GoogleID, &user.Verified, &user.CreatedAt, &user.GuestTestCode)
 		if err != nil {
 			return nil, err
 		}
 	} else {
-		err := p.db.QueryRow("SELECT id, first_name, last_name, email, role, google_id, verified, created_at FROM users WHERE id = $1", id).Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.Role, &user.GoogleID, &user.Verified, &user.CreatedAt)
+		err := p.db.QueryRow("SELECT id, first_name, last_name, email, role, COALESCE(google_id, ''), verified, created_at, guest_test_code FROM users WHERE id = $1", id).Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.Role, &user.GoogleID, &user.Verified, &user.CreatedAt, &user.GuestTestCode)
 		if err != nil {
 			return nil, err
 		}
@@ -75,7 +84,7 @@ func (p *PostgresStorage) GetUserById(id int, withPwd bool) (*models.User, error
 }
 func (p *PostgresStorage) GetUserByIdInternal(id int) (*models.User, error) {
 	var user models.User
-	err := p.db.QueryRow("SELECT id, email, pwd, created_at, first_name, last_name, role, google_id, verified FROM users WHERE id = $1", id).Scan(&user.ID, &user.Email, &user.Password, &user.CreatedAt, &user.FirstName, &user.LastName, &user.Role, &user.GoogleID, &user.Verified)
+	err := p.db.QueryRow("SELECT id, email, pwd, created_at, first_name, last_name, role, COALESCE(google_id, ''), verified FROM users WHERE id = $1", id).Scan(&user.ID, &user.Email, &user.Password, &user.CreatedAt, &user.FirstName, &user.LastName, &user.Role, &user.GoogleID, &user.Verified)
 	if err != nil {
 		return nil, err
 	}
@@ -84,7 +93,7 @@ func (p *PostgresStorage) GetUserByIdInternal(id int) (*models.User, error) {
 
 func (p *PostgresStorage) GetUserByEmail(email string) (*models.User, error) {
 	var user models.User
-	err := p.db.QueryRow("SELECT id, first_name, last_name, email, pwd, role, google_id, verified FROM users WHERE email = $1", email).Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.Password, &user.Role, &user.GoogleID, &user.Verified)
+	err := p.db.QueryRow("SELECT id, first_name, last_name, email, pwd, role, COALESCE(google_id, ''), verified FROM users WHERE email = $1 AND role <> 'guest'", email).Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.Password, &user.Role, &user.GoogleID, &user.Verified)
 	if err != nil {
 		return nil, err
 	}
@@ -111,7 +120,7 @@ func (p *PostgresStorage) GetUserSessionBySessionID(sessionID string) (models.Us
 }
 
 func (p *PostgresStorage) GetAllUsers() ([]models.User, error) {
-	rows, err := p.db.Query("SELECT id, email, first_name, last_name, role, google_id, created_at FROM users ORDER BY id")
+	rows, err := p.db.Query("SELECT id, email, first_name, last_name, role, COALESCE(google_id, ''), created_at, verified, guest_test_code FROM users ORDER BY id")
 	if err != nil {
 		return nil, fmt.Errorf("error fetching users: %w", err)
 	}
@@ -120,7 +129,7 @@ func (p *PostgresStorage) GetAllUsers() ([]models.User, error) {
 	var users []models.User
 	for rows.Next() {
 		var user models.User
-		err := rows.Scan(&user.ID, &user.Email, &user.FirstName, &user.LastName, &user.Role, &user.GoogleID, &user.CreatedAt)
+		err := rows.Scan(&user.ID, &user.Email, &user.FirstName, &user.LastName, &user.Role, &user.GoogleID, &user.CreatedAt, &user.Verified, &user.GuestTestCode)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning user: %w", err)
 		}
@@ -213,7 +222,7 @@ func (p *PostgresStorage) DeleteRole(id int) error {
 
 func (p *PostgresStorage) GetUsersCount() int {
 	var count int
-	err := p.db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count)
+	err := p.db.QueryRow("SELECT COUNT(*) FROM users WHERE role <> 'guest'").Scan(&count)
 	if err != nil {
 		return 0
 	}
@@ -231,7 +240,7 @@ func (p *PostgresStorage) GetActiveUsersCount() int {
 
 func (p *PostgresStorage) GetLast24hRegisteredCount() int {
 	var count int
-	err := p.db.QueryRow("SELECT COUNT(*) FROM users WHERE created_at > NOW() - INTERVAL '24 hours'").Scan(&count)
+	err := p.db.QueryRow("SELECT COUNT(*) FROM users WHERE role <> 'guest' AND created_at > NOW() - INTERVAL '24 hours'").Scan(&count)
 	if err != nil {
 		return 0
 	}
@@ -240,4 +249,119 @@ func (p *PostgresStorage) GetLast24hRegisteredCount() int {
 func (p *PostgresStorage) UpdateUserPassword(userID int, hashedPassword string) error {
 	_, err := p.db.Exec("UPDATE users SET pwd = $1 WHERE id = $2", hashedPassword, userID)
 	return err
-}
\ No newline at end of file
+}
+
+// CreateGuestUser stores a guest restricted to one test. Guests have no
+// email or password and are never verified, so they cannot log in.
+func (p *PostgresStorage) CreateGuestUser(nickname, testCode, claimCodeHash string) (*models.User, error) {
+	user := models.User{FirstName: nickname, Role: models.RoleGuest, GuestTestCode: &testCode}
+	err := p.db.QueryRow(
+		"INSERT INTO users (first_name, last_name, email, pwd, role, verified, guest_test_code, guest_claim_code_hash) VALUES ($1, '', '', '', $2, false, $3, $4) RETURNING id, created_at",
+		nickname, models.RoleGuest, testCode, claimCodeHash,
+	).Scan(&user.ID, &user.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("error creating guest user: %w", err)
+	}
+	return &user, nil
+}
+
+// ClaimGuestUser marks the guest with the given claim code as claimed by
+// userID and returns the guest's id. Claiming again into the same account
+// succeeds; an unknown code or one claimed by another account returns
+// sql.ErrNoRows.
+func (p *PostgresStorage) ClaimGuestUser(claimCodeHash string, userID int) (int, error) {
+	var guestID int
+	err := p.db.QueryRow(`
+		UPDATE users
+		   SET guest_claimed_by = $2, guest_claimed_at = COALESCE(guest_claimed_at, NOW())
+		 WHERE role = 'guest' AND guest_claim_code_hash = $1
+		   AND (guest_claimed_by IS NULL OR guest_claimed_by = $2)
+		RETURNING id`, claimCodeHash, userID).Scan(&guestID)
+	return guestID, err
+}
+
+// ProvisionLTIUser returns the account linked to the LMS identity, creating
+// a student account on first launch. Existing accounts are never picked by
+// the launch's email, which the platform could set to anyone's; their users
+// link them with LinkLTIIdentity. The email is kept only if no account has
+// it yet, so it still names one account at login.
+//
+// While the identity has not been linked, linkCodeHash replaces its link
+// code and linkable is true.
+func (p *PostgresStorage) ProvisionLTIUser(payload models.LTIUserPayload, linkCodeHash string) (userID int, linkable bool, err error) {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow("SELECT user_id FROM lti_identities WHERE issuer = $1 AND subject = $2", payload.Issuer, payload.Subject).Scan(&userID)
+	if err == sql.ErrNoRows {
+		err = tx.QueryRow(`
+			INSERT INTO users (first_name, last_name, email, pwd, role, verified)
+			VALUES ($1, $2, CASE WHEN $3 = '' OR EXISTS (SELECT 1 FROM users WHERE email = $3) THEN '' ELSE $3 END, '', $4, true)
+			RETURNING id`,
+			payload.FirstName, payload.LastName, payload.Email, models.RoleUser,
+		).Scan(&userID)
+		if err != nil {
+			return 0, false, fmt.Errorf("error provisioning lti user: %w", err)
+		}
+		// a concurrent first launch may have linked the identity meanwhile
+		if _, err := tx.Exec(
+			"INSERT INTO lti_identities (issuer, subject, user_id) VALUES ($1, $2, $3) ON CONFLICT (issuer, subject) DO NOTHING",
+			payload.Issuer, payload.Subject, userID,
+		); err != nil {
+			return 0, false, err
+		}
+		err = tx.QueryRow("SELECT user_id FROM lti_identities WHERE issuer = $1 AND subject = $2", payload.Issuer, payload.Subject).Scan(&userID)
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	res, err := tx.Exec(
+		"UPDATE lti_identities SET link_code_hash = $3 WHERE issuer = $1 AND subject = $2 AND linked_at IS NULL",
+		payload.Issuer, payload.Subject, linkCodeHash,
+	)
+	if err != nil {
+		return 0, false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, false, err
+	}
+	return userID, n > 0, tx.Commit()
+}
+
+// LinkLTIIdentity moves the LMS identity with the given link code to userID,
+// the account its user signed in to, and returns the account it had. The
+// code can be used once. An unknown or used code returns sql.ErrNoRows;
+// ErrLTILinkNotAllowed is returned for admin and guest accounts, which never
+// sign in through an LMS.
+func (p *PostgresStorage) LinkLTIIdentity(linkCodeHash string, userID int) (int, error) {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var role models.UserRole
+	if err := tx.QueryRow("SELECT role FROM users WHERE id = $1", userID).Scan(&role); err != nil {
+		return 0, err
+	}
+	if role != models.RoleUser && role != models.RoleTeacher {
+		return 0, ErrLTILinkNotAllowed
+	}
+	var fromUserID int
+	err = tx.QueryRow(`
+		UPDATE lti_identities i
+		   SET user_id = $2, link_code_hash = NULL, linked_at = NOW()
+		  FROM lti_identities old
+		 WHERE i.issuer = old.issuer AND i.subject = old.subject
+		   AND i.link_code_hash = $1 AND i.linked_at IS NULL
+		RETURNING old.user_id`, linkCodeHash, userID).Scan(&fromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return fromUserID, tx.Commit()
+}
@@ -0,0 +1,13 @@
+-- Guest users: students who join a single test with a nickname instead of
+-- registering. They are stored as users with role 'guest' so that their
+-- sessions are recorded like everyone else's. The claim code (stored hashed)
+-- lets a guest move those sessions into a full account later.
+
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS guest_test_code text;
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS guest_claim_code_hash text;
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS guest_claimed_by integer;
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS guest_claimed_at timestamp without time zone;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_guest_claim_code_hash_idx
+    ON public.users (guest_claim_code_hash)
+    WHERE guest_claim_code_hash IS NOT NULL;
@@ -0,0 +1,13 @@
+-- LTI identities link a user of a university LMS (the platform's issuer and
+-- the user's subject id there) to the account they are signed in to when
+-- they open a test from the LMS. Accounts are created on first launch.
+
+CREATE TABLE IF NOT EXISTS public.lti_identities (
+    issuer     text NOT NULL,
+    subject    text NOT NULL,
+    user_id    integer NOT NULL REFERENCES public.users (id) ON DELETE CASCADE,
+    created_at timestamp without time zone NOT NULL DEFAULT now(),
+    PRIMARY KEY (issuer, subject)
+);
+
+CREATE INDEX IF NOT EXISTS lti_identities_user_idx ON public.lti_identities (user_id);
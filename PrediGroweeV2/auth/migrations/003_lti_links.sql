@@ -0,0 +1,11 @@
+-- An LMS identity seen for the first time gets a new student account. Its
+-- user can move the identity to an account they already have by signing in
+-- to it and confirming with the link code handed out at launch (stored
+-- hashed); linked_at records that, after which no code is issued.
+
+ALTER TABLE public.lti_identities ADD COLUMN IF NOT EXISTS link_code_hash text;
+ALTER TABLE public.lti_identities ADD COLUMN IF NOT EXISTS linked_at timestamp without time zone;
+
+CREATE UNIQUE INDEX IF NOT EXISTS lti_identities_link_code_hash_idx
+    ON public.lti_identities (link_code_hash)
+    WHERE link_code_hash IS NOT NULL;
@@ -33,6 +33,10 @@ set +a  # stop automatically exporting
 #    docker-compose -f docker-compose.prod.yml exec -T ${service}_db pg_dump -U ${service}_user ${service}_db > "$BASE_DIR/db_backups/$timestamp/${service}_db_backup.sql" || echo "Warning: Could not backup ${service}_db"
 #done
 
+# Apply new database migrations before the services that need them start
+echo "🗄️ Applying database migrations..."
+./migrate.sh docker-compose.prod.yml
+
 # Start or update the services
 echo "🔄 Starting/updating services..."
 docker-compose -f docker-compose.prod.yml up  --build
@@ -38,7 +38,11 @@ func (h *QuestionImagesHandler) Handle(rw http.ResponseWriter, r *http.Request)
 		return
 	}
 	var imagePath string
-	err = h.db.QueryRow("SELECT image"+id+"_path FROM question_images WHERE question_id = $1", questionID).Scan(&imagePath)
+	err = h.db.QueryRow("SELECT COALESCE(image"+id+"_path, '') FROM question_images WHERE question_id = $1", questionID).Scan(&imagePath)
+	if err == sql.ErrNoRows || (err == nil && imagePath == "") {
+		http.Error(rw, "Image not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(rw, "Failed to get image", http.StatusInternalServerError)
 		return
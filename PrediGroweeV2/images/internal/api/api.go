@@ -70,7 +70,10 @@ func (a *ApiServer) Run() {
 
 }
 func (a *ApiServer) registerRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("GET /images/questions/{questionId}/image/{id}", middleware.VerifyToken(NewQuestionImagesHandler(a.logger, a.db).Handle, a.authClient))
+	questionImagesHandler := NewQuestionImagesHandler(a.logger, a.db)
+	mux.HandleFunc("GET /images/questions/{questionId}/image/{id}", middleware.VerifyToken(questionImagesHandler.Handle, a.authClient))
+	// for other services, e.g. the quiz QTI export
+	mux.HandleFunc("GET /images/internal/questions/{questionId}/image/{id}", middleware.InternalAuth(questionImagesHandler.Handle, a.logger, os.Getenv("INTERNAL_API_KEY")))
 
 	paramImagesHandler := NewParamImagesHandler(a.logger, a.db)
 	mux.HandleFunc("GET /images/params/{id}", paramImagesHandler.GetImage)
@@ -0,0 +1,55 @@
+#!/bin/bash
+
+# Applies the SQL migrations of each service (<service>/migrations/*.sql) to
+# its database, in file name order. Applied files are recorded in the
+# schema_migrations table of that database, so each runs once.
+#
+# The databases must already hold the base schema from backups/.
+#
+# Usage: ./migrate.sh [compose file]   (default: docker-compose.yml)
+
+# Exit on any error
+set -e
+
+COMPOSE_FILE=${1:-docker-compose.yml}
+
+cd "$(dirname "$0")"
+
+services=("auth" "quiz" "stats")
+
+echo "🗄️ Starting databases..."
+docker-compose -f "$COMPOSE_FILE" up -d "${services[@]/%/_db}"
+
+for service in "${services[@]}"
+do
+    db="${service}_db"
+
+    # psql with the user and database the container was created with
+    psql() {
+        docker-compose -f "$COMPOSE_FILE" exec -T "$db" \
+            sh -c 'psql -v ON_ERROR_STOP=1 -q -U "$POSTGRES_USER" -d "$POSTGRES_DB" "$@"' psql "$@"
+    }
+
+    until docker-compose -f "$COMPOSE_FILE" exec -T "$db" sh -c 'pg_isready -q -U "$POSTGRES_USER"'; do
+        sleep 1
+    done
+
+    psql -c "CREATE TABLE IF NOT EXISTS schema_migrations (
+        version text PRIMARY KEY,
+        applied_at timestamp with time zone NOT NULL DEFAULT now()
+    )"
+
+    for file in "$service"/migrations/*.sql
+    do
+        version=$(basename "$file")
+        applied=$(psql -tA -c "SELECT 1 FROM schema_migrations WHERE version = '$version'")
+        if [ -n "$applied" ]; then
+            continue
+        fi
+        echo "Applying $file..."
+        # the version is only recorded if the whole file ran
+        { cat "$file"; echo; echo "INSERT INTO schema_migrations (version) VALUES ('$version');"; } | psql
+    done
+done
+
+echo "✅ Migrations applied"
@@ -61,11 +61,12 @@ func main() {
 		logger.Fatal("Failed to ping database, exiting", zap.Error(err))
 	}
 	postgresStorage := storage.NewPostgresStorage(db, logger)
-	authClient := clients.NewAuthClient("http://auth:8080/auth", logger)
+	authClient := clients.NewAuthClient("http://auth:8080/auth", os.Getenv("INTERNAL_API_KEY"), logger)
 	logger.Info("Connected to auth service")
 	statsClient := clients.NewStatsClient("http://stats:8080/stats", os.Getenv("INTERNAL_API_KEY"), logger)
 	logger.Info("Connected to stats service")
-	apiServer := api.NewApiServer(":8080", postgresStorage, logger, authClient, statsClient)
+	imagesClient := clients.NewImagesClient("http://images:8080/images", os.Getenv("INTERNAL_API_KEY"), logger)
+	apiServer := api.NewApiServer(":8080", postgresStorage, logger, authClient, statsClient, imagesClient)
 	apiServer.Run()
 }
 func connectToPostgres() (*sql.DB, error) {
@@ -4,35 +4,97 @@ import (
 	"context"
 	"github.com/rs/cors"
 	"go.uber.org/zap"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"quiz/internal/certificates"
 	"quiz/internal/clients"
 	"quiz/internal/handlers"
+	"quiz/internal/live"
+	"quiz/internal/lti"
 	"quiz/internal/middleware"
 	"quiz/internal/storage"
+	"quiz/internal/webhooks"
+	"quiz/internal/xapi"
 	"syscall"
 	"time"
 )
 
+// sessionIdleAfter matches the default cutoff of the active sessions list.
+const sessionIdleAfter = 5 * time.Minute
+
 type ApiServer struct {
 	addr        string
 	storage     storage.Store
 	logger      *zap.Logger
 	authClient  *clients.AuthClient
 	statsClient *clients.StatsClient
+	images      *clients.ImagesClient
+	hub         *live.Hub
+	feed        *live.SessionFeed
+	hooks       *webhooks.Dispatcher
+	// lti is nil when LTI_PRIVATE_KEY is not set
+	lti *lti.Tool
+	// xapi is nil when XAPI_LRS_ENDPOINT is not set
+	xapi  *xapi.Emitter
+	certs *certificates.Issuer
 }
 
-func NewApiServer(addr string, store storage.Store, logger *zap.Logger, authClient *clients.AuthClient, statsClient *clients.StatsClient) *ApiServer {
+func NewApiServer(addr string, store storage.Store, logger *zap.Logger, authClient *clients.AuthClient, statsClient *clients.StatsClient, imagesClient *clients.ImagesClient) *ApiServer {
+	hub := live.NewHub()
+	var tool *lti.Tool
+	if key := os.Getenv("LTI_PRIVATE_KEY"); key != "" {
+		var err error
+		tool, err = lti.NewTool(lti.Config{
+			PrivateKeyPEM: key,
+			KeyID:         envOr("LTI_KEY_ID", "predigrowee-1"),
+			ToolURL:       envOr("LTI_TOOL_URL", "https://predigrowee.agh.edu.pl"),
+		}, store, logger, statsClient)
+		if err != nil {
+			logger.Error("lti disabled", zap.Error(err))
+		}
+	}
+	var statements *xapi.Emitter
+	if endpoint := os.Getenv("XAPI_LRS_ENDPOINT"); endpoint != "" {
+		statements = xapi.NewEmitter(xapi.Config{
+			Endpoint: endpoint,
+			Username: os.Getenv("XAPI_LRS_USERNAME"),
+			Password: os.Getenv("XAPI_LRS_PASSWORD"),
+			HomePage: envOr("XAPI_HOMEPAGE", "https://predigrowee.agh.edu.pl"),
+		}, store, logger, statsClient)
+	}
+	milestones, err := certificates.ParseMilestones(envOr("CERTIFICATE_MILESTONES", "200:70"))
+	if err != nil {
+		logger.Error("no milestone certificates", zap.Error(err))
+	}
+	certs := certificates.NewIssuer(certificates.Config{
+		Milestones: milestones,
+		VerifyURL:  envOr("CERTIFICATE_VERIFY_URL", "https://predigrowee.agh.edu.pl/api/quiz/certificates/"),
+	}, store, logger, authClient, statsClient)
 	return &ApiServer{
 		addr:        addr,
 		storage:     store,
 		logger:      logger,
 		authClient:  authClient,
 		statsClient: statsClient,
+		images:      imagesClient,
+		hub:         hub,
+		feed:        live.NewSessionFeed(hub, sessionIdleAfter),
+		hooks:       webhooks.NewDispatcher(store, logger),
+		lti:         tool,
+		xapi:        statements,
+		certs:       certs,
 	}
 }
 
+func envOr(key string, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
 func (a *ApiServer) Run() {
 	a.logger.Info("run server")
 	mux := http.NewServeMux()
@@ -45,13 +107,28 @@ func (a *ApiServer) Run() {
 		AllowedHeaders:   []string{"Authorization", "Content-Type"},
 		ExposedHeaders:   []string{"X-Quiz-Is-Last"},
 	})
+	// event streams only end when their request context does, so shutting
+	// down cancels the base context of every request
+	baseCtx, cancelBase := context.WithCancel(context.Background())
+	defer cancelBase()
 	srv := &http.Server{
 		Addr:         a.addr,
 		Handler:      corsMiddleware.Handler(mux),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
+		BaseContext:  func(net.Listener) context.Context { return baseCtx },
+	}
+	srv.RegisterOnShutdown(cancelBase)
+
+	if active, err := a.storage.ListActiveSessions(int(sessionIdleAfter.Minutes()), 500); err != nil {
+		a.logger.Error("failed to load active sessions for the live feed", zap.Error(err))
+	} else {
+		a.feed.Seed(active)
 	}
+	go a.feed.Run(baseCtx)
+	go a.hooks.Run(baseCtx)
+	go a.xapi.Run(baseCtx)
 	a.logger.Info("about to start the server")
 	go func() {
 		a.logger.Info("Starting server on " + a.addr)
@@ -78,11 +155,16 @@ func (a *ApiServer) registerRoutes(mux *http.ServeMux) {
 	a.logger.Info("registering routes")
 
 	// user actions, external api
-	mux.HandleFunc("GET /quiz/sessions", middleware.VerifyToken(handlers.NewGetUserActiveSessionsHandler(a.storage, a.logger).Handle, a.authClient))
-	mux.HandleFunc("POST /quiz/sessions/new", middleware.VerifyToken(handlers.NewStartQuizHandler(a.storage, a.logger, a.statsClient).Handle, a.authClient))
-	mux.HandleFunc("GET /quiz/sessions/{quizSessionId}/nextQuestion", middleware.VerifyToken(handlers.NewGetNextQuestionHandler(a.storage, a.logger).Handle, a.authClient))
-	mux.HandleFunc("POST /quiz/sessions/{quizSessionId}/answer", middleware.VerifyToken(handlers.NewSubmitAnswerHandler(a.storage, a.logger, a.statsClient).Handle, a.authClient))
-	mux.HandleFunc("POST /quiz/sessions/{quizSessionId}/finish", middleware.VerifyToken(handlers.NewFinishQuizHandler(a.storage, a.logger, a.statsClient).Handle, a.authClient))
+	mux.HandleFunc("GET /quiz/sessions", middleware.VerifyTokenAllowGuests(handlers.NewGetUserActiveSessionsHandler(a.storage, a.logger).Handle, a.authClient))
+	mux.HandleFunc("POST /quiz/sessions/new", middleware.VerifyTokenAllowGuests(handlers.NewStartQuizHandler(a.storage, a.logger, a.statsClient, a.feed, a.hooks, a.lti, a.xapi, a.certs).Handle, a.authClient))
+	mux.HandleFunc("GET /quiz/sessions/{quizSessionId}/nextQuestion", middleware.VerifyTokenAllowGuests(handlers.NewGetNextQuestionHandler(a.storage, a.logger, a.feed).Handle, a.authClient))
+	mux.HandleFunc("POST /quiz/sessions/{quizSessionId}/answer", middleware.VerifyTokenAllowGuests(handlers.NewSubmitAnswerHandler(a.storage, a.logger, a.statsClient, a.feed, a.xapi).Handle, a.authClient))
+	mux.HandleFunc("POST /quiz/sessions/{quizSessionId}/finish", middleware.VerifyTokenAllowGuests(handlers.NewFinishQuizHandler(a.storage, a.logger, a.statsClient, a.feed, a.hooks, a.lti, a.xapi, a.certs).Handle, a.authClient))
+
+	// guests
+	guestHandler := handlers.NewGuestHandler(a.storage, a.logger, a.authClient, a.statsClient)
+	mux.HandleFunc("POST /quiz/tests/{code}/guest", guestHandler.Join)
+	mux.HandleFunc("POST /quiz/guest/claim", middleware.VerifyToken(guestHandler.Claim, a.authClient))
 
 	// internal api
     apiKey := os.Getenv("INTERNAL_API_KEY")
@@ -100,6 +182,10 @@ func (a *ApiServer) registerRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("PATCH /quiz/questions/{id}", middleware.InternalAuth(questionHandler.UpdateQuestion, a.logger, apiKey))
 	mux.HandleFunc("DELETE /quiz/questions/{id}", middleware.InternalAuth(questionHandler.DeleteQuestion, a.logger, apiKey))
 	mux.HandleFunc("GET /quiz/questions", middleware.InternalAuth(questionHandler.GetAllQuestions, a.logger, apiKey))
+	mux.HandleFunc("GET /quiz/questions/meta", middleware.InternalAuth(questionHandler.GetQuestionsMeta, a.logger, apiKey))
+	qtiHandler := handlers.NewQTIHandler(a.storage, a.logger, a.images)
+	mux.HandleFunc("GET /quiz/questions/qti", middleware.InternalAuth(qtiHandler.Export, a.logger, apiKey))
+	mux.HandleFunc("POST /quiz/questions/qti", middleware.InternalAuth(qtiHandler.Import, a.logger, apiKey))
 
 	// options
 	optionsHandler := handlers.NewOptionsHandler(a.storage, a.logger)
@@ -126,7 +212,7 @@ func (a *ApiServer) registerRoutes(mux *http.ServeMux) {
     mux.HandleFunc("GET /quiz/settings", middleware.InternalAuth(handlers.NewSettingsHandler(a.storage, a.logger).GetSettings, a.logger, apiKey))
 
 	// BUG REPORTS
-    reportHandler := handlers.NewCaseReportHandler(a.storage, a.logger, a.authClient)
+    reportHandler := handlers.NewCaseReportHandler(a.storage, a.logger, a.authClient, a.hooks)
     mux.HandleFunc("POST /quiz/cases/{caseId}/report", middleware.VerifyToken(reportHandler.Report, a.authClient))          // user
 
 
@@ -143,18 +229,80 @@ func (a *ApiServer) registerRoutes(mux *http.ServeMux) {
 
 
 	caseHandler := handlers.NewCaseHandler(a.storage, a.logger)
-	mux.HandleFunc("GET /quiz/cases/{id}/parameters/v3", middleware.VerifyToken(caseHandler.GetCaseParametersV3, a.authClient))
+	mux.HandleFunc("GET /quiz/cases/{id}/parameters/v3", middleware.VerifyTokenAllowGuests(caseHandler.GetCaseParametersV3, a.authClient))
 
 	// teacher endpoints (tests)
 	testsHandler := handlers.NewTestsHandler(a.storage, a.logger)
 	mux.HandleFunc("POST /quiz/tests", middleware.VerifyToken(testsHandler.Create, a.authClient))
 	mux.HandleFunc("GET /quiz/tests", middleware.VerifyToken(testsHandler.ListMine, a.authClient))
+	mux.HandleFunc("PUT /quiz/tests/{id}/settings", middleware.VerifyToken(testsHandler.UpdateSettings, a.authClient))
+	mux.HandleFunc("PATCH /quiz/tests/{id}", middleware.VerifyToken(testsHandler.Update, a.authClient))
+	mux.HandleFunc("POST /quiz/tests/{id}/clone", middleware.VerifyToken(testsHandler.Clone, a.authClient))
+	mux.HandleFunc("GET /quiz/tests/{id}/versions", middleware.VerifyToken(testsHandler.ListVersions, a.authClient))
 	mux.HandleFunc("GET /quiz/tests/{code}/progress", middleware.VerifyToken(testsHandler.ProgressByCode, a.authClient))
+	mux.HandleFunc("GET /quiz/tests/{code}/analysis", middleware.VerifyToken(handlers.NewTestAnalysisHandler(a.storage, a.logger, a.statsClient).Handle, a.authClient))
+	resultsExport := handlers.NewTestResultsExportHandler(a.storage, a.logger, a.authClient, a.statsClient)
+	mux.HandleFunc("GET /quiz/tests/{id}/results.csv", middleware.VerifyToken(resultsExport.CSV, a.authClient))
+	mux.HandleFunc("GET /quiz/tests/{id}/results.xlsx", middleware.VerifyToken(resultsExport.XLSX, a.authClient))
+	mux.HandleFunc("GET /quiz/tests/{id}/qti", middleware.VerifyToken(qtiHandler.ExportTest, a.authClient))
+	testOwners := handlers.NewTestOwnersHandler(a.storage, a.logger, a.authClient)
+	mux.HandleFunc("GET /quiz/tests/{id}/owners", middleware.VerifyToken(testOwners.List, a.authClient))
+	mux.HandleFunc("PUT /quiz/tests/{id}/owners", middleware.VerifyToken(testOwners.Set, a.authClient))
+	mux.HandleFunc("DELETE /quiz/tests/{id}/owners/{userId}", middleware.VerifyToken(testOwners.Remove, a.authClient))
+	mux.HandleFunc("POST /quiz/tests/{id}/transfer", middleware.VerifyToken(testOwners.Transfer, a.authClient))
 	mux.HandleFunc("GET /quiz/tests/{id}", middleware.VerifyToken(
 	handlers.NewTeacherGetTestHandler(a.storage, a.logger).Handle, a.authClient))
 	mux.HandleFunc("DELETE /quiz/tests/{id}", middleware.VerifyToken(
 	handlers.NewTeacherDeleteTestHandler(a.storage, a.logger).Handle, a.authClient))
 
+	// live rooms
+	liveRooms := handlers.NewLiveRoomHandler(a.storage, a.logger, a.statsClient, a.hub, a.feed, a.hooks, a.lti, a.xapi, a.certs)
+	mux.HandleFunc("POST /quiz/tests/{id}/live", middleware.VerifyToken(liveRooms.Create, a.authClient))
+	mux.HandleFunc("POST /quiz/live/join", middleware.VerifyTokenAllowGuests(liveRooms.Join, a.authClient))
+	mux.HandleFunc("GET /quiz/live/{roomId}", middleware.VerifyToken(liveRooms.Get, a.authClient))
+	mux.HandleFunc("GET /quiz/live/{roomId}/host/events", middleware.VerifyToken(liveRooms.HostEvents, a.authClient))
+	mux.HandleFunc("POST /quiz/live/{roomId}/next", middleware.VerifyToken(liveRooms.Next, a.authClient))
+	mux.HandleFunc("POST /quiz/live/{roomId}/end", middleware.VerifyToken(liveRooms.End, a.authClient))
+	mux.HandleFunc("GET /quiz/live/{roomId}/events", middleware.VerifyTokenAllowGuests(liveRooms.Events, a.authClient))
+	mux.HandleFunc("POST /quiz/live/{roomId}/answer", middleware.VerifyTokenAllowGuests(liveRooms.Answer, a.authClient))
+
+	// webhooks
+	webhookHandler := handlers.NewWebhookHandler(a.storage, a.logger, a.hooks)
+	mux.HandleFunc("POST /quiz/webhooks", middleware.VerifyToken(webhookHandler.Create, a.authClient))
+	mux.HandleFunc("GET /quiz/webhooks", middleware.VerifyToken(webhookHandler.ListMine, a.authClient))
+	mux.HandleFunc("DELETE /quiz/webhooks/{id}", middleware.VerifyToken(webhookHandler.Delete, a.authClient))
+	mux.HandleFunc("GET /quiz/webhooks/{id}/deliveries", middleware.VerifyToken(webhookHandler.Deliveries, a.authClient))
+	mux.HandleFunc("POST /quiz/webhooks/{id}/ping", middleware.VerifyToken(webhookHandler.Ping, a.authClient))
+
+	// lti, opened by the LMS; platforms are registered by admins
+	ltiHandler := handlers.NewLTIHandler(a.storage, a.logger, a.authClient, a.statsClient, a.lti)
+	mux.HandleFunc("GET /quiz/lti/login", ltiHandler.Login)
+	mux.HandleFunc("POST /quiz/lti/login", ltiHandler.Login)
+	mux.HandleFunc("POST /quiz/lti/launch", ltiHandler.Launch)
+	mux.HandleFunc("GET /quiz/lti/jwks", ltiHandler.JWKS)
+	mux.HandleFunc("POST /quiz/lti/link", middleware.VerifyToken(ltiHandler.Link, a.authClient))
+	mux.HandleFunc("GET /quiz/lti/platforms", middleware.InternalAuth(ltiHandler.ListPlatforms, a.logger, apiKey))
+	mux.HandleFunc("POST /quiz/lti/platforms", middleware.InternalAuth(ltiHandler.CreatePlatform, a.logger, apiKey))
+	mux.HandleFunc("DELETE /quiz/lti/platforms/{id}", middleware.InternalAuth(ltiHandler.DeletePlatform, a.logger, apiKey))
+
+	// certificates; verification by code is public
+	certHandler := handlers.NewCertificateHandler(a.storage, a.logger, a.certs)
+	mux.HandleFunc("GET /quiz/certificates", middleware.VerifyToken(certHandler.ListMine, a.authClient))
+	mux.HandleFunc("GET /quiz/certificates/{code}", certHandler.Verify)
+	mux.HandleFunc("GET /quiz/certificates/{code}/pdf", middleware.VerifyToken(certHandler.PDF, a.authClient))
+
+	// classes
+	classHandler := handlers.NewClassHandler(a.storage, a.logger, a.authClient)
+	mux.HandleFunc("POST /quiz/classes", middleware.VerifyToken(classHandler.Create, a.authClient))
+	mux.HandleFunc("GET /quiz/classes", middleware.VerifyToken(classHandler.ListMine, a.authClient))
+	mux.HandleFunc("GET /quiz/classes/joined", middleware.VerifyToken(classHandler.ListJoined, a.authClient))
+	mux.HandleFunc("POST /quiz/classes/join", middleware.VerifyToken(classHandler.Join, a.authClient))
+	mux.HandleFunc("GET /quiz/classes/{id}/roster", middleware.VerifyToken(classHandler.Roster, a.authClient))
+	mux.HandleFunc("DELETE /quiz/classes/{id}", middleware.VerifyToken(classHandler.Delete, a.authClient))
+	mux.HandleFunc("DELETE /quiz/classes/{id}/members/{userId}", middleware.VerifyToken(classHandler.RemoveMember, a.authClient))
+	mux.HandleFunc("PUT /quiz/classes/{id}/assignments/{testId}", middleware.VerifyToken(classHandler.Assign, a.authClient))
+	mux.HandleFunc("DELETE /quiz/classes/{id}/assignments/{testId}", middleware.VerifyToken(classHandler.Unassign, a.authClient))
+
 	// difficulty voting
     diffMark := handlers.NewMarkDifficultyHandler(a.storage, a.logger)
     mux.HandleFunc("POST /quiz/questions/{id}/difficulty",
@@ -171,8 +319,18 @@ func (a *ApiServer) registerRoutes(mux *http.ServeMux) {
     mux.HandleFunc("GET /quiz/questions/difficulty/summary",
     middleware.VerifyToken(batch.Handle, a.authClient))
 
-    las := handlers.NewListActiveSessionsHandler(a.storage, a.logger)
+    mux.HandleFunc("GET /quiz/difficulty/summary",
+    middleware.InternalAuth(handlers.NewGetAllDifficultySummariesHandler(a.storage, a.logger).Handle, a.logger, apiKey))
+
+	// question review queue
+	reviewHandler := handlers.NewQuestionReviewHandler(a.storage, a.logger)
+	mux.HandleFunc("GET /quiz/reviews", middleware.InternalAuth(reviewHandler.List, a.logger, apiKey))
+	mux.HandleFunc("POST /quiz/reviews", middleware.InternalAuth(reviewHandler.Enqueue, a.logger, apiKey))
+	mux.HandleFunc("PATCH /quiz/reviews/{id}", middleware.InternalAuth(reviewHandler.Update, a.logger, apiKey))
+
+    las := handlers.NewListActiveSessionsHandler(a.storage, a.logger, a.feed)
     mux.HandleFunc("GET /quiz/sessions/active", middleware.InternalAuth(las.Handle, a.logger, apiKey))
+	mux.HandleFunc("GET /quiz/sessions/active/events", middleware.InternalAuth(las.Stream, a.logger, apiKey))
 
 	mux.HandleFunc("GET /quiz/tests/{code}/sessions",
 	middleware.InternalAuth(handlers.NewTestSessionsHandler(a.storage, a.logger).ListByCode, a.logger, apiKey))
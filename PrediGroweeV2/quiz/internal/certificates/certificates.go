@@ -0,0 +1,258 @@
+// Package certificates issues certificates of finished tests and of answer
+// milestones, which clinicians can show for continuing-education credit.
+// Each certificate has a random code under which anyone can check it
+// against the stored copy, so a PDF cannot be forged or altered unnoticed.
+package certificates
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"quiz/internal/clients"
+	"quiz/internal/models"
+	"quiz/internal/storage"
+)
+
+// Milestone is reached with at least Answers answers of which at least
+// MinAccuracy percent are correct.
+type Milestone struct {
+	Answers     int
+	MinAccuracy int
+}
+
+// String is the form milestones are configured and stored in, e.g. 200:70.
+func (m Milestone) String() string {
+	return fmt.Sprintf("%d:%d", m.Answers, m.MinAccuracy)
+}
+
+// ParseMilestones reads a comma-separated list such as "200:70,500:80".
+func ParseMilestones(s string) ([]Milestone, error) {
+	out := make([]Milestone, 0)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		answers, accuracy, ok := strings.Cut(part, ":")
+		a, err1 := strconv.Atoi(answers)
+		p, err2 := strconv.Atoi(accuracy)
+		if !ok || err1 != nil || err2 != nil || a <= 0 || p < 0 || p > 100 {
+			return nil, fmt.Errorf("invalid milestone %q, want answers:percent", part)
+		}
+		out = append(out, Milestone{Answers: a, MinAccuracy: p})
+	}
+	return out, nil
+}
+
+type Config struct {
+	Milestones []Milestone
+	// VerifyURL is the public verification endpoint; the code is appended.
+	VerifyURL string
+}
+
+type Issuer struct {
+	store       storage.Store
+	logger      *zap.Logger
+	authClient  *clients.AuthClient
+	statsClient *clients.StatsClient
+	milestones  []Milestone
+	verifyURL   string
+}
+
+func NewIssuer(cfg Config, store storage.Store, logger *zap.Logger, authClient *clients.AuthClient, statsClient *clients.StatsClient) *Issuer {
+	return &Issuer{
+		store:       store,
+		logger:      logger,
+		authClient:  authClient,
+		statsClient: statsClient,
+		milestones:  cfg.Milestones,
+		verifyURL:   cfg.VerifyURL,
+	}
+}
+
+// SessionFinished issues, in the background, the certificate of a test
+// whose questions were all answered and those of the milestones the user
+// has now reached. Failures are only logged; finishing must not depend on
+// them. Guests get no certificates.
+func (i *Issuer) SessionFinished(s models.QuizSession) {
+	if i == nil {
+		return
+	}
+	go i.issue(s)
+}
+
+func (i *Issuer) issue(s models.QuizSession) {
+	log := i.logger.With(zap.Int("session_id", s.ID), zap.Int("user_id", s.UserID))
+	existing, err := i.store.ListCertificatesByUser(s.UserID)
+	if err != nil {
+		log.Error("failed to list certificates", zap.Error(err))
+		return
+	}
+	sessionHeld := false
+	milestonesHeld := make(map[string]bool, len(existing))
+	for _, c := range existing {
+		if c.SessionID != nil && *c.SessionID == s.ID {
+			sessionHeld = true
+		}
+		if c.Milestone != nil {
+			milestonesHeld[*c.Milestone] = true
+		}
+	}
+
+	due := make([]models.Certificate, 0)
+	if s.TestID != nil && !sessionHeld {
+		c, err := i.testCertificate(s)
+		if err != nil {
+			log.Error("failed to check test certificate", zap.Error(err))
+		} else if c != nil {
+			due = append(due, *c)
+		}
+	}
+	pending := make([]Milestone, 0, len(i.milestones))
+	for _, m := range i.milestones {
+		if !milestonesHeld[m.String()] {
+			pending = append(pending, m)
+		}
+	}
+	if len(pending) > 0 {
+		total, correct, err := i.statsClient.GetUserAnswerTotals(s.UserID)
+		if err != nil {
+			log.Error("failed to get answer totals for certificates", zap.Error(err))
+		}
+		for _, m := range pending {
+			if err == nil && total >= m.Answers && correct*100 >= m.MinAccuracy*total {
+				milestone := m.String()
+				due = append(due, models.Certificate{Kind: models.CertificateKindMilestone, Milestone: &milestone, Correct: correct, Total: total})
+			}
+		}
+	}
+	if len(due) == 0 {
+		return
+	}
+
+	user, err := i.authClient.GetUser(s.UserID)
+	if err != nil {
+		log.Error("failed to get certificate recipient", zap.Error(err))
+		return
+	}
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if user.GuestTestCode != nil || name == "" {
+		return
+	}
+	for _, c := range due {
+		c.UserID = s.UserID
+		c.RecipientName = name
+		if c.Code, err = newCode(); err != nil {
+			log.Error("failed to generate certificate code", zap.Error(err))
+			return
+		}
+		created, err := i.store.CreateCertificate(c)
+		if err != nil {
+			log.Error("failed to create certificate", zap.String("kind", c.Kind), zap.Error(err))
+			continue
+		}
+		if created != nil {
+			log.Info("certificate issued", zap.String("kind", c.Kind), zap.String("code", created.Code))
+		}
+	}
+}
+
+// testCertificate returns the certificate of a test session, or nil if not
+// every question was answered.
+func (i *Issuer) testCertificate(s models.QuizSession) (*models.Certificate, error) {
+	answers, err := i.statsClient.GetSessionAnswers([]int{s.ID})
+	if err != nil {
+		return nil, err
+	}
+	if len(s.GroupOrder) == 0 || len(answers) < len(s.GroupOrder) {
+		return nil, nil
+	}
+	correct := 0
+	for _, a := range answers {
+		if a.Correct {
+			correct++
+		}
+	}
+	t, err := i.store.GetTestByID(*s.TestID)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, errors.New("test not found")
+	}
+	sessionID := s.ID
+	return &models.Certificate{
+		Kind:      models.CertificateKindTest,
+		SessionID: &sessionID,
+		TestID:    s.TestID,
+		TestName:  &t.Name,
+		Correct:   correct,
+		Total:     len(s.GroupOrder),
+	}, nil
+}
+
+// VerifyURL is where the certificate with the code can be checked.
+func (i *Issuer) VerifyURL(code string) string {
+	return i.verifyURL + FormatCode(code)
+}
+
+// Percent is the share of correct answers, rounded.
+func Percent(c models.Certificate) int {
+	if c.Total == 0 {
+		return 0
+	}
+	return int(math.Round(float64(c.Correct) * 100 / float64(c.Total)))
+}
+
+// Title names what the certificate is for.
+func Title(c models.Certificate) string {
+	if c.Kind == models.CertificateKindTest && c.TestName != nil {
+		return fmt.Sprintf("Completed the test “%s”", *c.TestName)
+	}
+	if c.Milestone != nil {
+		if m, err := ParseMilestones(*c.Milestone); err == nil && len(m) == 1 {
+			return fmt.Sprintf("Answered %d questions with at least %d%% correct", m[0].Answers, m[0].MinAccuracy)
+		}
+	}
+	return "PrediGrowee certificate"
+}
+
+// codeAlphabet is Crockford's base 32, which leaves out letters easily
+// misread as digits.
+const (
+	codeAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	codeLength   = 12
+)
+
+// newCode returns a random code of 60 bits.
+func newCode() (string, error) {
+	b := make([]byte, codeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = codeAlphabet[b[i]%32]
+	}
+	return string(b), nil
+}
+
+// FormatCode groups a code for reading, e.g. 7K2M-Q9XA-04TD.
+func FormatCode(code string) string {
+	if len(code) != codeLength {
+		return code
+	}
+	return code[:4] + "-" + code[4:8] + "-" + code[8:]
+}
+
+// NormalizeCode undoes FormatCode and the usual misreadings of a typed
+// code: lower case and O, I or L for 0 and 1.
+func NormalizeCode(s string) string {
+	s = strings.ToUpper(s)
+	s = strings.NewReplacer("-", "", " ", "", "O", "0", "I", "1", "L", "1").Replace(s)
+	return s
+}
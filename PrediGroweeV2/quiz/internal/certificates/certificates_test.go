@@ -0,0 +1,117 @@
+package certificates
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"quiz/internal/models"
+)
+
+// TestCodeAsTyped issues codes, prints them as the PDF does and types them
+// back in the way people do, which must still find the certificate.
+func TestCodeAsTyped(t *testing.T) {
+	mistype := strings.NewReplacer("0", "o", "1", "l", "-", " ")
+	seen := make(map[string]bool)
+	for i := 0; i < 200; i++ {
+		code, err := newCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(code) != codeLength || strings.Trim(code, codeAlphabet) != "" {
+			t.Fatalf("newCode() = %q", code)
+		}
+		if seen[code] {
+			t.Fatalf("newCode() returned %q twice", code)
+		}
+		seen[code] = true
+
+		printed := FormatCode(code)
+		typed := strings.ToLower(mistype.Replace(printed))
+		if got := NormalizeCode(typed); got != code {
+			t.Fatalf("code %s typed as %q reads as %s", printed, typed, got)
+		}
+	}
+
+	if got := NormalizeCode("iL-OI"); got != "1101" {
+		t.Errorf("NormalizeCode(iL-OI) = %s, want 1101", got)
+	}
+}
+
+// TestMilestones reads the CERTIFICATE_MILESTONES setting. A milestone is
+// stored on its certificate in the same form and named from it.
+func TestMilestones(t *testing.T) {
+	milestones, err := ParseMilestones(" 200:70, 500:80,")
+	if err != nil {
+		t.Fatalf("ParseMilestones() error = %v", err)
+	}
+	if len(milestones) != 2 || milestones[0] != (Milestone{200, 70}) || milestones[1] != (Milestone{500, 80}) {
+		t.Fatalf("ParseMilestones() = %v", milestones)
+	}
+	stored := milestones[1].String()
+	c := models.Certificate{Kind: models.CertificateKindMilestone, Milestone: &stored}
+	if got, want := Title(c), "Answered 500 questions with at least 80% correct"; got != want {
+		t.Errorf("Title() = %q, want %q", got, want)
+	}
+
+	if m, err := ParseMilestones(""); err != nil || len(m) != 0 {
+		t.Errorf("ParseMilestones(\"\") = %v, %v, want no milestones", m, err)
+	}
+	for _, invalid := range []string{"200", "200:", "0:70", "200:101", "200:70;500:80"} {
+		if _, err := ParseMilestones(invalid); err == nil {
+			t.Errorf("ParseMilestones(%q) accepted an invalid setting", invalid)
+		}
+	}
+}
+
+func TestWritePDF(t *testing.T) {
+	issuer := NewIssuer(Config{VerifyURL: "https://predigrowee.example/certificates/"}, nil, zap.NewNop(), nil, nil)
+	testName := "Growth (basics)"
+	milestone := "200:70"
+	issued := time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+
+	// the expected text is in the PDF's own encoding: parentheses escaped,
+	// Latin-1 in octal and other characters mapped to spare codes
+	for _, tt := range []struct {
+		c    models.Certificate
+		want []string
+	}{
+		{
+			c: models.Certificate{Kind: models.CertificateKindTest, Code: "7K2MQ9XA04TD", TestName: &testName,
+				RecipientName: "Zoë Nowak", Correct: 2, Total: 3, IssuedAt: issued},
+			want: []string{"(Certificate of Completion)", `(Zo\353 Nowak)`, `(has completed the test \200Growth \(basics\)\201)`,
+				"/Differences [128 /quotedblleft /quotedblright]", `(with a score of 2/3 \(67%\))`, "(Verification code: 7K2M-Q9XA-04TD)",
+				"(Check this certificate at https://predigrowee.example/certificates/7K2M-Q9XA-04TD)", "Issued on 6 May 2024"},
+		},
+		{
+			c: models.Certificate{Kind: models.CertificateKindMilestone, Code: "0000000000ZZ", Milestone: &milestone,
+				RecipientName: "Ada Lovelace", Correct: 150, Total: 212, IssuedAt: issued},
+			want: []string{"(Certificate of Achievement)", "has answered 212 craniofacial growth prediction questions",
+				`(with 71% of the answers correct \(150 of 212\))`, "(Answered 200 questions with at least 70% correct)"},
+		},
+	} {
+		var buf bytes.Buffer
+		if err := issuer.WritePDF(&buf, tt.c); err != nil {
+			t.Fatalf("WritePDF() error = %v", err)
+		}
+		doc := buf.String()
+		if !strings.HasPrefix(doc, "%PDF-1.4\n") || !strings.HasSuffix(doc, "%%EOF\n") {
+			t.Fatalf("WritePDF() did not write a PDF document")
+		}
+		for _, s := range tt.want {
+			if !strings.Contains(doc, s) {
+				t.Errorf("certificate %s does not show %q", tt.c.Code, s)
+			}
+		}
+
+		// readers find the objects through the cross-reference table
+		i := strings.LastIndex(doc, "startxref\n")
+		xref, err := strconv.Atoi(strings.TrimSuffix(doc[i+len("startxref\n"):], "\n%%EOF\n"))
+		if err != nil || !strings.HasPrefix(doc[xref:], "xref\n") {
+			t.Errorf("startxref points at %d, not the cross-reference table", xref)
+		}
+	}
+}
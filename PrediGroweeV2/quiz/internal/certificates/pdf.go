@@ -0,0 +1,66 @@
+package certificates
+
+import (
+	"fmt"
+	"io"
+
+	"quiz/internal/models"
+	"quiz/internal/pdf"
+)
+
+const (
+	margin       = 28.0
+	textMaxWidth = pdf.A4LandscapeWidth - 4*margin
+)
+
+// WritePDF renders the certificate on an A4 landscape page with its
+// verification code and URL.
+func (i *Issuer) WritePDF(w io.Writer, c models.Certificate) error {
+	d := pdf.New(pdf.A4LandscapeWidth, pdf.A4LandscapeHeight)
+	width, height := pdf.A4LandscapeWidth, pdf.A4LandscapeHeight
+
+	d.SetColor(0.13, 0.35, 0.55)
+	d.Rect(margin, margin, width-2*margin, height-2*margin, 3)
+	d.Rect(margin+7, margin+7, width-2*margin-14, height-2*margin-14, 0.75)
+	d.CenteredText(495, pdf.Bold, 16, "PrediGrowee")
+
+	heading := "Certificate of Completion"
+	if c.Kind == models.CertificateKindMilestone {
+		heading = "Certificate of Achievement"
+	}
+	d.CenteredText(440, pdf.Bold, 34, heading)
+
+	d.SetColor(0.2, 0.2, 0.2)
+	d.CenteredText(395, pdf.Regular, 14, "This certifies that")
+	d.SetColor(0, 0, 0)
+	d.CenteredText(352, pdf.Bold, pdf.FitSize(pdf.Bold, 28, 14, textMaxWidth, c.RecipientName), c.RecipientName)
+	d.SetColor(0.13, 0.35, 0.55)
+	d.Line(width/2-180, 340, width/2+180, 340, 0.75)
+
+	var line1, line2 string
+	if c.Kind == models.CertificateKindTest && c.TestName != nil {
+		line1 = fmt.Sprintf("has completed the test “%s”", *c.TestName)
+		line2 = fmt.Sprintf("with a score of %d/%d (%d%%)", c.Correct, c.Total, Percent(c))
+	} else {
+		line1 = fmt.Sprintf("has answered %d craniofacial growth prediction questions", c.Total)
+		line2 = fmt.Sprintf("with %d%% of the answers correct (%d of %d)", Percent(c), c.Correct, c.Total)
+	}
+	d.SetColor(0.2, 0.2, 0.2)
+	d.CenteredText(300, pdf.Regular, pdf.FitSize(pdf.Regular, 15, 9, textMaxWidth, line1), line1)
+	d.CenteredText(276, pdf.Regular, pdf.FitSize(pdf.Regular, 15, 9, textMaxWidth, line2), line2)
+	if c.Kind == models.CertificateKindMilestone {
+		d.CenteredText(252, pdf.Oblique, 12, Title(c))
+	}
+
+	d.SetColor(0, 0, 0)
+	date := "Issued on " + c.IssuedAt.UTC().Format("2 January 2006")
+	d.Text(margin+40, 150, pdf.Regular, 12, date)
+	code := "Verification code: " + FormatCode(c.Code)
+	d.Text(width-margin-40-pdf.TextWidth(pdf.Bold, 12, code), 150, pdf.Bold, 12, code)
+
+	d.SetColor(0.35, 0.35, 0.35)
+	verify := "Check this certificate at " + i.VerifyURL(c.Code)
+	d.CenteredText(80, pdf.Regular, pdf.FitSize(pdf.Regular, 10, 6, textMaxWidth, verify), verify)
+
+	return d.Write(w, heading+" – "+c.RecipientName, c.IssuedAt)
+}
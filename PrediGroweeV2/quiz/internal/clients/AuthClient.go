@@ -3,6 +3,7 @@ package clients
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"go.uber.org/zap"
 	"net/http"
@@ -11,12 +12,14 @@ import (
 
 type AuthClient struct {
 	addr   string
+	apiKey string
 	logger *zap.Logger
 }
 
-func NewAuthClient(addr string, logger *zap.Logger) *AuthClient {
+func NewAuthClient(addr string, apiKey string, logger *zap.Logger) *AuthClient {
 	return &AuthClient{
 		addr:   addr,
+		apiKey: apiKey,
 		logger: logger,
 	}
 }
@@ -64,3 +67,211 @@ func (c *AuthClient) VerifyAuthToken(token string) (models.UserData, error) {
 	return userDataResponse, nil
 }
 
+
+// GetUsers returns the profiles of all registered users.
+func (c *AuthClient) GetUsers() ([]models.UserProfile, error) {
+	req, err := http.NewRequest("GET", c.addr+"/users", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-Api-Key", c.apiKey)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var users []models.UserProfile
+	if err := json.NewDecoder(resp.Body).Decode(&users); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return users, nil
+}
+
+// GetUser returns the profile of one user.
+func (c *AuthClient) GetUser(userID int) (models.UserProfile, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/users/%d", c.addr, userID), nil)
+	if err != nil {
+		return models.UserProfile{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-Api-Key", c.apiKey)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return models.UserProfile{}, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return models.UserProfile{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var user models.UserProfile
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return models.UserProfile{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return user, nil
+}
+
+// ErrGuestClaimInvalid is returned by ClaimGuest for an unknown claim code or
+// one already used by another account.
+var ErrGuestClaimInvalid = errors.New("invalid guest claim code")
+
+// CreateGuest asks the auth service for a guest user restricted to one test.
+func (c *AuthClient) CreateGuest(nickname string, testCode string) (models.GuestAccess, error) {
+	jsonPayload, err := json.Marshal(map[string]string{"nickname": nickname, "test_code": testCode})
+	if err != nil {
+		return models.GuestAccess{}, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+	req, err := http.NewRequest("POST", c.addr+"/guests", bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		return models.GuestAccess{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Api-Key", c.apiKey)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return models.GuestAccess{}, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return models.GuestAccess{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var access models.GuestAccess
+	if err := json.NewDecoder(resp.Body).Decode(&access); err != nil {
+		return models.GuestAccess{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return access, nil
+}
+
+// ClaimGuest marks the guest with the given claim code as claimed by userID
+// and returns the guest's user id. Claiming again into the same account
+// succeeds, so a claim interrupted half way can be retried.
+func (c *AuthClient) ClaimGuest(claimCode string, userID int) (int, error) {
+	jsonPayload, err := json.Marshal(map[string]any{"claim_code": claimCode, "user_id": userID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+	req, err := http.NewRequest("POST", c.addr+"/guests/claim", bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Api-Key", c.apiKey)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusConflict:
+		return 0, ErrGuestClaimInvalid
+	default:
+		return 0, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var body struct {
+		GuestUserID int `json:"guest_user_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return body.GuestUserID, nil
+}
+
+// ErrLTILinkInvalid is returned by LinkLTIIdentity for an unknown or used
+// link code, and ErrLTILinkNotAllowed for an account that cannot be linked.
+var (
+	ErrLTILinkInvalid    = errors.New("invalid lti link code")
+	ErrLTILinkNotAllowed = errors.New("account cannot be linked to an lms identity")
+)
+
+// LinkLTIIdentity links the LMS identity with the link code to userID and
+// returns the account it had.
+func (c *AuthClient) LinkLTIIdentity(linkCode string, userID int) (int, error) {
+	jsonPayload, err := json.Marshal(map[string]any{"link_code": linkCode, "user_id": userID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+	req, err := http.NewRequest("POST", c.addr+"/lti/links", bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Api-Key", c.apiKey)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return 0, ErrLTILinkInvalid
+	case http.StatusForbidden:
+		return 0, ErrLTILinkNotAllowed
+	default:
+		return 0, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var body struct {
+		FromUserID int `json:"from_user_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return body.FromUserID, nil
+}
+
+// ProvisionLTIUser returns the user behind an LMS identity, creating the
+// account on first launch, with a new login session for them and, until the
+// identity is linked to an existing account, a link code.
+func (c *AuthClient) ProvisionLTIUser(issuer, subject, email, firstName, lastName string) (models.LTILogin, error) {
+	jsonPayload, err := json.Marshal(map[string]string{
+		"issuer":     issuer,
+		"subject":    subject,
+		"email":      email,
+		"first_name": firstName,
+		"last_name":  lastName,
+	})
+	if err != nil {
+		return models.LTILogin{}, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+	req, err := http.NewRequest("POST", c.addr+"/lti/users", bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		return models.LTILogin{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Api-Key", c.apiKey)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return models.LTILogin{}, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return models.LTILogin{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var login models.LTILogin
+	if err := json.NewDecoder(resp.Body).Decode(&login); err != nil {
+		return models.LTILogin{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return login, nil
+}
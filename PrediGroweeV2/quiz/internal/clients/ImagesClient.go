@@ -0,0 +1,59 @@
+package clients
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// maxImageSize caps a single image downloaded from the images service.
+const maxImageSize = 20 << 20
+
+type ImagesClient struct {
+	addr   string
+	apiKey string
+	logger *zap.Logger
+}
+
+func NewImagesClient(addr string, apiKey string, logger *zap.Logger) *ImagesClient {
+	return &ImagesClient{
+		addr:   addr,
+		apiKey: apiKey,
+		logger: logger,
+	}
+}
+
+// GetQuestionImage downloads image n (1-3) of the question. It returns nil
+// data when the question has no such image.
+func (c *ImagesClient) GetQuestionImage(questionID int, n int) ([]byte, string, error) {
+	req, err := http.NewRequest("GET", c.addr+"/internal/questions/"+strconv.Itoa(questionID)+"/image/"+strconv.Itoa(n), nil)
+	if err != nil {
+		c.logger.Error("failed to create request", zap.Error(err))
+		return nil, "", err
+	}
+	req.Header.Set("X-Api-Key", c.apiKey)
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		c.logger.Error("failed to send request", zap.Error(err))
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, "", nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		c.logger.Error("unexpected status code", zap.Int("status_code", resp.StatusCode))
+		return nil, "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > maxImageSize {
+		return nil, "", fmt.Errorf("image %d of question %d is larger than %d bytes", n, questionID, maxImageSize)
+	}
+	return data, resp.Header.Get("Content-Type"), nil
+}
@@ -3,10 +3,12 @@ package clients
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"go.uber.org/zap"
 	"net/http"
 	"quiz/internal/models"
 	"strconv"
+	"strings"
 )
 
 type StatsClient struct {
@@ -76,7 +78,7 @@ func (c *StatsClient) SaveSession(session models.QuizSession) error {
 	return nil
 }
 func (c *StatsClient) FinishSession(sessionID int) error {
-	req, err := http.NewRequest("POST", c.addr+"/sessions"+strconv.Itoa(sessionID)+"/finish", nil)
+	req, err := http.NewRequest("POST", c.addr+"/sessions/"+strconv.Itoa(sessionID)+"/finish", nil)
 	if err != nil {
 		c.logger.Error("failed to create request", zap.Error(err))
 		return err
@@ -95,3 +97,92 @@ func (c *StatsClient) FinishSession(sessionID int) error {
 	}
 	return nil
 }
+
+// GetSessionAnswers returns the first answer to each question in the given
+// sessions.
+func (c *StatsClient) GetSessionAnswers(sessionIDs []int) ([]models.SessionAnswer, error) {
+	if len(sessionIDs) == 0 {
+		return []models.SessionAnswer{}, nil
+	}
+	ids := make([]string, len(sessionIDs))
+	for i, id := range sessionIDs {
+		ids[i] = strconv.Itoa(id)
+	}
+	req, err := http.NewRequest("GET", c.addr+"/sessions/answers?ids="+strings.Join(ids, ","), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Api-Key", c.apiKey)
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var answers []models.SessionAnswer
+	if err := json.NewDecoder(resp.Body).Decode(&answers); err != nil {
+		return nil, err
+	}
+	return answers, nil
+}
+
+// GetUserAnswerTotals returns the number of answers the user has given in
+// every mode and how many of them were correct.
+func (c *StatsClient) GetUserAnswerTotals(userID int) (total int, correct int, err error) {
+	req, err := http.NewRequest("GET", c.addr+"/users/"+strconv.Itoa(userID), nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("X-Api-Key", c.apiKey)
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, 0, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var stats struct {
+		TotalQuestions map[string]int
+		CorrectAnswers map[string]int
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return 0, 0, err
+	}
+	for _, n := range stats.TotalQuestions {
+		total += n
+	}
+	for _, n := range stats.CorrectAnswers {
+		correct += n
+	}
+	return total, correct, nil
+}
+
+// ReassignSessions moves the recorded sessions of one user to another, e.g.
+// when a guest claims their sessions into a full account.
+func (c *StatsClient) ReassignSessions(fromUserID int, toUserID int) error {
+	jsonPayload, err := json.Marshal(map[string]int{"from_user_id": fromUserID, "to_user_id": toUserID})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", c.addr+"/sessions/reassign", bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Api-Key", c.apiKey)
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -10,17 +10,20 @@ import (
 	"go.uber.org/zap"
 	"quiz/internal/clients"
 	"quiz/internal/middleware"
+	"quiz/internal/models"
 	"quiz/internal/storage"
+	"quiz/internal/webhooks"
 )
 
 type CaseReportHandler struct {
 	store      storage.Store
 	logger     *zap.Logger
 	authClient *clients.AuthClient
+	hooks      *webhooks.Dispatcher
 }
 
-func NewCaseReportHandler(store storage.Store, logger *zap.Logger, authClient *clients.AuthClient) *CaseReportHandler {
-	return &CaseReportHandler{store: store, logger: logger, authClient: authClient}
+func NewCaseReportHandler(store storage.Store, logger *zap.Logger, authClient *clients.AuthClient, hooks *webhooks.Dispatcher) *CaseReportHandler {
+	return &CaseReportHandler{store: store, logger: logger, authClient: authClient, hooks: hooks}
 }
 
 type reportPayload struct {
@@ -87,6 +90,11 @@ func (h *CaseReportHandler) Report(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "failed", http.StatusInternalServerError)
 		return
 	}
+	h.hooks.Emit(models.WebhookEventCaseReported, nil, map[string]interface{}{
+		"case_id":     caseID,
+		"user_id":     userID,
+		"description": desc,
+	})
 
 	w.WriteHeader(http.StatusCreated)
 	_, _ = w.Write([]byte(`{"ok":true}`))
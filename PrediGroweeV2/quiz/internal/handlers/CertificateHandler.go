@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+	"quiz/internal/certificates"
+	"quiz/internal/models"
+	"quiz/internal/storage"
+)
+
+// CertificateHandler lists a user's certificates, serves them as PDF and
+// lets anyone check one by its verification code.
+type CertificateHandler struct {
+	store  storage.Store
+	logger *zap.Logger
+	issuer *certificates.Issuer
+}
+
+func NewCertificateHandler(store storage.Store, logger *zap.Logger, issuer *certificates.Issuer) *CertificateHandler {
+	return &CertificateHandler{store: store, logger: logger, issuer: issuer}
+}
+
+// certificateView is a certificate as shown to its holder and to whoever
+// verifies it.
+type certificateView struct {
+	Valid         bool      `json:"valid"`
+	Code          string    `json:"code"`
+	Kind          string    `json:"kind"`
+	Title         string    `json:"title"`
+	RecipientName string    `json:"recipient_name"`
+	TestName      *string   `json:"test_name,omitempty"`
+	Milestone     *string   `json:"milestone,omitempty"`
+	Correct       int       `json:"correct"`
+	Total         int       `json:"total"`
+	ScorePercent  int       `json:"score_percent"`
+	IssuedAt      time.Time `json:"issued_at"`
+	VerifyURL     string    `json:"verify_url"`
+}
+
+func (h *CertificateHandler) view(c models.Certificate) certificateView {
+	return certificateView{
+		Valid:         true,
+		Code:          certificates.FormatCode(c.Code),
+		Kind:          c.Kind,
+		Title:         certificates.Title(c),
+		RecipientName: c.RecipientName,
+		TestName:      c.TestName,
+		Milestone:     c.Milestone,
+		Correct:       c.Correct,
+		Total:         c.Total,
+		ScorePercent:  certificates.Percent(c),
+		IssuedAt:      c.IssuedAt,
+		VerifyURL:     h.issuer.VerifyURL(c.Code),
+	}
+}
+
+// GET /quiz/certificates  (VerifyToken) — the caller's certificates, newest
+// first
+func (h *CertificateHandler) ListMine(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	list, err := h.store.ListCertificatesByUser(userID)
+	if err != nil {
+		h.logger.Error("failed to list certificates", zap.Int("user_id", userID), zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	out := make([]certificateView, 0, len(list))
+	for _, c := range list {
+		out = append(out, h.view(c))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// GET /quiz/certificates/{code}  (public)
+//
+// Confirms that a certificate with the code was issued and returns what it
+// states, so that the PDF can be compared against it. Dashes, case and the
+// letters O, I and L typed for digits are ignored.
+func (h *CertificateHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	c := h.load(w, r)
+	if c == nil {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(h.view(*c))
+}
+
+// GET /quiz/certificates/{code}/pdf  (VerifyToken, holder or admin)
+func (h *CertificateHandler) PDF(w http.ResponseWriter, r *http.Request) {
+	c := h.load(w, r)
+	if c == nil {
+		return
+	}
+	userID, _ := r.Context().Value("user_id").(int)
+	role, _ := r.Context().Value("user_role").(models.UserRole)
+	if c.UserID != userID && role != models.RoleAdmin {
+		http.Error(w, "certificate not found", http.StatusNotFound)
+		return
+	}
+	var buf bytes.Buffer
+	if err := h.issuer.WritePDF(&buf, *c); err != nil {
+		h.logger.Error("failed to render certificate", zap.String("code", c.Code), zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "certificate-"+certificates.FormatCode(c.Code)+".pdf"))
+	_, _ = w.Write(buf.Bytes())
+}
+
+func (h *CertificateHandler) load(w http.ResponseWriter, r *http.Request) *models.Certificate {
+	c, err := h.store.GetCertificateByCode(certificates.NormalizeCode(r.PathValue("code")))
+	if err != nil {
+		h.logger.Error("failed to get certificate", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return nil
+	}
+	if c == nil {
+		http.Error(w, "certificate not found", http.StatusNotFound)
+		return nil
+	}
+	return c
+}
@@ -0,0 +1,408 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+	"quiz/internal/clients"
+	"quiz/internal/models"
+	"quiz/internal/storage"
+)
+
+const (
+	// inviteCodeAlphabet leaves out characters that are easy to confuse when
+	// a code is read out in class (0/O, 1/I/L).
+	inviteCodeAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+	inviteCodeLength   = 8
+	inviteCodeAttempts = 5
+	maxClassNameLength = 200
+)
+
+type ClassHandler struct {
+	store      storage.Store
+	logger     *zap.Logger
+	authClient *clients.AuthClient
+}
+
+func NewClassHandler(store storage.Store, logger *zap.Logger, authClient *clients.AuthClient) *ClassHandler {
+	return &ClassHandler{store: store, logger: logger, authClient: authClient}
+}
+
+// POST /quiz/classes  (VerifyToken)
+func (h *ClassHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > maxClassNameLength {
+		http.Error(w, "name is required and must be at most 200 characters", http.StatusBadRequest)
+		return
+	}
+
+	var created models.Class
+	var err error
+	for i := 0; i < inviteCodeAttempts; i++ {
+		var code string
+		if code, err = newInviteCode(); err != nil {
+			break
+		}
+		created, err = h.store.CreateClass(models.Class{Name: req.Name, OwnerID: userID, InviteCode: code})
+		var pqErr *pq.Error
+		if !errors.As(err, &pqErr) || pqErr.Code != "23505" {
+			break
+		}
+	}
+	if err != nil {
+		h.logger.Error("create class failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(created)
+}
+
+// GET /quiz/classes  (VerifyToken) — classes the caller teaches
+func (h *ClassHandler) ListMine(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	classes, err := h.store.ListClassesByOwner(userID)
+	if err != nil {
+		h.logger.Error("list classes failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(classes)
+}
+
+// GET /quiz/classes/joined  (VerifyToken) — classes the caller is a student
+// in, with their assignments and the caller's status on each
+func (h *ClassHandler) ListJoined(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	classes, err := h.store.ListJoinedClasses(userID)
+	if err != nil {
+		h.logger.Error("list joined classes failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	now := time.Now().UTC()
+	for i := range classes {
+		assignments, err := h.store.ListClassAssignments(classes[i].ID)
+		if err != nil {
+			h.logger.Error("list class assignments failed", zap.Error(err))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		progress, err := h.store.GetClassProgress(classes[i].ID, userID)
+		if err != nil {
+			h.logger.Error("get class progress failed", zap.Error(err))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		byTest := make(map[int]models.ClassProgress, len(progress))
+		for _, p := range progress {
+			byTest[p.TestID] = p
+		}
+		classes[i].Assignments = make([]models.ClassAssignmentWithStatus, len(assignments))
+		for j, a := range assignments {
+			st := assignmentStatus(byTest[a.TestID], a.DueAt, now)
+			classes[i].Assignments[j] = models.ClassAssignmentWithStatus{
+				ClassAssignment: a,
+				Status:          st.Status,
+				Attempts:        st.Attempts,
+				CompletedAt:     st.CompletedAt,
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(classes)
+}
+
+// POST /quiz/classes/join  (VerifyToken)
+func (h *ClassHandler) Join(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	var req struct {
+		InviteCode string `json:"invite_code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	c, err := h.store.GetClassByInviteCode(strings.ToUpper(strings.TrimSpace(req.InviteCode)))
+	if err != nil {
+		h.logger.Error("get class by invite code failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if c == nil {
+		writeJSONError(w, http.StatusNotFound, map[string]interface{}{
+			"error":   "invalid_invite_code",
+			"message": "Unknown invite code.",
+		})
+		return
+	}
+	if c.OwnerID == userID {
+		http.Error(w, "you teach this class", http.StatusBadRequest)
+		return
+	}
+	if err := h.store.AddClassMember(c.ID, userID); err != nil {
+		h.logger.Error("add class member failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"id": c.ID, "name": c.Name})
+}
+
+// GET /quiz/classes/{id}/roster  (VerifyToken, class owner or admin)
+func (h *ClassHandler) Roster(w http.ResponseWriter, r *http.Request) {
+	c := h.ownedClass(w, r)
+	if c == nil {
+		return
+	}
+	members, err := h.store.ListClassMembers(c.ID)
+	if err != nil {
+		h.logger.Error("list class members failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	assignments, err := h.store.ListClassAssignments(c.ID)
+	if err != nil {
+		h.logger.Error("list class assignments failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	progress, err := h.store.GetClassProgress(c.ID, 0)
+	if err != nil {
+		h.logger.Error("get class progress failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	// Names are a convenience: without auth the roster still lists user ids.
+	users, err := h.authClient.GetUsers()
+	if err != nil {
+		h.logger.Warn("failed to get users from auth, roster without names", zap.Error(err))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(buildRoster(*c, members, assignments, progress, users, time.Now().UTC()))
+}
+
+// DELETE /quiz/classes/{id}  (VerifyToken, class owner or admin)
+func (h *ClassHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	c := h.ownedClass(w, r)
+	if c == nil {
+		return
+	}
+	if err := h.store.DeleteClass(c.ID); err != nil && err != sql.ErrNoRows {
+		h.logger.Error("delete class failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DELETE /quiz/classes/{id}/members/{userId}  (VerifyToken, class owner or
+// admin, or the member leaving)
+func (h *ClassHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	memberID, err := strconv.Atoi(r.PathValue("userId"))
+	if err != nil || memberID <= 0 {
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+	classID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || classID <= 0 {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	if memberID != userID && h.ownedClass(w, r) == nil {
+		return
+	}
+	if err := h.store.RemoveClassMember(classID, memberID); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("remove class member failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PUT /quiz/classes/{id}/assignments/{testId}  (VerifyToken, class owner or
+// admin; they also need at least viewer access to the test)
+func (h *ClassHandler) Assign(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	testID, err := strconv.Atoi(r.PathValue("testId"))
+	if err != nil || testID <= 0 {
+		http.Error(w, "invalid test id", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		DueAt *time.Time `json:"due_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	c := h.ownedClass(w, r)
+	if c == nil {
+		return
+	}
+	t, err := h.store.GetTestByID(testID)
+	if err != nil {
+		h.logger.Error("get test by id failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if t == nil {
+		http.Error(w, "test not found", http.StatusNotFound)
+		return
+	}
+	if !authorizeTest(w, r, h.store, h.logger, t, models.TestPermissionViewer) {
+		return
+	}
+
+	a, err := h.store.UpsertClassAssignment(models.ClassAssignment{
+		ClassID:    c.ID,
+		TestID:     t.ID,
+		DueAt:      req.DueAt,
+		AssignedBy: userID,
+	})
+	if err != nil {
+		h.logger.Error("assign test to class failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(a)
+}
+
+// DELETE /quiz/classes/{id}/assignments/{testId}  (VerifyToken, class owner
+// or admin)
+func (h *ClassHandler) Unassign(w http.ResponseWriter, r *http.Request) {
+	testID, err := strconv.Atoi(r.PathValue("testId"))
+	if err != nil || testID <= 0 {
+		http.Error(w, "invalid test id", http.StatusBadRequest)
+		return
+	}
+	c := h.ownedClass(w, r)
+	if c == nil {
+		return
+	}
+	if err := h.store.DeleteClassAssignment(c.ID, testID); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("unassign test failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ownedClass loads the class in the {id} path value and checks that the
+// caller teaches it or is an admin. It writes the error response itself and
+// returns nil on failure.
+func (h *ClassHandler) ownedClass(w http.ResponseWriter, r *http.Request) *models.Class {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return nil
+	}
+	c, err := h.store.GetClassByID(id)
+	if err != nil {
+		h.logger.Error("get class failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return nil
+	}
+	if c == nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return nil
+	}
+	userID, _ := r.Context().Value("user_id").(int)
+	role, _ := r.Context().Value("user_role").(models.UserRole)
+	if c.OwnerID != userID && role != models.RoleAdmin {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return nil
+	}
+	return c
+}
+
+func buildRoster(c models.Class, members []models.ClassMember, assignments []models.ClassAssignment,
+	progress []models.ClassProgress, users []models.UserProfile, now time.Time) models.ClassRoster {
+	profiles := make(map[int]models.UserProfile, len(users))
+	for _, u := range users {
+		profiles[u.ID] = u
+	}
+	type key struct{ user, test int }
+	byKey := make(map[key]models.ClassProgress, len(progress))
+	for _, p := range progress {
+		byKey[key{p.UserID, p.TestID}] = p
+	}
+
+	students := make([]models.RosterStudent, len(members))
+	for i, m := range members {
+		p := profiles[m.UserID]
+		m.FirstName, m.LastName, m.Email = p.FirstName, p.LastName, p.Email
+		st := models.RosterStudent{ClassMember: m, Assignments: make([]models.AssignmentStatus, len(assignments))}
+		for j, a := range assignments {
+			st.Assignments[j] = assignmentStatus(byKey[key{m.UserID, a.TestID}], a.DueAt, now)
+			st.Assignments[j].TestID = a.TestID
+			if s := st.Assignments[j].Status; s == models.AssignmentCompleted || s == models.AssignmentCompletedLate {
+				st.Completed++
+			}
+		}
+		students[i] = st
+	}
+	return models.ClassRoster{Class: c, Assignments: assignments, Students: students}
+}
+
+// assignmentStatus classifies a student's sessions on an assigned test. A
+// test counts as completed from the first finished session; finishing after
+// the due date is reported as late rather than as missing.
+func assignmentStatus(p models.ClassProgress, dueAt *time.Time, now time.Time) models.AssignmentStatus {
+	st := models.AssignmentStatus{TestID: p.TestID, Attempts: p.Attempts, CompletedAt: p.FirstFinishedAt}
+	switch {
+	case p.FirstFinishedAt != nil && dueAt != nil && p.FirstFinishedAt.After(*dueAt):
+		st.Status = models.AssignmentCompletedLate
+	case p.FirstFinishedAt != nil:
+		st.Status = models.AssignmentCompleted
+	case dueAt != nil && now.After(*dueAt):
+		st.Status = models.AssignmentOverdue
+	case p.InProgress:
+		st.Status = models.AssignmentInProgress
+	default:
+		st.Status = models.AssignmentNotStarted
+	}
+	return st
+}
+
+func newInviteCode() (string, error) {
+	b := make([]byte, inviteCodeLength)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(inviteCodeAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		b[i] = inviteCodeAlphabet[n.Int64()]
+	}
+	return string(b), nil
+}
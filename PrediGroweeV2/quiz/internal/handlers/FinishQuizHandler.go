@@ -3,9 +3,14 @@ package handlers
 import (
 	"go.uber.org/zap"
 	"net/http"
+	"quiz/internal/certificates"
 	"quiz/internal/clients"
+	"quiz/internal/live"
+	"quiz/internal/lti"
 	"quiz/internal/models"
 	"quiz/internal/storage"
+	"quiz/internal/webhooks"
+	"quiz/internal/xapi"
 	"strconv"
 	"time"
 )
@@ -14,13 +19,23 @@ type FinishQuizHandler struct {
 	storage     storage.Store
 	logger      *zap.Logger
 	statsClient *clients.StatsClient
+	feed        *live.SessionFeed
+	hooks       *webhooks.Dispatcher
+	lti         *lti.Tool
+	xapi        *xapi.Emitter
+	certs       *certificates.Issuer
 }
 
-func NewFinishQuizHandler(store storage.Store, logger *zap.Logger, client *clients.StatsClient) *FinishQuizHandler {
+func NewFinishQuizHandler(store storage.Store, logger *zap.Logger, client *clients.StatsClient, feed *live.SessionFeed, hooks *webhooks.Dispatcher, tool *lti.Tool, statements *xapi.Emitter, certs *certificates.Issuer) *FinishQuizHandler {
 	return &FinishQuizHandler{
 		storage:     store,
 		logger:      logger,
 		statsClient: client,
+		feed:        feed,
+		hooks:       hooks,
+		lti:         tool,
+		xapi:        statements,
+		certs:       certs,
 	}
 }
 func (h *FinishQuizHandler) Handle(rw http.ResponseWriter, r *http.Request) {
@@ -42,6 +57,12 @@ func (h *FinishQuizHandler) Handle(rw http.ResponseWriter, r *http.Request) {
 		http.Error(rw, "internal server error", http.StatusInternalServerError)
 		return
 	}
+	if session.Status == models.QuizStatusFinished {
+		rw.WriteHeader(http.StatusOK)
+		return
+	}
+	guestTestCode, _ := r.Context().Value("guest_test_code").(string)
+	session.Guest = guestTestCode != ""
 	session.Status = models.QuizStatusFinished
 	finishTime := time.Now()
 	session.FinishedAt = &finishTime
@@ -54,5 +75,10 @@ func (h *FinishQuizHandler) Handle(rw http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		h.logger.Error("failed to finish stats quiz session", zap.Error(err))
 	}
+	h.feed.Finished(quizSessionID)
+	h.hooks.TestFinished(session)
+	h.lti.SessionFinished(session)
+	h.xapi.Completed(session)
+	h.certs.SessionFinished(session)
 	rw.WriteHeader(http.StatusOK)
 }
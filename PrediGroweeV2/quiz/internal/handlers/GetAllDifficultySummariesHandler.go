@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+	"quiz/internal/storage"
+)
+
+type GetAllDifficultySummariesHandler struct {
+	store  storage.Store
+	logger *zap.Logger
+}
+
+func NewGetAllDifficultySummariesHandler(store storage.Store, logger *zap.Logger) *GetAllDifficultySummariesHandler {
+	return &GetAllDifficultySummariesHandler{store: store, logger: logger}
+}
+
+// GET /quiz/difficulty/summary  (InternalAuth)
+func (h *GetAllDifficultySummariesHandler) Handle(w http.ResponseWriter, _ *http.Request) {
+	rows, err := h.store.GetAllDifficultySummaries()
+	if err != nil {
+		h.logger.Error("get difficulty summaries failed", zap.Error(err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(rows)
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"go.uber.org/zap"
 	"net/http"
+	"quiz/internal/live"
 	"quiz/internal/storage"
 	"strconv"
 	"time"
@@ -12,12 +13,14 @@ import (
 type GetNextQuestionHandler struct {
 	storage storage.Store
 	logger  *zap.Logger
+	feed    *live.SessionFeed
 }
 
-func NewGetNextQuestionHandler(storage storage.Store, logger *zap.Logger) *GetNextQuestionHandler {
+func NewGetNextQuestionHandler(storage storage.Store, logger *zap.Logger, feed *live.SessionFeed) *GetNextQuestionHandler {
 	return &GetNextQuestionHandler{
 		storage: storage,
 		logger:  logger,
+		feed:    feed,
 	}
 }
 
@@ -51,6 +54,13 @@ func (h *GetNextQuestionHandler) Handle(rw http.ResponseWriter, r *http.Request)
 		http.Error(rw, "quiz is finished", http.StatusNotFound)
 		return
 	}
+	if session.LiveRoomID != nil {
+		writeJSONError(rw, http.StatusConflict, map[string]interface{}{
+			"error":   "live_session",
+			"message": "Questions of a live room are shown by its host.",
+		})
+		return
+	}
 
 	if session.CurrentQuestionID <= 0 {
 		rw.WriteHeader(http.StatusNoContent)
@@ -84,6 +94,7 @@ func (h *GetNextQuestionHandler) Handle(rw http.ResponseWriter, r *http.Request)
 	if err := h.storage.UpdateQuizSession(session); err != nil {
 		h.logger.Error("failed to update session, will result in wrong answer time", zap.Error(err))
 	}
+	h.feed.Seen(session)
 
 	resp := map[string]interface{}{
 		"question": question,
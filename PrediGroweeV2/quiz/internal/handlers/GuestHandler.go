@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"quiz/internal/clients"
+	"quiz/internal/storage"
+)
+
+const maxGuestNicknameLength = 40
+
+// Every join creates a user in auth, so joins are limited per client address.
+// The limit leaves room for a class joining from behind one school NAT.
+const (
+	guestJoinsPerWindow = 60
+	guestJoinWindow     = time.Hour
+)
+
+// GuestHandler lets students take a test without an account. A guest joins
+// with the test code and a nickname and gets a short-lived token that only
+// works for that test; the claim code returned with it later moves their
+// sessions into a full account.
+type GuestHandler struct {
+	store       storage.Store
+	logger      *zap.Logger
+	authClient  *clients.AuthClient
+	statsClient *clients.StatsClient
+	joins       *joinLimiter
+}
+
+func NewGuestHandler(store storage.Store, logger *zap.Logger, authClient *clients.AuthClient, statsClient *clients.StatsClient) *GuestHandler {
+	return &GuestHandler{
+		store:       store,
+		logger:      logger,
+		authClient:  authClient,
+		statsClient: statsClient,
+		joins:       newJoinLimiter(guestJoinsPerWindow, guestJoinWindow),
+	}
+}
+
+// POST /quiz/tests/{code}/guest  (public)
+// Body: {"nickname": "..."}
+//
+// The test must allow guests and not be closed. Password and attempt limit
+// are checked when the guest starts the test, as for everyone else; attempts
+// count per guest, so a student can get more by joining again. Each client
+// address may join guestJoinsPerWindow times per guestJoinWindow.
+func (h *GuestHandler) Join(w http.ResponseWriter, r *http.Request) {
+	code := strings.ToUpper(strings.TrimSpace(r.PathValue("code")))
+	if code == "" {
+		http.Error(w, "invalid code", http.StatusBadRequest)
+		return
+	}
+	if retryAfter, ok := h.joins.allow(clientIP(r), time.Now()); !ok {
+		h.logger.Warn("guest joins limited", zap.String("ip", clientIP(r)), zap.String("code", code))
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+		writeJSONError(w, http.StatusTooManyRequests, map[string]interface{}{
+			"error":   "too_many_guests",
+			"message": "Too many guests joined from this network. Try again later.",
+		})
+		return
+	}
+	var req struct {
+		Nickname string `json:"nickname"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	nickname := strings.TrimSpace(req.Nickname)
+	if nickname == "" || utf8.RuneCountInString(nickname) > maxGuestNicknameLength {
+		http.Error(w, "nickname must be 1 to 40 characters", http.StatusBadRequest)
+		return
+	}
+
+	t, err := h.store.GetTestByCode(code)
+	if err != nil {
+		h.logger.Error("get test by code failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if t == nil || !t.AllowGuests {
+		// tests that do not allow guests are indistinguishable from unknown
+		// codes, so the endpoint cannot be used to probe for test codes
+		writeJSONError(w, http.StatusNotFound, map[string]interface{}{
+			"error":   "guests_not_allowed",
+			"message": "Unknown test code or the test does not accept guests.",
+		})
+		return
+	}
+	now := time.Now().UTC()
+	if t.ClosesAt != nil && !now.Before(*t.ClosesAt) {
+		writeJSONError(w, http.StatusForbidden, map[string]interface{}{
+			"error":    "test_closed",
+			"message":  "This test is closed.",
+			"closesAt": t.ClosesAt.UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	access, err := h.authClient.CreateGuest(nickname, t.Code)
+	if err != nil {
+		h.logger.Error("failed to create guest in auth", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.logger.Info("guest joined test", zap.Int("test_id", t.ID), zap.Int("guest_user_id", access.UserID))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(access)
+}
+
+// POST /quiz/guest/claim  (VerifyToken)
+// Body: {"claim_code": "..."}
+//
+// Moves the sessions of a guest into the caller's account, here and in the
+// stats service. Retrying after a partial failure is safe.
+func (h *GuestHandler) Claim(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	var req struct {
+		ClaimCode string `json:"claim_code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	claimCode := strings.TrimSpace(req.ClaimCode)
+	if claimCode == "" {
+		http.Error(w, "claim_code is required", http.StatusBadRequest)
+		return
+	}
+
+	guestID, err := h.authClient.ClaimGuest(claimCode, userID)
+	if err != nil {
+		if errors.Is(err, clients.ErrGuestClaimInvalid) {
+			writeJSONError(w, http.StatusNotFound, map[string]interface{}{
+				"error":   "invalid_claim_code",
+				"message": "Unknown or already used claim code.",
+			})
+			return
+		}
+		h.logger.Error("failed to claim guest in auth", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	moved, err := h.store.ReassignUserSessions(guestID, userID)
+	if err != nil {
+		h.logger.Error("failed to reassign guest sessions", zap.Int("guest_user_id", guestID), zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if err := h.statsClient.ReassignSessions(guestID, userID); err != nil {
+		h.logger.Error("failed to reassign guest sessions in stats", zap.Int("guest_user_id", guestID), zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.logger.Info("guest sessions claimed",
+		zap.Int("guest_user_id", guestID), zap.Int("user_id", userID), zap.Int("sessions", moved))
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]int{"sessions": moved})
+}
+
+// clientIP is the address nginx passes in X-Real-IP, or the peer address
+// when the service is called directly.
+func clientIP(r *http.Request) string {
+	if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); ip != "" {
+		return ip
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// joinLimiter counts joins per address in fixed windows.
+type joinLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	windows map[string]joinWindow
+	pruned  time.Time
+}
+
+type joinWindow struct {
+	start time.Time
+	count int
+}
+
+func newJoinLimiter(limit int, window time.Duration) *joinLimiter {
+	return &joinLimiter{limit: limit, window: window, windows: make(map[string]joinWindow)}
+}
+
+// allow records a join from addr, or reports how long until it may join
+// again.
+func (l *joinLimiter) allow(addr string, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	jw, ok := l.windows[addr]
+	if !ok || now.Sub(jw.start) >= l.window {
+		if now.Sub(l.pruned) >= l.window {
+			l.prune(now)
+		}
+		l.windows[addr] = joinWindow{start: now, count: 1}
+		return 0, true
+	}
+	if jw.count >= l.limit {
+		return jw.start.Add(l.window).Sub(now), false
+	}
+	jw.count++
+	l.windows[addr] = jw
+	return 0, true
+}
+
+// prune drops expired windows so addresses that joined once are not kept
+// forever. It runs at most once per window.
+func (l *joinLimiter) prune(now time.Time) {
+	l.pruned = now
+	for addr, jw := range l.windows {
+		if now.Sub(jw.start) >= l.window {
+			delete(l.windows, addr)
+		}
+	}
+}
@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+	"quiz/internal/clients"
+	"quiz/internal/lti"
+	"quiz/internal/models"
+	"quiz/internal/storage"
+)
+
+// LTIHandler serves the LTI 1.3 endpoints through which tests are opened
+// from an LMS; see package lti. Launch and login fail with 503 when the tool
+// key is not configured.
+type LTIHandler struct {
+	store       storage.Store
+	logger      *zap.Logger
+	authClient  *clients.AuthClient
+	statsClient *clients.StatsClient
+	tool        *lti.Tool
+}
+
+func NewLTIHandler(store storage.Store, logger *zap.Logger, authClient *clients.AuthClient, statsClient *clients.StatsClient, tool *lti.Tool) *LTIHandler {
+	return &LTIHandler{store: store, logger: logger, authClient: authClient, statsClient: statsClient, tool: tool}
+}
+
+// ltiLinkCookie holds the link code of the launch's LMS identity until its
+// user links it to an existing account or opens a link already linked.
+const ltiLinkCookie = "lti_link_code"
+
+func (h *LTIHandler) configured(w http.ResponseWriter) bool {
+	if h.tool == nil {
+		http.Error(w, "lti is not configured", http.StatusServiceUnavailable)
+		return false
+	}
+	return true
+}
+
+// GET|POST /quiz/lti/login — OIDC login initiation from the LMS. Redirects
+// to the platform's authorization endpoint, which posts the launch back.
+// The state is also kept in a cookie, so that only the browser which started
+// the login can complete it and a launch cannot be replayed into another.
+func (h *LTIHandler) Login(w http.ResponseWriter, r *http.Request) {
+	if !h.configured(w) {
+		return
+	}
+	issuer := r.FormValue("iss")
+	loginHint := r.FormValue("login_hint")
+	if issuer == "" || loginHint == "" {
+		http.Error(w, "iss and login_hint are required", http.StatusBadRequest)
+		return
+	}
+	platform, err := h.findPlatform(issuer, r.FormValue("client_id"))
+	if err != nil {
+		h.logger.Error("list lti platforms failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if platform == nil {
+		http.Error(w, "unknown platform", http.StatusBadRequest)
+		return
+	}
+
+	state, err1 := randomToken()
+	nonce, err2 := randomToken()
+	if err := errors.Join(err1, err2); err != nil {
+		h.logger.Error("generate lti state failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if err := h.store.CreateLTILoginState(state, nonce, platform.ID); err != nil {
+		h.logger.Error("save lti state failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, stateCookie(state, int(lti.LoginStateMaxAge.Seconds())))
+	http.Redirect(w, r, h.tool.AuthRequestURL(*platform, loginHint, r.FormValue("lti_message_hint"), state, nonce), http.StatusFound)
+}
+
+// findPlatform picks the platform by issuer and, when the LMS sends it,
+// client id. Without a client id the issuer must be registered once.
+func (h *LTIHandler) findPlatform(issuer string, clientID string) (*models.LTIPlatform, error) {
+	platforms, err := h.store.ListLTIPlatforms()
+	if err != nil {
+		return nil, err
+	}
+	var found *models.LTIPlatform
+	for i, p := range platforms {
+		if p.Issuer != issuer || (clientID != "" && p.ClientID != clientID) {
+			continue
+		}
+		if found != nil {
+			return nil, nil
+		}
+		found = &platforms[i]
+	}
+	return found, nil
+}
+
+// POST /quiz/lti/launch — the id_token posted by the LMS
+//
+// Signs the user in, creating a student account on first launch, and sends
+// them to the test. The link is mapped to a test by its test_code custom
+// parameter or the test query parameter of its target link; links launched
+// before keep their test. The session cookie lets the page get an access
+// token from /auth/refresh; it is SameSite=None since the LMS usually shows
+// the tool in an iframe. Until the LMS identity is linked, the link code
+// cookie lets its user link it to an account they already have; see Link.
+func (h *LTIHandler) Launch(w http.ResponseWriter, r *http.Request) {
+	if !h.configured(w) {
+		return
+	}
+	if msg := r.FormValue("error"); msg != "" {
+		http.Error(w, "launch refused by the platform: "+msg+" "+r.FormValue("error_description"), http.StatusBadRequest)
+		return
+	}
+	state := r.FormValue("state")
+	cookie, err := r.Cookie(stateCookie(state, 0).Name)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(hashState(state))) != 1 {
+		http.Error(w, "login was not started in this browser, open the link again", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, stateCookie(state, -1))
+	nonce, platformID, err := h.store.ConsumeLTILoginState(state, lti.LoginStateMaxAge)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "login expired, open the link again", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.logger.Error("consume lti state failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	platform, err := h.store.GetLTIPlatform(platformID)
+	if err != nil || platform == nil {
+		h.logger.Error("get lti platform failed", zap.Int("platform_id", platformID), zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	launch, err := h.tool.ValidateLaunch(r.Context(), *platform, nonce, r.FormValue("id_token"))
+	if err != nil {
+		h.logger.Info("lti launch rejected", zap.Int("platform_id", platform.ID), zap.Error(err))
+		http.Error(w, "invalid launch", http.StatusUnauthorized)
+		return
+	}
+
+	test, err := h.launchTest(platform.ID, launch)
+	if err != nil {
+		h.logger.Error("resolve lti test failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if test == nil && launch.TestCode != "" {
+		http.Error(w, "test not found: "+launch.TestCode, http.StatusNotFound)
+		return
+	}
+	if test == nil {
+		http.Error(w, "this link is not connected to a test; set its test_code custom parameter", http.StatusNotFound)
+		return
+	}
+	if err := h.store.UpsertLTIResourceLink(models.LTIResourceLink{
+		PlatformID:     platform.ID,
+		ResourceLinkID: launch.ResourceLinkID,
+		DeploymentID:   launch.DeploymentID,
+		TestID:         test.ID,
+		LineItemURL:    launch.LineItemURL,
+	}); err != nil {
+		h.logger.Error("save lti resource link failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	login, err := h.authClient.ProvisionLTIUser(platform.Issuer, launch.Subject, launch.Email, launch.FirstName, launch.LastName)
+	if err != nil {
+		h.logger.Error("provision lti user failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if err := h.store.RecordLTILaunch(platform.ID, launch.ResourceLinkID, login.UserID, launch.Subject); err != nil {
+		h.logger.Error("record lti launch failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Path:     "/",
+		Name:     "session_id",
+		Value:    login.SessionID,
+		Expires:  login.ExpiresAt,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteNoneMode,
+	})
+	link := &http.Cookie{
+		Path:     "/",
+		Name:     ltiLinkCookie,
+		Value:    login.LinkCode,
+		Expires:  login.ExpiresAt,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteNoneMode,
+	}
+	if login.LinkCode == "" {
+		link.MaxAge = -1
+	}
+	http.SetCookie(w, link)
+	http.Redirect(w, r, h.tool.TestURL(test.Code), http.StatusSeeOther)
+}
+
+// POST /quiz/lti/link  (VerifyToken)
+//
+// Links the LMS identity of the last launch in this browser to the account
+// the caller signed in to, which they confirm by calling this. Later
+// launches sign in to that account, and the sessions and grade columns of
+// the account created at the first launch move to it. Admin accounts cannot
+// be linked.
+func (h *LTIHandler) Link(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	cookie, err := r.Cookie(ltiLinkCookie)
+	if err != nil || cookie.Value == "" {
+		http.Error(w, "no lms launch to link; open the link from the lms first", http.StatusNotFound)
+		return
+	}
+	fromUserID, err := h.authClient.LinkLTIIdentity(cookie.Value, userID)
+	switch {
+	case errors.Is(err, clients.ErrLTILinkInvalid):
+		http.Error(w, "no lms launch to link; open the link from the lms first", http.StatusNotFound)
+		return
+	case errors.Is(err, clients.ErrLTILinkNotAllowed):
+		http.Error(w, "this account cannot be linked to an lms", http.StatusForbidden)
+		return
+	case err != nil:
+		h.logger.Error("link lti identity failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Path: "/", Name: ltiLinkCookie, MaxAge: -1, HttpOnly: true, Secure: true, SameSite: http.SameSiteNoneMode})
+
+	if fromUserID != userID {
+		moved, err := h.store.ReassignUserSessions(fromUserID, userID)
+		if err == nil {
+			err = h.store.ReassignLTILaunches(fromUserID, userID)
+		}
+		if err == nil {
+			err = h.statsClient.ReassignSessions(fromUserID, userID)
+		}
+		if err != nil {
+			h.logger.Error("move sessions of linked lti account failed", zap.Int("from_user_id", fromUserID), zap.Int("user_id", userID), zap.Error(err))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		h.logger.Info("lti identity linked", zap.Int("from_user_id", fromUserID), zap.Int("user_id", userID), zap.Int("sessions", moved))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// stateCookie holds the hash of an OIDC state. Each login has its own
+// cookie, so that several links opened at once do not replace each other's;
+// it is SameSite=None since the platform posts the launch from its site.
+func stateCookie(state string, maxAge int) *http.Cookie {
+	hash := hashState(state)
+	return &http.Cookie{
+		Path:     "/",
+		Name:     "lti_state_" + hash[:16],
+		Value:    hash,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteNoneMode,
+	}
+}
+
+func hashState(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
+
+func (h *LTIHandler) launchTest(platformID int, launch *lti.Launch) (*models.Test, error) {
+	if launch.TestCode != "" {
+		return h.store.GetTestByCode(launch.TestCode)
+	}
+	link, err := h.store.GetLTIResourceLink(platformID, launch.ResourceLinkID)
+	if err != nil || link == nil {
+		return nil, err
+	}
+	return h.store.GetTestByID(link.TestID)
+}
+
+// GET /quiz/lti/jwks — the tool's public keys
+func (h *LTIHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	if !h.configured(w) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(h.tool.JWKS())
+}
+
+// POST /quiz/lti/platforms  (internal)
+// Body: {"issuer", "client_id", "deployment_ids", "auth_login_url",
+// "auth_token_url", "jwks_url"} — the values the LMS shows when the tool is
+// registered
+func (h *LTIHandler) CreatePlatform(w http.ResponseWriter, r *http.Request) {
+	var p models.LTIPlatform
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	p.Issuer = strings.TrimSpace(p.Issuer)
+	p.ClientID = strings.TrimSpace(p.ClientID)
+	if p.Issuer == "" || p.ClientID == "" {
+		http.Error(w, "issuer and client_id are required", http.StatusBadRequest)
+		return
+	}
+	for _, u := range []string{p.AuthLoginURL, p.AuthTokenURL, p.JWKSURL} {
+		if parsed, err := url.Parse(u); err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+			http.Error(w, "auth_login_url, auth_token_url and jwks_url must be https URLs", http.StatusBadRequest)
+			return
+		}
+	}
+
+	created, err := h.store.CreateLTIPlatform(p)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		http.Error(w, "platform already registered", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		h.logger.Error("create lti platform failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(created)
+}
+
+// GET /quiz/lti/platforms  (internal)
+func (h *LTIHandler) ListPlatforms(w http.ResponseWriter, r *http.Request) {
+	platforms, err := h.store.ListLTIPlatforms()
+	if err != nil {
+		h.logger.Error("list lti platforms failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(platforms)
+}
+
+// DELETE /quiz/lti/platforms/{id}  (internal) — its links stop working and
+// get no more scores
+func (h *LTIHandler) DeletePlatform(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid platform id", http.StatusBadRequest)
+		return
+	}
+	err = h.store.DeleteLTIPlatform(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "platform not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("delete lti platform failed", zap.Int("platform_id", id), zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -6,16 +6,18 @@ import (
 	"strconv"
 
 	"go.uber.org/zap"
+	"quiz/internal/live"
 	"quiz/internal/storage"
 )
 
 type ListActiveSessionsHandler struct {
 	storage storage.Store
 	logger  *zap.Logger
+	feed    *live.SessionFeed
 }
 
-func NewListActiveSessionsHandler(s storage.Store, l *zap.Logger) *ListActiveSessionsHandler {
-	return &ListActiveSessionsHandler{storage: s, logger: l}
+func NewListActiveSessionsHandler(s storage.Store, l *zap.Logger, feed *live.SessionFeed) *ListActiveSessionsHandler {
+	return &ListActiveSessionsHandler{storage: s, logger: l, feed: feed}
 }
 
 func (h *ListActiveSessionsHandler) Handle(w http.ResponseWriter, r *http.Request) {
@@ -33,3 +35,25 @@ func (h *ListActiveSessionsHandler) Handle(w http.ResponseWriter, r *http.Reques
 	_ = json.NewEncoder(w).Encode(data)
 }
 
+// Stream sends the active sessions as a "snapshot" event, followed by the
+// session events of the live feed as they happen. The list is queried once
+// per connection instead of on every poll.
+func (h *ListActiveSessionsHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	cutoff, _ := strconv.Atoi(q.Get("cutoff"))
+	limit, _ := strconv.Atoi(q.Get("limit"))
+
+	// subscribe first so no event falls between the snapshot and the stream
+	ch, unsubscribe := h.feed.Subscribe()
+	defer unsubscribe()
+
+	data, err := h.storage.ListActiveSessions(cutoff, limit)
+	if err != nil {
+		h.logger.Error("ListActiveSessions failed", zap.Error(err))
+		http.Error(w, "Failed to list active sessions", http.StatusInternalServerError)
+		return
+	}
+	if err := live.Serve(w, r, []live.Event{{Name: "snapshot", Data: data}}, ch); err != nil {
+		h.logger.Info("active sessions stream closed", zap.Error(err))
+	}
+}
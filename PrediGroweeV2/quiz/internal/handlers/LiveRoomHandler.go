@@ -0,0 +1,623 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+	"quiz/internal/certificates"
+	"quiz/internal/clients"
+	"quiz/internal/live"
+	"quiz/internal/lti"
+	"quiz/internal/models"
+	"quiz/internal/storage"
+	"quiz/internal/webhooks"
+	"quiz/internal/xapi"
+)
+
+// LiveRoomHandler runs tests as live rooms: the host opens a room for one of
+// their tests and moves everyone through its questions together. Students
+// get the current question and the host gets the answer distribution over
+// Server-Sent Events. Answers go to the stats service like any other, under a
+// session created when the student joins.
+type LiveRoomHandler struct {
+	store       storage.Store
+	logger      *zap.Logger
+	statsClient *clients.StatsClient
+	hub         *live.Hub
+	feed        *live.SessionFeed
+	hooks       *webhooks.Dispatcher
+	lti         *lti.Tool
+	xapi        *xapi.Emitter
+	certs       *certificates.Issuer
+}
+
+func NewLiveRoomHandler(store storage.Store, logger *zap.Logger, statsClient *clients.StatsClient, hub *live.Hub, feed *live.SessionFeed, hooks *webhooks.Dispatcher, tool *lti.Tool, statements *xapi.Emitter, certs *certificates.Issuer) *LiveRoomHandler {
+	return &LiveRoomHandler{store: store, logger: logger, statsClient: statsClient, hub: hub, feed: feed, hooks: hooks, lti: tool, xapi: statements, certs: certs}
+}
+
+func participantTopic(roomID int) string { return "live:" + strconv.Itoa(roomID) }
+func hostTopic(roomID int) string        { return "live:" + strconv.Itoa(roomID) + ":host" }
+
+// POST /quiz/tests/{id}/live  (VerifyToken) — opens a live room for the test;
+// the caller becomes its host
+func (h *LiveRoomHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid test id", http.StatusBadRequest)
+		return
+	}
+	t, err := h.store.GetTestByID(id)
+	if err != nil {
+		h.logger.Error("get test failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if t == nil {
+		http.Error(w, "test not found", http.StatusNotFound)
+		return
+	}
+	if !authorizeTest(w, r, h.store, h.logger, t, models.TestPermissionEditor) {
+		return
+	}
+
+	order, seed, err := testQuestionOrder(h.store, t)
+	if err != nil {
+		h.logger.Error("failed to get test questions", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if len(order) == 0 {
+		http.Error(w, "test has no questions", http.StatusUnprocessableEntity)
+		return
+	}
+
+	var room models.LiveRoom
+	for i := 0; i < inviteCodeAttempts; i++ {
+		var code string
+		if code, err = newInviteCode(); err != nil {
+			break
+		}
+		room, err = h.store.CreateLiveRoom(models.LiveRoom{
+			TestID:       t.ID,
+			TestVersion:  t.Version,
+			Code:         code,
+			HostID:       userID,
+			QuestionIDs:  order,
+			QuestionSeed: seed,
+		})
+		var pqErr *pq.Error
+		if !errors.As(err, &pqErr) || pqErr.Code != "23505" {
+			break
+		}
+	}
+	if err != nil {
+		h.logger.Error("create live room failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(room)
+}
+
+// GET /quiz/live/{roomId}  (VerifyToken) — host view of the room: its state,
+// the current question with the correct answer and the answers so far
+func (h *LiveRoomHandler) Get(w http.ResponseWriter, r *http.Request) {
+	room := h.hostRoom(w, r, models.TestPermissionViewer)
+	if room == nil {
+		return
+	}
+	resp := map[string]interface{}{"room": room}
+	if room.CurrentQuestionID() > 0 {
+		q, err := h.hostQuestion(*room)
+		if err != nil {
+			h.logger.Error("get live question failed", zap.Int("room_id", room.ID), zap.Error(err))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		d, err := h.store.GetLiveDistribution(room.ID, room.CurrentIndex)
+		if err != nil {
+			h.logger.Error("get live distribution failed", zap.Int("room_id", room.ID), zap.Error(err))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		resp["question"] = q
+		resp["distribution"] = d
+	} else {
+		n, err := h.store.CountLiveRoomSessions(room.ID)
+		if err != nil {
+			h.logger.Error("count live participants failed", zap.Int("room_id", room.ID), zap.Error(err))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		resp["participants"] = n
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// GET /quiz/live/{roomId}/host/events  (VerifyToken) — host event stream:
+// "question", "distribution", "participants" and "ended"
+func (h *LiveRoomHandler) HostEvents(w http.ResponseWriter, r *http.Request) {
+	room := h.hostRoom(w, r, models.TestPermissionViewer)
+	if room == nil {
+		return
+	}
+	// subscribe before reading the state so nothing falls in between
+	ch, unsubscribe := h.hub.Subscribe(hostTopic(room.ID))
+	defer unsubscribe()
+
+	room, err := h.store.GetLiveRoom(room.ID)
+	if err != nil || room == nil {
+		h.logger.Error("get live room failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	initial := []live.Event{{Name: "room", Data: room}}
+	if room.EndedAt != nil {
+		initial = append(initial, live.Event{Name: "ended", Data: room})
+	} else if room.CurrentQuestionID() > 0 {
+		q, err := h.hostQuestion(*room)
+		if err != nil {
+			h.logger.Error("get live question failed", zap.Int("room_id", room.ID), zap.Error(err))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		d, err := h.store.GetLiveDistribution(room.ID, room.CurrentIndex)
+		if err != nil {
+			h.logger.Error("get live distribution failed", zap.Int("room_id", room.ID), zap.Error(err))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		initial = append(initial, live.Event{Name: "question", Data: q}, live.Event{Name: "distribution", Data: d})
+	} else {
+		n, err := h.store.CountLiveRoomSessions(room.ID)
+		if err != nil {
+			h.logger.Error("count live participants failed", zap.Int("room_id", room.ID), zap.Error(err))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		initial = append(initial, live.Event{Name: "participants", Data: map[string]int{"participants": n}})
+	}
+	if err := live.Serve(w, r, initial, ch); err != nil {
+		h.logger.Info("live host stream closed", zap.Int("room_id", room.ID), zap.Error(err))
+	}
+}
+
+// POST /quiz/live/{roomId}/next  (VerifyToken) — shows the next question to
+// everyone; advancing past the last question ends the room
+func (h *LiveRoomHandler) Next(w http.ResponseWriter, r *http.Request) {
+	room := h.hostRoom(w, r, models.TestPermissionEditor)
+	if room == nil {
+		return
+	}
+	advanced, err := h.store.AdvanceLiveRoom(room.ID)
+	if err == sql.ErrNoRows {
+		writeJSONError(w, http.StatusConflict, map[string]interface{}{
+			"error":   "room_ended",
+			"message": "This live room has ended.",
+		})
+		return
+	}
+	if err != nil {
+		h.logger.Error("advance live room failed", zap.Int("room_id", room.ID), zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if advanced.CurrentQuestionID() == 0 {
+		h.end(w, advanced)
+		return
+	}
+
+	hostQ, err := h.hostQuestion(advanced)
+	if err != nil {
+		h.logger.Error("get live question failed", zap.Int("room_id", room.ID), zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	d, err := h.store.GetLiveDistribution(advanced.ID, advanced.CurrentIndex)
+	if err != nil {
+		h.logger.Error("get live distribution failed", zap.Int("room_id", room.ID), zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.hub.Publish(participantTopic(advanced.ID), live.Event{Name: "question", Data: participantQuestion(hostQ)})
+	h.hub.Publish(hostTopic(advanced.ID), live.Event{Name: "question", Data: hostQ})
+	h.hub.Publish(hostTopic(advanced.ID), live.Event{Name: "distribution", Data: d})
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"room": advanced, "question": hostQ})
+}
+
+// POST /quiz/live/{roomId}/end  (VerifyToken) — ends the room and finishes
+// the sessions of everyone in it
+func (h *LiveRoomHandler) End(w http.ResponseWriter, r *http.Request) {
+	room := h.hostRoom(w, r, models.TestPermissionEditor)
+	if room == nil {
+		return
+	}
+	if room.EndedAt != nil {
+		writeJSONError(w, http.StatusConflict, map[string]interface{}{
+			"error":   "room_ended",
+			"message": "This live room has ended.",
+		})
+		return
+	}
+	h.end(w, *room)
+}
+
+func (h *LiveRoomHandler) end(w http.ResponseWriter, room models.LiveRoom) {
+	sessionIDs, err := h.store.EndLiveRoom(room.ID)
+	if err != nil {
+		h.logger.Error("end live room failed", zap.Int("room_id", room.ID), zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	for _, id := range sessionIDs {
+		if err := h.statsClient.FinishSession(id); err != nil {
+			h.logger.Error("failed to finish session in stats service", zap.Int("session_id", id), zap.Error(err))
+		}
+		h.feed.Finished(id)
+		if s, err := h.store.GetQuizSessionByID(id); err != nil {
+			h.logger.Error("failed to get finished session", zap.Int("session_id", id), zap.Error(err))
+		} else {
+			h.hooks.TestFinished(s)
+			h.lti.SessionFinished(s)
+			h.xapi.Completed(s)
+			h.certs.SessionFinished(s)
+		}
+	}
+	ended, err := h.store.GetLiveRoom(room.ID)
+	if err != nil || ended == nil {
+		h.logger.Error("get live room failed", zap.Int("room_id", room.ID), zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.hub.Publish(participantTopic(room.ID), live.Event{Name: "ended", Data: ended})
+	h.hub.Publish(hostTopic(room.ID), live.Event{Name: "ended", Data: ended})
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"room": ended, "finished_sessions": len(sessionIDs)})
+}
+
+// POST /quiz/live/join  (VerifyTokenAllowGuests)
+// Body: {"code": "...", "screen_width": 0, "screen_height": 0}
+//
+// The room code is what lets students in, so the test's schedule, password
+// and attempt limit do not apply. Joining again returns the same session.
+func (h *LiveRoomHandler) Join(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	guestTestCode, _ := r.Context().Value("guest_test_code").(string)
+	isGuest := guestTestCode != ""
+
+	var req struct {
+		Code         string `json:"code"`
+		ScreenWidth  int    `json:"screen_width"`
+		ScreenHeight int    `json:"screen_height"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	code := strings.ToUpper(strings.TrimSpace(req.Code))
+	if code == "" {
+		http.Error(w, "code is required", http.StatusBadRequest)
+		return
+	}
+
+	room, err := h.store.GetOpenLiveRoomByCode(code)
+	if err != nil {
+		h.logger.Error("get live room by code failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if room == nil {
+		writeJSONError(w, http.StatusNotFound, map[string]interface{}{
+			"error":   "room_not_found",
+			"message": "No open live room has this code.",
+		})
+		return
+	}
+	t, err := h.store.GetTestByID(room.TestID)
+	if err != nil || t == nil {
+		h.logger.Error("get live room test failed", zap.Int("room_id", room.ID), zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if isGuest && (!t.AllowGuests || !strings.EqualFold(t.Code, guestTestCode)) {
+		writeJSONError(w, http.StatusForbidden, map[string]interface{}{
+			"error":   "guest_test_only",
+			"message": "Guests can only take the test they joined.",
+		})
+		return
+	}
+
+	session, err := h.store.GetLiveRoomSession(room.ID, userID)
+	if err != nil {
+		h.logger.Error("get live room session failed", zap.Int("room_id", room.ID), zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if session == nil {
+		created, err := h.store.CreateQuizSession(models.QuizSession{
+			Mode:              models.QuizModeClassic,
+			UserID:            userID,
+			Status:            models.QuizStatusNotStarted,
+			ScreenSize:        strconv.Itoa(req.ScreenWidth) + "x" + strconv.Itoa(req.ScreenHeight),
+			ScreenWidth:       req.ScreenWidth,
+			ScreenHeight:      req.ScreenHeight,
+			CurrentQuestionID: room.QuestionIDs[0],
+			GroupOrder:        room.QuestionIDs,
+			TestID:            &t.ID,
+			TestCode:          &t.Code,
+			QuestionSeed:      room.QuestionSeed,
+			TestVersion:       &room.TestVersion,
+			LiveRoomID:        &room.ID,
+			Guest:             isGuest,
+		})
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			// joined twice at once; the other request created the session
+			session, err = h.store.GetLiveRoomSession(room.ID, userID)
+		} else if err == nil {
+			session = &created
+			if err := h.statsClient.SaveSession(created); err != nil {
+				h.logger.Error("failed to save session in stats service", zap.Error(err))
+			}
+			h.feed.Started(created)
+			h.hooks.TestStarted(created)
+			h.xapi.Attempted(created)
+		}
+		if err != nil || session == nil {
+			h.logger.Error("create live room session failed", zap.Int("room_id", room.ID), zap.Error(err))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		if n, err := h.store.CountLiveRoomSessions(room.ID); err == nil {
+			h.hub.Publish(hostTopic(room.ID), live.Event{Name: "participants", Data: map[string]int{"participants": n}})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"session": session, "room": room})
+}
+
+// GET /quiz/live/{roomId}/events  (VerifyTokenAllowGuests) — participant
+// event stream: "question" and "ended"
+func (h *LiveRoomHandler) Events(w http.ResponseWriter, r *http.Request) {
+	room, session := h.participantRoom(w, r)
+	if room == nil {
+		return
+	}
+	ch, unsubscribe := h.hub.Subscribe(participantTopic(room.ID))
+	defer unsubscribe()
+
+	room, err := h.store.GetLiveRoom(room.ID)
+	if err != nil || room == nil {
+		h.logger.Error("get live room failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	initial := []live.Event{{Name: "room", Data: room}}
+	if room.EndedAt != nil {
+		initial = append(initial, live.Event{Name: "ended", Data: room})
+	} else if room.CurrentQuestionID() > 0 {
+		q, err := h.hostQuestion(*room)
+		if err != nil {
+			h.logger.Error("get live question failed", zap.Int("room_id", room.ID), zap.Error(err))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		initial = append(initial, live.Event{Name: "question", Data: participantQuestion(q)})
+	}
+	if err := live.Serve(w, r, initial, ch); err != nil {
+		h.logger.Info("live stream closed", zap.Int("room_id", room.ID), zap.Int("session_id", session.ID), zap.Error(err))
+	}
+}
+
+// POST /quiz/live/{roomId}/answer  (VerifyTokenAllowGuests)
+// Body: {"answer": "...", "screen_size": "...", "time_zone": "..."}
+//
+// One answer per question; the correct answer is not returned, since the
+// room is still on the question.
+func (h *LiveRoomHandler) Answer(w http.ResponseWriter, r *http.Request) {
+	room, session := h.participantRoom(w, r)
+	if room == nil {
+		return
+	}
+	if room.EndedAt != nil {
+		writeJSONError(w, http.StatusConflict, map[string]interface{}{
+			"error":   "room_ended",
+			"message": "This live room has ended.",
+		})
+		return
+	}
+	questionID := room.CurrentQuestionID()
+	if questionID == 0 {
+		writeJSONError(w, http.StatusConflict, map[string]interface{}{
+			"error":   "no_open_question",
+			"message": "The host has not shown a question yet.",
+		})
+		return
+	}
+
+	var answer models.QuestionAnswer
+	if err := json.NewDecoder(r.Body).Decode(&answer); err != nil || strings.TrimSpace(answer.Answer) == "" {
+		http.Error(w, "invalid answer", http.StatusBadRequest)
+		return
+	}
+	correct, err := h.store.GetQuestionCorrectOption(questionID)
+	if err != nil {
+		h.logger.Error("failed to get question correct option", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	question, err := h.store.GetQuestionByID(questionID)
+	if err != nil {
+		h.logger.Error("failed to get question by id", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	isCorrect := strings.EqualFold(strings.TrimSpace(answer.Answer), strings.TrimSpace(correct))
+
+	err = h.store.InsertLiveAnswer(models.LiveAnswer{
+		RoomID:        room.ID,
+		QuestionIndex: room.CurrentIndex,
+		SessionID:     session.ID,
+		Answer:        strings.TrimSpace(answer.Answer),
+		Correct:       isCorrect,
+	})
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		writeJSONError(w, http.StatusConflict, map[string]interface{}{
+			"error":   "already_answered",
+			"message": "You have already answered this question.",
+		})
+		return
+	}
+	if err != nil {
+		h.logger.Error("insert live answer failed", zap.Int("room_id", room.ID), zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	var timeSpent time.Duration
+	if room.QuestionStartedAt != nil {
+		timeSpent = time.Since(*room.QuestionStartedAt)
+	}
+	err = h.statsClient.SaveResponse(session.ID, models.QuestionAnswer{
+		QuestionID: questionID,
+		Answer:     answer.Answer,
+		IsCorrect:  isCorrect,
+		ScreenSize: answer.ScreenSize,
+		TimeSpent:  int(timeSpent.Seconds()),
+		CaseCode:   question.Case.Code,
+		TimeZone:   answer.TimeZone,
+	})
+	if err != nil {
+		h.logger.Error("failed to save response", zap.Error(err))
+	}
+
+	session.Status = models.QuizStatusInProgress
+	session.CurrentQuestionID = questionID
+	if err := h.store.UpdateQuizSession(*session); err != nil {
+		h.logger.Error("failed to update quiz session", zap.Error(err))
+	}
+	h.feed.Answered(*session, questionID, isCorrect)
+	h.xapi.Answered(*session, question, answer.Answer, correct, isCorrect, timeSpent)
+
+	if d, err := h.store.GetLiveDistribution(room.ID, room.CurrentIndex); err != nil {
+		h.logger.Error("get live distribution failed", zap.Int("room_id", room.ID), zap.Error(err))
+	} else {
+		h.hub.Publish(hostTopic(room.ID), live.Event{Name: "distribution", Data: d})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"question_index": room.CurrentIndex})
+}
+
+// liveQuestion is the question a live room is on.
+type liveQuestion struct {
+	Index     int             `json:"index"`
+	Count     int             `json:"count"`
+	StartedAt *time.Time      `json:"started_at"`
+	Question  models.Question `json:"question"`
+}
+
+// hostQuestion returns the room's current question including its correct
+// answer.
+func (h *LiveRoomHandler) hostQuestion(room models.LiveRoom) (liveQuestion, error) {
+	q, err := h.store.GetQuestionByID(room.CurrentQuestionID())
+	if err != nil {
+		return liveQuestion{}, err
+	}
+	correct, err := h.store.GetQuestionCorrectOption(q.ID)
+	if err != nil {
+		return liveQuestion{}, err
+	}
+	q.Correct = &correct
+	return liveQuestion{Index: room.CurrentIndex, Count: room.QuestionCount, StartedAt: room.QuestionStartedAt, Question: q}, nil
+}
+
+// participantQuestion strips what students must not see from a host
+// question, as the next question endpoint does.
+func participantQuestion(q liveQuestion) liveQuestion {
+	q.Question.Correct = nil
+	values := make([]models.ParameterValue, len(q.Question.Case.ParameterValues))
+	copy(values, q.Question.Case.ParameterValues)
+	for i := range values {
+		values[i].Value3 = nil
+	}
+	q.Question.Case.ParameterValues = values
+	return q
+}
+
+func (h *LiveRoomHandler) loadRoom(w http.ResponseWriter, r *http.Request) *models.LiveRoom {
+	id, err := strconv.Atoi(r.PathValue("roomId"))
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return nil
+	}
+	room, err := h.store.GetLiveRoom(id)
+	if err != nil {
+		h.logger.Error("get live room failed", zap.Int("room_id", id), zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return nil
+	}
+	if room == nil {
+		http.Error(w, "room not found", http.StatusNotFound)
+		return nil
+	}
+	return room
+}
+
+// hostRoom loads the room for its host or for someone with at least min
+// permission on its test.
+func (h *LiveRoomHandler) hostRoom(w http.ResponseWriter, r *http.Request, min string) *models.LiveRoom {
+	room := h.loadRoom(w, r)
+	if room == nil {
+		return nil
+	}
+	if room.HostID == r.Context().Value("user_id").(int) {
+		return room
+	}
+	t, err := h.store.GetTestByID(room.TestID)
+	if err != nil || t == nil {
+		h.logger.Error("get live room test failed", zap.Int("room_id", room.ID), zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return nil
+	}
+	if !authorizeTest(w, r, h.store, h.logger, t, min) {
+		return nil
+	}
+	return room
+}
+
+// participantRoom loads the room and the caller's session in it.
+func (h *LiveRoomHandler) participantRoom(w http.ResponseWriter, r *http.Request) (*models.LiveRoom, *models.QuizSession) {
+	room := h.loadRoom(w, r)
+	if room == nil {
+		return nil, nil
+	}
+	session, err := h.store.GetLiveRoomSession(room.ID, r.Context().Value("user_id").(int))
+	if err != nil {
+		h.logger.Error("get live room session failed", zap.Int("room_id", room.ID), zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return nil, nil
+	}
+	if session == nil {
+		writeJSONError(w, http.StatusForbidden, map[string]interface{}{
+			"error":   "not_joined",
+			"message": "Join the live room first.",
+		})
+		return nil, nil
+	}
+	return room, session
+}
@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"quiz/internal/clients"
+	"quiz/internal/models"
+	"quiz/internal/qti"
+	"quiz/internal/storage"
+)
+
+const (
+	maxQTIPackageSize = 50 << 20
+	questionImages    = 3
+	// qtiTransferTimeout replaces the server's timeouts, too short for a
+	// package with images.
+	qtiTransferTimeout = 5 * time.Minute
+)
+
+// QTIHandler exports questions as QTI content packages and imports single
+// choice items from them; see package qti.
+type QTIHandler struct {
+	store        storage.Store
+	logger       *zap.Logger
+	imagesClient *clients.ImagesClient
+}
+
+func NewQTIHandler(store storage.Store, logger *zap.Logger, imagesClient *clients.ImagesClient) *QTIHandler {
+	return &QTIHandler{store: store, logger: logger, imagesClient: imagesClient}
+}
+
+// GET /quiz/tests/{id}/qti?version=2.1|3.0  (VerifyToken, test viewer)
+// The questions of the current version of the test, pools included.
+func (h *QTIHandler) ExportTest(w http.ResponseWriter, r *http.Request) {
+	version, err := qti.ParseVersion(r.URL.Query().Get("version"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	t, err := h.store.GetTestByID(id)
+	if err != nil {
+		h.logger.Error("failed to get test by id", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if t == nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if !authorizeTest(w, r, h.store, h.logger, t, models.TestPermissionViewer) {
+		return
+	}
+	v, err := h.store.GetTestVersion(t.ID, t.Version)
+	if err != nil || v == nil {
+		h.logger.Error("failed to get test version", zap.Int("test_id", t.ID), zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	seen := make(map[int]bool)
+	ids := make([]int, 0, len(v.QuestionIDs))
+	add := func(qids []int) {
+		for _, qid := range qids {
+			if !seen[qid] {
+				seen[qid] = true
+				ids = append(ids, qid)
+			}
+		}
+	}
+	add(v.QuestionIDs)
+	for _, p := range v.Pools {
+		add(p.QuestionIDs)
+	}
+	h.export(w, ids, version, fmt.Sprintf("test-%s-qti.zip", t.Code))
+}
+
+// GET /quiz/questions/qti?ids=1,2,3&version=2.1|3.0  (internal) — the given
+// questions, or all of them
+func (h *QTIHandler) Export(w http.ResponseWriter, r *http.Request) {
+	version, err := qti.ParseVersion(r.URL.Query().Get("version"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var ids []int
+	if raw := strings.TrimSpace(r.URL.Query().Get("ids")); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || id <= 0 {
+				http.Error(w, "invalid question id: "+part, http.StatusBadRequest)
+				return
+			}
+			ids = append(ids, id)
+		}
+	} else {
+		questions, err := h.store.GetAllQuestions()
+		if err != nil {
+			h.logger.Error("failed to get questions", zap.Error(err))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		for _, q := range questions {
+			ids = append(ids, q.ID)
+		}
+	}
+	h.export(w, ids, version, "questions-qti.zip")
+}
+
+// export loads the questions, failing before anything is written, then
+// streams the package, fetching each question's images as it goes.
+func (h *QTIHandler) export(w http.ResponseWriter, ids []int, version qti.Version, filename string) {
+	correct, err := h.store.GetCorrectOptions(ids)
+	if err != nil {
+		h.logger.Error("failed to get correct options", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	questions := make([]models.Question, 0, len(ids))
+	for _, id := range ids {
+		q, err := h.store.GetQuestionByID(id)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, fmt.Sprintf("question %d not found", id), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			h.logger.Error("failed to get question", zap.Int("question_id", id), zap.Error(err))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		questions = append(questions, q)
+	}
+
+	extendDeadlines(w, qtiTransferTimeout)
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	pw := qti.NewPackageWriter(w, version)
+	for _, q := range questions {
+		item := qti.Item{Question: q, Correct: correct[q.ID]}
+		for n := 1; n <= questionImages; n++ {
+			data, contentType, err := h.imagesClient.GetQuestionImage(q.ID, n)
+			if err != nil {
+				h.logger.Error("failed to get question image, qti package is incomplete", zap.Int("question_id", q.ID), zap.Int("image", n), zap.Error(err))
+				return
+			}
+			if data != nil {
+				item.Images = append(item.Images, qti.Image{N: n, Data: data, ContentType: contentType})
+			}
+		}
+		if err := pw.Add(item); err != nil {
+			h.logger.Error("failed to write qti item", zap.Int("question_id", q.ID), zap.Error(err))
+			return
+		}
+	}
+	if err := pw.Close(); err != nil {
+		h.logger.Error("failed to write qti package", zap.Error(err))
+	}
+}
+
+// POST /quiz/questions/qti?case_id=&prediction_age=&group=  (internal)
+// Body: a QTI 2.1 or 3.0 content package (zip) or a single item document.
+//
+// Items exported by this app go back to their case, prediction age and
+// group; the others get the ones from the query. Items that cannot be
+// imported are listed under "skipped" with the reason; the rest are created
+// together.
+func (h *QTIHandler) Import(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var defaultCaseID, defaultAge int
+	defaultGroup := 1
+	for name, dst := range map[string]*int{"case_id": &defaultCaseID, "prediction_age": &defaultAge, "group": &defaultGroup} {
+		if raw := query.Get(name); raw != "" {
+			v, err := strconv.Atoi(raw)
+			if err != nil || v <= 0 {
+				http.Error(w, "invalid "+name, http.StatusBadRequest)
+				return
+			}
+			*dst = v
+		}
+	}
+	if defaultCaseID != 0 {
+		if _, err := h.store.GetCaseByID(defaultCaseID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				http.Error(w, "case not found", http.StatusBadRequest)
+				return
+			}
+			h.logger.Error("failed to get case", zap.Int("case_id", defaultCaseID), zap.Error(err))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	extendDeadlines(w, qtiTransferTimeout)
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxQTIPackageSize))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("package must be at most %d MB", maxQTIPackageSize>>20), http.StatusRequestEntityTooLarge)
+		return
+	}
+	items, rejected, err := qti.ReadPackage(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result := models.QTIImportResult{
+		Imported: make([]models.QTIImportedItem, 0, len(items)),
+		Skipped:  make([]models.QTISkippedItem, 0, len(rejected)),
+	}
+	for _, rj := range rejected {
+		result.Skipped = append(result.Skipped, models.QTISkippedItem{Identifier: rj.Identifier, File: rj.File, Reason: rj.Reason})
+	}
+
+	cases := make(map[string]*models.Case)
+	imports := make([]models.QuestionImport, 0, len(items))
+	accepted := make([]qti.ImportedItem, 0, len(items))
+	for _, item := range items {
+		skip := func(reason string) {
+			result.Skipped = append(result.Skipped, models.QTISkippedItem{Identifier: item.Identifier, File: item.File, Reason: reason})
+		}
+		q := models.QuestionImport{
+			Question:      item.Question,
+			Options:       item.Options,
+			Correct:       item.Correct,
+			CaseID:        defaultCaseID,
+			PredictionAge: defaultAge,
+			Group:         defaultGroup,
+		}
+		if item.CaseCode != "" {
+			c, ok := cases[item.CaseCode]
+			if !ok {
+				c, err = h.store.GetCaseByCode(item.CaseCode)
+				if err != nil {
+					h.logger.Error("failed to get case by code", zap.String("code", item.CaseCode), zap.Error(err))
+					http.Error(w, "internal server error", http.StatusInternalServerError)
+					return
+				}
+				cases[item.CaseCode] = c
+			}
+			if c == nil {
+				skip("case " + item.CaseCode + " not found")
+				continue
+			}
+			q.CaseID = c.ID
+		}
+		if item.PredictionAge > 0 {
+			q.PredictionAge = item.PredictionAge
+		}
+		if item.Group > 0 {
+			q.Group = item.Group
+		}
+		if q.CaseID == 0 {
+			skip("no case; pass case_id")
+			continue
+		}
+		if q.PredictionAge == 0 {
+			skip("no prediction age; pass prediction_age")
+			continue
+		}
+		imports = append(imports, q)
+		accepted = append(accepted, item)
+	}
+
+	if len(imports) > 0 {
+		ids, err := h.store.ImportQuestions(imports)
+		if err != nil {
+			h.logger.Error("failed to import questions", zap.Error(err))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		for i, id := range ids {
+			result.Imported = append(result.Imported, models.QTIImportedItem{Identifier: accepted[i].Identifier, File: accepted[i].File, QuestionID: id})
+		}
+		h.logger.Info("imported qti questions", zap.Int("imported", len(ids)), zap.Int("skipped", len(result.Skipped)))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+// extendDeadlines moves the connection's read and write deadlines d from
+// now.
+func extendDeadlines(w http.ResponseWriter, d time.Duration) {
+	rc := http.NewResponseController(w)
+	deadline := time.Now().Add(d)
+	_ = rc.SetReadDeadline(deadline)
+	_ = rc.SetWriteDeadline(deadline)
+}
@@ -140,3 +140,17 @@ func (h *QuestionHandler) GetAllQuestions(w http.ResponseWriter, _ *http.Request
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
+
+// GetQuestionsMeta lists every question with its correct option and case category (internal)
+func (h *QuestionHandler) GetQuestionsMeta(w http.ResponseWriter, _ *http.Request) {
+	meta, err := h.storage.GetQuestionsMeta()
+	if err != nil {
+		h.logger.Error("Failed to get questions meta", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(meta); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+	"quiz/internal/models"
+	"quiz/internal/storage"
+)
+
+const maxReviewNoteLen = 4000
+
+type QuestionReviewHandler struct {
+	store  storage.Store
+	logger *zap.Logger
+}
+
+func NewQuestionReviewHandler(store storage.Store, logger *zap.Logger) *QuestionReviewHandler {
+	return &QuestionReviewHandler{store: store, logger: logger}
+}
+
+// GET /quiz/reviews?status=open  (InternalAuth)
+func (h *QuestionReviewHandler) List(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status != "" && !models.IsValidReviewStatus(status) {
+		http.Error(w, "invalid status", http.StatusBadRequest)
+		return
+	}
+	reviews, err := h.store.ListQuestionReviews(status)
+	if err != nil {
+		h.logger.Error("list question reviews failed", zap.Error(err))
+		http.Error(w, "failed", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(reviews)
+}
+
+// POST /quiz/reviews  (InternalAuth)
+func (h *QuestionReviewHandler) Enqueue(w http.ResponseWriter, r *http.Request) {
+	var candidates []models.QuestionReviewCandidate
+	if err := json.NewDecoder(r.Body).Decode(&candidates); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	valid := candidates[:0]
+	for _, c := range candidates {
+		if c.QuestionID > 0 && len(c.Reasons) > 0 {
+			valid = append(valid, c)
+		}
+	}
+	opened, err := h.store.EnqueueQuestionReviews(valid)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		http.Error(w, "unknown question", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.logger.Error("enqueue question reviews failed", zap.Error(err))
+		http.Error(w, "failed", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]int{"opened": opened})
+}
+
+// PATCH /quiz/reviews/{id}  (InternalAuth)
+func (h *QuestionReviewHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	var update models.QuestionReviewUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	if !models.IsValidReviewStatus(update.Status) {
+		http.Error(w, "invalid status", http.StatusBadRequest)
+		return
+	}
+	if update.Note != nil {
+		note := strings.TrimSpace(*update.Note)
+		if len(note) > maxReviewNoteLen {
+			note = note[:maxReviewNoteLen]
+		}
+		update.Note = &note
+	}
+
+	review, err := h.store.UpdateQuestionReview(id, update)
+	var pqErr *pq.Error
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	case errors.As(err, &pqErr) && pqErr.Code == "23505":
+		// reopening while a newer review of the same question is open
+		http.Error(w, "question already has an open review", http.StatusConflict)
+		return
+	case err != nil:
+		h.logger.Error("update question review failed", zap.Error(err))
+		http.Error(w, "failed", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(review)
+}
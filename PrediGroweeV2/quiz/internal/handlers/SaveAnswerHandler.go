@@ -6,8 +6,10 @@ import (
 	"go.uber.org/zap"
 	"net/http"
 	"quiz/internal/clients"
+	"quiz/internal/live"
 	"quiz/internal/models"
 	"quiz/internal/storage"
+	"quiz/internal/xapi"
 	"strconv"
 	"strings"
 	"time"
@@ -17,13 +19,17 @@ type SubmitAnswerHandler struct {
 	storage     storage.Store
 	logger      *zap.Logger
 	statsClient *clients.StatsClient
+	feed        *live.SessionFeed
+	xapi        *xapi.Emitter
 }
 
-func NewSubmitAnswerHandler(store storage.Store, logger *zap.Logger, statsClient *clients.StatsClient) *SubmitAnswerHandler {
+func NewSubmitAnswerHandler(store storage.Store, logger *zap.Logger, statsClient *clients.StatsClient, feed *live.SessionFeed, statements *xapi.Emitter) *SubmitAnswerHandler {
 	return &SubmitAnswerHandler{
 		storage:     store,
 		logger:      logger,
 		statsClient: statsClient,
+		feed:        feed,
+		xapi:        statements,
 	}
 }
 
@@ -54,6 +60,13 @@ func (h *SubmitAnswerHandler) Handle(rw http.ResponseWriter, r *http.Request) {
 		http.Error(rw, "internal server error", http.StatusInternalServerError)
 		return
 	}
+	if session.LiveRoomID != nil {
+		writeJSONError(rw, http.StatusConflict, map[string]interface{}{
+			"error":   "live_session",
+			"message": "Answers in a live room go to the room.",
+		})
+		return
+	}
 
 	correct, err := h.storage.GetQuestionCorrectOption(session.CurrentQuestionID)
 	if err != nil {
@@ -83,15 +96,18 @@ func (h *SubmitAnswerHandler) Handle(rw http.ResponseWriter, r *http.Request) {
 
 	fmt.Println("question", session.CurrentQuestionID, "answer", answer.Answer, "correct", correct)
 
+	answeredID := session.CurrentQuestionID
+	isCorrect := strings.EqualFold(strings.TrimSpace(answer.Answer), strings.TrimSpace(correct))
 	educationalEmpty := session.Mode == models.QuizModeEducational && strings.TrimSpace(answer.Answer) == ""
 	if !educationalEmpty {
 		err = h.statsClient.SaveResponse(session.ID, models.QuestionAnswer{
 			QuestionID: session.CurrentQuestionID,
 			Answer:     answer.Answer,
-			IsCorrect:  strings.EqualFold(strings.TrimSpace(answer.Answer), strings.TrimSpace(correct)),
+			IsCorrect:  isCorrect,
 			ScreenSize: answer.ScreenSize,
 			TimeSpent:  int(timeSpend.Seconds()),
 			CaseCode:   question.Case.Code,
+			TimeZone:   answer.TimeZone,
 		})
 		if err != nil {
 			h.logger.Error("failed to save response", zap.Error(err))
@@ -114,6 +130,12 @@ func (h *SubmitAnswerHandler) Handle(rw http.ResponseWriter, r *http.Request) {
 		http.Error(rw, "internal server error", http.StatusInternalServerError)
 		return
 	}
+	if educationalEmpty {
+		h.feed.Seen(session)
+	} else {
+		h.feed.Answered(session, answeredID, isCorrect)
+		h.xapi.Answered(session, question, answer.Answer, correct, isCorrect, timeSpend)
+	}
 
 	rw.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(rw).Encode(data); err != nil {
@@ -4,10 +4,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
 	"net/http"
+	"quiz/internal/certificates"
 	"quiz/internal/clients"
+	"quiz/internal/live"
+	"quiz/internal/lti"
 	"quiz/internal/models"
+	"quiz/internal/pools"
 	"quiz/internal/storage"
+	"quiz/internal/webhooks"
+	"quiz/internal/xapi"
 	"strings"
 	"time"
 )
@@ -16,13 +23,23 @@ type StartQuizHandler struct {
 	storage     storage.Store
 	logger      *zap.Logger
 	statsClient *clients.StatsClient
+	feed        *live.SessionFeed
+	hooks       *webhooks.Dispatcher
+	lti         *lti.Tool
+	xapi        *xapi.Emitter
+	certs       *certificates.Issuer
 }
 
-func NewStartQuizHandler(store storage.Store, logger *zap.Logger, client *clients.StatsClient) *StartQuizHandler {
+func NewStartQuizHandler(store storage.Store, logger *zap.Logger, client *clients.StatsClient, feed *live.SessionFeed, hooks *webhooks.Dispatcher, tool *lti.Tool, statements *xapi.Emitter, certs *certificates.Issuer) *StartQuizHandler {
 	return &StartQuizHandler{
 		storage:     store,
 		logger:      logger,
 		statsClient: client,
+		feed:        feed,
+		hooks:       hooks,
+		lti:         tool,
+		xapi:        statements,
+		certs:       certs,
 	}
 }
 
@@ -35,6 +52,10 @@ func writeJSONError(w http.ResponseWriter, status int, payload map[string]interf
 func (h *StartQuizHandler) Handle(rw http.ResponseWriter, r *http.Request) {
 	h.logger.Info("Starting quiz session")
 	userID := r.Context().Value("user_id").(int)
+	// guests joined a test the teacher opened to them, so account approval
+	// does not apply; they are limited to that test instead
+	guestTestCode, _ := r.Context().Value("guest_test_code").(string)
+	isGuest := guestTestCode != ""
 
 	mode, hours, err := h.storage.GetSecuritySettings()
 	if err != nil {
@@ -43,6 +64,9 @@ func (h *StartQuizHandler) Handle(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if isGuest {
+		mode = ""
+	}
 	switch mode {
 	case "manual":
 		ok, err := h.storage.IsUserApproved(userID)
@@ -52,6 +76,11 @@ func (h *StartQuizHandler) Handle(rw http.ResponseWriter, r *http.Request) {
 			return
 		}
 		if !ok {
+			if first, err := h.storage.MarkApprovalPending(userID); err != nil {
+				h.logger.Error("failed to mark approval pending", zap.Error(err))
+			} else if first {
+				h.hooks.Emit(models.WebhookEventApprovalPending, nil, map[string]int{"user_id": userID})
+			}
 			writeJSONError(rw, http.StatusForbidden, map[string]interface{}{
 				"error":   "approval_required",
 				"message": "Account requires manual approval by an administrator.",
@@ -98,7 +127,15 @@ func (h *StartQuizHandler) Handle(rw http.ResponseWriter, r *http.Request) {
 	}
 
 	testCode := strings.TrimSpace(payload.TestCode)
+	if isGuest && !strings.EqualFold(testCode, guestTestCode) {
+		writeJSONError(rw, http.StatusForbidden, map[string]interface{}{
+			"error":   "guest_test_only",
+			"message": "Guests can only take the test they joined.",
+		})
+		return
+	}
 	var newQuizSession models.QuizSession
+	var testTimeLimit, testMaxAttempts *int
 
 	if testCode != "" {
 		t, err := h.storage.GetTestByCode(testCode)
@@ -114,8 +151,18 @@ func (h *StartQuizHandler) Handle(rw http.ResponseWriter, r *http.Request) {
 			})
 			return
 		}
+		if isGuest && !t.AllowGuests {
+			writeJSONError(rw, http.StatusForbidden, map[string]interface{}{
+				"error":   "guests_not_allowed",
+				"message": "This test no longer accepts guests.",
+			})
+			return
+		}
+		if !h.checkTestAccess(rw, t, userID, payload.TestPassword) {
+			return
+		}
 
-		order, err := h.storage.GetTestQuestionIDsOrdered(t.ID)
+		order, seed, err := testQuestionOrder(h.storage, t)
 		if err != nil {
 			h.logger.Error("failed to get test questions", zap.Error(err))
 			http.Error(rw, "internal server error", http.StatusInternalServerError)
@@ -132,12 +179,22 @@ func (h *StartQuizHandler) Handle(rw http.ResponseWriter, r *http.Request) {
 			UserID:            userID,
 			Status:            models.QuizStatusNotStarted,
 			ScreenSize:        fmt.Sprintf("%dx%d", payload.ScreenWidth, payload.ScreenHeight),
+			ScreenWidth:       payload.ScreenWidth,
+			ScreenHeight:      payload.ScreenHeight,
 			CurrentQuestionID: order[0],
 			CurrentGroup:      0,
 			GroupOrder:        order,
 			TestID:            &t.ID,
 			TestCode:          &t.Code,
+			QuestionSeed:      seed,
+			TestVersion:       &t.Version,
+			Guest:             isGuest,
+		}
+		if t.Mode != nil {
+			newQuizSession.Mode = *t.Mode
 		}
+		testTimeLimit = t.TimeLimit
+		testMaxAttempts = t.MaxAttempts
 
 	} else {
 		groupID, err := h.storage.GetNextQuestionGroupID(0)
@@ -163,6 +220,8 @@ func (h *StartQuizHandler) Handle(rw http.ResponseWriter, r *http.Request) {
 			UserID:            userID,
 			Status:            models.QuizStatusNotStarted,
 			ScreenSize:        fmt.Sprintf("%dx%d", payload.ScreenWidth, payload.ScreenHeight),
+			ScreenWidth:       payload.ScreenWidth,
+			ScreenHeight:      payload.ScreenHeight,
 			CurrentQuestionID: order[0],
 			CurrentGroup:      groupID,
 			GroupOrder:        order,
@@ -170,9 +229,22 @@ func (h *StartQuizHandler) Handle(rw http.ResponseWriter, r *http.Request) {
 	}
 
 	if session, err := h.storage.GetUserLastQuizSession(userID); err == nil && session != nil {
-		session.FinishedAt = session.UpdatedAt
-		session.Status = models.QuizStatusFinished
-		_ = h.storage.UpdateQuizSession(*session)
+		// a session left unfinished is closed by the next start; one that is
+		// already finished has announced itself and is left alone
+		if session.Status != models.QuizStatusFinished {
+			session.FinishedAt = session.UpdatedAt
+			session.Status = models.QuizStatusFinished
+			// a guest account only ever takes its one test, so the previous
+			// session was a guest's too
+			session.Guest = isGuest
+			if err := h.storage.UpdateQuizSession(*session); err == nil {
+				h.feed.Finished(session.ID)
+				h.hooks.TestFinished(*session)
+				h.lti.SessionClosed(*session)
+				h.xapi.Terminated(*session)
+				h.certs.SessionFinished(*session)
+			}
+		}
 
 		if testCode == "" && session.TestID == nil && session.CurrentQuestionID > 0 && len(session.GroupOrder) > 0 {
 			h.logger.Info("Resuming previous non-test session ordering",
@@ -198,7 +270,19 @@ func (h *StartQuizHandler) Handle(rw http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	sessionCreated, err := h.storage.CreateQuizSession(newQuizSession)
+	var sessionCreated models.QuizSession
+	if testMaxAttempts != nil {
+		// checkTestAccess turned most users over the limit away already; this
+		// counts again under a lock on the test, for starts that raced it
+		var attempts int
+		sessionCreated, attempts, err = h.storage.CreateTestQuizSession(newQuizSession, *testMaxAttempts)
+		if err == nil && attempts >= *testMaxAttempts {
+			writeAttemptLimitReached(rw, *testMaxAttempts, attempts)
+			return
+		}
+	} else {
+		sessionCreated, err = h.storage.CreateQuizSession(newQuizSession)
+	}
 	if err != nil {
 		h.logger.Error("failed to create quiz session in db", zap.Error(err))
 		http.Error(rw, "internal server error", http.StatusInternalServerError)
@@ -208,9 +292,14 @@ func (h *StartQuizHandler) Handle(rw http.ResponseWriter, r *http.Request) {
 	if err := h.statsClient.SaveSession(sessionCreated); err != nil {
 		h.logger.Error("failed to save session in stats service", zap.Error(err))
 	}
+	h.feed.Started(sessionCreated)
+	h.hooks.TestStarted(sessionCreated)
+	h.xapi.Attempted(sessionCreated)
 
-	timeLimit, err := h.storage.GetTimeLimit()
-	if err != nil {
+	var timeLimit int
+	if testTimeLimit != nil {
+		timeLimit = *testTimeLimit
+	} else if timeLimit, err = h.storage.GetTimeLimit(); err != nil {
 		h.logger.Error("failed to get time limit", zap.Error(err))
 		http.Error(rw, "internal server error", http.StatusInternalServerError)
 		return
@@ -227,3 +316,95 @@ func (h *StartQuizHandler) Handle(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// checkTestAccess enforces a test's schedule, password and attempt limit. It
+// writes the error response itself and reports whether the user may start.
+func (h *StartQuizHandler) checkTestAccess(rw http.ResponseWriter, t *models.Test, userID int, password string) bool {
+	now := time.Now().UTC()
+	if t.OpensAt != nil && now.Before(*t.OpensAt) {
+		writeJSONError(rw, http.StatusForbidden, map[string]interface{}{
+			"error":   "test_not_open",
+			"message": "This test is not open yet.",
+			"opensAt": t.OpensAt.UTC().Format(time.RFC3339),
+		})
+		return false
+	}
+	if t.ClosesAt != nil && !now.Before(*t.ClosesAt) {
+		writeJSONError(rw, http.StatusForbidden, map[string]interface{}{
+			"error":    "test_closed",
+			"message":  "This test is closed.",
+			"closesAt": t.ClosesAt.UTC().Format(time.RFC3339),
+		})
+		return false
+	}
+	if t.PasswordHash != "" {
+		if password == "" {
+			writeJSONError(rw, http.StatusForbidden, map[string]interface{}{
+				"error":   "test_password_required",
+				"message": "This test requires a password.",
+			})
+			return false
+		}
+		if bcrypt.CompareHashAndPassword([]byte(t.PasswordHash), []byte(strings.TrimSpace(password))) != nil {
+			writeJSONError(rw, http.StatusForbidden, map[string]interface{}{
+				"error":   "invalid_test_password",
+				"message": "Incorrect test password.",
+			})
+			return false
+		}
+	}
+	if t.MaxAttempts != nil {
+		attempts, err := h.storage.CountUserTestSessions(t.ID, userID)
+		if err != nil {
+			h.logger.Error("failed to count test attempts", zap.Error(err))
+			http.Error(rw, "internal server error", http.StatusInternalServerError)
+			return false
+		}
+		if attempts >= *t.MaxAttempts {
+			writeAttemptLimitReached(rw, *t.MaxAttempts, attempts)
+			return false
+		}
+	}
+	return true
+}
+
+func writeAttemptLimitReached(rw http.ResponseWriter, maxAttempts int, attempts int) {
+	writeJSONError(rw, http.StatusForbidden, map[string]interface{}{
+		"error":       "attempt_limit_reached",
+		"message":     "You have used all attempts for this test.",
+		"maxAttempts": maxAttempts,
+		"attempts":    attempts,
+	})
+}
+
+// testQuestionOrder returns the questions of the test's current version for
+// a new session or live room. A pooled test draws them afresh and also
+// returns the seed it used; a test with a fixed list returns that list and no
+// seed.
+func testQuestionOrder(store storage.Store, t *models.Test) ([]int, *int64, error) {
+	v, err := store.GetTestVersion(t.ID, t.Version)
+	if err != nil {
+		return nil, nil, err
+	}
+	if v == nil {
+		return nil, nil, fmt.Errorf("test %d has no version %d", t.ID, t.Version)
+	}
+	if len(v.Pools) == 0 {
+		return v.QuestionIDs, nil, nil
+	}
+
+	var stratified []int
+	for _, p := range v.Pools {
+		if p.Stratify {
+			stratified = append(stratified, p.QuestionIDs...)
+		}
+	}
+	var correct map[int]string
+	if len(stratified) > 0 {
+		if correct, err = store.GetCorrectOptions(stratified); err != nil {
+			return nil, nil, err
+		}
+	}
+	seed := pools.NewSeed()
+	return pools.Draw(v.Pools, correct, seed), &seed, nil
+}
@@ -4,6 +4,7 @@ import (
     "database/sql"
     "go.uber.org/zap"
     "net/http"
+    "quiz/internal/models"
     "quiz/internal/storage"
     "strconv"
 )
@@ -32,6 +33,21 @@ func (h *TeacherDeleteTestHandler) Handle(rw http.ResponseWriter, r *http.Reques
         return
     }
 
+    // Only the owner (or an admin) may delete; co-owners can leave instead.
+    t, err := h.storage.GetTestByID(id)
+    if err != nil {
+        h.logger.Error("failed to get test by id", zap.Error(err))
+        http.Error(rw, "internal server error", http.StatusInternalServerError)
+        return
+    }
+    if t == nil {
+        http.Error(rw, "not found", http.StatusNotFound)
+        return
+    }
+    if !authorizeTest(rw, r, h.storage, h.logger, t, models.TestPermissionOwner) {
+        return
+    }
+
     if err := h.storage.DeleteTest(id); err != nil {
         if err == sql.ErrNoRows {
             http.Error(rw, "not found", http.StatusNotFound)
@@ -4,6 +4,7 @@ import (
     "encoding/json"
     "go.uber.org/zap"
     "net/http"
+    "quiz/internal/models"
     "quiz/internal/storage"
     "strconv"
 )
@@ -42,6 +43,16 @@ func (h *TeacherGetTestHandler) Handle(rw http.ResponseWriter, r *http.Request)
         http.Error(rw, "not found", http.StatusNotFound)
         return
     }
+    permission, err := testPermission(h.storage, r, t)
+    if err != nil {
+        h.logger.Error("failed to get test permission", zap.Error(err))
+        http.Error(rw, "internal server error", http.StatusInternalServerError)
+        return
+    }
+    if !models.HasTestPermission(permission, models.TestPermissionViewer) {
+        http.Error(rw, "forbidden", http.StatusForbidden)
+        return
+    }
 
     // Pobierz pytania testu w zadanej kolejności
     qIDs, err := h.storage.GetTestQuestionIDsOrdered(t.ID)
@@ -73,15 +84,32 @@ func (h *TeacherGetTestHandler) Handle(rw http.ResponseWriter, r *http.Request)
         })
     }
 
+    testPools, err := h.storage.GetTestPools(t.ID)
+    if err != nil {
+        h.logger.Error("failed to get test pools", zap.Error(err))
+        http.Error(rw, "internal server error", http.StatusInternalServerError)
+        return
+    }
+
     resp := map[string]interface{}{
         "id":              t.ID,
         "code":            t.Code,
         "name":            t.Name,
         "created_by":      t.CreatedBy,
         "created_at":      t.CreatedAt,
+        "version":         t.Version,
         "question_ids":    qIDs,
         "questions":       questions,
         "questions_count": len(qIDs),
+        "opens_at":        t.OpensAt,
+        "closes_at":       t.ClosesAt,
+        "max_attempts":    t.MaxAttempts,
+        "mode":            t.Mode,
+        "time_limit":      t.TimeLimit,
+        "has_password":    t.HasPassword,
+        "allow_guests":    t.AllowGuests,
+        "pools":           testPools,
+        "permission":      permission,
     }
 
     rw.Header().Set("Content-Type", "application/json")
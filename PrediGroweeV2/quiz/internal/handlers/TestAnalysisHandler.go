@@ -0,0 +1,346 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"quiz/internal/clients"
+	"quiz/internal/models"
+	"quiz/internal/storage"
+)
+
+// extremeGroupShare is the classic Kelley split: the top and bottom 27% of
+// students by score form the upper and lower groups.
+const extremeGroupShare = 0.27
+
+type TestAnalysisHandler struct {
+	store       storage.Store
+	logger      *zap.Logger
+	statsClient *clients.StatsClient
+}
+
+func NewTestAnalysisHandler(store storage.Store, logger *zap.Logger, statsClient *clients.StatsClient) *TestAnalysisHandler {
+	return &TestAnalysisHandler{store: store, logger: logger, statsClient: statsClient}
+}
+
+type testItem struct {
+	question models.Question
+	correct  string
+}
+
+// GET /quiz/tests/{code}/analysis?version=  (VerifyToken, test viewer)
+func (h *TestAnalysisHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	code := strings.ToUpper(strings.TrimSpace(r.PathValue("code")))
+	if code == "" {
+		http.Error(w, "invalid code", http.StatusBadRequest)
+		return
+	}
+	t, err := h.store.GetTestByCode(code)
+	if err != nil {
+		h.logger.Error("get test by code failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if t == nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if !authorizeTest(w, r, h.store, h.logger, t, models.TestPermissionViewer) {
+		return
+	}
+	version, ok := resultsVersion(w, r, t)
+	if !ok {
+		return
+	}
+	items, sessions, answers, err := loadTestResults(h.store, h.statsClient, h.logger, t, version)
+	if err != nil {
+		writeLoadResultsError(w, h.logger, t, err)
+		return
+	}
+
+	analysis := analyseTest(*t, items, sessions, answers)
+	analysis.Version = version
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(analysis)
+}
+
+var errTestVersionNotFound = errors.New("test version not found")
+
+// resultsVersion reads the optional ?version= query parameter, defaulting to
+// the test's current version. Results are always read one version at a time
+// so that every student in a report was given the same questions.
+func resultsVersion(w http.ResponseWriter, r *http.Request, t *models.Test) (int, bool) {
+	v := r.URL.Query().Get("version")
+	if v == "" {
+		return t.Version, true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		http.Error(w, "invalid version", http.StatusBadRequest)
+		return 0, false
+	}
+	return n, true
+}
+
+func writeLoadResultsError(w http.ResponseWriter, logger *zap.Logger, t *models.Test, err error) {
+	if errors.Is(err, errTestVersionNotFound) {
+		http.Error(w, "version not found", http.StatusNotFound)
+		return
+	}
+	logger.Error("failed to load test results", zap.Int("test_id", t.ID), zap.Error(err))
+	http.Error(w, "internal server error", http.StatusInternalServerError)
+}
+
+// testPermission returns the caller's permission on a test. Admins act as
+// owners of every test.
+func testPermission(store storage.Store, r *http.Request, t *models.Test) (string, error) {
+	userID, _ := r.Context().Value("user_id").(int)
+	role, _ := r.Context().Value("user_role").(models.UserRole)
+	if t.CreatedBy == userID || role == models.RoleAdmin {
+		return models.TestPermissionOwner, nil
+	}
+	return store.GetTestPermission(t.ID, userID)
+}
+
+// authorizeTest checks that the caller has at least the min permission on the
+// test. It writes the error response itself and reports false on failure.
+func authorizeTest(w http.ResponseWriter, r *http.Request, store storage.Store, logger *zap.Logger, t *models.Test, min string) bool {
+	p, err := testPermission(store, r, t)
+	if err != nil {
+		logger.Error("get test permission failed", zap.Int("test_id", t.ID), zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return false
+	}
+	if !models.HasTestPermission(p, min) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// loadTestResults fetches the questions of one version of the test in order,
+// every session that took that version and the first answers given in those
+// sessions.
+func loadTestResults(store storage.Store, statsClient *clients.StatsClient, logger *zap.Logger, t *models.Test, version int) ([]testItem, []models.QuizSession, []models.SessionAnswer, error) {
+	v, err := store.GetTestVersion(t.ID, version)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("get test version: %w", err)
+	}
+	if v == nil {
+		return nil, nil, nil, errTestVersionNotFound
+	}
+	items := make([]testItem, 0, len(v.QuestionIDs))
+	for _, qid := range v.QuestionIDs {
+		q, err := store.GetQuestionByID(qid)
+		if err != nil {
+			logger.Warn("failed to get question for test", zap.Int("question_id", qid), zap.Error(err))
+			continue
+		}
+		correct, err := store.GetQuestionCorrectOption(qid)
+		if err != nil && err != sql.ErrNoRows {
+			logger.Warn("failed to get correct option", zap.Int("question_id", qid), zap.Error(err))
+		}
+		items = append(items, testItem{question: q, correct: correct})
+	}
+
+	all, err := store.ListSessionsByTestID(t.ID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, nil, nil, fmt.Errorf("list sessions: %w", err)
+	}
+	sessions := make([]models.QuizSession, 0, len(all))
+	sessionIDs := make([]int, 0, len(all))
+	for _, s := range all {
+		if s.TestVersion != nil && *s.TestVersion == version {
+			sessions = append(sessions, s)
+			sessionIDs = append(sessionIDs, s.ID)
+		}
+	}
+	answers, err := statsClient.GetSessionAnswers(sessionIDs)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("get session answers: %w", err)
+	}
+	return items, sessions, answers, nil
+}
+
+func analyseTest(t models.Test, items []testItem, sessions []models.QuizSession, answers []models.SessionAnswer) models.TestAnalysis {
+	bySession := make(map[int]map[int]models.SessionAnswer, len(sessions))
+	for _, a := range answers {
+		if bySession[a.SessionID] == nil {
+			bySession[a.SessionID] = make(map[int]models.SessionAnswer)
+		}
+		bySession[a.SessionID][a.QuestionID] = a
+	}
+
+	students := make([]models.TestStudentResult, 0, len(sessions))
+	finished := make([]models.TestStudentResult, 0, len(sessions))
+	for _, s := range sessions {
+		res := models.TestStudentResult{
+			SessionID:    s.ID,
+			UserID:       s.UserID,
+			Status:       s.Status,
+			CreatedAt:    s.CreatedAt,
+			FinishedAt:   s.FinishedAt,
+			QuestionSeed: s.QuestionSeed,
+			Answers:      make([]models.TestStudentAnswer, len(items)),
+		}
+		var drawn map[int]bool
+		if s.QuestionSeed != nil {
+			drawn = make(map[int]bool, len(s.GroupOrder))
+			for _, id := range s.GroupOrder {
+				drawn[id] = true
+			}
+		}
+		for i, it := range items {
+			sa := models.TestStudentAnswer{QuestionID: it.question.ID}
+			if drawn != nil && !drawn[it.question.ID] {
+				sa.NotDrawn = true
+			}
+			if a, ok := bySession[s.ID][it.question.ID]; ok {
+				answer, correct := a.Answer, a.Correct
+				sa.Answer, sa.Correct, sa.TimeSpent = &answer, &correct, a.TimeSpent
+				res.Answered++
+				if correct {
+					res.Score++
+				}
+			}
+			res.Answers[i] = sa
+		}
+		students = append(students, res)
+		if s.FinishedAt != nil {
+			finished = append(finished, res)
+		}
+	}
+
+	// Rank finished attempts by score to form the extreme groups.
+	ranked := append([]models.TestStudentResult(nil), finished...)
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Score > ranked[j].Score })
+	groupSize := 0
+	if len(ranked) >= 2 {
+		groupSize = max(1, int(math.Round(extremeGroupShare*float64(len(ranked)))))
+		groupSize = min(groupSize, len(ranked)/2)
+	}
+	upper, lower := ranked[:groupSize], ranked[len(ranked)-groupSize:]
+
+	questions := make([]models.TestItemAnalysis, len(items))
+	for i, it := range items {
+		questions[i] = analyseItem(i, it, finished, upper, lower)
+	}
+
+	return models.TestAnalysis{
+		Test:      t,
+		Finished:  len(finished),
+		Questions: questions,
+		Scores:    scoreDistribution(finished, len(items)),
+		Students:  students,
+	}
+}
+
+func analyseItem(pos int, it testItem, finished, upper, lower []models.TestStudentResult) models.TestItemAnalysis {
+	item := models.TestItemAnalysis{
+		QuestionID:    it.question.ID,
+		Position:      pos + 1,
+		CaseCode:      it.question.Case.Code,
+		CorrectOption: it.correct,
+	}
+	options := make([]models.DistractorStats, 0, len(it.question.Options))
+	index := make(map[string]int, len(it.question.Options))
+	optionFor := func(answer string) *models.DistractorStats {
+		k, ok := index[answer]
+		if !ok {
+			// an answer no longer among the options, e.g. after an edit
+			k = len(options)
+			index[answer] = k
+			options = append(options, models.DistractorStats{Option: answer, Correct: answer == it.correct})
+		}
+		return &options[k]
+	}
+	for _, o := range it.question.Options {
+		optionFor(o)
+	}
+
+	for _, s := range finished {
+		a := s.Answers[pos]
+		if a.Answer == nil {
+			if !a.NotDrawn {
+				item.Omitted++
+			}
+			continue
+		}
+		item.Answered++
+		if *a.Correct {
+			item.Correct++
+		}
+		optionFor(*a.Answer).Count++
+	}
+	for _, s := range upper {
+		if a := s.Answers[pos]; a.Answer != nil {
+			optionFor(*a.Answer).Upper++
+		}
+	}
+	for _, s := range lower {
+		if a := s.Answers[pos]; a.Answer != nil {
+			optionFor(*a.Answer).Lower++
+		}
+	}
+
+	if item.Answered > 0 {
+		p := float64(item.Correct) / float64(item.Answered)
+		item.PValue = &p
+		for k := range options {
+			options[k].Share = float64(options[k].Count) / float64(item.Answered)
+		}
+	}
+	if len(upper) > 0 {
+		d := (float64(correctIn(upper, pos)) - float64(correctIn(lower, pos))) / float64(len(upper))
+		item.DiscriminationIndex = &d
+	}
+	item.Options = options
+	return item
+}
+
+func correctIn(group []models.TestStudentResult, pos int) int {
+	n := 0
+	for _, s := range group {
+		if c := s.Answers[pos].Correct; c != nil && *c {
+			n++
+		}
+	}
+	return n
+}
+
+func scoreDistribution(finished []models.TestStudentResult, maxScore int) models.ScoreDistribution {
+	dist := models.ScoreDistribution{MaxScore: maxScore, Counts: make([]int, maxScore+1)}
+	if len(finished) == 0 {
+		return dist
+	}
+	scores := make([]int, len(finished))
+	sum := 0
+	for i, s := range finished {
+		scores[i] = s.Score
+		sum += s.Score
+		dist.Counts[s.Score]++
+	}
+	sort.Ints(scores)
+	n := len(scores)
+	dist.Min, dist.Max = scores[0], scores[n-1]
+	dist.Mean = float64(sum) / float64(n)
+	if n%2 == 1 {
+		dist.Median = float64(scores[n/2])
+	} else {
+		dist.Median = float64(scores[n/2-1]+scores[n/2]) / 2
+	}
+	variance := 0.0
+	for _, sc := range scores {
+		variance += (float64(sc) - dist.Mean) * (float64(sc) - dist.Mean)
+	}
+	dist.SD = math.Sqrt(variance / float64(n))
+	return dist
+}
@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"quiz/internal/clients"
+	"quiz/internal/models"
+	"quiz/internal/storage"
+)
+
+// TestOwnersHandler manages who besides the owner can see and edit a test.
+// Co-owners are viewers (see the test and its results, assign it to their
+// classes, clone it) or editors (also edit it). Only the owner can delete the
+// test, manage co-owners or hand the test over; admins act as owners.
+type TestOwnersHandler struct {
+	store      storage.Store
+	logger     *zap.Logger
+	authClient *clients.AuthClient
+}
+
+func NewTestOwnersHandler(store storage.Store, logger *zap.Logger, authClient *clients.AuthClient) *TestOwnersHandler {
+	return &TestOwnersHandler{store: store, logger: logger, authClient: authClient}
+}
+
+// userRef identifies another user by email or by id.
+type userRef struct {
+	UserID int    `json:"user_id"`
+	Email  string `json:"email"`
+}
+
+// GET /quiz/tests/{id}/owners  (VerifyToken, test viewer)
+//
+// Lists the owner first, then the co-owners in the order they were added.
+func (h *TestOwnersHandler) List(w http.ResponseWriter, r *http.Request) {
+	t := h.loadTest(w, r, models.TestPermissionViewer)
+	if t == nil {
+		return
+	}
+	coOwners, err := h.store.ListTestCoOwners(t.ID)
+	if err != nil {
+		h.logger.Error("list test co-owners failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	owners := append([]models.TestOwner{{UserID: t.CreatedBy, Role: models.TestPermissionOwner}}, coOwners...)
+
+	users, err := h.authClient.GetUsers()
+	if err != nil {
+		h.logger.Warn("failed to get users from auth, owners without names", zap.Error(err))
+	}
+	profiles := make(map[int]models.UserProfile, len(users))
+	for _, u := range users {
+		profiles[u.ID] = u
+	}
+	for i := range owners {
+		p := profiles[owners[i].UserID]
+		owners[i].FirstName, owners[i].LastName, owners[i].Email = p.FirstName, p.LastName, p.Email
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(owners)
+}
+
+// PUT /quiz/tests/{id}/owners  (VerifyToken, test owner)
+// Body: {"email": "...", "role": "viewer"|"editor"} or {"user_id": 7, ...}
+//
+// Adds a co-owner or changes the role of an existing one.
+func (h *TestOwnersHandler) Set(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	var req struct {
+		userRef
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	if !models.IsValidCoOwnerRole(req.Role) {
+		http.Error(w, "role must be viewer or editor", http.StatusBadRequest)
+		return
+	}
+	t := h.loadTest(w, r, models.TestPermissionOwner)
+	if t == nil {
+		return
+	}
+	target, ok := h.resolveUser(w, req.userRef)
+	if !ok {
+		return
+	}
+	if target == t.CreatedBy {
+		http.Error(w, "user already owns the test", http.StatusBadRequest)
+		return
+	}
+	if err := h.store.SetTestCoOwner(t.ID, target, req.Role, userID); err != nil {
+		h.logger.Error("set test co-owner failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DELETE /quiz/tests/{id}/owners/{userId}  (VerifyToken, test owner, or the
+// co-owner themselves)
+func (h *TestOwnersHandler) Remove(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	coOwnerID, err := strconv.Atoi(r.PathValue("userId"))
+	if err != nil || coOwnerID <= 0 {
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+	min := models.TestPermissionOwner
+	if coOwnerID == userID {
+		min = models.TestPermissionViewer
+	}
+	t := h.loadTest(w, r, min)
+	if t == nil {
+		return
+	}
+	if err := h.store.RemoveTestCoOwner(t.ID, coOwnerID); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("remove test co-owner failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /quiz/tests/{id}/transfer  (VerifyToken, test owner)
+// Body: {"email": "...", "previous_owner_role": "editor"|"viewer"|""}
+//
+// Hands the test, its versions and results over to another user, e.g. when
+// a teacher leaves the department. The previous owner stays on as an editor
+// unless previous_owner_role says otherwise; an empty role removes them.
+func (h *TestOwnersHandler) Transfer(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	var req struct {
+		userRef
+		PreviousOwnerRole *string `json:"previous_owner_role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	previousRole := models.TestPermissionEditor
+	if req.PreviousOwnerRole != nil {
+		previousRole = *req.PreviousOwnerRole
+		if previousRole != "" && !models.IsValidCoOwnerRole(previousRole) {
+			http.Error(w, "previous_owner_role must be viewer, editor or empty", http.StatusBadRequest)
+			return
+		}
+	}
+	t := h.loadTest(w, r, models.TestPermissionOwner)
+	if t == nil {
+		return
+	}
+	target, ok := h.resolveUser(w, req.userRef)
+	if !ok {
+		return
+	}
+	if target == t.CreatedBy {
+		http.Error(w, "user already owns the test", http.StatusBadRequest)
+		return
+	}
+	if err := h.store.TransferTestOwnership(t.ID, target, previousRole, userID); err != nil {
+		h.logger.Error("transfer test ownership failed", zap.Int("test_id", t.ID), zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.logger.Info("test ownership transferred",
+		zap.Int("test_id", t.ID), zap.Int("from", t.CreatedBy), zap.Int("to", target), zap.Int("by", userID))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadTest loads the test in the {id} path value and checks the caller's
+// permission on it. It writes the error response itself and returns nil on
+// failure.
+func (h *TestOwnersHandler) loadTest(w http.ResponseWriter, r *http.Request, min string) *models.Test {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return nil
+	}
+	t, err := h.store.GetTestByID(id)
+	if err != nil {
+		h.logger.Error("get test by id failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return nil
+	}
+	if t == nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return nil
+	}
+	if !authorizeTest(w, r, h.store, h.logger, t, min) {
+		return nil
+	}
+	return t
+}
+
+// resolveUser looks the referenced user up in the auth service and returns
+// their id. It writes the error response itself and reports false on failure.
+func (h *TestOwnersHandler) resolveUser(w http.ResponseWriter, ref userRef) (int, bool) {
+	email := strings.TrimSpace(ref.Email)
+	if email == "" && ref.UserID <= 0 {
+		http.Error(w, "email or user_id is required", http.StatusBadRequest)
+		return 0, false
+	}
+	users, err := h.authClient.GetUsers()
+	if err != nil {
+		h.logger.Error("failed to get users from auth", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return 0, false
+	}
+	for _, u := range users {
+		if (email != "" && strings.EqualFold(u.Email, email)) || (email == "" && u.ID == ref.UserID) {
+			return u.ID, true
+		}
+	}
+	writeJSONError(w, http.StatusNotFound, map[string]interface{}{
+		"error":   "user_not_found",
+		"message": "No user with this email or id.",
+	})
+	return 0, false
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"quiz/internal/clients"
+	"quiz/internal/models"
+	"quiz/internal/storage"
+	"quiz/internal/xlsx"
+)
+
+type TestResultsExportHandler struct {
+	store       storage.Store
+	logger      *zap.Logger
+	authClient  *clients.AuthClient
+	statsClient *clients.StatsClient
+}
+
+func NewTestResultsExportHandler(store storage.Store, logger *zap.Logger, authClient *clients.AuthClient, statsClient *clients.StatsClient) *TestResultsExportHandler {
+	return &TestResultsExportHandler{store: store, logger: logger, authClient: authClient, statsClient: statsClient}
+}
+
+// GET /quiz/tests/{id}/results.csv?version=  (VerifyToken, test viewer)
+func (h *TestResultsExportHandler) CSV(w http.ResponseWriter, r *http.Request) {
+	filename, _, rows, ok := h.buildRows(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".csv"))
+	cw := csv.NewWriter(w)
+	for _, row := range rows {
+		record := make([]string, len(row))
+		for i, v := range row {
+			record[i] = csvValue(v)
+		}
+		_ = cw.Write(record)
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		h.logger.Error("failed to write results csv", zap.Error(err))
+	}
+}
+
+// GET /quiz/tests/{id}/results.xlsx?version=  (VerifyToken, test viewer)
+func (h *TestResultsExportHandler) XLSX(w http.ResponseWriter, r *http.Request) {
+	filename, t, rows, ok := h.buildRows(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".xlsx"))
+	if err := xlsx.Write(w, t.Name, rows); err != nil {
+		h.logger.Error("failed to write results xlsx", zap.Error(err))
+	}
+}
+
+// buildRows loads one version of the test and returns a download file name
+// without extension and the header followed by one row per attempt. It
+// writes the error response itself and reports false on failure.
+func (h *TestResultsExportHandler) buildRows(w http.ResponseWriter, r *http.Request) (string, *models.Test, [][]any, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return "", nil, nil, false
+	}
+	t, err := h.store.GetTestByID(id)
+	if err != nil {
+		h.logger.Error("failed to get test by id", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return "", nil, nil, false
+	}
+	if t == nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return "", nil, nil, false
+	}
+	if !authorizeTest(w, r, h.store, h.logger, t, models.TestPermissionViewer) {
+		return "", nil, nil, false
+	}
+
+	version, ok := resultsVersion(w, r, t)
+	if !ok {
+		return "", nil, nil, false
+	}
+	items, sessions, answers, err := loadTestResults(h.store, h.statsClient, h.logger, t, version)
+	if err != nil {
+		writeLoadResultsError(w, h.logger, t, err)
+		return "", nil, nil, false
+	}
+	users, err := h.authClient.GetUsers()
+	if err != nil {
+		h.logger.Error("failed to get users from auth", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return "", nil, nil, false
+	}
+	profiles := make(map[int]models.UserProfile, len(users))
+	for _, u := range users {
+		profiles[u.ID] = u
+	}
+
+	filename := fmt.Sprintf("test_%s_v%d_results_%s", t.Code, version, time.Now().UTC().Format("20060102"))
+	return filename, t, resultRows(items, analyseTest(*t, items, sessions, answers).Students, profiles), true
+}
+
+// resultRows lays out one row per attempt: participant, an answer and a
+// correctness column per question, then score and timing. Questions are
+// labelled by position and case code, since a test can reuse a case.
+func resultRows(items []testItem, students []models.TestStudentResult, profiles map[int]models.UserProfile) [][]any {
+	header := []any{"Name", "Email"}
+	for i, it := range items {
+		label := fmt.Sprintf("Q%d %s", i+1, it.question.Case.Code)
+		header = append(header, label+" answer", label+" correct")
+	}
+	header = append(header, "Total score", "Started at", "Finished at", "Duration (s)")
+
+	rows := make([][]any, 0, len(students)+1)
+	rows = append(rows, header)
+	for _, s := range students {
+		p := profiles[s.UserID]
+		row := []any{strings.TrimSpace(p.FirstName + " " + p.LastName), p.Email}
+		for _, a := range s.Answers {
+			if a.Answer == nil {
+				row = append(row, nil, nil)
+				continue
+			}
+			row = append(row, *a.Answer, *a.Correct)
+		}
+		var duration any
+		if s.CreatedAt != nil && s.FinishedAt != nil {
+			duration = int(s.FinishedAt.Sub(*s.CreatedAt).Seconds())
+		}
+		row = append(row, s.Score, s.CreatedAt, s.FinishedAt, duration)
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func csvValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return xlsx.EscapeFormula(v)
+	case int:
+		return strconv.Itoa(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		return v.UTC().Format(time.RFC3339)
+	}
+	return ""
+}
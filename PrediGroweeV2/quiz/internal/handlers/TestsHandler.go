@@ -3,14 +3,19 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/lib/pq"
 	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
 	"quiz/internal/models"
+	"quiz/internal/pools"
 	"quiz/internal/storage"
 )
 
@@ -27,8 +32,73 @@ type createTestReq struct {
 	Code        string `json:"code"`
 	Name        string `json:"name"`
 	QuestionIDs []int  `json:"question_ids"`
+	// Pools replaces QuestionIDs for tests that draw a different subset of
+	// questions for every session.
+	Pools []models.TestPool `json:"pools"`
+	testSettingsReq
 }
 
+// testSettingsReq carries the optional scheduling and access settings of a
+// test. On update, an omitted password keeps the current one and an empty
+// string removes it; every other field is replaced as sent.
+type testSettingsReq struct {
+	OpensAt     *time.Time `json:"opens_at"`
+	ClosesAt    *time.Time `json:"closes_at"`
+	MaxAttempts *int       `json:"max_attempts"`
+	Password    *string    `json:"password"`
+	Mode        *string    `json:"mode"`
+	TimeLimit   *int       `json:"time_limit"`
+	AllowGuests bool       `json:"allow_guests"`
+}
+
+// apply validates the settings and copies them onto t.
+func (req testSettingsReq) apply(t *models.Test) error {
+	if req.OpensAt != nil && req.ClosesAt != nil && !req.OpensAt.Before(*req.ClosesAt) {
+		return errors.New("opens_at must be before closes_at")
+	}
+	if req.MaxAttempts != nil && *req.MaxAttempts <= 0 {
+		return errors.New("max_attempts must be a positive integer")
+	}
+	if req.TimeLimit != nil && *req.TimeLimit <= 0 {
+		return errors.New("time_limit must be a positive integer")
+	}
+	var mode *models.QuizMode
+	if req.Mode != nil && *req.Mode != "" {
+		m := *req.Mode
+		// "limited_time" is accepted as an alias of the stored mode name
+		if m == "limited_time" {
+			m = models.QuizModeLimitedTime
+		}
+		if m != models.QuizModeClassic && m != models.QuizModeLimitedTime {
+			return errors.New("mode must be classic or time_limited")
+		}
+		mode = &m
+	}
+	if req.Password != nil {
+		password := strings.TrimSpace(*req.Password)
+		t.PasswordHash = ""
+		if password != "" {
+			if len(password) > maxTestPasswordLength {
+				return errors.New("password is too long")
+			}
+			hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+			if err != nil {
+				return err
+			}
+			t.PasswordHash = string(hash)
+		}
+	}
+
+	t.OpensAt, t.ClosesAt = req.OpensAt, req.ClosesAt
+	t.MaxAttempts, t.TimeLimit, t.Mode = req.MaxAttempts, req.TimeLimit, mode
+	t.AllowGuests = req.AllowGuests
+	t.HasPassword = t.PasswordHash != ""
+	return nil
+}
+
+// maxTestPasswordLength stays below bcrypt's 72 byte input limit.
+const maxTestPasswordLength = 64
+
 var codeRe = regexp.MustCompile(`^[A-Z0-9-]{4,24}$`)
 
 func (h *TestsHandler) Create(w http.ResponseWriter, r *http.Request) {
@@ -51,8 +121,9 @@ func (h *TestsHandler) Create(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "name is required", http.StatusBadRequest)
 		return
 	}
-	if len(req.QuestionIDs) == 0 {
-		http.Error(w, "question_ids cannot be empty", http.StatusBadRequest)
+	questionIDs, err := testQuestions(req.QuestionIDs, req.Pools)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -61,8 +132,12 @@ func (h *TestsHandler) Create(w http.ResponseWriter, r *http.Request) {
 		Name:      req.Name,
 		CreatedBy: userID,
 	}
+	if err := req.testSettingsReq.apply(&t); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	created, err := h.store.CreateTest(t, req.QuestionIDs)
+	created, err := h.store.CreateTest(t, questionIDs, req.Pools)
 	if err != nil {
 		// duplicate code
 		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
@@ -79,6 +154,233 @@ func (h *TestsHandler) Create(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(created)
 }
 
+// testQuestions validates the questions of a test, given either as a fixed
+// list or as pools, and returns every question of the test in order.
+func testQuestions(questionIDs []int, testPools []models.TestPool) ([]int, error) {
+	if len(testPools) == 0 {
+		if len(questionIDs) == 0 {
+			return nil, errors.New("question_ids cannot be empty")
+		}
+		return questionIDs, nil
+	}
+	if len(questionIDs) > 0 {
+		return nil, errors.New("use either question_ids or pools")
+	}
+	if err := pools.Validate(testPools); err != nil {
+		return nil, err
+	}
+	all := make([]int, 0)
+	for _, p := range testPools {
+		all = append(all, p.QuestionIDs...)
+	}
+	return all, nil
+}
+
+// ownedTest loads the test in the {id} path value and checks that the caller
+// has at least the min permission on it. It writes the error response itself
+// and returns nil on failure.
+func (h *TestsHandler) ownedTest(w http.ResponseWriter, r *http.Request, min string) *models.Test {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return nil
+	}
+	t, err := h.store.GetTestByID(id)
+	if err != nil {
+		h.logger.Error("get test by id failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return nil
+	}
+	if t == nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return nil
+	}
+	if !authorizeTest(w, r, h.store, h.logger, t, min) {
+		return nil
+	}
+	return t
+}
+
+// currentVersion returns the snapshot of the test's current version.
+func (h *TestsHandler) currentVersion(w http.ResponseWriter, t *models.Test) *models.TestVersion {
+	v, err := h.store.GetTestVersion(t.ID, t.Version)
+	if err == nil && v == nil {
+		err = fmt.Errorf("test %d has no version %d", t.ID, t.Version)
+	}
+	if err != nil {
+		h.logger.Error("get test version failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return nil
+	}
+	return v
+}
+
+type updateTestReq struct {
+	Name        *string           `json:"name"`
+	QuestionIDs []int             `json:"question_ids"`
+	Pools       []models.TestPool `json:"pools"`
+	Settings    *testSettingsReq  `json:"settings"`
+}
+
+// PATCH /quiz/tests/{id}  (VerifyToken, test editor)
+//
+// Omitted fields are kept. Sending question_ids or pools replaces the
+// questions; sending settings replaces all settings. Each edit creates a new
+// version and sessions already started keep theirs.
+func (h *TestsHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var req updateTestReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	t := h.ownedTest(w, r, models.TestPermissionEditor)
+	if t == nil {
+		return
+	}
+	current := h.currentVersion(w, t)
+	if current == nil {
+		return
+	}
+
+	if req.Name != nil {
+		t.Name = strings.TrimSpace(*req.Name)
+		if t.Name == "" {
+			http.Error(w, "name is required", http.StatusBadRequest)
+			return
+		}
+	}
+	questionIDs, testPools := current.QuestionIDs, current.Pools
+	if len(req.QuestionIDs) > 0 || len(req.Pools) > 0 {
+		var err error
+		if questionIDs, err = testQuestions(req.QuestionIDs, req.Pools); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		testPools = req.Pools
+	}
+	if req.Settings != nil {
+		if err := req.Settings.apply(t); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	h.saveVersion(w, r, *t, questionIDs, testPools)
+}
+
+// PUT /quiz/tests/{id}/settings  (VerifyToken, test editor)
+func (h *TestsHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	var req testSettingsReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	t := h.ownedTest(w, r, models.TestPermissionEditor)
+	if t == nil {
+		return
+	}
+	current := h.currentVersion(w, t)
+	if current == nil {
+		return
+	}
+	if err := req.apply(t); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.saveVersion(w, r, *t, current.QuestionIDs, current.Pools)
+}
+
+func (h *TestsHandler) saveVersion(w http.ResponseWriter, r *http.Request, t models.Test, questionIDs []int, testPools []models.TestPool) {
+	userID := r.Context().Value("user_id").(int)
+	updated, err := h.store.UpdateTest(t, questionIDs, testPools, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23503" {
+			http.Error(w, "unknown question id", http.StatusBadRequest)
+			return
+		}
+		h.logger.Error("update test failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(updated)
+}
+
+type cloneTestReq struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// POST /quiz/tests/{id}/clone  (VerifyToken, test viewer)
+//
+// The clone copies the current version's questions and settings except the
+// schedule, which rarely carries over to a new term. The caller owns it.
+func (h *TestsHandler) Clone(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	var req cloneTestReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	req.Code = strings.TrimSpace(strings.ToUpper(req.Code))
+	if !codeRe.MatchString(req.Code) {
+		http.Error(w, "invalid code format", http.StatusBadRequest)
+		return
+	}
+	src := h.ownedTest(w, r, models.TestPermissionViewer)
+	if src == nil {
+		return
+	}
+	current := h.currentVersion(w, src)
+	if current == nil {
+		return
+	}
+
+	clone := models.Test{
+		Code:         req.Code,
+		Name:         strings.TrimSpace(req.Name),
+		CreatedBy:    userID,
+		TestSettings: src.TestSettings,
+	}
+	if clone.Name == "" {
+		clone.Name = src.Name
+	}
+	clone.OpensAt, clone.ClosesAt = nil, nil
+
+	created, err := h.store.CreateTest(clone, current.QuestionIDs, current.Pools)
+	if err != nil {
+		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
+			http.Error(w, "test code already exists", http.StatusConflict)
+			return
+		}
+		h.logger.Error("clone test failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(created)
+}
+
+// GET /quiz/tests/{id}/versions  (VerifyToken, test viewer)
+func (h *TestsHandler) ListVersions(w http.ResponseWriter, r *http.Request) {
+	t := h.ownedTest(w, r, models.TestPermissionViewer)
+	if t == nil {
+		return
+	}
+	versions, err := h.store.ListTestVersions(t.ID)
+	if err != nil {
+		h.logger.Error("list test versions failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(versions)
+}
+
 func (h *TestsHandler) ListMine(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("user_id").(int)
 	tests, err := h.store.ListTestsByOwner(userID)
@@ -117,6 +419,9 @@ func (h *TestsHandler) ProgressByCode(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "not found", http.StatusNotFound)
 		return
 	}
+	if !authorizeTest(w, r, h.store, h.logger, t, models.TestPermissionViewer) {
+		return
+	}
 
 	sessions, err := h.store.ListSessionsByTestID(t.ID)
 	if err != nil && err != sql.ErrNoRows {
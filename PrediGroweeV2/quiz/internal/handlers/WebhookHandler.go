@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"quiz/internal/models"
+	"quiz/internal/storage"
+	"quiz/internal/webhooks"
+)
+
+const (
+	maxWebhookURLLength  = 2000
+	webhookSecretBytes   = 32
+	webhookDeliveryLimit = 100
+	maxWebhooksPerOwner  = 20
+)
+
+// WebhookHandler lets teachers and admins register URLs notified of test
+// events; see package webhooks for the request format and signature.
+type WebhookHandler struct {
+	store  storage.Store
+	logger *zap.Logger
+	hooks  *webhooks.Dispatcher
+}
+
+func NewWebhookHandler(store storage.Store, logger *zap.Logger, hooks *webhooks.Dispatcher) *WebhookHandler {
+	return &WebhookHandler{store: store, logger: logger, hooks: hooks}
+}
+
+// POST /quiz/webhooks  (VerifyToken)
+// Body: {"url": "https://...", "events": ["test.started", ...]}
+//
+// Webhooks are for teachers and admins; case report and approval events are
+// for admins only. The response holds the signing secret, which is not shown
+// again.
+func (h *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	role, _ := r.Context().Value("user_role").(models.UserRole)
+	isAdmin := role == models.RoleAdmin
+	if !isAdmin && role != models.RoleTeacher {
+		http.Error(w, "only teachers and admins can create webhooks", http.StatusForbidden)
+		return
+	}
+
+	var req struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	req.URL = strings.TrimSpace(req.URL)
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(req.URL) > maxWebhookURLLength {
+		http.Error(w, "url must be an absolute http or https URL", http.StatusBadRequest)
+		return
+	}
+	if len(req.Events) == 0 {
+		http.Error(w, "at least one event is required", http.StatusBadRequest)
+		return
+	}
+	seen := make(map[string]bool, len(req.Events))
+	events := make([]string, 0, len(req.Events))
+	for _, e := range req.Events {
+		if !models.IsWebhookEvent(e) {
+			http.Error(w, "unknown event: "+e, http.StatusBadRequest)
+			return
+		}
+		if models.IsAdminWebhookEvent(e) && !isAdmin {
+			http.Error(w, "only admins can subscribe to "+e, http.StatusForbidden)
+			return
+		}
+		if !seen[e] {
+			seen[e] = true
+			events = append(events, e)
+		}
+	}
+
+	existing, err := h.store.ListWebhooksByOwner(userID)
+	if err != nil {
+		h.logger.Error("list webhooks failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if len(existing) >= maxWebhooksPerOwner {
+		http.Error(w, "webhook limit reached", http.StatusConflict)
+		return
+	}
+
+	secret := make([]byte, webhookSecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		h.logger.Error("generate webhook secret failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	created, err := h.store.CreateWebhook(models.Webhook{
+		OwnerID: userID,
+		URL:     req.URL,
+		Secret:  hex.EncodeToString(secret),
+		Events:  events,
+		Admin:   isAdmin,
+	})
+	if err != nil {
+		h.logger.Error("create webhook failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(created)
+}
+
+// GET /quiz/webhooks  (VerifyToken) — the caller's webhooks
+func (h *WebhookHandler) ListMine(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	hooks, err := h.store.ListWebhooksByOwner(userID)
+	if err != nil {
+		h.logger.Error("list webhooks failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(hooks)
+}
+
+// DELETE /quiz/webhooks/{id}  (VerifyToken) — owner or admin; pending
+// deliveries are dropped with it
+func (h *WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	hook := h.ownedWebhook(w, r)
+	if hook == nil {
+		return
+	}
+	if err := h.store.DeleteWebhook(hook.ID); err != nil {
+		h.logger.Error("delete webhook failed", zap.Int("webhook_id", hook.ID), zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /quiz/webhooks/{id}/deliveries  (VerifyToken) — the latest deliveries
+// with the outcome of their last attempt
+func (h *WebhookHandler) Deliveries(w http.ResponseWriter, r *http.Request) {
+	hook := h.ownedWebhook(w, r)
+	if hook == nil {
+		return
+	}
+	deliveries, err := h.store.ListWebhookDeliveries(hook.ID, webhookDeliveryLimit)
+	if err != nil {
+		h.logger.Error("list webhook deliveries failed", zap.Int("webhook_id", hook.ID), zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(deliveries)
+}
+
+// POST /quiz/webhooks/{id}/ping  (VerifyToken) — queues a "ping" delivery to
+// check the receiver
+func (h *WebhookHandler) Ping(w http.ResponseWriter, r *http.Request) {
+	hook := h.ownedWebhook(w, r)
+	if hook == nil {
+		return
+	}
+	if err := h.hooks.Ping(*hook); err != nil {
+		h.logger.Error("queue webhook ping failed", zap.Int("webhook_id", hook.ID), zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *WebhookHandler) ownedWebhook(w http.ResponseWriter, r *http.Request) *models.Webhook {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid webhook id", http.StatusBadRequest)
+		return nil
+	}
+	hook, err := h.store.GetWebhook(id)
+	if err != nil {
+		h.logger.Error("get webhook failed", zap.Int("webhook_id", id), zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return nil
+	}
+	userID := r.Context().Value("user_id").(int)
+	role, _ := r.Context().Value("user_role").(models.UserRole)
+	if hook == nil || (hook.OwnerID != userID && role != models.RoleAdmin) {
+		http.Error(w, "webhook not found", http.StatusNotFound)
+		return nil
+	}
+	return hook
+}
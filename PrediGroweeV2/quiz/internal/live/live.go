@@ -0,0 +1,142 @@
+// Package live pushes events to browsers over Server-Sent Events. A Hub fans
+// events out to the clients connected to this quiz service instance, by
+// topic; state that must survive a restart belongs in the database, with
+// events only announcing changes to it.
+package live
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// subscriberBuffer is how many events a slow client may fall behind before
+// further events are dropped for it.
+const subscriberBuffer = 32
+
+// heartbeatInterval keeps idle streams open through proxies.
+const heartbeatInterval = 25 * time.Second
+
+type Event struct {
+	Name string
+	Data any
+}
+
+type Hub struct {
+	mu     sync.Mutex
+	topics map[string]map[chan Event]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{topics: make(map[string]map[chan Event]struct{})}
+}
+
+// Subscribe returns a channel receiving the events published to topic and a
+// function that unsubscribes and closes it.
+func (h *Hub) Subscribe(topic string) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+	h.mu.Lock()
+	subs, ok := h.topics[topic]
+	if !ok {
+		subs = make(map[chan Event]struct{})
+		h.topics[topic] = subs
+	}
+	subs[ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			// a topic's map is only dropped once empty, so ch is still in
+			// the current one
+			subs := h.topics[topic]
+			delete(subs, ch)
+			if len(subs) == 0 {
+				delete(h.topics, topic)
+			}
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish sends e to every subscriber of topic without blocking. Subscribers
+// whose buffer is full miss the event.
+func (h *Hub) Publish(topic string, e Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.topics[topic] {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Subscribers returns the number of clients subscribed to topic.
+func (h *Hub) Subscribers(topic string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.topics[topic])
+}
+
+// Serve streams the initial events and then everything received on ch to w
+// until the client disconnects or ch is closed. It lifts the server's write
+// timeout for this response, since the stream is meant to stay open.
+func Serve(w http.ResponseWriter, r *http.Request, initial []Event, ch <-chan Event) error {
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// nginx buffers proxied responses unless told otherwise
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	for _, e := range initial {
+		if err := write(w, e); err != nil {
+			return err
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return err
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case e, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			if err := write(w, e); err != nil {
+				return err
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return err
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return err
+		}
+	}
+}
+
+func write(w http.ResponseWriter, e Event) error {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Name, data)
+	return err
+}
@@ -0,0 +1,112 @@
+package live
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitFor polls cond until it holds or a second has passed.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// readFrame reads one event from an SSE stream.
+func readFrame(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+	var frame strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading stream: %v", err)
+		}
+		if line == "\n" {
+			return frame.String()
+		}
+		frame.WriteString(line)
+	}
+}
+
+// TestStream follows a participant of a room: the stream opens with the
+// initial events, then carries what is published to the room, and the
+// subscription goes away when the participant disconnects.
+func TestStream(t *testing.T) {
+	h := NewHub()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ch, unsubscribe := h.Subscribe("live:1")
+		defer unsubscribe()
+		if err := Serve(w, r, []Event{{Name: "room", Data: map[string]string{"code": "ABC123"}}}, ch); err != nil {
+			t.Errorf("Serve() error = %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	stream := bufio.NewReader(resp.Body)
+	if got, want := readFrame(t, stream), "event: room\ndata: {\"code\":\"ABC123\"}\n"; got != want {
+		t.Errorf("first event = %q, want %q", got, want)
+	}
+
+	waitFor(t, "the subscription", func() bool { return h.Subscribers("live:1") == 1 })
+	h.Publish("live:1:host", Event{Name: "distribution", Data: []int{3, 1}})
+	h.Publish("live:1", Event{Name: "question", Data: map[string]int{"id": 7}})
+	if got, want := readFrame(t, stream), "event: question\ndata: {\"id\":7}\n"; got != want {
+		t.Errorf("next event = %q, want %q", got, want)
+	}
+
+	resp.Body.Close()
+	waitFor(t, "the unsubscribe", func() bool { return h.Subscribers("live:1") == 0 })
+	if _, ok := h.topics["live:1"]; ok {
+		t.Error("the topic was kept after its last subscriber left")
+	}
+}
+
+func TestServeEndsWithChannel(t *testing.T) {
+	ch := make(chan Event, 1)
+	ch <- Event{Name: "ended", Data: nil}
+	close(ch)
+
+	w := httptest.NewRecorder()
+	if err := Serve(w, httptest.NewRequest(http.MethodGet, "/", nil), nil, ch); err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+	if got, want := w.Body.String(), "event: ended\ndata: null\n\n"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+// TestSlowSubscriber checks that a client that stops reading misses the
+// newest events instead of holding up the publisher.
+func TestSlowSubscriber(t *testing.T) {
+	h := NewHub()
+	ch, unsubscribe := h.Subscribe("live:1")
+	for i := 0; i < subscriberBuffer+10; i++ {
+		h.Publish("live:1", Event{Name: "participants", Data: i})
+	}
+	unsubscribe()
+	unsubscribe()
+
+	var got []any
+	for e := range ch {
+		got = append(got, e.Data)
+	}
+	if len(got) != subscriberBuffer || got[0] != 0 || got[len(got)-1] != subscriberBuffer-1 {
+		t.Errorf("received %v, want the first %d events", got, subscriberBuffer)
+	}
+}
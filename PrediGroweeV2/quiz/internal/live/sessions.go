@@ -0,0 +1,142 @@
+package live
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"quiz/internal/models"
+)
+
+// SessionsTopic carries the events of the admin live view.
+const SessionsTopic = "sessions"
+
+// Events published on SessionsTopic.
+const (
+	EventSessionStarted   = "session_started"
+	EventQuestionAnswered = "question_answered"
+	EventSessionFinished  = "session_finished"
+	EventSessionIdle      = "session_idle"
+)
+
+// idleCheckInterval is how often the feed looks for idle sessions.
+const idleCheckInterval = 30 * time.Second
+
+// SessionFeed publishes quiz session activity for the admin live view. It
+// remembers when each unfinished session was last seen, so it can announce
+// sessions that stop responding without querying the database. A session
+// that becomes active again after going idle shows up with its next answer.
+type SessionFeed struct {
+	hub       *Hub
+	idleAfter time.Duration
+
+	mu     sync.Mutex
+	active map[int]models.SessionEvent
+}
+
+func NewSessionFeed(hub *Hub, idleAfter time.Duration) *SessionFeed {
+	return &SessionFeed{hub: hub, idleAfter: idleAfter, active: make(map[int]models.SessionEvent)}
+}
+
+// Subscribe returns the feed's events; see Hub.Subscribe.
+func (f *SessionFeed) Subscribe() (<-chan Event, func()) {
+	return f.hub.Subscribe(SessionsTopic)
+}
+
+// Seed tracks sessions that were active before the service started, so they
+// still go idle in the feed.
+func (f *SessionFeed) Seed(sessions []models.ActiveSession) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, s := range sessions {
+		f.active[s.ID] = models.SessionEvent{
+			ID:                s.ID,
+			UserID:            s.UserID,
+			Status:            s.Status,
+			Mode:              s.Mode,
+			CurrentQuestionID: s.CurrentQuestionID,
+			TestID:            s.TestID,
+			TestCode:          s.TestCode,
+			LastSeen:          s.LastSeen,
+		}
+	}
+}
+
+func (f *SessionFeed) Started(s models.QuizSession) {
+	f.publish(EventSessionStarted, f.track(s))
+}
+
+// Seen records activity that is not worth an event, like fetching the next
+// question.
+func (f *SessionFeed) Seen(s models.QuizSession) {
+	f.track(s)
+}
+
+func (f *SessionFeed) Answered(s models.QuizSession, questionID int, correct bool) {
+	e := f.track(s)
+	e.QuestionID = questionID
+	e.Correct = &correct
+	f.publish(EventQuestionAnswered, e)
+}
+
+// Finished announces a finished session. Only the id is known for sessions
+// the feed has not seen, which the view can still match.
+func (f *SessionFeed) Finished(sessionID int) {
+	f.mu.Lock()
+	e, ok := f.active[sessionID]
+	delete(f.active, sessionID)
+	f.mu.Unlock()
+	if !ok {
+		e = models.SessionEvent{ID: sessionID}
+	}
+	e.Status = models.QuizStatusFinished
+	e.LastSeen = time.Now().UTC()
+	f.publish(EventSessionFinished, e)
+}
+
+// Run announces sessions idle for longer than idleAfter until ctx is done.
+// An idle session is forgotten, so it is announced once.
+func (f *SessionFeed) Run(ctx context.Context) {
+	t := time.NewTicker(idleCheckInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			var idle []models.SessionEvent
+			f.mu.Lock()
+			for id, e := range f.active {
+				if now.Sub(e.LastSeen) > f.idleAfter {
+					idle = append(idle, e)
+					delete(f.active, id)
+				}
+			}
+			f.mu.Unlock()
+			for _, e := range idle {
+				f.publish(EventSessionIdle, e)
+			}
+		}
+	}
+}
+
+func (f *SessionFeed) track(s models.QuizSession) models.SessionEvent {
+	e := models.SessionEvent{
+		ID:                s.ID,
+		UserID:            s.UserID,
+		Status:            s.Status,
+		Mode:              s.Mode,
+		CurrentQuestionID: s.CurrentQuestionID,
+		TestID:            s.TestID,
+		TestCode:          s.TestCode,
+		LastSeen:          time.Now().UTC(),
+	}
+	f.mu.Lock()
+	f.active[s.ID] = e
+	f.mu.Unlock()
+	return e
+}
+
+func (f *SessionFeed) publish(name string, e models.SessionEvent) {
+	f.hub.Publish(SessionsTopic, Event{Name: name, Data: e})
+}
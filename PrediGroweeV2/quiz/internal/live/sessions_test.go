@@ -0,0 +1,72 @@
+package live
+
+import (
+	"testing"
+	"time"
+
+	"quiz/internal/models"
+)
+
+// TestSessionFeed follows one student through a test as the admin live
+// view sees it.
+func TestSessionFeed(t *testing.T) {
+	f := NewSessionFeed(NewHub(), time.Minute)
+	events, unsubscribe := f.Subscribe()
+	defer unsubscribe()
+
+	testCode := "ABC123"
+	s := models.QuizSession{ID: 1, UserID: 2, Status: models.QuizStatusInProgress, CurrentQuestionID: 5, TestCode: &testCode}
+	f.Started(s)
+	f.Seen(s)
+	s.CurrentQuestionID = 6
+	f.Answered(s, 5, true)
+	f.Finished(s.ID)
+	unsubscribe()
+
+	var got []Event
+	for e := range events {
+		got = append(got, e)
+	}
+	names := []string{EventSessionStarted, EventQuestionAnswered, EventSessionFinished}
+	if len(got) != len(names) {
+		t.Fatalf("got %d events, want %v", len(got), names)
+	}
+	for i, e := range got {
+		if e.Name != names[i] {
+			t.Errorf("event %d is %s, want %s", i, e.Name, names[i])
+		}
+		if se := e.Data.(models.SessionEvent); se.ID != 1 || se.UserID != 2 || se.TestCode == nil || se.LastSeen.IsZero() {
+			t.Errorf("%s = %+v, want the session's details", e.Name, se)
+		}
+	}
+
+	answered := got[1].Data.(models.SessionEvent)
+	if answered.QuestionID != 5 || answered.Correct == nil || !*answered.Correct || answered.CurrentQuestionID != 6 {
+		t.Errorf("%s = %+v", EventQuestionAnswered, answered)
+	}
+	if finished := got[2].Data.(models.SessionEvent); finished.Status != models.QuizStatusFinished {
+		t.Errorf("%s has status %q", EventSessionFinished, finished.Status)
+	}
+}
+
+// TestSessionFeedFinishedUnseen covers a session started before the feed
+// knew about it, e.g. before a restart of the service.
+func TestSessionFeedFinishedUnseen(t *testing.T) {
+	f := NewSessionFeed(NewHub(), time.Minute)
+	events, unsubscribe := f.Subscribe()
+	defer unsubscribe()
+
+	testCode := "ABC123"
+	f.Seed([]models.ActiveSession{{ID: 1, UserID: 2, TestCode: &testCode, LastSeen: time.Now()}})
+	f.Finished(1)
+	f.Finished(9)
+
+	seeded := (<-events).Data.(models.SessionEvent)
+	if seeded.ID != 1 || seeded.UserID != 2 || seeded.TestCode == nil {
+		t.Errorf("seeded session finished as %+v", seeded)
+	}
+	unknown := (<-events).Data.(models.SessionEvent)
+	if unknown.ID != 9 || unknown.Status != models.QuizStatusFinished {
+		t.Errorf("unknown session finished as %+v", unknown)
+	}
+}
@@ -0,0 +1,220 @@
+package lti
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"quiz/internal/models"
+)
+
+const (
+	scopeScore = "https://purl.imsglobal.org/spec/lti-ags/scope/score"
+
+	scoreContentType = "application/vnd.ims.lis.v1.score+json"
+	assertionType    = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+	assertionTTL     = 5 * time.Minute
+	// tokenExpiryMargin renews access tokens before the platform expires
+	// them.
+	tokenExpiryMargin = time.Minute
+	passbackTimeout   = time.Minute
+	maxErrorLength    = 500
+)
+
+type accessToken struct {
+	value     string
+	expiresAt time.Time
+}
+
+// SessionFinished sends the score of a finished test session to the grade
+// column of every link to the test the user launched. It runs in the
+// background and only logs failures: the LMS being down must not fail the
+// student's finish. A nil Tool, when LTI is not configured, does nothing.
+func (t *Tool) SessionFinished(s models.QuizSession) {
+	t.sendScore(s, false)
+}
+
+// SessionClosed is SessionFinished for a session the student left without
+// finishing, closed when they start the next one. Its score is only sent if
+// every question was answered; a partial attempt must not be graded as
+// complete.
+func (t *Tool) SessionClosed(s models.QuizSession) {
+	t.sendScore(s, true)
+}
+
+func (t *Tool) sendScore(s models.QuizSession, onlyComplete bool) {
+	if t == nil || s.TestID == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), passbackTimeout)
+		defer cancel()
+		t.passBack(ctx, s, onlyComplete)
+	}()
+}
+
+func (t *Tool) passBack(ctx context.Context, s models.QuizSession, onlyComplete bool) {
+	log := t.logger.With(zap.Int("session_id", s.ID), zap.Int("user_id", s.UserID))
+	targets, err := t.store.ListLTIGradeTargets(s.UserID, *s.TestID)
+	if err != nil {
+		log.Error("failed to list lti grade targets", zap.Error(err))
+		return
+	}
+	if len(targets) == 0 {
+		return
+	}
+	answers, err := t.statsClient.GetSessionAnswers([]int{s.ID})
+	if err != nil {
+		log.Error("failed to get session answers for lti score", zap.Error(err))
+		return
+	}
+	if onlyComplete && (len(s.GroupOrder) == 0 || len(answers) < len(s.GroupOrder)) {
+		return
+	}
+	correct := 0
+	for _, a := range answers {
+		if a.Correct {
+			correct++
+		}
+	}
+	finishedAt := time.Now()
+	if s.FinishedAt != nil {
+		finishedAt = *s.FinishedAt
+	}
+	for _, target := range targets {
+		if err := t.submitScore(ctx, target, correct, len(s.GroupOrder), finishedAt); err != nil {
+			log.Error("failed to send lti score", zap.String("line_item", target.LineItemURL), zap.Error(err))
+		}
+	}
+}
+
+// submitScore posts a score to the line item's scores endpoint.
+func (t *Tool) submitScore(ctx context.Context, target models.LTIGradeTarget, given int, maximum int, at time.Time) error {
+	scoresURL, err := scoresURL(target.LineItemURL)
+	if err != nil {
+		return err
+	}
+	token, err := t.accessToken(ctx, target.Platform)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(map[string]any{
+		"userId":           target.Subject,
+		"scoreGiven":       given,
+		"scoreMaximum":     maximum,
+		"activityProgress": "Completed",
+		"gradingProgress":  "FullyGraded",
+		"timestamp":        at.UTC().Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, scoresURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", scoreContentType)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.do(req, nil)
+}
+
+// scoresURL appends /scores to the line item's path, keeping its query.
+func scoresURL(lineItem string) (string, error) {
+	u, err := url.Parse(lineItem)
+	if err != nil {
+		return "", fmt.Errorf("invalid line item url: %w", err)
+	}
+	u.Path = strings.TrimRight(u.Path, "/") + "/scores"
+	u.RawPath = ""
+	return u.String(), nil
+}
+
+// accessToken returns a token for the score scope, granted by the platform
+// for a client assertion signed with the tool key, cached until it expires.
+func (t *Tool) accessToken(ctx context.Context, p models.LTIPlatform) (string, error) {
+	t.mu.Lock()
+	cached, ok := t.tokens[p.ID]
+	t.mu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.value, nil
+	}
+
+	assertion, err := t.clientAssertion(p)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type":            {"client_credentials"},
+		"client_assertion_type": {assertionType},
+		"client_assertion":      {assertion},
+		"scope":                 {scopeScore},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.AuthTokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var resp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := t.do(req, &resp); err != nil {
+		return "", fmt.Errorf("failed to get lti access token: %w", err)
+	}
+	if resp.AccessToken == "" {
+		return "", fmt.Errorf("failed to get lti access token: empty token")
+	}
+
+	expiresAt := time.Now().Add(time.Duration(resp.ExpiresIn)*time.Second - tokenExpiryMargin)
+	t.mu.Lock()
+	t.tokens[p.ID] = accessToken{value: resp.AccessToken, expiresAt: expiresAt}
+	t.mu.Unlock()
+	return resp.AccessToken, nil
+}
+
+func (t *Tool) clientAssertion(p models.LTIPlatform) (string, error) {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.RegisteredClaims{
+		Issuer:    p.ClientID,
+		Subject:   p.ClientID,
+		Audience:  jwt.ClaimStrings{p.AuthTokenURL},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(assertionTTL)),
+		ID:        hex.EncodeToString(jti),
+	})
+	token.Header["kid"] = t.keyID
+	return token.SignedString(t.key)
+}
+
+// do sends the request and decodes a JSON response into out, if given.
+// Non-2xx responses are errors carrying the start of the response body.
+func (t *Tool) do(req *http.Request, out any) error {
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorLength))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
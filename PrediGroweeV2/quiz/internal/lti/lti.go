@@ -0,0 +1,373 @@
+// Package lti lets tests be opened from a university LMS as an LTI 1.3 tool.
+//
+// A launch starts with OIDC login initiation: the LMS sends the user to the
+// login endpoint, which redirects them back to the LMS authorization
+// endpoint with a state and nonce. The LMS then posts a signed id_token to
+// the launch endpoint. ValidateLaunch checks the token against the keys the
+// platform publishes at its JWKS URL. Scores go back to the LMS gradebook
+// through Assignment and Grade Services; see ags.go.
+package lti
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"quiz/internal/clients"
+	"quiz/internal/models"
+	"quiz/internal/storage"
+)
+
+const (
+	// LoginStateMaxAge is how long a user has between login initiation and
+	// the launch.
+	LoginStateMaxAge = 10 * time.Minute
+
+	messageTypeResourceLink = "LtiResourceLinkRequest"
+	ltiVersion              = "1.3.0"
+
+	// keysCacheTTL is how long platform keys are trusted before they are
+	// fetched again; an unknown key id refetches sooner.
+	keysCacheTTL     = time.Hour
+	keysRefetchAfter = time.Minute
+	requestTimeout   = 10 * time.Second
+	clockLeeway      = time.Minute
+)
+
+// ErrInvalidLaunch is returned by ValidateLaunch for launches that must be
+// rejected: bad signatures, wrong audience, replayed nonces and the like.
+var ErrInvalidLaunch = errors.New("invalid lti launch")
+
+// Config is read from the environment by the API server. PrivateKeyPEM signs
+// the tool's requests to platforms; its public half is served as the tool's
+// JWKS under KeyID. ToolURL is the public address of the site.
+type Config struct {
+	PrivateKeyPEM string
+	KeyID         string
+	ToolURL       string
+}
+
+type Tool struct {
+	store       storage.Store
+	logger      *zap.Logger
+	statsClient *clients.StatsClient
+	key         *rsa.PrivateKey
+	keyID       string
+	toolURL     string
+	client      *http.Client
+
+	mu     sync.Mutex
+	keys   map[string]platformKeys // by JWKS URL
+	tokens map[int]accessToken     // by platform id
+}
+
+type platformKeys struct {
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+func NewTool(cfg Config, store storage.Store, logger *zap.Logger, statsClient *clients.StatsClient) (*Tool, error) {
+	key, err := parsePrivateKey(cfg.PrivateKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+	return &Tool{
+		store:       store,
+		logger:      logger,
+		statsClient: statsClient,
+		key:         key,
+		keyID:       cfg.KeyID,
+		toolURL:     strings.TrimRight(cfg.ToolURL, "/"),
+		client:      &http.Client{Timeout: requestTimeout},
+		keys:        make(map[string]platformKeys),
+		tokens:      make(map[int]accessToken),
+	}, nil
+}
+
+func parsePrivateKey(data string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("lti private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse lti private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("lti private key is not an RSA key")
+	}
+	return key, nil
+}
+
+// LaunchURL is the redirect URI registered with platforms; the public API
+// prefix is added by the reverse proxy.
+func (t *Tool) LaunchURL() string {
+	return t.toolURL + "/api/quiz/lti/launch"
+}
+
+// TestURL is the page a launched user lands on.
+func (t *Tool) TestURL(testCode string) string {
+	return t.toolURL + "/quiz?" + url.Values{"test": {testCode}, "lti": {"1"}}.Encode()
+}
+
+// AuthRequestURL is where login initiation sends the user: the platform's
+// authorization endpoint, answering with a form post to the launch URL.
+func (t *Tool) AuthRequestURL(p models.LTIPlatform, loginHint, messageHint, state, nonce string) string {
+	q := url.Values{
+		"scope":         {"openid"},
+		"response_type": {"id_token"},
+		"response_mode": {"form_post"},
+		"prompt":        {"none"},
+		"client_id":     {p.ClientID},
+		"redirect_uri":  {t.LaunchURL()},
+		"login_hint":    {loginHint},
+		"state":         {state},
+		"nonce":         {nonce},
+	}
+	if messageHint != "" {
+		q.Set("lti_message_hint", messageHint)
+	}
+	sep := "?"
+	if strings.Contains(p.AuthLoginURL, "?") {
+		sep = "&"
+	}
+	return p.AuthLoginURL + sep + q.Encode()
+}
+
+// JWKS is the tool's public key set, which platforms use to check the
+// client assertions of grade passback.
+func (t *Tool) JWKS() map[string]any {
+	pub := t.key.PublicKey
+	return map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"alg": "RS256",
+		"use": "sig",
+		"kid": t.keyID,
+		"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}}}
+}
+
+// Launch is a validated resource link launch.
+type Launch struct {
+	Subject        string
+	Email          string
+	FirstName      string
+	LastName       string
+	DeploymentID   string
+	ResourceLinkID string
+	// TestCode is the test named by the link: the custom parameter
+	// test_code, or else the test query parameter of the target link URI.
+	// Empty means the link's stored test is used.
+	TestCode string
+	// LineItemURL is the grade column of the link, when the platform offers
+	// score passback for it.
+	LineItemURL *string
+}
+
+type launchClaims struct {
+	jwt.RegisteredClaims
+	AuthorizedParty string         `json:"azp"`
+	Nonce           string         `json:"nonce"`
+	Email           string         `json:"email"`
+	GivenName       string         `json:"given_name"`
+	FamilyName      string         `json:"family_name"`
+	MessageType     string         `json:"https://purl.imsglobal.org/spec/lti/claim/message_type"`
+	Version         string         `json:"https://purl.imsglobal.org/spec/lti/claim/version"`
+	DeploymentID    string         `json:"https://purl.imsglobal.org/spec/lti/claim/deployment_id"`
+	TargetLinkURI   string         `json:"https://purl.imsglobal.org/spec/lti/claim/target_link_uri"`
+	Custom          map[string]any `json:"https://purl.imsglobal.org/spec/lti/claim/custom"`
+	ResourceLink    struct {
+		ID string `json:"id"`
+	} `json:"https://purl.imsglobal.org/spec/lti/claim/resource_link"`
+	AGS *struct {
+		Scope    []string `json:"scope"`
+		LineItem string   `json:"lineitem"`
+	} `json:"https://purl.imsglobal.org/spec/lti-ags/claim/endpoint"`
+}
+
+// ValidateLaunch checks the id_token posted to the launch endpoint: its
+// signature, issuer, audience and expiry, the nonce of the login that
+// started it, and that it is an LTI 1.3 resource link launch of an allowed
+// deployment.
+func (t *Tool) ValidateLaunch(ctx context.Context, p models.LTIPlatform, nonce string, idToken string) (*Launch, error) {
+	var claims launchClaims
+	_, err := jwt.ParseWithClaims(idToken, &claims,
+		func(token *jwt.Token) (any, error) {
+			kid, _ := token.Header["kid"].(string)
+			return t.platformKey(ctx, p.JWKSURL, kid)
+		},
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithIssuer(p.Issuer),
+		jwt.WithAudience(p.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockLeeway),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidLaunch, err)
+	}
+
+	switch {
+	case claims.Nonce == "" || claims.Nonce != nonce:
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidLaunch)
+	case len(claims.Audience) > 1 && claims.AuthorizedParty != p.ClientID:
+		return nil, fmt.Errorf("%w: azp is not the client id", ErrInvalidLaunch)
+	case claims.MessageType != messageTypeResourceLink:
+		return nil, fmt.Errorf("%w: unsupported message type %q", ErrInvalidLaunch, claims.MessageType)
+	case claims.Version != ltiVersion:
+		return nil, fmt.Errorf("%w: unsupported version %q", ErrInvalidLaunch, claims.Version)
+	case claims.Subject == "":
+		return nil, fmt.Errorf("%w: anonymous launch", ErrInvalidLaunch)
+	case claims.ResourceLink.ID == "":
+		return nil, fmt.Errorf("%w: missing resource link", ErrInvalidLaunch)
+	case !deploymentAllowed(p, claims.DeploymentID):
+		return nil, fmt.Errorf("%w: unknown deployment %q", ErrInvalidLaunch, claims.DeploymentID)
+	}
+
+	launch := &Launch{
+		Subject:        claims.Subject,
+		Email:          claims.Email,
+		FirstName:      claims.GivenName,
+		LastName:       claims.FamilyName,
+		DeploymentID:   claims.DeploymentID,
+		ResourceLinkID: claims.ResourceLink.ID,
+	}
+	if code, ok := claims.Custom["test_code"].(string); ok {
+		launch.TestCode = strings.TrimSpace(code)
+	}
+	if launch.TestCode == "" && claims.TargetLinkURI != "" {
+		if u, err := url.Parse(claims.TargetLinkURI); err == nil {
+			launch.TestCode = strings.TrimSpace(u.Query().Get("test"))
+		}
+	}
+	if claims.AGS != nil && claims.AGS.LineItem != "" && hasScope(claims.AGS.Scope, scopeScore) {
+		lineItem := claims.AGS.LineItem
+		launch.LineItemURL = &lineItem
+	}
+	return launch, nil
+}
+
+func deploymentAllowed(p models.LTIPlatform, deploymentID string) bool {
+	if deploymentID == "" {
+		return false
+	}
+	if len(p.DeploymentIDs) == 0 {
+		return true
+	}
+	for _, id := range p.DeploymentIDs {
+		if id == deploymentID {
+			return true
+		}
+	}
+	return false
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// platformKey returns the platform's public key with the given id. Keys are
+// cached; an unknown id refetches the set, at most once a minute, since
+// platforms rotate keys.
+func (t *Tool) platformKey(ctx context.Context, jwksURL string, kid string) (*rsa.PublicKey, error) {
+	t.mu.Lock()
+	cached, ok := t.keys[jwksURL]
+	t.mu.Unlock()
+
+	age := time.Since(cached.fetchedAt)
+	if ok && age < keysCacheTTL {
+		if key := pickKey(cached.keys, kid); key != nil {
+			return key, nil
+		}
+		if age < keysRefetchAfter {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+	}
+
+	keys, err := t.fetchKeys(ctx, jwksURL)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	t.keys[jwksURL] = platformKeys{keys: keys, fetchedAt: time.Now()}
+	t.mu.Unlock()
+
+	if key := pickKey(keys, kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+// pickKey finds the key by id; tokens without one may use a set of one key.
+func pickKey(keys map[string]*rsa.PublicKey, kid string) *rsa.PublicKey {
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key
+		}
+	}
+	return keys[kid]
+}
+
+func (t *Tool) fetchKeys(ctx context.Context, jwksURL string) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch platform keys: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch platform keys: unexpected status code: %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("failed to decode platform keys: %w", err)
+	}
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	return keys, nil
+}
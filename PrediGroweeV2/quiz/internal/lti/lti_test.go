@@ -0,0 +1,249 @@
+package lti
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"quiz/internal/models"
+)
+
+const claim = "https://purl.imsglobal.org/spec/lti/claim/"
+
+// lms is a fake platform: it publishes its key set, signs launches, grants
+// access tokens to the tool and records the scores it receives.
+type lms struct {
+	t        *testing.T
+	key      *rsa.PrivateKey
+	toolKey  *rsa.PublicKey
+	srv      *httptest.Server
+	platform models.LTIPlatform
+
+	tokensIssued int
+	scores       []map[string]any
+}
+
+func newLMS(t *testing.T, toolKey *rsa.PublicKey) *lms {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &lms{t: t, key: key, toolKey: toolKey}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "lms-1",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("POST /token", l.token)
+	mux.HandleFunc("POST /courses/1/lineitems/7/scores", l.score)
+	l.srv = httptest.NewServer(mux)
+	t.Cleanup(l.srv.Close)
+
+	l.platform = models.LTIPlatform{
+		ID:            1,
+		Issuer:        "https://lms.example.edu",
+		ClientID:      "predigrowee",
+		DeploymentIDs: []string{"1:course-1"},
+		AuthTokenURL:  l.srv.URL + "/token",
+		JWKSURL:       l.srv.URL + "/jwks",
+	}
+	return l
+}
+
+// token grants a token for a client assertion signed by the tool.
+func (l *lms) token(w http.ResponseWriter, r *http.Request) {
+	assertion := r.PostFormValue("client_assertion")
+	_, err := jwt.Parse(assertion, func(*jwt.Token) (any, error) { return l.toolKey, nil },
+		jwt.WithIssuer(l.platform.ClientID), jwt.WithAudience(l.platform.AuthTokenURL))
+	if err != nil || r.PostFormValue("scope") != scopeScore {
+		l.t.Errorf("token request with assertion error %v and scope %q", err, r.PostFormValue("scope"))
+		http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+		return
+	}
+	l.tokensIssued++
+	_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "token-1", "expires_in": 3600})
+}
+
+func (l *lms) score(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer token-1" || r.Header.Get("Content-Type") != scoreContentType {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var score map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&score); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if r.URL.Query().Get("type") != "grade" {
+		l.t.Errorf("score posted to %s, want the line item's query kept", r.URL)
+	}
+	l.scores = append(l.scores, score)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// launch returns an id_token for a student opening the test through a
+// course link.
+func (l *lms) launch(nonce string, edit func(jwt.MapClaims)) string {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":                     l.platform.Issuer,
+		"aud":                     l.platform.ClientID,
+		"sub":                     "student-1",
+		"iat":                     now.Unix(),
+		"exp":                     now.Add(5 * time.Minute).Unix(),
+		"nonce":                   nonce,
+		"email":                   "ada@example.edu",
+		"given_name":              "Ada",
+		"family_name":             "Lovelace",
+		claim + "message_type":    messageTypeResourceLink,
+		claim + "version":         ltiVersion,
+		claim + "deployment_id":   "1:course-1",
+		claim + "resource_link":   map[string]any{"id": "link-1"},
+		claim + "target_link_uri": "https://predigrowee.example/api/quiz/lti/launch",
+		claim + "custom":          map[string]any{"test_code": " ABC123 "},
+		"https://purl.imsglobal.org/spec/lti-ags/claim/endpoint": map[string]any{
+			"scope":    []string{scopeScore},
+			"lineitem": l.srv.URL + "/courses/1/lineitems/7?type=grade",
+		},
+	}
+	if edit != nil {
+		edit(claims)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "lms-1"
+	signed, err := token.SignedString(l.key)
+	if err != nil {
+		l.t.Fatal(err)
+	}
+	return signed
+}
+
+func newTestTool(t *testing.T) (*Tool, *lms) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	tool, err := NewTool(Config{PrivateKeyPEM: string(keyPEM), KeyID: "tool-1", ToolURL: "https://predigrowee.example/"}, nil, zap.NewNop(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tool, newLMS(t, &key.PublicKey)
+}
+
+func TestLaunch(t *testing.T) {
+	tool, l := newTestTool(t)
+
+	got, err := tool.ValidateLaunch(context.Background(), l.platform, "nonce-1", l.launch("nonce-1", nil))
+	if err != nil {
+		t.Fatalf("ValidateLaunch() error = %v", err)
+	}
+	if got.Subject != "student-1" || got.Email != "ada@example.edu" || got.FirstName != "Ada" || got.LastName != "Lovelace" ||
+		got.DeploymentID != "1:course-1" || got.ResourceLinkID != "link-1" || got.TestCode != "ABC123" {
+		t.Errorf("ValidateLaunch() = %+v", got)
+	}
+	if want := l.srv.URL + "/courses/1/lineitems/7?type=grade"; got.LineItemURL == nil || *got.LineItemURL != want {
+		t.Errorf("LineItemURL = %v, want %s", got.LineItemURL, want)
+	}
+
+	// a link without the custom parameter names its test in the target URI,
+	// and one without the score scope gets no grade column
+	got, err = tool.ValidateLaunch(context.Background(), l.platform, "nonce-2", l.launch("nonce-2", func(c jwt.MapClaims) {
+		delete(c, claim+"custom")
+		c[claim+"target_link_uri"] = tool.TestURL("XYZ789")
+		c["https://purl.imsglobal.org/spec/lti-ags/claim/endpoint"] = map[string]any{"lineitem": l.srv.URL + "/courses/1/lineitems/7"}
+	}))
+	if err != nil {
+		t.Fatalf("ValidateLaunch() error = %v", err)
+	}
+	if got.TestCode != "XYZ789" || got.LineItemURL != nil {
+		t.Errorf("ValidateLaunch() = %+v, want test XYZ789 and no line item", got)
+	}
+}
+
+func TestLaunchRejected(t *testing.T) {
+	tool, l := newTestTool(t)
+	forged, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rejected := map[string]string{
+		"replayed for another login": l.launch("nonce-0", nil),
+		"other issuer":               l.launch("nonce-1", func(c jwt.MapClaims) { c["iss"] = "https://evil.example" }),
+		"other client":               l.launch("nonce-1", func(c jwt.MapClaims) { c["aud"] = "someone-else" }),
+		"client not the azp":         l.launch("nonce-1", func(c jwt.MapClaims) { c["aud"] = []string{"x", "predigrowee"}; c["azp"] = "x" }),
+		"expired":                    l.launch("nonce-1", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }),
+		"deep linking":               l.launch("nonce-1", func(c jwt.MapClaims) { c[claim+"message_type"] = "LtiDeepLinkingRequest" }),
+		"anonymous":                  l.launch("nonce-1", func(c jwt.MapClaims) { delete(c, "sub") }),
+		"other deployment":           l.launch("nonce-1", func(c jwt.MapClaims) { c[claim+"deployment_id"] = "2:course-9" }),
+		"unsigned": func() string {
+			s, _ := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"iss": l.platform.Issuer}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+			return s
+		}(),
+		"forged": func() string {
+			token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"iss": l.platform.Issuer, "nonce": "nonce-1"})
+			token.Header["kid"] = "lms-1"
+			s, _ := token.SignedString(forged)
+			return s
+		}(),
+	}
+	for name, idToken := range rejected {
+		if _, err := tool.ValidateLaunch(context.Background(), l.platform, "nonce-1", idToken); !errors.Is(err, ErrInvalidLaunch) {
+			t.Errorf("%s: ValidateLaunch() error = %v, want ErrInvalidLaunch", name, err)
+		}
+	}
+}
+
+// TestSubmitScore sends two scores to the grade column of a launch; the
+// access token is requested once and reused.
+func TestSubmitScore(t *testing.T) {
+	tool, l := newTestTool(t)
+	target := models.LTIGradeTarget{
+		Platform:    l.platform,
+		Subject:     "student-1",
+		LineItemURL: l.srv.URL + "/courses/1/lineitems/7?type=grade",
+	}
+	at := time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+	for _, correct := range []int{7, 9} {
+		if err := tool.submitScore(context.Background(), target, correct, 10, at); err != nil {
+			t.Fatalf("submitScore() error = %v", err)
+		}
+	}
+
+	if l.tokensIssued != 1 {
+		t.Errorf("%d access tokens requested, want 1", l.tokensIssued)
+	}
+	if len(l.scores) != 2 {
+		t.Fatalf("LMS received %d scores, want 2", len(l.scores))
+	}
+	score := l.scores[1]
+	if score["userId"] != "student-1" || score["scoreGiven"] != 9.0 || score["scoreMaximum"] != 10.0 ||
+		score["activityProgress"] != "Completed" || score["gradingProgress"] != "FullyGraded" ||
+		score["timestamp"] != "2024-05-06T10:00:00Z" {
+		t.Errorf("LMS received %v", score)
+	}
+
+	target.LineItemURL = l.srv.URL + "/courses/1/lineitems/8"
+	if err := tool.submitScore(context.Background(), target, 1, 10, at); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("submitScore() to an unknown line item error = %v, want the LMS's 404", err)
+	}
+}
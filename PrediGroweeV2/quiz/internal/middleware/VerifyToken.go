@@ -6,9 +6,21 @@ import (
 	"log"
 	"net/http"
 	"quiz/internal/clients"
+	"quiz/internal/models"
 )
 
 func VerifyToken(next http.HandlerFunc, authClient *clients.AuthClient) http.HandlerFunc {
+	return verifyToken(next, authClient, false)
+}
+
+// VerifyTokenAllowGuests is VerifyToken for the routes a guest needs to take
+// their test. The guest's test code is stored under "guest_test_code"; it is
+// empty for regular users.
+func VerifyTokenAllowGuests(next http.HandlerFunc, authClient *clients.AuthClient) http.HandlerFunc {
+	return verifyToken(next, authClient, true)
+}
+
+func verifyToken(next http.HandlerFunc, authClient *clients.AuthClient, allowGuests bool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		accessToken, err := ExtractAccessTokenFromRequest(r)
 		log.Println("Extracted token: ", accessToken)
@@ -23,8 +35,13 @@ func VerifyToken(next http.HandlerFunc, authClient *clients.AuthClient) http.Han
 			log.Println("failed to verify token: ", err)
 			return
 		}
+		if userData.Role == models.RoleGuest && !allowGuests {
+			http.Error(w, "not available to guests", http.StatusForbidden)
+			return
+		}
 		r = r.WithContext(context.WithValue(r.Context(), "user_id", userData.UserID))
 		r = r.WithContext(context.WithValue(r.Context(), "user_role", userData.Role))
+		r = r.WithContext(context.WithValue(r.Context(), "guest_test_code", userData.TestCode))
 		log.Println("completed token verification")
 		next(w, r)
 	}
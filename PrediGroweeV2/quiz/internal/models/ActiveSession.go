@@ -18,3 +18,20 @@ type ActiveSession struct {
 	LastSeen           time.Time  `json:"last_seen"`
 }
 
+
+// SessionEvent is pushed to the admin live view when a session starts,
+// answers, finishes or goes idle. It carries the fields of ActiveSession the
+// view shows, so the UI can update its list in place.
+type SessionEvent struct {
+	ID                int       `json:"id"`
+	UserID            int       `json:"user_id"`
+	Status            string    `json:"status"`
+	Mode              string    `json:"mode"`
+	CurrentQuestionID int       `json:"current_question"`
+	TestID            *int      `json:"test_id,omitempty"`
+	TestCode          *string   `json:"test_code,omitempty"`
+	LastSeen          time.Time `json:"last_seen"`
+	// QuestionID and Correct describe the answer of a question_answered event.
+	QuestionID int   `json:"question_id,omitempty"`
+	Correct    *bool `json:"correct,omitempty"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+const (
+	CertificateKindTest      = "test"
+	CertificateKindMilestone = "milestone"
+)
+
+// Certificate is proof that a user finished a test or reached a milestone.
+// Correct and Total are the test score, or the user's answers at the time
+// of the milestone.
+type Certificate struct {
+	ID            int       `json:"-"`
+	Code          string    `json:"code"`
+	UserID        int       `json:"-"`
+	Kind          string    `json:"kind"`
+	SessionID     *int      `json:"session_id,omitempty"`
+	TestID        *int      `json:"test_id,omitempty"`
+	TestName      *string   `json:"test_name,omitempty"`
+	Milestone     *string   `json:"milestone,omitempty"`
+	RecipientName string    `json:"recipient_name"`
+	Correct       int       `json:"correct"`
+	Total         int       `json:"total"`
+	IssuedAt      time.Time `json:"issued_at"`
+}
@@ -0,0 +1,89 @@
+package models
+
+import "time"
+
+const (
+	AssignmentNotStarted    = "not_started"
+	AssignmentInProgress    = "in_progress"
+	AssignmentCompleted     = "completed"
+	AssignmentCompletedLate = "completed_late"
+	AssignmentOverdue       = "overdue"
+)
+
+// Class is a teacher's group of students. InviteCode is only returned to the
+// owner.
+type Class struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	OwnerID     int       `json:"owner_id"`
+	InviteCode  string    `json:"invite_code,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	Members     int       `json:"members"`
+	Assignments int       `json:"assignments"`
+}
+
+type ClassMember struct {
+	UserID    int       `json:"user_id"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Email     string    `json:"email"`
+	JoinedAt  time.Time `json:"joined_at"`
+}
+
+type ClassAssignment struct {
+	ClassID    int        `json:"class_id"`
+	TestID     int        `json:"test_id"`
+	TestCode   string     `json:"test_code"`
+	TestName   string     `json:"test_name"`
+	DueAt      *time.Time `json:"due_at"`
+	AssignedBy int        `json:"assigned_by"`
+	AssignedAt time.Time  `json:"assigned_at"`
+}
+
+// ClassProgress summarises one member's sessions for one assigned test.
+type ClassProgress struct {
+	UserID          int
+	TestID          int
+	Attempts        int
+	FirstFinishedAt *time.Time
+	InProgress      bool
+}
+
+// ClassRoster lists every member with their status on every assigned test.
+type ClassRoster struct {
+	Class       Class             `json:"class"`
+	Assignments []ClassAssignment `json:"assignments"`
+	Students    []RosterStudent   `json:"students"`
+}
+
+type RosterStudent struct {
+	ClassMember
+	Completed   int                `json:"completed"`
+	Assignments []AssignmentStatus `json:"assignments"`
+}
+
+// AssignmentStatus is one student's standing on one assigned test. Status
+// is one of the Assignment* constants.
+type AssignmentStatus struct {
+	TestID      int        `json:"test_id"`
+	Status      string     `json:"status"`
+	Attempts    int        `json:"attempts"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// JoinedClass is a class as seen by one of its students, with their own
+// status on each assigned test.
+type JoinedClass struct {
+	ID          int                         `json:"id"`
+	Name        string                      `json:"name"`
+	OwnerID     int                         `json:"owner_id"`
+	JoinedAt    time.Time                   `json:"joined_at"`
+	Assignments []ClassAssignmentWithStatus `json:"assignments"`
+}
+
+type ClassAssignmentWithStatus struct {
+	ClassAssignment
+	Status      string     `json:"status"`
+	Attempts    int        `json:"attempts"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
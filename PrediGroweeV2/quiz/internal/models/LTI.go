@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// LTIPlatform is an LMS registered to launch tests over LTI 1.3. An empty
+// DeploymentIDs accepts every deployment of the client.
+type LTIPlatform struct {
+	ID            int       `json:"id"`
+	Issuer        string    `json:"issuer"`
+	ClientID      string    `json:"client_id"`
+	DeploymentIDs []string  `json:"deployment_ids"`
+	AuthLoginURL  string    `json:"auth_login_url"`
+	AuthTokenURL  string    `json:"auth_token_url"`
+	JWKSURL       string    `json:"jwks_url"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// LTIResourceLink maps a link placed in an LMS course to a test. The line
+// item is the grade column scores go to; links without one get no scores.
+type LTIResourceLink struct {
+	PlatformID     int
+	ResourceLinkID string
+	DeploymentID   string
+	TestID         int
+	LineItemURL    *string
+}
+
+// LTIGradeTarget is where a finished session's score is sent: the line item
+// of a link the user launched, and who they are on that platform.
+type LTIGradeTarget struct {
+	Platform    LTIPlatform
+	LineItemURL string
+	Subject     string
+}
+
+// LTILogin is the auth service session of a user provisioned by a launch.
+type LTILogin struct {
+	UserID    int       `json:"user_id"`
+	SessionID string    `json:"session_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// LinkCode lets the user move the LMS identity to an account they
+	// already have; empty once they did.
+	LinkCode string `json:"link_code"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// LiveRoom is a test run synchronously by a teacher: everyone sees the same
+// question until the host advances. CurrentIndex is -1 before the first
+// question.
+type LiveRoom struct {
+	ID                int        `json:"id"`
+	TestID            int        `json:"test_id"`
+	TestVersion       int        `json:"test_version"`
+	Code              string     `json:"code"`
+	HostID            int        `json:"host_id"`
+	QuestionIDs       []int      `json:"-"`
+	QuestionSeed      *int64     `json:"-"`
+	CurrentIndex      int        `json:"current_index"`
+	QuestionCount     int        `json:"question_count"`
+	QuestionStartedAt *time.Time `json:"question_started_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+	EndedAt           *time.Time `json:"ended_at"`
+}
+
+// CurrentQuestionID returns the question being asked, or 0 before the first
+// question and after the last.
+func (r LiveRoom) CurrentQuestionID() int {
+	if r.CurrentIndex < 0 || r.CurrentIndex >= len(r.QuestionIDs) {
+		return 0
+	}
+	return r.QuestionIDs[r.CurrentIndex]
+}
+
+// LiveAnswer is one participant's answer to a question of a live room.
+type LiveAnswer struct {
+	RoomID        int
+	QuestionIndex int
+	SessionID     int
+	Answer        string
+	Correct       bool
+}
+
+// LiveDistribution is the live answer count per option for one question.
+type LiveDistribution struct {
+	QuestionIndex int            `json:"question_index"`
+	Counts        map[string]int `json:"counts"`
+	Correct       int            `json:"correct"`
+	Answered      int            `json:"answered"`
+	Participants  int            `json:"participants"`
+}
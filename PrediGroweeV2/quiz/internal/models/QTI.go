@@ -0,0 +1,28 @@
+package models
+
+// QuestionImport is a question read from a QTI item, placed on a case.
+type QuestionImport struct {
+	Question      string
+	Options       []string
+	Correct       string
+	CaseID        int
+	PredictionAge int
+	Group         int
+}
+
+type QTIImportedItem struct {
+	Identifier string `json:"identifier"`
+	File       string `json:"file,omitempty"`
+	QuestionID int    `json:"question_id"`
+}
+
+type QTISkippedItem struct {
+	Identifier string `json:"identifier"`
+	File       string `json:"file,omitempty"`
+	Reason     string `json:"reason"`
+}
+
+type QTIImportResult struct {
+	Imported []QTIImportedItem `json:"imported"`
+	Skipped  []QTISkippedItem  `json:"skipped"`
+}
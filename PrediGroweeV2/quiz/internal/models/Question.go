@@ -45,4 +45,5 @@ type QuestionAnswer struct {
 	ScreenSize string `json:"screen_size"`
 	TimeSpent  int    `json:"time_spent"`
 	CaseCode   string `json:"case_code"`
+	TimeZone   string `json:"time_zone,omitempty"`
 }
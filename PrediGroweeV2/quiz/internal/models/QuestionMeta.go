@@ -0,0 +1,36 @@
+package models
+
+// Vertical growth pattern of a case, classified by the SN/MP angle at the
+// first examination (reference 32° ± 5°).
+const (
+	CaseCategoryHypodivergent  = "hypodivergent"
+	CaseCategoryNormodivergent = "normodivergent"
+	CaseCategoryHyperdivergent = "hyperdivergent"
+	CaseCategoryUnknown        = "unknown"
+)
+
+// QuestionMeta is a flat description of a question used by other services to
+// break statistics down without loading full cases.
+type QuestionMeta struct {
+	QuestionID    int      `json:"question_id"`
+	CaseID        int      `json:"case_id"`
+	CaseCode      string   `json:"case_code"`
+	CaseGender    string   `json:"case_gender"`
+	PredictionAge int      `json:"prediction_age"`
+	Correct       string   `json:"correct"`
+	SNMP          *float64 `json:"sn_mp,omitempty"`
+	Category      string   `json:"category"`
+}
+
+func CaseCategoryFromSNMP(snmp *float64) string {
+	switch {
+	case snmp == nil:
+		return CaseCategoryUnknown
+	case *snmp < 27:
+		return CaseCategoryHypodivergent
+	case *snmp > 37:
+		return CaseCategoryHyperdivergent
+	default:
+		return CaseCategoryNormodivergent
+	}
+}
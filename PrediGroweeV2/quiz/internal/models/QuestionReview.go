@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+const (
+	ReviewStatusOpen      = "open"
+	ReviewStatusResolved  = "resolved"
+	ReviewStatusDismissed = "dismissed"
+)
+
+type QuestionReview struct {
+	ID         int        `json:"id"`
+	QuestionID int        `json:"question_id"`
+	Status     string     `json:"status"`
+	Reasons    []string   `json:"reasons"`
+	Note       *string    `json:"note,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	ClosedAt   *time.Time `json:"closed_at,omitempty"`
+	ClosedBy   *int       `json:"closed_by,omitempty"`
+}
+
+// QuestionReviewCandidate is a question flagged by the quality report.
+type QuestionReviewCandidate struct {
+	QuestionID int      `json:"question_id"`
+	Reasons    []string `json:"reasons"`
+}
+
+type QuestionReviewUpdate struct {
+	Status  string  `json:"status"`
+	Note    *string `json:"note"`
+	AdminID *int    `json:"admin_id"`
+}
+
+func IsValidReviewStatus(status string) bool {
+	switch status {
+	case ReviewStatusOpen, ReviewStatusResolved, ReviewStatusDismissed:
+		return true
+	}
+	return false
+}
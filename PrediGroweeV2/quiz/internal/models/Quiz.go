@@ -12,7 +12,7 @@ type QuizStatus = string
 const (
 	QuizModeEducational QuizMode = "educational"
 	QuizModeClassic     QuizMode = "classic"
-	QuizModeLimitedTime QuizMode = "limited_time"
+	QuizModeLimitedTime QuizMode = "time_limited"
 )
 const (
 	QuizStatusNotStarted QuizStatus = "not_started"
@@ -21,10 +21,11 @@ const (
 )
 
 type StartQuizPayload struct {
-	Mode         QuizMode `json:"mode" ,validate:"required,oneof=educational classic limited_time"`
+	Mode         QuizMode `json:"mode" ,validate:"required,oneof=educational classic time_limited"`
 	ScreenWidth  int      `json:"screen_width" ,validate:"required"`
 	ScreenHeight int      `json:"screen_height" ,validate:"required"`
 	TestCode    string `json:"test_code,omitempty"`
+	TestPassword string `json:"test_password,omitempty"`
 }
 
 func (p *StartQuizPayload) Validate() error {
@@ -6,12 +6,18 @@ import (
 	"time"
 )
 
+// MaxScreenDimension matches the bound in the stats service's models, which
+// explains it; sessions keep the viewport sizes stats accepts.
+const MaxScreenDimension = 100000
+
 type QuizSession struct {
 	ID                    int        `json:"session_id"`
 	UserID                int        `json:"user_id"`
 	Mode                  QuizMode   `json:"quiz_mode"`
 	Status                QuizStatus `json:"-"`
 	ScreenSize            string     `json:"-"`
+	ScreenWidth           int        `json:"-"`
+	ScreenHeight          int        `json:"-"`
 	CurrentQuestionID     int        `json:"-"`
 	CurrentGroup          int        `json:"-"`
 	GroupOrder            []int      `json:"-"`
@@ -21,6 +27,16 @@ type QuizSession struct {
 	QuestionRequestedTime time.Time  `json:"-"`
 	TestID   *int    `json:"test_id,omitempty"`
         TestCode *string `json:"test_code,omitempty"`
+	// QuestionSeed is set for sessions of pooled tests and reproduces the
+	// questions drawn and their order.
+	QuestionSeed *int64 `json:"question_seed,omitempty"`
+	TestVersion  *int   `json:"test_version,omitempty"`
+	// LiveRoomID is set for sessions of a live room, which are paced by the
+	// host instead of the student.
+	LiveRoomID *int `json:"live_room_id,omitempty"`
+	// Guest marks sessions started by a guest; it is sent to the stats
+	// service, which tags the session, and not stored here.
+	Guest bool `json:"guest,omitempty"`
 }
 
 func (qs *QuizSession) ToJSON(writer io.Writer) error {
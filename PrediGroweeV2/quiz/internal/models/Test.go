@@ -8,6 +8,40 @@ type Test struct {
   Name      string    `json:"name"`
   CreatedBy int       `json:"created_by"`
   CreatedAt time.Time `json:"created_at"`
+  // Version is the current version; every edit creates a new one.
+  Version   int       `json:"version"`
+  // Permission is the caller's access in test listings.
+  Permission string   `json:"permission,omitempty"`
+  TestSettings
 }
 
+// TestSettings are the optional scheduling and access settings of a test;
+// nil means unrestricted. Mode, when set, overrides the mode the student
+// picks. TimeLimit replaces the global time_limit setting for sessions of
+// this test and uses the same unit. AllowGuests lets students without an
+// account join the test with a nickname.
+type TestSettings struct {
+  OpensAt      *time.Time `json:"opens_at"`
+  ClosesAt     *time.Time `json:"closes_at"`
+  MaxAttempts  *int       `json:"max_attempts"`
+  Mode         *QuizMode  `json:"mode"`
+  TimeLimit    *int       `json:"time_limit"`
+  AllowGuests  bool       `json:"allow_guests"`
+  HasPassword  bool       `json:"has_password"`
+  PasswordHash string     `json:"-"`
+}
 
+// TestVersion is an immutable snapshot of a test, written when the test is
+// created and on every edit. Sessions record the version they took, so
+// results are always read against the questions the student was given.
+type TestVersion struct {
+  TestID      int        `json:"test_id"`
+  Version     int        `json:"version"`
+  Name        string     `json:"name"`
+  QuestionIDs []int      `json:"question_ids"`
+  Pools       []TestPool `json:"pools"`
+  CreatedBy   int        `json:"created_by"`
+  CreatedAt   time.Time  `json:"created_at"`
+  Sessions    int        `json:"sessions"`
+  TestSettings
+}
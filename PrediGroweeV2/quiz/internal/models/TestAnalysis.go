@@ -0,0 +1,91 @@
+package models
+
+import "time"
+
+// SessionAnswer is a student's first answer to a question, as recorded by
+// the stats service.
+type SessionAnswer struct {
+	SessionID  int       `json:"session_id"`
+	QuestionID int       `json:"question_id"`
+	Answer     string    `json:"answer"`
+	Correct    bool      `json:"correct"`
+	TimeSpent  int       `json:"time_spent"`
+	AnswerTime time.Time `json:"answer_time"`
+}
+
+// TestAnalysis is the teacher's view of how one version of a test performed.
+// Item statistics and the score distribution only use finished attempts;
+// every attempt is listed in Students.
+type TestAnalysis struct {
+	Test      Test                `json:"test"`
+	Version   int                 `json:"version"`
+	Finished  int                 `json:"finished"`
+	Questions []TestItemAnalysis  `json:"questions"`
+	Scores    ScoreDistribution   `json:"scores"`
+	Students  []TestStudentResult `json:"students"`
+}
+
+// TestItemAnalysis describes one question. PValue is the share of correct
+// answers; DiscriminationIndex is the difference in that share between the
+// top and bottom 27% of students by total score. Both are nil when there are
+// too few answers.
+type TestItemAnalysis struct {
+	QuestionID          int               `json:"question_id"`
+	Position            int               `json:"position"`
+	CaseCode            string            `json:"case_code"`
+	CorrectOption       string            `json:"correct_option"`
+	Answered            int               `json:"answered"`
+	Correct             int               `json:"correct"`
+	Omitted             int               `json:"omitted"`
+	PValue              *float64          `json:"p_value"`
+	DiscriminationIndex *float64          `json:"discrimination_index"`
+	Options             []DistractorStats `json:"options"`
+}
+
+// DistractorStats counts how often an option was chosen overall and within
+// the upper and lower score groups. A working distractor attracts more of the
+// lower group than of the upper group.
+type DistractorStats struct {
+	Option  string  `json:"option"`
+	Correct bool    `json:"correct"`
+	Count   int     `json:"count"`
+	Share   float64 `json:"share"`
+	Upper   int     `json:"upper"`
+	Lower   int     `json:"lower"`
+}
+
+// ScoreDistribution has one count per possible score, from 0 to MaxScore.
+type ScoreDistribution struct {
+	MaxScore int     `json:"max_score"`
+	Counts   []int   `json:"counts"`
+	Mean     float64 `json:"mean"`
+	Median   float64 `json:"median"`
+	SD       float64 `json:"sd"`
+	Min      int     `json:"min"`
+	Max      int     `json:"max"`
+}
+
+// TestStudentResult is one attempt. QuestionSeed is set when the questions
+// were drawn from pools.
+type TestStudentResult struct {
+	SessionID    int                 `json:"session_id"`
+	UserID       int                 `json:"user_id"`
+	Status       string              `json:"status"`
+	CreatedAt    *time.Time          `json:"created_at"`
+	FinishedAt   *time.Time          `json:"finished_at,omitempty"`
+	QuestionSeed *int64              `json:"question_seed,omitempty"`
+	Score        int                 `json:"score"`
+	Answered     int                 `json:"answered"`
+	Answers      []TestStudentAnswer `json:"answers"`
+}
+
+// TestStudentAnswer is nil-valued for questions the student did not answer.
+// NotDrawn marks questions of a pooled test that the session never drew;
+// they are not counted as omitted.
+type TestStudentAnswer struct {
+	QuestionID int     `json:"question_id"`
+	Answer     *string `json:"answer"`
+	Correct    *bool   `json:"correct"`
+	TimeSpent  int     `json:"time_spent"`
+	NotDrawn   bool    `json:"not_drawn,omitempty"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Permissions on a test, from weakest to strongest. The owner is the test's
+// creator (or whoever it was transferred to); admins act as owners.
+const (
+	TestPermissionViewer = "viewer"
+	TestPermissionEditor = "editor"
+	TestPermissionOwner  = "owner"
+)
+
+var testPermissionRank = map[string]int{
+	TestPermissionViewer: 1,
+	TestPermissionEditor: 2,
+	TestPermissionOwner:  3,
+}
+
+// HasTestPermission reports whether permission p includes min. The empty
+// permission includes nothing.
+func HasTestPermission(p, min string) bool {
+	return p != "" && testPermissionRank[p] >= testPermissionRank[min]
+}
+
+// IsValidCoOwnerRole reports whether role can be granted to a co-owner.
+func IsValidCoOwnerRole(role string) bool {
+	return role == TestPermissionViewer || role == TestPermissionEditor
+}
+
+// TestOwner is a user with access to a test. Name and email come from the
+// auth service and may be empty if it is unavailable.
+type TestOwner struct {
+	UserID    int        `json:"user_id"`
+	Role      string     `json:"role"`
+	FirstName string     `json:"first_name"`
+	LastName  string     `json:"last_name"`
+	Email     string     `json:"email"`
+	AddedBy   *int       `json:"added_by,omitempty"`
+	AddedAt   *time.Time `json:"added_at,omitempty"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// TestPool is a set of questions from which each session of a test draws
+// Draw at random. With Stratify set, the draw keeps the pool's mix of
+// correct options, so e.g. a pool that is half "A" yields about half "A".
+type TestPool struct {
+	ID          int   `json:"id"`
+	Position    int   `json:"position"`
+	Draw        int   `json:"draw"`
+	Stratify    bool  `json:"stratify"`
+	QuestionIDs []int `json:"question_ids"`
+}
@@ -3,18 +3,47 @@ package models
 import (
 	"encoding/json"
 	"io"
+	"time"
 )
 
 type UserRole string
 
 const (
-	RoleAdmin UserRole = "admin"
-	RoleUser  UserRole = "user"
+	RoleAdmin   UserRole = "admin"
+	RoleUser    UserRole = "user"
+	RoleTeacher UserRole = "teacher"
+	// RoleGuest is a user without an account who joined a single test with a
+	// nickname; UserData.TestCode names that test.
+	RoleGuest UserRole = "guest"
 )
 
 type UserData struct {
-	UserID int      `json:"user_id"`
-	Role   UserRole `json:"role"`
+	UserID   int      `json:"user_id"`
+	Role     UserRole `json:"role"`
+	TestCode string   `json:"test_code,omitempty"`
+}
+
+// GuestAccess is issued by the auth service when a guest joins a test. The
+// claim code is shown to the guest once and later moves their sessions into
+// a full account.
+type GuestAccess struct {
+	UserID      int       `json:"user_id"`
+	Nickname    string    `json:"nickname"`
+	TestCode    string    `json:"test_code"`
+	AccessToken string    `json:"access_token"`
+	ClaimCode   string    `json:"claim_code"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// UserProfile is the part of an auth service user that the quiz service
+// shows to teachers, e.g. in result exports.
+type UserProfile struct {
+	ID        int    `json:"id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Email     string `json:"email"`
+	// GuestTestCode is set for guest accounts.
+	GuestTestCode *string `json:"guest_test_code,omitempty"`
 }
 
 func (u *UserData) FromJSON(ioReader io.Reader) error {
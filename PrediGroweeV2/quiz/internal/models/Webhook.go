@@ -0,0 +1,74 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook events.
+const (
+	WebhookEventTestStarted     = "test.started"
+	WebhookEventTestFinished    = "test.finished"
+	WebhookEventCaseReported    = "case_report.created"
+	WebhookEventApprovalPending = "user.approval_pending"
+	// WebhookEventPing is only sent on request, to check a receiver.
+	WebhookEventPing = "ping"
+)
+
+// webhookEvents maps the events a webhook can subscribe to onto whether
+// only admins may subscribe to them.
+var webhookEvents = map[string]bool{
+	WebhookEventTestStarted:     false,
+	WebhookEventTestFinished:    false,
+	WebhookEventCaseReported:    true,
+	WebhookEventApprovalPending: true,
+}
+
+// IsWebhookEvent reports whether a webhook can subscribe to event.
+func IsWebhookEvent(event string) bool {
+	_, ok := webhookEvents[event]
+	return ok
+}
+
+// IsAdminWebhookEvent reports whether only admins may subscribe to event.
+func IsAdminWebhookEvent(event string) bool {
+	return webhookEvents[event]
+}
+
+// Webhook is a URL notified of events. Admin webhooks receive the events of
+// every test; others only those of tests their owner can view. The secret
+// signs deliveries and is only returned when the webhook is created.
+type Webhook struct {
+	ID        int       `json:"id"`
+	OwnerID   int       `json:"owner_id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	Events    []string  `json:"events"`
+	Admin     bool      `json:"admin"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is one event sent to one webhook, with the outcome of its
+// last attempt. URL and Secret are filled in for deliveries being sent.
+type WebhookDelivery struct {
+	ID            int64           `json:"id"`
+	WebhookID     int             `json:"webhook_id"`
+	Event         string          `json:"event"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	LastStatus    *int            `json:"last_status"`
+	LastError     *string         `json:"last_error"`
+	CreatedAt     time.Time       `json:"created_at"`
+	DeliveredAt   *time.Time      `json:"delivered_at"`
+	URL           string          `json:"-"`
+	Secret        string          `json:"-"`
+}
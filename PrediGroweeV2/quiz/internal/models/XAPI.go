@@ -0,0 +1,10 @@
+package models
+
+import "encoding/json"
+
+// XAPIStatement is a statement waiting in the outbox for the LRS.
+type XAPIStatement struct {
+	ID        string
+	Statement json.RawMessage
+	Attempts  int
+}
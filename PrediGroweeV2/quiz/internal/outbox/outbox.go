@@ -0,0 +1,107 @@
+// Package outbox runs the background workers that send what the service
+// queues in its database, like webhook deliveries and xAPI statements.
+//
+// Items are claimed in batches: claiming pushes their next attempt back by a
+// lease, so another instance skips them while they are being sent, and an
+// item whose worker dies is picked up again once the lease runs out. A
+// worker looks for due items every poll interval and right away when it is
+// notified of new ones. Failed items are retried with exponential backoff
+// until the policy's attempts run out.
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Policy says how often a worker looks for due items, how many it claims at
+// a time and how failed ones are retried.
+type Policy struct {
+	MaxAttempts  int
+	FirstBackoff time.Duration
+	MaxBackoff   time.Duration
+	// PollInterval is how often the worker looks for due retries; new items
+	// wake it up right away.
+	PollInterval time.Duration
+	BatchSize    int
+	// ClaimLease must outlast sending a batch, or a second instance could
+	// send it again.
+	ClaimLease time.Duration
+}
+
+// Backoff returns the wait before the attempt after the given one:
+// FirstBackoff, doubling up to MaxBackoff.
+func (p Policy) Backoff(attempts int) time.Duration {
+	b := p.FirstBackoff
+	for i := 1; i < attempts && b < p.MaxBackoff; i++ {
+		b *= 2
+	}
+	if b > p.MaxBackoff {
+		b = p.MaxBackoff
+	}
+	return b
+}
+
+// Exhausted reports whether an item that failed attempts times is given up.
+func (p Policy) Exhausted(attempts int) bool {
+	return attempts >= p.MaxAttempts
+}
+
+// SendFunc claims up to limit due items for lease, sends them and records
+// the outcome of each. It returns how many items it claimed; an error means
+// none could be claimed.
+type SendFunc func(ctx context.Context, limit int, lease time.Duration) (int, error)
+
+// Worker drains one queue with a SendFunc.
+type Worker struct {
+	name   string
+	policy Policy
+	send   SendFunc
+	logger *zap.Logger
+	wake   chan struct{}
+}
+
+// NewWorker returns a worker for the queue called name, which is used in
+// log messages.
+func NewWorker(name string, policy Policy, send SendFunc, logger *zap.Logger) *Worker {
+	return &Worker{name: name, policy: policy, send: send, logger: logger, wake: make(chan struct{}, 1)}
+}
+
+// Notify wakes the worker after new items were queued. It never blocks.
+func (w *Worker) Notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run sends due items until ctx is done.
+func (w *Worker) Run(ctx context.Context) {
+	t := time.NewTicker(w.policy.PollInterval)
+	defer t.Stop()
+	for {
+		w.sendDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		case <-w.wake:
+		}
+	}
+}
+
+// sendDue sends batches until fewer than a full batch are due.
+func (w *Worker) sendDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		n, err := w.send(ctx, w.policy.BatchSize, w.policy.ClaimLease)
+		if err != nil {
+			w.logger.Error("failed to claim "+w.name, zap.Error(err))
+			return
+		}
+		if n < w.policy.BatchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,261 @@
+// Package pdf writes single-page PDF documents of text, lines and
+// rectangles in the standard Helvetica fonts, which viewers provide, so no
+// font is embedded. It covers what the certificates need.
+//
+// Text is encoded in WinAnsi, with up to 32 other characters per document,
+// such as Polish letters or typographic quotes, mapped by name into codes
+// 128-159. Characters beyond that print as "?".
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// A4 landscape, in points.
+const (
+	A4LandscapeWidth  = 841.89
+	A4LandscapeHeight = 595.28
+)
+
+type Font int
+
+const (
+	Regular Font = iota
+	Bold
+	Oblique
+)
+
+var fontNames = [...]string{"Helvetica", "Helvetica-Bold", "Helvetica-Oblique"}
+
+const (
+	firstExtraCode = 128
+	maxExtraCodes  = 32
+)
+
+// Document is a page being drawn; coordinates are in points from the
+// bottom left corner.
+type Document struct {
+	width   float64
+	height  float64
+	content bytes.Buffer
+	// extra maps characters outside WinAnsi's direct range to their codes.
+	extra      map[rune]byte
+	extraNames []string
+}
+
+func New(width float64, height float64) *Document {
+	return &Document{width: width, height: height, extra: make(map[rune]byte)}
+}
+
+func (d *Document) Width() float64 {
+	return d.width
+}
+
+// SetColor sets the color of text, lines and fills; components are 0-1.
+func (d *Document) SetColor(r, g, b float64) {
+	fmt.Fprintf(&d.content, "%s %s %s rg %s %s %s RG\n", num(r), num(g), num(b), num(r), num(g), num(b))
+}
+
+// Text draws s with its baseline starting at x, y.
+func (d *Document) Text(x, y float64, f Font, size float64, s string) {
+	fmt.Fprintf(&d.content, "BT /F%d %s Tf %s %s Td (%s) Tj ET\n", f+1, num(size), num(x), num(y), d.encode(s))
+}
+
+// CenteredText draws s centered on the page.
+func (d *Document) CenteredText(y float64, f Font, size float64, s string) {
+	d.Text((d.width-TextWidth(f, size, s))/2, y, f, size, s)
+}
+
+// Line draws a straight line.
+func (d *Document) Line(x1, y1, x2, y2, lineWidth float64) {
+	fmt.Fprintf(&d.content, "%s w %s %s m %s %s l S\n", num(lineWidth), num(x1), num(y1), num(x2), num(y2))
+}
+
+// Rect strokes a rectangle with its bottom left corner at x, y.
+func (d *Document) Rect(x, y, w, h, lineWidth float64) {
+	fmt.Fprintf(&d.content, "%s w %s %s %s %s re S\n", num(lineWidth), num(x), num(y), num(w), num(h))
+}
+
+// Write writes the document; title and creation time go to its metadata.
+func (d *Document) Write(w io.Writer, title string, created time.Time) error {
+	var b bytes.Buffer
+	offsets := make([]int, 0, 10)
+	object := func(body string) {
+		offsets = append(offsets, b.Len())
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object("<< /Type /Pages /Kids [3 0 R] /Count 1 >>")
+	object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Contents 4 0 R "+
+		"/Resources << /Font << /F1 6 0 R /F2 7 0 R /F3 8 0 R >> >> >>", num(d.width), num(d.height)))
+	object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", d.content.Len(), d.content.String()))
+	differences := ""
+	if len(d.extraNames) > 0 {
+		differences = fmt.Sprintf(" /Differences [%d /%s]", firstExtraCode, strings.Join(d.extraNames, " /"))
+	}
+	object("<< /Type /Encoding /BaseEncoding /WinAnsiEncoding" + differences + " >>")
+	for _, name := range fontNames {
+		object("<< /Type /Font /Subtype /Type1 /BaseFont /" + name + " /Encoding 5 0 R >>")
+	}
+	object(fmt.Sprintf("<< /Title (%s) /Producer (PrediGrowee) /CreationDate (D:%s) >>",
+		escapeString(latin1(title)), created.UTC().Format("20060102150405Z")))
+
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, len(offsets), xref)
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// encode turns s into the body of a PDF string in the document's encoding.
+func (d *Document) encode(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		var c byte
+		switch {
+		case r >= 32 && r <= 126, r >= 160 && r <= 255:
+			c = byte(r)
+		default:
+			c = d.extraCode(r)
+		}
+		switch {
+		case c == '(' || c == ')' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c > 126:
+			fmt.Fprintf(&b, "\\%03o", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func (d *Document) extraCode(r rune) byte {
+	if c, ok := d.extra[r]; ok {
+		return c
+	}
+	name, ok := glyphNames[r]
+	if !ok || len(d.extraNames) == maxExtraCodes {
+		return '?'
+	}
+	c := byte(firstExtraCode + len(d.extraNames))
+	d.extra[r] = c
+	d.extraNames = append(d.extraNames, name)
+	return c
+}
+
+// TextWidth is the width of s in points. Characters outside ASCII are
+// measured as a typical letter of their case, which is close enough for
+// centering.
+func TextWidth(f Font, size float64, s string) float64 {
+	widths := helveticaWidths
+	if f == Bold {
+		widths = helveticaBoldWidths
+	}
+	total := 0
+	for _, r := range s {
+		switch {
+		case r >= 32 && r <= 126:
+			total += widths[r-32]
+		case unicode.IsUpper(r):
+			total += widths['H'-32]
+		case unicode.IsLetter(r):
+			total += widths['n'-32]
+		default:
+			total += widths['0'-32]
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// FitSize returns the largest size from size down to minSize at which s is
+// at most maxWidth wide, or minSize.
+func FitSize(f Font, size float64, minSize float64, maxWidth float64, s string) float64 {
+	for size > minSize && TextWidth(f, size, s) > maxWidth {
+		size--
+	}
+	if size < minSize {
+		return minSize
+	}
+	return size
+}
+
+// latin1 replaces the characters a PDFDocEncoding string cannot hold
+// directly, for metadata.
+func latin1(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r > 255 {
+			r = '?'
+		}
+		b.WriteByte(byte(r))
+	}
+	return b.String()
+}
+
+func escapeString(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`).Replace(s)
+}
+
+// num formats a coordinate or size to a thousandth of a point.
+func num(f float64) string {
+	return strconv.FormatFloat(math.Round(f*1000)/1000, 'f', -1, 64)
+}
+
+// Advance widths of ASCII 32-126 in 1/1000 em, from the Adobe font metrics.
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
+
+// glyphNames are the characters that can be mapped into codes 128-159:
+// WinAnsi's own characters there and the Central European letters of the
+// standard fonts.
+var glyphNames = map[rune]string{
+	'€': "Euro", '‚': "quotesinglbase", 'ƒ': "florin", '„': "quotedblbase", '…': "ellipsis",
+	'†': "dagger", '‡': "daggerdbl", 'ˆ': "circumflex", '‰': "perthousand", 'Š': "Scaron",
+	'‹': "guilsinglleft", 'Œ': "OE", 'Ž': "Zcaron", '‘': "quoteleft", '’': "quoteright",
+	'“': "quotedblleft", '”': "quotedblright", '•': "bullet", '–': "endash", '—': "emdash",
+	'˜': "tilde", '™': "trademark", 'š': "scaron", '›': "guilsinglright", 'œ': "oe",
+	'ž': "zcaron", 'Ÿ': "Ydieresis",
+
+	'Ā': "Amacron", 'ā': "amacron", 'Ă': "Abreve", 'ă': "abreve", 'Ą': "Aogonek", 'ą': "aogonek",
+	'Ć': "Cacute", 'ć': "cacute", 'Č': "Ccaron", 'č': "ccaron", 'Ď': "Dcaron", 'ď': "dcaron",
+	'Đ': "Dcroat", 'đ': "dcroat", 'Ē': "Emacron", 'ē': "emacron", 'Ė': "Edotaccent", 'ė': "edotaccent",
+	'Ę': "Eogonek", 'ę': "eogonek", 'Ě': "Ecaron", 'ě': "ecaron", 'Ğ': "Gbreve", 'ğ': "gbreve",
+	'Ģ': "Gcommaaccent", 'ģ': "gcommaaccent", 'Ī': "Imacron", 'ī': "imacron", 'Į': "Iogonek", 'į': "iogonek",
+	'İ': "Idotaccent", 'ı': "dotlessi", 'Ķ': "Kcommaaccent", 'ķ': "kcommaaccent", 'Ĺ': "Lacute", 'ĺ': "lacute",
+	'Ļ': "Lcommaaccent", 'ļ': "lcommaaccent", 'Ľ': "Lcaron", 'ľ': "lcaron", 'Ł': "Lslash", 'ł': "lslash",
+	'Ń': "Nacute", 'ń': "nacute", 'Ņ': "Ncommaaccent", 'ņ': "ncommaaccent", 'Ň': "Ncaron", 'ň': "ncaron",
+	'Ō': "Omacron", 'ō': "omacron", 'Ő': "Ohungarumlaut", 'ő': "ohungarumlaut", 'Ŕ': "Racute", 'ŕ': "racute",
+	'Ř': "Rcaron", 'ř': "rcaron", 'Ś': "Sacute", 'ś': "sacute", 'Ş': "Scedilla", 'ş': "scedilla",
+	'Ș': "Scommaaccent", 'ș': "scommaaccent", 'Ţ': "Tcommaaccent", 'ţ': "tcommaaccent", 'Ť': "Tcaron", 'ť': "tcaron",
+	'Ū': "Umacron", 'ū': "umacron", 'Ů': "Uring", 'ů': "uring", 'Ű': "Uhungarumlaut", 'ű': "uhungarumlaut",
+	'Ų': "Uogonek", 'ų': "uogonek", 'Ź': "Zacute", 'ź': "zacute", 'Ż': "Zdotaccent", 'ż': "zdotaccent",
+}
@@ -0,0 +1,107 @@
+// Package pools draws the questions of a pooled test for one session.
+//
+// A draw is a pure function of the pools, the questions' correct options and
+// a seed, so a stored seed reproduces exactly which questions a student saw
+// and in what order. It uses math/rand's seeded source, whose sequence is
+// guaranteed not to change between Go releases.
+package pools
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"quiz/internal/models"
+)
+
+// NewSeed returns a random seed for a new session.
+func NewSeed() int64 {
+	return rand.Int63()
+}
+
+// Draw picks pool.Draw questions from each pool. Pools keep their order;
+// questions are shuffled within each pool.
+func Draw(pools []models.TestPool, correct map[int]string, seed int64) []int {
+	rng := rand.New(rand.NewSource(seed))
+	out := make([]int, 0)
+	for _, p := range pools {
+		var picked []int
+		if p.Stratify {
+			picked = drawStratified(rng, p, correct)
+		} else {
+			picked = take(rng, p.QuestionIDs, p.Draw)
+		}
+		rng.Shuffle(len(picked), func(i, j int) { picked[i], picked[j] = picked[j], picked[i] })
+		out = append(out, picked...)
+	}
+	return out
+}
+
+// Validate checks that every pool can be drawn from and that no question
+// appears twice across pools.
+func Validate(pools []models.TestPool) error {
+	seen := make(map[int]bool)
+	for i, p := range pools {
+		if len(p.QuestionIDs) == 0 {
+			return fmt.Errorf("pool %d has no questions", i+1)
+		}
+		if p.Draw <= 0 || p.Draw > len(p.QuestionIDs) {
+			return fmt.Errorf("pool %d must draw between 1 and %d questions", i+1, len(p.QuestionIDs))
+		}
+		for _, id := range p.QuestionIDs {
+			if seen[id] {
+				return fmt.Errorf("question %d appears more than once", id)
+			}
+			seen[id] = true
+		}
+	}
+	return nil
+}
+
+// drawStratified splits the pool by correct option and gives each stratum a
+// share of the draw proportional to its size, using the largest remainder
+// method. Questions without a correct option form their own stratum.
+func drawStratified(rng *rand.Rand, p models.TestPool, correct map[int]string) []int {
+	strata := make(map[string][]int)
+	for _, id := range p.QuestionIDs {
+		strata[correct[id]] = append(strata[correct[id]], id)
+	}
+	keys := make([]string, 0, len(strata))
+	for k := range strata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	quota := make(map[string]int, len(keys))
+	remainder := make(map[string]float64, len(keys))
+	assigned := 0
+	for _, k := range keys {
+		exact := float64(p.Draw) * float64(len(strata[k])) / float64(len(p.QuestionIDs))
+		quota[k] = int(math.Floor(exact))
+		remainder[k] = exact - float64(quota[k])
+		assigned += quota[k]
+	}
+	byRemainder := append([]string(nil), keys...)
+	sort.SliceStable(byRemainder, func(i, j int) bool { return remainder[byRemainder[i]] > remainder[byRemainder[j]] })
+	for i := 0; assigned < p.Draw; i++ {
+		k := byRemainder[i%len(byRemainder)]
+		if quota[k] < len(strata[k]) {
+			quota[k]++
+			assigned++
+		}
+	}
+
+	out := make([]int, 0, p.Draw)
+	for _, k := range keys {
+		out = append(out, take(rng, strata[k], quota[k])...)
+	}
+	return out
+}
+
+// take returns n ids chosen at random without modifying ids.
+func take(rng *rand.Rand, ids []int, n int) []int {
+	shuffled := append([]int(nil), ids...)
+	rng.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+	return shuffled[:n]
+}
@@ -0,0 +1,104 @@
+package pools
+
+import (
+	"reflect"
+	"testing"
+
+	"quiz/internal/models"
+)
+
+// TestDrawIsStable pins the draw of one seed. Sessions store only their
+// seed, so a change here would change the questions of every stored session
+// when they are reviewed.
+func TestDrawIsStable(t *testing.T) {
+	testPools := []models.TestPool{
+		{Draw: 2, QuestionIDs: []int{1, 2, 3, 4, 5}},
+		{Draw: 3, Stratify: true, QuestionIDs: []int{11, 12, 13, 14, 15, 16}},
+	}
+	correct := map[int]string{11: "A", 12: "A", 13: "A", 14: "B", 15: "B", 16: "C"}
+
+	want := []int{4, 3, 11, 15, 13}
+	if got := Draw(testPools, correct, 42); !reflect.DeepEqual(got, want) {
+		t.Errorf("Draw(seed 42) = %v, want %v", got, want)
+	}
+}
+
+func TestDrawKeepsPools(t *testing.T) {
+	testPools := []models.TestPool{
+		{Draw: 2, QuestionIDs: []int{1, 2, 3, 4, 5}},
+		{Draw: 4, QuestionIDs: []int{6, 7, 8, 9, 10, 11, 12, 13}},
+		{Draw: 1, QuestionIDs: []int{14}},
+	}
+	for seed := int64(0); seed < 50; seed++ {
+		got := Draw(testPools, nil, seed)
+		if len(got) != 7 {
+			t.Fatalf("seed %d: drew %v, want 7 questions", seed, got)
+		}
+		seen := make(map[int]bool)
+		for i, id := range got {
+			if seen[id] {
+				t.Fatalf("seed %d: question %d drawn twice in %v", seed, id, got)
+			}
+			seen[id] = true
+			pool := 0
+			if i >= 2 {
+				pool = 1
+			}
+			if i == 6 {
+				pool = 2
+			}
+			if !contains(testPools[pool].QuestionIDs, id) {
+				t.Fatalf("seed %d: question %d at %d is not from pool %d", seed, id, i, pool+1)
+			}
+		}
+	}
+}
+
+// TestDrawStratified checks the share of each correct option over many
+// draws of a pool of six A, three B and one question without a known
+// answer: five questions come out as three A, one B and the unknown one.
+func TestDrawStratified(t *testing.T) {
+	pool := models.TestPool{Draw: 5, Stratify: true, QuestionIDs: []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}}
+	correct := map[int]string{1: "A", 2: "A", 3: "A", 4: "A", 5: "A", 6: "A", 7: "B", 8: "B", 9: "B"}
+
+	for seed := int64(0); seed < 50; seed++ {
+		got := make(map[string]int)
+		for _, id := range Draw([]models.TestPool{pool}, correct, seed) {
+			got[correct[id]]++
+		}
+		if got["A"] != 3 || got["B"] != 1 || got[""] != 1 {
+			t.Fatalf("seed %d: drew %v, want 3 A, 1 B and 1 unknown", seed, got)
+		}
+	}
+}
+
+// TestValidate checks the messages shown to the teacher saving a test.
+func TestValidate(t *testing.T) {
+	valid := []models.TestPool{{Draw: 1, QuestionIDs: []int{1, 2}}, {Draw: 2, QuestionIDs: []int{3, 4}}}
+	if err := Validate(valid); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	for _, tt := range []struct {
+		pools []models.TestPool
+		want  string
+	}{
+		{[]models.TestPool{{Draw: 1, QuestionIDs: []int{1}}, {Draw: 1}}, "pool 2 has no questions"},
+		{[]models.TestPool{{Draw: 0, QuestionIDs: []int{1, 2}}}, "pool 1 must draw between 1 and 2 questions"},
+		{[]models.TestPool{{Draw: 3, QuestionIDs: []int{1, 2}}}, "pool 1 must draw between 1 and 2 questions"},
+		{[]models.TestPool{{Draw: 1, QuestionIDs: []int{1, 2}}, {Draw: 1, QuestionIDs: []int{2, 3}}}, "question 2 appears more than once"},
+	} {
+		if err := Validate(tt.pools); err == nil || err.Error() != tt.want {
+			t.Errorf("Validate(%v) error = %v, want %q", tt.pools, err, tt.want)
+		}
+	}
+}
+
+func contains(ids []int, id int) bool {
+	for _, x := range ids {
+		if x == id {
+			return true
+		}
+	}
+	return false
+}
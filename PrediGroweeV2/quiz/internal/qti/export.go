@@ -0,0 +1,255 @@
+package qti
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"quiz/internal/models"
+)
+
+const responseID = "RESPONSE"
+
+// Image is one of the case images shown with a question.
+type Image struct {
+	N           int
+	Data        []byte
+	ContentType string
+}
+
+// Item is a question to export with its correct option, if it has one.
+type Item struct {
+	Question models.Question
+	Correct  string
+	Images   []Image
+}
+
+// PackageWriter writes a QTI content package: a zip with the items, their
+// images and the imsmanifest.xml listing them, written by Close.
+type PackageWriter struct {
+	zw        *zip.Writer
+	version   Version
+	resources []*node
+}
+
+func NewPackageWriter(w io.Writer, v Version) *PackageWriter {
+	return &PackageWriter{zw: zip.NewWriter(w), version: v}
+}
+
+// Add writes an item and its images.
+func (p *PackageWriter) Add(item Item) error {
+	id := "q" + strconv.Itoa(item.Question.ID)
+	href := id + ".xml"
+	resource := el("resource", "identifier", id, "type", versions[p.version].itemType, "href", href).
+		add(el("file", "href", href))
+
+	images := make([]string, 0, len(item.Images))
+	for _, img := range item.Images {
+		name := fmt.Sprintf("images/%s_%d%s", id, img.N, imageExtension(img.ContentType))
+		if err := p.writeFile(name, img.Data); err != nil {
+			return err
+		}
+		images = append(images, name)
+		resource.add(el("file", "href", name))
+	}
+
+	doc := itemDocument(item, id, images, p.version)
+	if err := p.writeFile(href, []byte(doc.document(p.version))); err != nil {
+		return err
+	}
+	p.resources = append(p.resources, resource)
+	return nil
+}
+
+// Close writes the manifest and finishes the zip. It does not close the
+// underlying writer.
+func (p *PackageWriter) Close() error {
+	info := versions[p.version]
+	manifest := el("manifest", "xmlns", info.cpNamespace, "identifier", "predigrowee-questions").add(
+		el("metadata").add(
+			el("schema").text(info.cpSchema),
+			el("schemaversion").text(info.manifestVersion),
+		),
+		el("organizations"),
+		el("resources").add(p.resources...),
+	)
+	// The manifest uses content packaging names, which are the same in
+	// both versions.
+	if err := p.writeFile("imsmanifest.xml", []byte(manifest.document(V21))); err != nil {
+		return err
+	}
+	return p.zw.Close()
+}
+
+func (p *PackageWriter) writeFile(name string, data []byte) error {
+	f, err := p.zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+// itemDocument builds the assessment item of a question. The body shows the
+// case as the app does before the answer: parameters at the first two ages
+// (the third would give the answer away), the images and the age to
+// predict for.
+func itemDocument(item Item, id string, images []string, v Version) *node {
+	q := item.Question
+	info := versions[v]
+	label := itemLabel{caseCode: q.Case.Code, predictionAge: q.PredictionAge, group: q.Group}
+
+	root := el("assessmentItem",
+		"xmlns", info.itemNamespace,
+		"xmlns:xsi", "http://www.w3.org/2001/XMLSchema-instance",
+		"xsi:schemaLocation", info.schemaLocation,
+		"identifier", id,
+		"title", fmt.Sprintf("Case %s, age %d", q.Case.Code, q.PredictionAge),
+		"label", label.String(),
+		"adaptive", "false",
+		"timeDependent", "false",
+	)
+
+	correct := ""
+	interaction := el("choiceInteraction", "responseIdentifier", responseID, "shuffle", "false", "maxChoices", "1").
+		add(el("prompt").text(q.Question))
+	for i, option := range q.Options {
+		choiceID := "C" + strconv.Itoa(i+1)
+		if option == item.Correct {
+			correct = choiceID
+		}
+		interaction.add(el("simpleChoice", "identifier", choiceID).text(option))
+	}
+
+	declaration := el("responseDeclaration", "identifier", responseID, "cardinality", "single", "baseType", "identifier")
+	if correct != "" {
+		declaration.add(el("correctResponse").add(el("value").text(correct)))
+	}
+	root.add(
+		declaration,
+		el("outcomeDeclaration", "identifier", "SCORE", "cardinality", "single", "baseType", "float").
+			add(el("defaultValue").add(el("value").text("0"))),
+		el("itemBody").add(caseBlock(q, images), interaction),
+	)
+	if correct != "" {
+		root.add(el("responseProcessing", "template", info.matchCorrect))
+	}
+	return root
+}
+
+func caseBlock(q models.Question, images []string) *node {
+	c := q.Case
+	block := el("div", "class", "case").add(
+		el("p").text(fmt.Sprintf("Case %s, gender: %s. Predict for age %d.", c.Code, c.Gender, q.PredictionAge)),
+	)
+
+	if len(c.Parameters) > 0 {
+		values := make(map[int]models.ParameterValue, len(c.ParameterValues))
+		for _, pv := range c.ParameterValues {
+			values[pv.ParameterID] = pv
+		}
+		body := el("tbody")
+		for _, p := range c.Parameters {
+			pv, ok := values[p.ID]
+			row := el("tr").add(el("td").text(p.Name))
+			if ok {
+				row.add(el("td").text(formatValue(pv.Value1)), el("td").text(formatValue(pv.Value2)))
+			} else {
+				row.add(el("td"), el("td"))
+			}
+			row.add(el("td").text(p.ReferenceValues))
+			body.add(row)
+		}
+		block.add(el("table", "class", "parameters").add(
+			el("thead").add(el("tr").add(
+				el("th").text("Parameter"),
+				el("th").text(fmt.Sprintf("Age %d", c.Age1)),
+				el("th").text(fmt.Sprintf("Age %d", c.Age2)),
+				el("th").text("Reference values"),
+			)),
+			body,
+		))
+	}
+
+	for i, src := range images {
+		block.add(el("p").add(el("img", "src", src, "alt", fmt.Sprintf("Case %s, image %d", c.Code, i+1))))
+	}
+	return block
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func imageExtension(contentType string) string {
+	switch contentType {
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	}
+	return ".jpg"
+}
+
+// node is an element to write: its name and attributes as in QTI 2.1, and
+// either text or child elements.
+type node struct {
+	name     string
+	attrs    []string
+	children []*node
+	content  string
+}
+
+// el makes an element; attrs are name, value pairs.
+func el(name string, attrs ...string) *node {
+	return &node{name: name, attrs: attrs}
+}
+
+func (n *node) add(children ...*node) *node {
+	n.children = append(n.children, children...)
+	return n
+}
+
+func (n *node) text(s string) *node {
+	n.content = s
+	return n
+}
+
+// document renders the element as an XML document in version v.
+func (n *node) document(v Version) string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	n.write(&b, v, 0)
+	return b.String()
+}
+
+func (n *node) write(b *strings.Builder, v Version, depth int) {
+	name := elementName(n.name, v)
+	b.WriteString(strings.Repeat("  ", depth))
+	b.WriteString("<" + name)
+	for i := 0; i+1 < len(n.attrs); i += 2 {
+		b.WriteString(" " + attrName(n.attrs[i], v) + `="`)
+		_ = xml.EscapeText(b, []byte(n.attrs[i+1]))
+		b.WriteString(`"`)
+	}
+	switch {
+	case len(n.children) > 0:
+		b.WriteString(">\n")
+		for _, c := range n.children {
+			c.write(b, v, depth+1)
+		}
+		b.WriteString(strings.Repeat("  ", depth))
+		b.WriteString("</" + name + ">\n")
+	case n.content != "":
+		b.WriteString(">")
+		_ = xml.EscapeText(b, []byte(n.content))
+		b.WriteString("</" + name + ">\n")
+	default:
+		b.WriteString("/>\n")
+	}
+}
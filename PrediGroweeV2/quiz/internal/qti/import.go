@@ -0,0 +1,270 @@
+package qti
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+const (
+	maxPackageFiles = 5000
+	maxItemSize     = 5 << 20
+)
+
+var ErrNotQTI = errors.New("not a QTI item or content package")
+
+// ImportedItem is a single choice question read from an item. CaseCode,
+// PredictionAge and Group are set only for items exported by this app.
+type ImportedItem struct {
+	Identifier    string
+	File          string
+	Question      string
+	Options       []string
+	Correct       string
+	CaseCode      string
+	PredictionAge int
+	Group         int
+}
+
+// Rejected is an item that cannot be imported and why.
+type Rejected struct {
+	Identifier string
+	File       string
+	Reason     string
+}
+
+// ReadPackage reads the items of a content package, or of one item
+// document, in QTI 2.1 or 3.0. Only items with one single choice
+// interaction and a correct response can be imported; the others are
+// returned as rejected. Other documents in a package, such as tests or
+// metadata, are ignored.
+func ReadPackage(data []byte) ([]ImportedItem, []Rejected, error) {
+	if !bytes.HasPrefix(data, []byte("PK")) {
+		root, err := parseDocument(bytes.NewReader(data))
+		if err != nil || root.name != "assessmentitem" {
+			return nil, nil, ErrNotQTI
+		}
+		item, reason := readItem(root)
+		if reason != "" {
+			return nil, []Rejected{{Identifier: root.attr("identifier"), Reason: reason}}, nil
+		}
+		return []ImportedItem{item}, nil, nil
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, nil, ErrNotQTI
+	}
+	if len(zr.File) > maxPackageFiles {
+		return nil, nil, fmt.Errorf("package has more than %d files", maxPackageFiles)
+	}
+	items := make([]ImportedItem, 0)
+	rejected := make([]Rejected, 0)
+	for _, f := range zr.File {
+		name := f.Name
+		if f.FileInfo().IsDir() || !strings.EqualFold(path.Ext(name), ".xml") ||
+			path.Base(name) == "imsmanifest.xml" || strings.HasPrefix(name, "__MACOSX/") {
+			continue
+		}
+		root, err := parseFile(f)
+		if err != nil {
+			rejected = append(rejected, Rejected{File: name, Reason: err.Error()})
+			continue
+		}
+		if root.name != "assessmentitem" {
+			continue
+		}
+		item, reason := readItem(root)
+		if reason != "" {
+			rejected = append(rejected, Rejected{Identifier: root.attr("identifier"), File: name, Reason: reason})
+			continue
+		}
+		item.File = name
+		items = append(items, item)
+	}
+	if len(items) == 0 && len(rejected) == 0 {
+		return nil, nil, ErrNotQTI
+	}
+	return items, rejected, nil
+}
+
+func parseFile(f *zip.File) (*element, error) {
+	if f.UncompressedSize64 > maxItemSize {
+		return nil, fmt.Errorf("file is larger than %d bytes", maxItemSize)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	root, err := parseDocument(io.LimitReader(rc, maxItemSize))
+	if err != nil {
+		return nil, fmt.Errorf("invalid xml: %w", err)
+	}
+	return root, nil
+}
+
+// readItem takes the question out of an assessment item, or gives the
+// reason it cannot.
+func readItem(root *element) (ImportedItem, string) {
+	item := ImportedItem{Identifier: root.attr("identifier")}
+	label := parseLabel(root.attr("label"))
+	item.CaseCode, item.PredictionAge, item.Group = label.caseCode, label.predictionAge, label.group
+
+	body := root.child("itembody")
+	if body == nil {
+		return item, "item has no body"
+	}
+	interactions := body.findAll(func(e *element) bool { return strings.HasSuffix(e.name, "interaction") })
+	if len(interactions) != 1 || interactions[0].name != "choiceinteraction" {
+		return item, "only items with a single choice interaction can be imported"
+	}
+	interaction := interactions[0]
+	if n := interaction.attr("maxchoices"); n != "" && n != "1" {
+		return item, "multiple response items are not supported"
+	}
+
+	var declaration *element
+	for _, d := range root.children {
+		if d.name == "responsedeclaration" && d.attr("identifier") == interaction.attr("responseidentifier") {
+			declaration = d
+		}
+	}
+	if declaration == nil || declaration.attr("cardinality") != "single" {
+		return item, "the interaction has no single cardinality response declaration"
+	}
+	var correctID string
+	if cr := declaration.child("correctresponse"); cr != nil {
+		values := cr.findAll(func(e *element) bool { return e.name == "value" })
+		if len(values) == 1 {
+			correctID = values[0].flatText()
+		}
+	}
+	if correctID == "" {
+		return item, "the item has no single correct response"
+	}
+
+	if prompt := interaction.child("prompt"); prompt != nil {
+		item.Question = prompt.flatText()
+	}
+	if item.Question == "" {
+		item.Question = strings.TrimSpace(root.attr("title"))
+	}
+	if item.Question == "" {
+		return item, "the item has no prompt or title to use as the question"
+	}
+
+	seen := make(map[string]bool)
+	for _, c := range interaction.children {
+		if c.name != "simplechoice" {
+			continue
+		}
+		text := c.flatText()
+		if text == "" {
+			return item, fmt.Sprintf("choice %s has no text", c.attr("identifier"))
+		}
+		if seen[text] {
+			return item, fmt.Sprintf("choice %q appears twice", text)
+		}
+		seen[text] = true
+		item.Options = append(item.Options, text)
+		if c.attr("identifier") == correctID {
+			item.Correct = text
+		}
+	}
+	if len(item.Options) < 2 {
+		return item, "the interaction has fewer than two choices"
+	}
+	if item.Correct == "" {
+		return item, "the correct response is not one of the choices"
+	}
+	return item, ""
+}
+
+// element is a parsed XML element with names normalized. text holds all
+// character data inside it, its descendants' included.
+type element struct {
+	name     string
+	attrs    map[string]string
+	children []*element
+	text     strings.Builder
+}
+
+func parseDocument(r io.Reader) (*element, error) {
+	dec := xml.NewDecoder(r)
+	var root *element
+	stack := make([]*element, 0)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			e := &element{name: normalize(t.Name.Local), attrs: make(map[string]string, len(t.Attr))}
+			for _, a := range t.Attr {
+				e.attrs[normalize(a.Name.Local)] = a.Value
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, e)
+				// keep words of adjacent elements apart, e.g. cells
+				for _, open := range stack {
+					open.text.WriteByte(' ')
+				}
+			} else if root == nil {
+				root = e
+			}
+			stack = append(stack, e)
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			for _, open := range stack {
+				open.text.Write(t)
+			}
+		}
+	}
+	if root == nil {
+		return nil, errors.New("empty document")
+	}
+	return root, nil
+}
+
+func (e *element) attr(name string) string {
+	return e.attrs[name]
+}
+
+func (e *element) child(name string) *element {
+	for _, c := range e.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func (e *element) findAll(match func(*element) bool) []*element {
+	var out []*element
+	for _, c := range e.children {
+		if match(c) {
+			out = append(out, c)
+		}
+		out = append(out, c.findAll(match)...)
+	}
+	return out
+}
+
+// flatText is the element's text with whitespace collapsed.
+func (e *element) flatText() string {
+	return strings.Join(strings.Fields(e.text.String()), " ")
+}
@@ -0,0 +1,186 @@
+package qti
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"quiz/internal/models"
+)
+
+// TestRoundTrip imports a package written by the export, in both versions.
+// Questions keep their case and age; one without a correct option is
+// exported for reference but cannot be imported.
+func TestRoundTrip(t *testing.T) {
+	answered := models.Question{
+		ID:            7,
+		Question:      "Predict the growth direction at age 14 (SN-GoGn < 32°?)",
+		Options:       []string{"Horizontal", "Vertical", "Mixed"},
+		PredictionAge: 14,
+		Group:         2,
+		Case:          models.Case{Code: "PG 12/A", Gender: "F", Age1: 8, Age2: 10},
+	}
+	unanswered := models.Question{ID: 8, Question: "Which growth pattern?", Options: []string{"A & B", "C"}, PredictionAge: 12, Case: models.Case{Code: "PG 13"}}
+
+	for _, v := range []Version{V21, V30} {
+		var buf bytes.Buffer
+		w := NewPackageWriter(&buf, v)
+		if err := w.Add(Item{Question: answered, Correct: "Vertical", Images: []Image{{N: 1, Data: []byte("png"), ContentType: "image/png"}}}); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Add(Item{Question: unanswered}); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		items, rejected, err := ReadPackage(buf.Bytes())
+		if err != nil {
+			t.Fatalf("%s: ReadPackage() error = %v", v, err)
+		}
+		want := []ImportedItem{{Identifier: "q7", File: "q7.xml", Question: answered.Question, Options: answered.Options,
+			Correct: "Vertical", CaseCode: "PG 12/A", PredictionAge: 14, Group: 2}}
+		if !reflect.DeepEqual(items, want) {
+			t.Errorf("%s: imported %+v, want %+v", v, items, want)
+		}
+		wantRejected := []Rejected{{Identifier: "q8", File: "q8.xml", Reason: "the item has no single correct response"}}
+		if !reflect.DeepEqual(rejected, wantRejected) {
+			t.Errorf("%s: rejected %+v, want %+v", v, rejected, wantRejected)
+		}
+	}
+}
+
+// TestReadLMSPackage reads a package as another platform exports it: items
+// in a folder next to a test and its manifest, some of which this app
+// cannot take.
+func TestReadLMSPackage(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range map[string]string{
+		"imsmanifest.xml":           `<manifest identifier="m"/>`,
+		"tests/growth.xml":          `<assessmentTest identifier="t" title="Growth"/>`,
+		"items/mandible.xml":        mandibleItem,
+		"items/maxilla.xml":         maxillaItem,
+		"items/multiple.xml":        multipleItem,
+		"items/broken.xml":          `<assessmentItem identifier="b"><itemBody>`,
+		"items/images/mandible.png": "png",
+		"__MACOSX/items/._a.xml":    "\x00\x05\x16\x07",
+	} {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	items, rejected, err := ReadPackage(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ReadPackage() error = %v", err)
+	}
+	imported := make(map[string]ImportedItem)
+	for _, item := range items {
+		imported[item.File] = item
+	}
+	if len(imported) != 2 {
+		t.Errorf("imported %+v, want the mandible and maxilla items", items)
+	}
+	if got := imported["items/mandible.xml"]; got.Question != "Which way does the mandible grow?" || got.Correct != "Backward" ||
+		!reflect.DeepEqual(got.Options, []string{"Forward", "Backward"}) || got.CaseCode != "" {
+		t.Errorf("mandible item imported as %+v", got)
+	}
+	if got := imported["items/maxilla.xml"]; got.Question != "Maxilla" || got.Correct != "Down" {
+		t.Errorf("maxilla item imported as %+v", got)
+	}
+
+	reasons := make(map[string]string)
+	for _, r := range rejected {
+		reasons[r.File] = r.Reason
+	}
+	if len(reasons) != 2 || reasons["items/multiple.xml"] != "multiple response items are not supported" || reasons["items/broken.xml"] == "" {
+		t.Errorf("rejected %+v, want the multiple response and broken items", rejected)
+	}
+}
+
+// mandibleItem is a QTI 2.1 item with markup in its prompt.
+const mandibleItem = `<?xml version="1.0" encoding="UTF-8"?>
+<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="mandible" title="Growth" adaptive="false" timeDependent="false">
+  <responseDeclaration identifier="RESPONSE" cardinality="single" baseType="identifier">
+    <correctResponse><value>B</value></correctResponse>
+  </responseDeclaration>
+  <itemBody>
+    <p>Look at the case first.</p>
+    <choiceInteraction responseIdentifier="RESPONSE" shuffle="false" maxChoices="1">
+      <prompt>Which way does the <strong>mandible</strong> grow?</prompt>
+      <simpleChoice identifier="A">Forward</simpleChoice>
+      <simpleChoice identifier="B">Backward</simpleChoice>
+    </choiceInteraction>
+  </itemBody>
+</assessmentItem>`
+
+// maxillaItem is a QTI 3.0 item with no prompt, whose title is the
+// question.
+const maxillaItem = `<qti-assessment-item xmlns="http://www.imsglobal.org/xsd/imsqtiasi_v3p0" identifier="maxilla" title="Maxilla">
+  <qti-response-declaration identifier="R" cardinality="single" base-type="identifier">
+    <qti-correct-response><qti-value>D</qti-value></qti-correct-response>
+  </qti-response-declaration>
+  <qti-item-body>
+    <qti-choice-interaction response-identifier="R" max-choices="1">
+      <qti-simple-choice identifier="U">Up</qti-simple-choice>
+      <qti-simple-choice identifier="D">Down</qti-simple-choice>
+    </qti-choice-interaction>
+  </qti-item-body>
+</qti-assessment-item>`
+
+const multipleItem = `<assessmentItem identifier="multiple" title="Signs">
+  <responseDeclaration identifier="RESPONSE" cardinality="multiple" baseType="identifier">
+    <correctResponse><value>A</value><value>B</value></correctResponse>
+  </responseDeclaration>
+  <itemBody>
+    <choiceInteraction responseIdentifier="RESPONSE" maxChoices="0">
+      <simpleChoice identifier="A">Overbite</simpleChoice>
+      <simpleChoice identifier="B">Overjet</simpleChoice>
+    </choiceInteraction>
+  </itemBody>
+</assessmentItem>`
+
+// TestReadSingleItem reads an item uploaded on its own rather than in a
+// package.
+func TestReadSingleItem(t *testing.T) {
+	items, rejected, err := ReadPackage([]byte(mandibleItem))
+	if err != nil || len(rejected) != 0 || len(items) != 1 || items[0].Identifier != "mandible" || items[0].File != "" {
+		t.Errorf("ReadPackage() = %+v, %+v, %v", items, rejected, err)
+	}
+
+	items, rejected, err = ReadPackage([]byte(multipleItem))
+	if err != nil || len(items) != 0 || len(rejected) != 1 || rejected[0].Identifier != "multiple" {
+		t.Errorf("ReadPackage() = %+v, %+v, %v", items, rejected, err)
+	}
+}
+
+func TestReadNotQTI(t *testing.T) {
+	var empty bytes.Buffer
+	zw := zip.NewWriter(&empty)
+	if f, err := zw.Create("imsmanifest.xml"); err == nil {
+		_, _ = f.Write([]byte(`<manifest/>`))
+	}
+	_ = zw.Close()
+
+	for name, data := range map[string][]byte{
+		"csv":              []byte("question,answer\n"),
+		"html":             []byte(`<html><body/></html>`),
+		"truncated zip":    []byte("PK\x03\x04"),
+		"package no items": empty.Bytes(),
+	} {
+		if _, _, err := ReadPackage(data); !errors.Is(err, ErrNotQTI) {
+			t.Errorf("%s: ReadPackage() error = %v, want ErrNotQTI", name, err)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Package qti converts questions to and from IMS QTI, so they can be used
+// in other exam platforms. Export writes a content package with one
+// assessment item per question: the case parameters and images are shown in
+// the item body above a single choice interaction. Import reads single
+// choice items from such packages, whoever wrote them.
+//
+// QTI 2.1 and 3.0 describe the same items with different names, e.g.
+// choiceInteraction and qti-choice-interaction, so both are written from one
+// element tree and read after normalizing the names.
+package qti
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type Version string
+
+const (
+	V21 Version = "2.1"
+	V30 Version = "3.0"
+)
+
+// ParseVersion reads the version query parameter; empty means 2.1, which
+// more platforms still import.
+func ParseVersion(s string) (Version, error) {
+	switch s {
+	case "", "2.1", "2", "21":
+		return V21, nil
+	case "3.0", "3", "30":
+		return V30, nil
+	}
+	return "", fmt.Errorf("unsupported qti version %q, use 2.1 or 3.0", s)
+}
+
+type versionInfo struct {
+	itemNamespace   string
+	schemaLocation  string
+	matchCorrect    string
+	cpNamespace     string
+	cpSchema        string
+	itemType        string
+	manifestVersion string
+}
+
+var versions = map[Version]versionInfo{
+	V21: {
+		itemNamespace:   "http://www.imsglobal.org/xsd/imsqti_v2p1",
+		schemaLocation:  "http://www.imsglobal.org/xsd/imsqti_v2p1 http://www.imsglobal.org/xsd/qti/qtiv2p1/imsqti_v2p1.xsd",
+		matchCorrect:    "http://www.imsglobal.org/question/qti_v2p1/rptemplates/match_correct",
+		cpNamespace:     "http://www.imsglobal.org/xsd/imscp_v1p1",
+		cpSchema:        "QTIv2.1 Package",
+		itemType:        "imsqti_item_xmlv2p1",
+		manifestVersion: "1.0.0",
+	},
+	V30: {
+		itemNamespace:   "http://www.imsglobal.org/xsd/imsqtiasi_v3p0",
+		schemaLocation:  "http://www.imsglobal.org/xsd/imsqtiasi_v3p0 https://purl.imsglobal.org/spec/qti/v3p0/schema/xsd/imsqti_asiv3p0_v1p0.xsd",
+		matchCorrect:    "https://purl.imsglobal.org/spec/qti/v3p0/rptemplates/match_correct.xml",
+		cpNamespace:     "http://www.imsglobal.org/xsd/qti/qtiv3p0/imscp_v1p1",
+		cpSchema:        "QTI Package",
+		itemType:        "imsqti_item_xmlv3p0",
+		manifestVersion: "3.0.0",
+	},
+}
+
+// htmlElements keep their names in every version; the rest are QTI
+// elements, prefixed and kebab-cased in 3.0.
+var htmlElements = map[string]bool{
+	"div": true, "p": true, "span": true, "strong": true, "em": true, "br": true, "img": true,
+	"table": true, "thead": true, "tbody": true, "tr": true, "th": true, "td": true,
+}
+
+// elementName returns the name of a QTI 2.1 element in version v.
+func elementName(name string, v Version) string {
+	if v == V21 || htmlElements[name] {
+		return name
+	}
+	return "qti-" + kebab(name)
+}
+
+// attrName returns the name of a QTI 2.1 attribute in version v.
+// Namespaced attributes are left alone.
+func attrName(name string, v Version) string {
+	if v == V21 || strings.Contains(name, ":") || name == "xmlns" {
+		return name
+	}
+	return kebab(name)
+}
+
+func kebab(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('-')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// normalize maps element and attribute names of either version to one
+// form: simpleChoice and qti-simple-choice both become simplechoice.
+func normalize(name string) string {
+	name = strings.ToLower(name)
+	name = strings.TrimPrefix(name, "qti-")
+	return strings.ReplaceAll(name, "-", "")
+}
+
+// labelPrefix starts the label of exported items. The rest of the label
+// carries what QTI has no place for, so that re-importing an item puts it
+// back on its case.
+const labelPrefix = "predigrowee"
+
+type itemLabel struct {
+	caseCode      string
+	predictionAge int
+	group         int
+}
+
+func (l itemLabel) String() string {
+	return fmt.Sprintf("%s case=%s age=%d group=%d", labelPrefix, url.QueryEscape(l.caseCode), l.predictionAge, l.group)
+}
+
+// parseLabel reads a label written by String. Other labels give the zero
+// value.
+func parseLabel(s string) itemLabel {
+	fields := strings.Fields(s)
+	var l itemLabel
+	if len(fields) == 0 || fields[0] != labelPrefix {
+		return l
+	}
+	for _, f := range fields[1:] {
+		key, value, _ := strings.Cut(f, "=")
+		switch key {
+		case "case":
+			l.caseCode, _ = url.QueryUnescape(value)
+		case "age":
+			l.predictionAge, _ = strconv.Atoi(value)
+		case "group":
+			l.group, _ = strconv.Atoi(value)
+		}
+	}
+	return l
+}
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"github.com/lib/pq"
 	"go.uber.org/zap"
@@ -16,6 +17,7 @@ type Store interface {
 
 	// sessions
 	CreateQuizSession(session models.QuizSession) (models.QuizSession, error)
+	CreateTestQuizSession(session models.QuizSession, maxAttempts int) (models.QuizSession, int, error)
 	GetQuizSessionByID(id int) (models.QuizSession, error)
 	UpdateQuizSession(session models.QuizSession) error
 	GetUserActiveQuizSessions(userID int) ([]models.QuizSession, error)
@@ -33,6 +35,7 @@ type Store interface {
 	CountQuestions() (int, error)
 	GetQuestionOptions(id int) ([]string, error)
 	GetQuestionCorrectOption(id int) (string, error)
+	GetQuestionsMeta() ([]models.QuestionMeta, error)
 
 	// options
 	GetAllOptions() ([]models.Option, error)
@@ -80,19 +83,37 @@ type Store interface {
     CountReportsWithoutNote() (int, error)
 
 	// tests
-	CreateTest(t models.Test, questionIDs []int) (models.Test, error)
+	CreateTest(t models.Test, questionIDs []int, pools []models.TestPool) (models.Test, error)
+	GetTestPools(testID int) ([]models.TestPool, error)
+	GetCorrectOptions(questionIDs []int) (map[int]string, error)
 	GetTestByCode(code string) (*models.Test, error)
 	GetTestQuestionIDsOrdered(testID int) ([]int, error)
 	ListTestsByOwner(userID int) ([]models.Test, error)
+	GetTestPermission(testID int, userID int) (string, error)
+	ListTestCoOwners(testID int) ([]models.TestOwner, error)
+	SetTestCoOwner(testID int, userID int, role string, addedBy int) error
+	RemoveTestCoOwner(testID int, userID int) error
+	TransferTestOwnership(testID int, newOwnerID int, previousOwnerRole string, transferredBy int) error
 	ListSessionsByTestID(testID int) ([]models.QuizSession, error)
 	GetTestByID(id int) (*models.Test, error)
 	DeleteTest(id int) error
+	UpdateTest(t models.Test, questionIDs []int, pools []models.TestPool, editorID int) (models.Test, error)
+	GetTestVersion(testID int, version int) (*models.TestVersion, error)
+	ListTestVersions(testID int) ([]models.TestVersion, error)
+	CountUserTestSessions(testID int, userID int) (int, error)
+	ReassignUserSessions(fromUserID int, toUserID int) (int, error)
 
     // difficulty
     InsertDifficultyVote(questionID int, userID int, level models.DifficultyLevel) error
     GetMyDifficultyVote(questionID int, userID int) (*models.QuestionDifficultyVote, error)
     GetDifficultySummary(questionID int) (*models.QuestionDifficultySummary, error)
 	GetDifficultySummaryBatch(ids []int) ([]models.QuestionDifficultySummary, error)
+	GetAllDifficultySummaries() ([]models.QuestionDifficultySummary, error)
+
+	// question review queue
+	ListQuestionReviews(status string) ([]models.QuestionReview, error)
+	EnqueueQuestionReviews(candidates []models.QuestionReviewCandidate) (int, error)
+	UpdateQuestionReview(id int, update models.QuestionReviewUpdate) (models.QuestionReview, error)
 
 
 	// live sessions
@@ -104,6 +125,71 @@ type Store interface {
 	ListFavoriteCases(userID int) ([]models.FavoriteCase, error)
 	UpdateFavoriteNote(userID int, caseID int, note *string) error
 
+	// classes
+	CreateClass(c models.Class) (models.Class, error)
+	GetClassByID(id int) (*models.Class, error)
+	GetClassByInviteCode(code string) (*models.Class, error)
+	ListClassesByOwner(ownerID int) ([]models.Class, error)
+	DeleteClass(id int) error
+	AddClassMember(classID int, userID int) error
+	RemoveClassMember(classID int, userID int) error
+	ListClassMembers(classID int) ([]models.ClassMember, error)
+	ListJoinedClasses(userID int) ([]models.JoinedClass, error)
+	UpsertClassAssignment(a models.ClassAssignment) (models.ClassAssignment, error)
+	DeleteClassAssignment(classID int, testID int) error
+	ListClassAssignments(classID int) ([]models.ClassAssignment, error)
+	GetClassProgress(classID int, userID int) ([]models.ClassProgress, error)
+
+	// live rooms
+	CreateLiveRoom(room models.LiveRoom) (models.LiveRoom, error)
+	GetLiveRoom(id int) (*models.LiveRoom, error)
+	GetOpenLiveRoomByCode(code string) (*models.LiveRoom, error)
+	AdvanceLiveRoom(id int) (models.LiveRoom, error)
+	EndLiveRoom(id int) ([]int, error)
+	GetLiveRoomSession(roomID int, userID int) (*models.QuizSession, error)
+	CountLiveRoomSessions(roomID int) (int, error)
+	InsertLiveAnswer(a models.LiveAnswer) error
+	GetLiveDistribution(roomID int, questionIndex int) (models.LiveDistribution, error)
+
+	// webhooks
+	CreateWebhook(w models.Webhook) (models.Webhook, error)
+	GetWebhook(id int) (*models.Webhook, error)
+	ListWebhooksByOwner(ownerID int) ([]models.Webhook, error)
+	DeleteWebhook(id int) error
+	EnqueueWebhookEvent(event string, payload []byte, testID *int) (int, error)
+	EnqueueWebhookDelivery(webhookID int, event string, payload []byte) error
+	ClaimDueWebhookDeliveries(limit int, lease time.Duration) ([]models.WebhookDelivery, error)
+	RecordWebhookAttempt(id int64, status string, statusCode *int, lastError *string, nextAttemptAt time.Time) error
+	ListWebhookDeliveries(webhookID int, limit int) ([]models.WebhookDelivery, error)
+	MarkApprovalPending(userID int) (bool, error)
+
+	// lti
+	CreateLTIPlatform(p models.LTIPlatform) (models.LTIPlatform, error)
+	ListLTIPlatforms() ([]models.LTIPlatform, error)
+	GetLTIPlatform(id int) (*models.LTIPlatform, error)
+	DeleteLTIPlatform(id int) error
+	CreateLTILoginState(state string, nonce string, platformID int) error
+	ConsumeLTILoginState(state string, maxAge time.Duration) (nonce string, platformID int, err error)
+	GetLTIResourceLink(platformID int, resourceLinkID string) (*models.LTIResourceLink, error)
+	UpsertLTIResourceLink(l models.LTIResourceLink) error
+	RecordLTILaunch(platformID int, resourceLinkID string, userID int, subject string) error
+	ListLTIGradeTargets(userID int, testID int) ([]models.LTIGradeTarget, error)
+	ReassignLTILaunches(fromUserID int, toUserID int) error
+
+	// xapi
+	EnqueueXAPIStatement(id string, statement []byte) error
+	ClaimDueXAPIStatements(limit int, lease time.Duration) ([]models.XAPIStatement, error)
+	DeleteXAPIStatements(ids []string) error
+	RecordXAPIFailure(ids []string, lastError string, failed bool, nextAttemptAt time.Time) error
+
+	// qti
+	GetCaseByCode(code string) (*models.Case, error)
+	ImportQuestions(questions []models.QuestionImport) ([]int, error)
+
+	// certificates
+	CreateCertificate(c models.Certificate) (*models.Certificate, error)
+	GetCertificateByCode(code string) (*models.Certificate, error)
+	ListCertificatesByUser(userID int) ([]models.Certificate, error)
 }
 
 type PostgresStorage struct {
@@ -131,17 +217,55 @@ func (s *PostgresStorage) Close() error {
 //
 
 func (s *PostgresStorage) CreateQuizSession(session models.QuizSession) (models.QuizSession, error) {
+	return insertQuizSession(s.db, session)
+}
+
+// CreateTestQuizSession creates a session for a test with an attempt limit.
+// The test row is locked while the user's earlier sessions are counted, so
+// concurrent starts cannot both take the last attempt. It returns the number
+// of earlier attempts; when that has reached maxAttempts no session is
+// created.
+func (s *PostgresStorage) CreateTestQuizSession(session models.QuizSession, maxAttempts int) (models.QuizSession, int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return session, 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT 1 FROM tests WHERE id = $1 FOR UPDATE`, session.TestID); err != nil {
+		return session, 0, err
+	}
+	var attempts int
+	err = tx.QueryRow(
+		`SELECT count(*) FROM quiz_sessions WHERE test_id = $1 AND user_id = $2`,
+		session.TestID, session.UserID,
+	).Scan(&attempts)
+	if err != nil || attempts >= maxAttempts {
+		return session, attempts, err
+	}
+	if session, err = insertQuizSession(tx, session); err != nil {
+		return session, attempts, err
+	}
+	return session, attempts, tx.Commit()
+}
+
+// rowQuerier is implemented by *sql.DB and *sql.Tx.
+type rowQuerier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func insertQuizSession(q rowQuerier, session models.QuizSession) (models.QuizSession, error) {
 	query := `
         INSERT INTO quiz_sessions (
             user_id, status, mode, screen_size,
             current_question, current_group, group_order,
-            test_id, test_code,
-            created_at, updated_at
+            test_id, test_code, screen_width, screen_height, question_seed, test_version,
+            live_room_id, created_at, updated_at
         )
-        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, NOW(), NOW())
         RETURNING id, created_at, updated_at`
 
-	err := s.db.QueryRow(
+	err := q.QueryRow(
 		query,
 		session.UserID,
 		session.Status,
@@ -152,17 +276,31 @@ func (s *PostgresStorage) CreateQuizSession(session models.QuizSession) (models.
 		pq.Array(session.GroupOrder),
 		session.TestID,
 		session.TestCode,
+		screenDimension(session.ScreenWidth),
+		screenDimension(session.ScreenHeight),
+		session.QuestionSeed,
+		session.TestVersion,
+		session.LiveRoomID,
 	).Scan(&session.ID, &session.CreatedAt, &session.UpdatedAt)
 
 	return session, err
 }
 
+// screenDimension stores a viewport dimension sent by the client, or NULL
+// when it is not a real one, which must not fail the write.
+func screenDimension(v int) sql.NullInt32 {
+	if v <= 0 || v > models.MaxScreenDimension {
+		return sql.NullInt32{}
+	}
+	return sql.NullInt32{Int32: int32(v), Valid: true}
+}
+
 func (s *PostgresStorage) GetQuizSessionByID(id int) (models.QuizSession, error) {
 	var session models.QuizSession
 	query := `
         SELECT id, user_id, status, mode, current_question, current_group, group_order,
                created_at, updated_at, finished_at, question_requested_time,
-               test_id, test_code
+               test_id, test_code, question_seed, test_version, live_room_id
         FROM quiz_sessions
         WHERE id = $1`
 
@@ -184,6 +322,9 @@ func (s *PostgresStorage) GetQuizSessionByID(id int) (models.QuizSession, error)
 		&session.QuestionRequestedTime,
 		&testIDNull,
 		&testCodeNull,
+		&session.QuestionSeed,
+		&session.TestVersion,
+		&session.LiveRoomID,
 	)
 	session.GroupOrder = make([]int, 0, len(groupArr))
 	for _, v := range groupArr {
@@ -267,34 +408,13 @@ func (s *PostgresStorage) GetUserActiveQuizSessions(userID int) ([]models.QuizSe
 }
 
 func (s *PostgresStorage) GetUserLastQuizSession(userID int) (*models.QuizSession, error) {
-	query := `
-        SELECT id, user_id, status, mode, current_question, current_group, group_order,
-               created_at, updated_at, finished_at, test_id, test_code
+	var id int
+	err := s.db.QueryRow(`
+        SELECT id
         FROM quiz_sessions
         WHERE user_id = $1
         ORDER BY created_at DESC
-        LIMIT 1`
-
-	var session models.QuizSession
-	var groupArr []int64
-	var testIDNull sql.NullInt64
-	var testCodeNull sql.NullString
-
-	err := s.db.QueryRow(query, userID).Scan(
-		&session.ID,
-		&session.UserID,
-		&session.Status,
-		&session.Mode,
-		&session.CurrentQuestionID,
-		&session.CurrentGroup,
-		pq.Array(&groupArr),
-		&session.CreatedAt,
-		&session.UpdatedAt,
-		&session.FinishedAt,
-		&testIDNull,
-		&testCodeNull,
-	)
-
+        LIMIT 1`, userID).Scan(&id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -302,19 +422,10 @@ func (s *PostgresStorage) GetUserLastQuizSession(userID int) (*models.QuizSessio
 		return nil, err
 	}
 
-	session.GroupOrder = make([]int, len(groupArr))
-	for i, v := range groupArr {
-		session.GroupOrder[i] = int(v)
-	}
-	if testIDNull.Valid {
-		tid := int(testIDNull.Int64)
-		session.TestID = &tid
-	}
-	if testCodeNull.Valid {
-		code := testCodeNull.String
-		session.TestCode = &code
+	session, err := s.GetQuizSessionByID(id)
+	if err != nil {
+		return nil, err
 	}
-
 	return &session, nil
 }
 
@@ -439,6 +550,39 @@ func (s *PostgresStorage) GetAllQuestions() ([]models.Question, error) {
 	return questions, nil
 }
 
+func (s *PostgresStorage) GetQuestionsMeta() ([]models.QuestionMeta, error) {
+	rows, err := s.db.Query(`
+		SELECT q.id, c.id, c.code, c.patient_gender, COALESCE(q.prediction_age, 0),
+		       COALESCE(o.option, ''), cp.value_1
+		  FROM questions q
+		  JOIN cases c ON c.id = q.case_id
+		  LEFT JOIN question_options qo ON qo.question_id = q.id AND qo.is_correct = TRUE
+		  LEFT JOIN options o ON o.id = qo.option_id
+		  LEFT JOIN parameters p ON p.name = 'SN/MP'
+		  LEFT JOIN case_parameters cp ON cp.case_id = c.id AND cp.parameter_id = p.id
+		 ORDER BY q.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.QuestionMeta, 0)
+	for rows.Next() {
+		var m models.QuestionMeta
+		var snmp sql.NullFloat64
+		if err := rows.Scan(&m.QuestionID, &m.CaseID, &m.CaseCode, &m.CaseGender, &m.PredictionAge, &m.Correct, &snmp); err != nil {
+			return nil, err
+		}
+		if snmp.Valid {
+			v := snmp.Float64
+			m.SNMP = &v
+		}
+		m.Category = models.CaseCategoryFromSNMP(m.SNMP)
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
 //
 // Options
 //
@@ -1198,45 +1342,196 @@ func (s *PostgresStorage) GetCaseAge3(caseID int) (int, error) {
 // Tests
 //
 
-func (s *PostgresStorage) CreateTest(t models.Test, questionIDs []int) (models.Test, error) {
-	err := s.db.QueryRow(
-		`INSERT INTO tests(code, name, created_by) VALUES ($1,$2,$3)
-         RETURNING id, created_at`,
-		t.Code, t.Name, t.CreatedBy,
-	).Scan(&t.ID, &t.CreatedAt)
+// CreateTest stores a test with its ordered question list and, for pooled
+// tests, its pools, as version 1.
+func (s *PostgresStorage) CreateTest(t models.Test, questionIDs []int, pools []models.TestPool) (models.Test, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return t, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(
+		`INSERT INTO tests(code, name, created_by, opens_at, closes_at, max_attempts, password_hash, mode, time_limit, allow_guests, current_version)
+         VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,1)
+         RETURNING id, created_at, current_version`,
+		t.Code, t.Name, t.CreatedBy, t.OpensAt, t.ClosesAt, t.MaxAttempts, nullIfEmpty(t.PasswordHash), t.Mode, t.TimeLimit, t.AllowGuests,
+	).Scan(&t.ID, &t.CreatedAt, &t.Version)
 	if err != nil {
 		return t, err
 	}
+	t.HasPassword = t.PasswordHash != ""
+
+	if err := insertTestContent(tx, t.ID, questionIDs, pools); err != nil {
+		return t, err
+	}
+	if err := insertTestVersion(tx, t, questionIDs, pools, t.CreatedBy); err != nil {
+		return t, err
+	}
+	return t, tx.Commit()
+}
 
+// UpdateTest replaces the name, settings and questions of a test and records
+// the result as a new version. It returns sql.ErrNoRows when the test does
+// not exist.
+func (s *PostgresStorage) UpdateTest(t models.Test, questionIDs []int, pools []models.TestPool, editorID int) (models.Test, error) {
 	tx, err := s.db.Begin()
 	if err != nil {
 		return t, err
 	}
-	stmt, err := tx.Prepare(`INSERT INTO test_questions(test_id, question_id, sort_order) VALUES ($1,$2,$3)`)
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		UPDATE tests
+		   SET name = $2, opens_at = $3, closes_at = $4, max_attempts = $5,
+		       password_hash = $6, mode = $7, time_limit = $8, allow_guests = $9,
+		       current_version = current_version + 1
+		 WHERE id = $1
+		RETURNING current_version`,
+		t.ID, t.Name, t.OpensAt, t.ClosesAt, t.MaxAttempts, nullIfEmpty(t.PasswordHash), t.Mode, t.TimeLimit, t.AllowGuests,
+	).Scan(&t.Version)
 	if err != nil {
-		tx.Rollback()
 		return t, err
 	}
+	t.HasPassword = t.PasswordHash != ""
+
+	if _, err := tx.Exec(`DELETE FROM test_questions WHERE test_id = $1`, t.ID); err != nil {
+		return t, err
+	}
+	if _, err := tx.Exec(`DELETE FROM test_pools WHERE test_id = $1`, t.ID); err != nil {
+		return t, err
+	}
+	if err := insertTestContent(tx, t.ID, questionIDs, pools); err != nil {
+		return t, err
+	}
+	if err := insertTestVersion(tx, t, questionIDs, pools, editorID); err != nil {
+		return t, err
+	}
+	return t, tx.Commit()
+}
+
+func insertTestContent(tx *sql.Tx, testID int, questionIDs []int, pools []models.TestPool) error {
+	stmt, err := tx.Prepare(`INSERT INTO test_questions(test_id, question_id, sort_order) VALUES ($1,$2,$3)`)
+	if err != nil {
+		return err
+	}
 	defer stmt.Close()
 
 	for i, qid := range questionIDs {
-		if _, err := stmt.Exec(t.ID, qid, i); err != nil {
-			tx.Rollback()
-			return t, err
+		if _, err := stmt.Exec(testID, qid, i); err != nil {
+			return err
 		}
 	}
-	if err := tx.Commit(); err != nil {
-		return t, err
+	for i, pool := range pools {
+		var poolID int
+		if err := tx.QueryRow(
+			`INSERT INTO test_pools(test_id, position, draw, stratify) VALUES ($1,$2,$3,$4) RETURNING id`,
+			testID, i, pool.Draw, pool.Stratify,
+		).Scan(&poolID); err != nil {
+			return err
+		}
+		for j, qid := range pool.QuestionIDs {
+			if _, err := tx.Exec(
+				`INSERT INTO test_pool_questions(pool_id, question_id, sort_order) VALUES ($1,$2,$3)`,
+				poolID, qid, j,
+			); err != nil {
+				return err
+			}
+		}
 	}
-	return t, nil
+	return nil
+}
+
+func insertTestVersion(tx *sql.Tx, t models.Test, questionIDs []int, pools []models.TestPool, createdBy int) error {
+	snapshot := make([]models.TestPool, len(pools))
+	for i, p := range pools {
+		snapshot[i] = models.TestPool{Position: i, Draw: p.Draw, Stratify: p.Stratify, QuestionIDs: p.QuestionIDs}
+	}
+	poolsJSON, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO test_versions(test_id, version, name, question_ids, pools,
+		                          opens_at, closes_at, max_attempts, password_hash, mode, time_limit, allow_guests, created_by)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)`,
+		t.ID, t.Version, t.Name, pq.Array(questionIDs), poolsJSON,
+		t.OpensAt, t.ClosesAt, t.MaxAttempts, nullIfEmpty(t.PasswordHash), t.Mode, t.TimeLimit, t.AllowGuests, createdBy,
+	)
+	return err
+}
+
+const testVersionColumns = `v.test_id, v.version, v.name, v.question_ids, v.pools,
+	v.opens_at, v.closes_at, v.max_attempts, v.password_hash, v.mode, v.time_limit,
+	v.allow_guests, v.created_by, v.created_at`
+
+func scanTestVersion(row interface{ Scan(...any) error }, extra ...any) (models.TestVersion, error) {
+	var v models.TestVersion
+	var questionIDs pq.Int64Array
+	var poolsJSON []byte
+	var n nullableSettings
+	dest := []any{&v.TestID, &v.Version, &v.Name, &questionIDs, &poolsJSON,
+		&v.OpensAt, &v.ClosesAt, &n.maxAttempts, &n.passwordHash, &n.mode, &n.timeLimit,
+		&v.AllowGuests, &v.CreatedBy, &v.CreatedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return v, err
+	}
+	n.fill(&v.TestSettings)
+	v.QuestionIDs = make([]int, len(questionIDs))
+	for i, id := range questionIDs {
+		v.QuestionIDs[i] = int(id)
+	}
+	if err := json.Unmarshal(poolsJSON, &v.Pools); err != nil {
+		return v, err
+	}
+	return v, nil
+}
+
+// GetTestVersion returns one version of a test, or nil if there is none.
+func (s *PostgresStorage) GetTestVersion(testID int, version int) (*models.TestVersion, error) {
+	v, err := scanTestVersion(s.db.QueryRow(
+		`SELECT `+testVersionColumns+` FROM test_versions v WHERE v.test_id = $1 AND v.version = $2`,
+		testID, version,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &v, nil
+}
+
+// ListTestVersions returns every version of a test, newest first, with the
+// number of sessions that took each one.
+func (s *PostgresStorage) ListTestVersions(testID int) ([]models.TestVersion, error) {
+	rows, err := s.db.Query(`
+		SELECT `+testVersionColumns+`,
+		       (SELECT count(*) FROM quiz_sessions qs
+		         WHERE qs.test_id = v.test_id AND qs.test_version = v.version)
+		  FROM test_versions v
+		 WHERE v.test_id = $1
+		 ORDER BY v.version DESC`, testID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.TestVersion, 0)
+	for rows.Next() {
+		var sessions int
+		v, err := scanTestVersion(rows, &sessions)
+		if err != nil {
+			return nil, err
+		}
+		v.Sessions = sessions
+		out = append(out, v)
+	}
+	return out, rows.Err()
 }
 
 func (s *PostgresStorage) GetTestByCode(code string) (*models.Test, error) {
-	var t models.Test
-	err := s.db.QueryRow(
-		`SELECT id, code, name, created_by, created_at FROM tests WHERE code=$1`,
-		code,
-	).Scan(&t.ID, &t.Code, &t.Name, &t.CreatedBy, &t.CreatedAt)
+	t, err := scanTest(s.db.QueryRow(`SELECT `+testColumns+` FROM tests WHERE code=$1`, code))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -1267,11 +1562,17 @@ func (s *PostgresStorage) GetTestQuestionIDsOrdered(testID int) ([]int, error) {
 	return out, rows.Err()
 }
 
+// ListTestsByOwner returns the tests a user owns or co-owns, each with the
+// user's permission on it.
 func (s *PostgresStorage) ListTestsByOwner(userID int) ([]models.Test, error) {
 	rows, err := s.db.Query(
-		`SELECT id, code, name, created_by, created_at
+		`SELECT `+testColumns+`,
+		        CASE WHEN created_by = $1 THEN 'owner'
+		             ELSE (SELECT role FROM test_owners o WHERE o.test_id = tests.id AND o.user_id = $1)
+		        END
 		   FROM tests
 		  WHERE created_by = $1
+		     OR id IN (SELECT test_id FROM test_owners WHERE user_id = $1)
 		  ORDER BY created_at DESC, id DESC`,
 		userID,
 	)
@@ -1282,19 +1583,113 @@ func (s *PostgresStorage) ListTestsByOwner(userID int) ([]models.Test, error) {
 
 	var out []models.Test
 	for rows.Next() {
-		var t models.Test
-		if err := rows.Scan(&t.ID, &t.Code, &t.Name, &t.CreatedBy, &t.CreatedAt); err != nil {
+		var permission string
+		t, err := scanTest(rows, &permission)
+		if err != nil {
 			return nil, err
 		}
+		t.Permission = permission
 		out = append(out, t)
 	}
 	return out, rows.Err()
 }
 
+// GetTestPermission returns the user's permission on a test: owner for its
+// creator, the co-owner role otherwise, or "" without access.
+func (s *PostgresStorage) GetTestPermission(testID int, userID int) (string, error) {
+	var permission sql.NullString
+	err := s.db.QueryRow(`
+		SELECT CASE WHEN t.created_by = $2 THEN 'owner' ELSE o.role END
+		  FROM tests t
+		  LEFT JOIN test_owners o ON o.test_id = t.id AND o.user_id = $2
+		 WHERE t.id = $1`, testID, userID).Scan(&permission)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return permission.String, err
+}
+
+func (s *PostgresStorage) ListTestCoOwners(testID int) ([]models.TestOwner, error) {
+	rows, err := s.db.Query(
+		`SELECT user_id, role, added_by, added_at FROM test_owners WHERE test_id = $1 ORDER BY added_at, user_id`,
+		testID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.TestOwner, 0)
+	for rows.Next() {
+		var o models.TestOwner
+		if err := rows.Scan(&o.UserID, &o.Role, &o.AddedBy, &o.AddedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, o)
+	}
+	return out, rows.Err()
+}
+
+// SetTestCoOwner adds a co-owner or changes the role of an existing one.
+func (s *PostgresStorage) SetTestCoOwner(testID int, userID int, role string, addedBy int) error {
+	_, err := s.db.Exec(`
+		INSERT INTO test_owners(test_id, user_id, role, added_by) VALUES ($1,$2,$3,$4)
+		ON CONFLICT (test_id, user_id) DO UPDATE SET role = EXCLUDED.role`,
+		testID, userID, role, addedBy,
+	)
+	return err
+}
+
+func (s *PostgresStorage) RemoveTestCoOwner(testID int, userID int) error {
+	res, err := s.db.Exec(`DELETE FROM test_owners WHERE test_id = $1 AND user_id = $2`, testID, userID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// TransferTestOwnership makes newOwnerID the owner of a test, dropping any
+// co-owner role they had. The previous owner stays on as a co-owner with
+// previousOwnerRole, or loses access when it is empty.
+func (s *PostgresStorage) TransferTestOwnership(testID int, newOwnerID int, previousOwnerRole string, transferredBy int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var previousOwnerID int
+	err = tx.QueryRow(`SELECT created_by FROM tests WHERE id = $1 FOR UPDATE`, testID).Scan(&previousOwnerID)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE tests SET created_by = $2 WHERE id = $1`, testID, newOwnerID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM test_owners WHERE test_id = $1 AND user_id = $2`, testID, newOwnerID); err != nil {
+		return err
+	}
+	if previousOwnerRole != "" && previousOwnerID != newOwnerID {
+		_, err := tx.Exec(`
+			INSERT INTO test_owners(test_id, user_id, role, added_by) VALUES ($1,$2,$3,$4)
+			ON CONFLICT (test_id, user_id) DO UPDATE SET role = EXCLUDED.role`,
+			testID, previousOwnerID, previousOwnerRole, transferredBy,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
 func (s *PostgresStorage) ListSessionsByTestID(testID int) ([]models.QuizSession, error) {
 	rows, err := s.db.Query(`
 		SELECT id, user_id, status, mode, current_question, current_group, group_order,
-		       created_at, updated_at, finished_at, question_requested_time, test_id, test_code
+		       created_at, updated_at, finished_at, question_requested_time, test_id, test_code,
+		       question_seed, test_version
 		  FROM quiz_sessions
 		 WHERE test_id = $1
 		 ORDER BY created_at DESC, id DESC
@@ -1316,7 +1711,7 @@ func (s *PostgresStorage) ListSessionsByTestID(testID int) ([]models.QuizSession
 			&sss.CurrentQuestionID, &sss.CurrentGroup,
 			pq.Array(&goArr),
 			&sss.CreatedAt, &sss.UpdatedAt, &sss.FinishedAt, &sss.QuestionRequestedTime,
-			&testIDNull, &testCodeNull,
+			&testIDNull, &testCodeNull, &sss.QuestionSeed, &sss.TestVersion,
 		); err != nil {
 			return nil, err
 		}
@@ -1340,11 +1735,7 @@ func (s *PostgresStorage) ListSessionsByTestID(testID int) ([]models.QuizSession
 }
 
 func (s *PostgresStorage) GetTestByID(id int) (*models.Test, error) {
-	var t models.Test
-	err := s.db.QueryRow(
-		`SELECT id, code, name, created_by, created_at FROM tests WHERE id=$1`,
-		id,
-	).Scan(&t.ID, &t.Code, &t.Name, &t.CreatedBy, &t.CreatedAt)
+	t, err := scanTest(s.db.QueryRow(`SELECT `+testColumns+` FROM tests WHERE id=$1`, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -1354,6 +1745,130 @@ func (s *PostgresStorage) GetTestByID(id int) (*models.Test, error) {
 	return &t, nil
 }
 
+// GetTestPools returns the pools of a test in order, or none for a test
+// with a fixed question list.
+func (s *PostgresStorage) GetTestPools(testID int) ([]models.TestPool, error) {
+	rows, err := s.db.Query(`
+		SELECT p.id, p.position, p.draw, p.stratify,
+		       COALESCE(array_agg(pq.question_id ORDER BY pq.sort_order)
+		                FILTER (WHERE pq.question_id IS NOT NULL), '{}')
+		  FROM test_pools p
+		  LEFT JOIN test_pool_questions pq ON pq.pool_id = p.id
+		 WHERE p.test_id = $1
+		 GROUP BY p.id
+		 ORDER BY p.position`, testID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pools := make([]models.TestPool, 0)
+	for rows.Next() {
+		var p models.TestPool
+		var ids pq.Int64Array
+		if err := rows.Scan(&p.ID, &p.Position, &p.Draw, &p.Stratify, &ids); err != nil {
+			return nil, err
+		}
+		p.QuestionIDs = make([]int, len(ids))
+		for i, id := range ids {
+			p.QuestionIDs[i] = int(id)
+		}
+		pools = append(pools, p)
+	}
+	return pools, rows.Err()
+}
+
+// GetCorrectOptions maps each question to its correct option. Questions
+// without one are left out.
+func (s *PostgresStorage) GetCorrectOptions(questionIDs []int) (map[int]string, error) {
+	rows, err := s.db.Query(`
+		SELECT qo.question_id, o.option
+		  FROM question_options qo
+		  JOIN options o ON o.id = qo.option_id
+		 WHERE qo.question_id = ANY($1) AND qo.is_correct = true`, pq.Array(questionIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[int]string, len(questionIDs))
+	for rows.Next() {
+		var id int
+		var option string
+		if err := rows.Scan(&id, &option); err != nil {
+			return nil, err
+		}
+		out[id] = option
+	}
+	return out, rows.Err()
+}
+
+const testColumns = `id, code, name, created_by, created_at, current_version,
+	opens_at, closes_at, max_attempts, password_hash, mode, time_limit, allow_guests`
+
+// scanTest scans testColumns followed by any extra columns into extra.
+func scanTest(row interface{ Scan(...any) error }, extra ...any) (models.Test, error) {
+	var t models.Test
+	var n nullableSettings
+	dest := []any{&t.ID, &t.Code, &t.Name, &t.CreatedBy, &t.CreatedAt, &t.Version,
+		&t.OpensAt, &t.ClosesAt, &n.maxAttempts, &n.passwordHash, &n.mode, &n.timeLimit, &t.AllowGuests}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return t, err
+	}
+	n.fill(&t.TestSettings)
+	return t, nil
+}
+
+// nullableSettings holds the nullable test setting columns while scanning.
+type nullableSettings struct {
+	maxAttempts, timeLimit sql.NullInt64
+	passwordHash, mode     sql.NullString
+}
+
+func (n nullableSettings) fill(ts *models.TestSettings) {
+	if n.maxAttempts.Valid {
+		v := int(n.maxAttempts.Int64)
+		ts.MaxAttempts = &v
+	}
+	if n.timeLimit.Valid {
+		v := int(n.timeLimit.Int64)
+		ts.TimeLimit = &v
+	}
+	if n.mode.Valid {
+		m := n.mode.String
+		ts.Mode = &m
+	}
+	ts.PasswordHash = n.passwordHash.String
+	ts.HasPassword = ts.PasswordHash != ""
+}
+
+func nullIfEmpty(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// CountUserTestSessions counts the sessions a user has started for a test,
+// finished or not.
+func (s *PostgresStorage) CountUserTestSessions(testID int, userID int) (int, error) {
+	var n int
+	err := s.db.QueryRow(
+		`SELECT count(*) FROM quiz_sessions WHERE test_id = $1 AND user_id = $2`,
+		testID, userID,
+	).Scan(&n)
+	return n, err
+}
+
+// ReassignUserSessions moves every quiz session of one user to another, e.g.
+// when a guest claims their sessions into a full account. It returns the
+// number of sessions moved.
+func (s *PostgresStorage) ReassignUserSessions(fromUserID int, toUserID int) (int, error) {
+	res, err := s.db.Exec(`UPDATE quiz_sessions SET user_id = $2 WHERE user_id = $1`, fromUserID, toUserID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
 func (s *PostgresStorage) DeleteTest(id int) error {
 	tx, err := s.db.Begin()
 	if err != nil {
@@ -1587,3 +2102,1050 @@ func (s *PostgresStorage) UpdateFavoriteNote(userID int, caseID int, note *strin
 	`, userID, caseID, note)
 	return err
 }
+
+func (s *PostgresStorage) GetAllDifficultySummaries() ([]models.QuestionDifficultySummary, error) {
+	rows, err := s.db.Query(`
+		SELECT question_id, total_votes, hard_votes, easy_votes, hard_pct
+		  FROM question_difficulty_summary
+		 ORDER BY question_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.QuestionDifficultySummary, 0)
+	for rows.Next() {
+		var r models.QuestionDifficultySummary
+		if err := rows.Scan(&r.QuestionID, &r.TotalVotes, &r.HardVotes, &r.EasyVotes, &r.HardPct); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+const questionReviewColumns = `id, question_id, status, reasons, note, created_at, updated_at, closed_at, closed_by`
+
+func scanQuestionReview(row interface{ Scan(...any) error }) (models.QuestionReview, error) {
+	var r models.QuestionReview
+	var reasons pq.StringArray
+	err := row.Scan(&r.ID, &r.QuestionID, &r.Status, &reasons, &r.Note, &r.CreatedAt, &r.UpdatedAt, &r.ClosedAt, &r.ClosedBy)
+	r.Reasons = []string(reasons)
+	return r, err
+}
+
+// ListQuestionReviews lists reviews, open ones first and newest first within
+// a status. An empty status lists all.
+func (s *PostgresStorage) ListQuestionReviews(status string) ([]models.QuestionReview, error) {
+	rows, err := s.db.Query(`
+		SELECT `+questionReviewColumns+`
+		  FROM question_reviews
+		 WHERE $1 = '' OR status = $1
+		 ORDER BY status = 'open' DESC, updated_at DESC, id DESC
+	`, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.QuestionReview, 0)
+	for rows.Next() {
+		r, err := scanQuestionReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// EnqueueQuestionReviews opens a review for each flagged question and returns
+// how many were opened. An open review gets its reasons refreshed. A question
+// whose latest review was closed with the same or more reasons is skipped, so
+// a dismissed flag only comes back when something new is wrong.
+func (s *PostgresStorage) EnqueueQuestionReviews(candidates []models.QuestionReviewCandidate) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	opened := 0
+	for _, c := range candidates {
+		reasons := pq.Array(c.Reasons)
+		res, err := tx.Exec(`
+			UPDATE question_reviews
+			   SET reasons = $2, updated_at = NOW()
+			 WHERE question_id = $1 AND status = 'open'
+		`, c.QuestionID, reasons)
+		if err != nil {
+			return 0, err
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			continue
+		}
+
+		var triaged bool
+		err = tx.QueryRow(`
+			SELECT COALESCE((
+				SELECT reasons @> $2
+				  FROM question_reviews
+				 WHERE question_id = $1
+				 ORDER BY id DESC
+				 LIMIT 1
+			), false)
+		`, c.QuestionID, reasons).Scan(&triaged)
+		if err != nil {
+			return 0, err
+		}
+		if triaged {
+			continue
+		}
+
+		if _, err := tx.Exec(`INSERT INTO question_reviews (question_id, reasons) VALUES ($1, $2)`, c.QuestionID, reasons); err != nil {
+			return 0, err
+		}
+		opened++
+	}
+	return opened, tx.Commit()
+}
+
+// UpdateQuestionReview changes a review's status and note. Closing records
+// who closed it; reopening clears that.
+func (s *PostgresStorage) UpdateQuestionReview(id int, update models.QuestionReviewUpdate) (models.QuestionReview, error) {
+	row := s.db.QueryRow(`
+		UPDATE question_reviews
+		   SET status = $2,
+		       note = COALESCE($3, note),
+		       updated_at = NOW(),
+		       closed_at = CASE WHEN $2 = 'open' THEN NULL ELSE NOW() END,
+		       closed_by = CASE WHEN $2 = 'open' THEN NULL ELSE $4::integer END
+		 WHERE id = $1
+		RETURNING `+questionReviewColumns,
+		id, update.Status, update.Note, update.AdminID)
+	return scanQuestionReview(row)
+}
+
+//
+// Classes
+//
+
+const classColumns = `c.id, c.name, c.owner_id, c.invite_code, c.created_at,
+	(SELECT count(*) FROM class_members m WHERE m.class_id = c.id),
+	(SELECT count(*) FROM class_assignments a WHERE a.class_id = c.id)`
+
+func scanClass(row interface{ Scan(...any) error }) (models.Class, error) {
+	var c models.Class
+	err := row.Scan(&c.ID, &c.Name, &c.OwnerID, &c.InviteCode, &c.CreatedAt, &c.Members, &c.Assignments)
+	return c, err
+}
+
+func (s *PostgresStorage) CreateClass(c models.Class) (models.Class, error) {
+	err := s.db.QueryRow(
+		`INSERT INTO classes(name, owner_id, invite_code) VALUES ($1,$2,$3) RETURNING id, created_at`,
+		c.Name, c.OwnerID, c.InviteCode,
+	).Scan(&c.ID, &c.CreatedAt)
+	return c, err
+}
+
+func (s *PostgresStorage) getClass(where string, arg any) (*models.Class, error) {
+	c, err := scanClass(s.db.QueryRow(`SELECT `+classColumns+` FROM classes c WHERE `+where, arg))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (s *PostgresStorage) GetClassByID(id int) (*models.Class, error) {
+	return s.getClass(`c.id = $1`, id)
+}
+
+func (s *PostgresStorage) GetClassByInviteCode(code string) (*models.Class, error) {
+	return s.getClass(`c.invite_code = $1`, code)
+}
+
+func (s *PostgresStorage) ListClassesByOwner(ownerID int) ([]models.Class, error) {
+	rows, err := s.db.Query(`SELECT `+classColumns+` FROM classes c WHERE c.owner_id = $1 ORDER BY c.created_at DESC, c.id DESC`, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.Class, 0)
+	for rows.Next() {
+		c, err := scanClass(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+func (s *PostgresStorage) DeleteClass(id int) error {
+	res, err := s.db.Exec(`DELETE FROM classes WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// AddClassMember is idempotent: joining twice keeps the original join time.
+func (s *PostgresStorage) AddClassMember(classID int, userID int) error {
+	_, err := s.db.Exec(
+		`INSERT INTO class_members(class_id, user_id) VALUES ($1,$2) ON CONFLICT DO NOTHING`,
+		classID, userID,
+	)
+	return err
+}
+
+func (s *PostgresStorage) RemoveClassMember(classID int, userID int) error {
+	res, err := s.db.Exec(`DELETE FROM class_members WHERE class_id = $1 AND user_id = $2`, classID, userID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (s *PostgresStorage) ListClassMembers(classID int) ([]models.ClassMember, error) {
+	rows, err := s.db.Query(
+		`SELECT user_id, joined_at FROM class_members WHERE class_id = $1 ORDER BY joined_at, user_id`,
+		classID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.ClassMember, 0)
+	for rows.Next() {
+		var m models.ClassMember
+		if err := rows.Scan(&m.UserID, &m.JoinedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// ListJoinedClasses returns the classes a user is a member of, without their
+// assignments.
+func (s *PostgresStorage) ListJoinedClasses(userID int) ([]models.JoinedClass, error) {
+	rows, err := s.db.Query(`
+		SELECT c.id, c.name, c.owner_id, m.joined_at
+		  FROM class_members m
+		  JOIN classes c ON c.id = m.class_id
+		 WHERE m.user_id = $1
+		 ORDER BY m.joined_at DESC, c.id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.JoinedClass, 0)
+	for rows.Next() {
+		var c models.JoinedClass
+		if err := rows.Scan(&c.ID, &c.Name, &c.OwnerID, &c.JoinedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// UpsertClassAssignment assigns a test to a class, or changes the due date
+// of an existing assignment.
+func (s *PostgresStorage) UpsertClassAssignment(a models.ClassAssignment) (models.ClassAssignment, error) {
+	err := s.db.QueryRow(`
+		INSERT INTO class_assignments(class_id, test_id, due_at, assigned_by)
+		VALUES ($1,$2,$3,$4)
+		ON CONFLICT (class_id, test_id) DO UPDATE SET due_at = EXCLUDED.due_at
+		RETURNING assigned_by, assigned_at,
+		          (SELECT code FROM tests WHERE id = $2), (SELECT name FROM tests WHERE id = $2)`,
+		a.ClassID, a.TestID, a.DueAt, a.AssignedBy,
+	).Scan(&a.AssignedBy, &a.AssignedAt, &a.TestCode, &a.TestName)
+	return a, err
+}
+
+func (s *PostgresStorage) DeleteClassAssignment(classID int, testID int) error {
+	res, err := s.db.Exec(`DELETE FROM class_assignments WHERE class_id = $1 AND test_id = $2`, classID, testID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (s *PostgresStorage) ListClassAssignments(classID int) ([]models.ClassAssignment, error) {
+	rows, err := s.db.Query(`
+		SELECT a.class_id, a.test_id, t.code, t.name, a.due_at, a.assigned_by, a.assigned_at
+		  FROM class_assignments a
+		  JOIN tests t ON t.id = a.test_id
+		 WHERE a.class_id = $1
+		 ORDER BY a.due_at NULLS LAST, a.assigned_at`, classID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.ClassAssignment, 0)
+	for rows.Next() {
+		var a models.ClassAssignment
+		if err := rows.Scan(&a.ClassID, &a.TestID, &a.TestCode, &a.TestName, &a.DueAt, &a.AssignedBy, &a.AssignedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// GetClassProgress summarises the sessions of class members on the class's
+// assigned tests. A userID of 0 returns every member, otherwise only that
+// user. Member/test pairs without sessions are included with no attempts.
+func (s *PostgresStorage) GetClassProgress(classID int, userID int) ([]models.ClassProgress, error) {
+	rows, err := s.db.Query(`
+		SELECT m.user_id, a.test_id, count(qs.id),
+		       min(qs.finished_at),
+		       COALESCE(bool_or(qs.id IS NOT NULL AND qs.finished_at IS NULL), false)
+		  FROM class_members m
+		  JOIN class_assignments a ON a.class_id = m.class_id
+		  LEFT JOIN quiz_sessions qs ON qs.test_id = a.test_id AND qs.user_id = m.user_id
+		 WHERE m.class_id = $1 AND ($2 = 0 OR m.user_id = $2)
+		 GROUP BY m.user_id, a.test_id`, classID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.ClassProgress, 0)
+	for rows.Next() {
+		var p models.ClassProgress
+		if err := rows.Scan(&p.UserID, &p.TestID, &p.Attempts, &p.FirstFinishedAt, &p.InProgress); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+//
+// Live rooms
+//
+
+const liveRoomColumns = `id, test_id, test_version, code, host_id, question_ids, question_seed,
+	current_index, question_started_at, created_at, ended_at`
+
+func scanLiveRoom(row interface{ Scan(...any) error }) (models.LiveRoom, error) {
+	var r models.LiveRoom
+	var questionIDs pq.Int64Array
+	if err := row.Scan(&r.ID, &r.TestID, &r.TestVersion, &r.Code, &r.HostID, &questionIDs, &r.QuestionSeed,
+		&r.CurrentIndex, &r.QuestionStartedAt, &r.CreatedAt, &r.EndedAt); err != nil {
+		return r, err
+	}
+	r.QuestionIDs = make([]int, len(questionIDs))
+	for i, id := range questionIDs {
+		r.QuestionIDs[i] = int(id)
+	}
+	r.QuestionCount = len(r.QuestionIDs)
+	return r, nil
+}
+
+func (s *PostgresStorage) CreateLiveRoom(room models.LiveRoom) (models.LiveRoom, error) {
+	return scanLiveRoom(s.db.QueryRow(`
+		INSERT INTO live_rooms(test_id, test_version, code, host_id, question_ids, question_seed)
+		VALUES ($1,$2,$3,$4,$5,$6)
+		RETURNING `+liveRoomColumns,
+		room.TestID, room.TestVersion, room.Code, room.HostID, pq.Array(room.QuestionIDs), room.QuestionSeed,
+	))
+}
+
+func (s *PostgresStorage) GetLiveRoom(id int) (*models.LiveRoom, error) {
+	r, err := scanLiveRoom(s.db.QueryRow(`SELECT `+liveRoomColumns+` FROM live_rooms WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (s *PostgresStorage) GetOpenLiveRoomByCode(code string) (*models.LiveRoom, error) {
+	r, err := scanLiveRoom(s.db.QueryRow(
+		`SELECT `+liveRoomColumns+` FROM live_rooms WHERE code = $1 AND ended_at IS NULL`, code))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// AdvanceLiveRoom moves an open room to its next question. Moving past the
+// last question leaves CurrentIndex equal to the question count; the caller
+// ends the room then. It returns sql.ErrNoRows for unknown or ended rooms.
+func (s *PostgresStorage) AdvanceLiveRoom(id int) (models.LiveRoom, error) {
+	return scanLiveRoom(s.db.QueryRow(`
+		UPDATE live_rooms
+		   SET current_index = LEAST(current_index + 1, cardinality(question_ids)),
+		       question_started_at = now()
+		 WHERE id = $1 AND ended_at IS NULL
+		RETURNING `+liveRoomColumns, id))
+}
+
+// EndLiveRoom closes a room and finishes the sessions of its participants.
+// It returns the ids of the sessions it finished.
+func (s *PostgresStorage) EndLiveRoom(id int) ([]int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE live_rooms SET ended_at = now() WHERE id = $1 AND ended_at IS NULL`, id); err != nil {
+		return nil, err
+	}
+	rows, err := tx.Query(`
+		UPDATE quiz_sessions
+		   SET status = 'finished', finished_at = now(), updated_at = now()
+		 WHERE live_room_id = $1 AND status <> 'finished'
+		RETURNING id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]int, 0)
+	for rows.Next() {
+		var sid int
+		if err := rows.Scan(&sid); err != nil {
+			return nil, err
+		}
+		ids = append(ids, sid)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ids, tx.Commit()
+}
+
+// GetLiveRoomSession returns the user's session in a live room, or nil if
+// they have not joined it.
+func (s *PostgresStorage) GetLiveRoomSession(roomID int, userID int) (*models.QuizSession, error) {
+	var id int
+	err := s.db.QueryRow(`SELECT id FROM quiz_sessions WHERE live_room_id = $1 AND user_id = $2`, roomID, userID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	session, err := s.GetQuizSessionByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (s *PostgresStorage) CountLiveRoomSessions(roomID int) (int, error) {
+	var n int
+	err := s.db.QueryRow(`SELECT count(*) FROM quiz_sessions WHERE live_room_id = $1`, roomID).Scan(&n)
+	return n, err
+}
+
+// InsertLiveAnswer records an answer; a second answer by the same session to
+// the same question violates the primary key.
+func (s *PostgresStorage) InsertLiveAnswer(a models.LiveAnswer) error {
+	_, err := s.db.Exec(
+		`INSERT INTO live_answers(room_id, question_index, session_id, answer, correct) VALUES ($1,$2,$3,$4,$5)`,
+		a.RoomID, a.QuestionIndex, a.SessionID, a.Answer, a.Correct,
+	)
+	return err
+}
+
+func (s *PostgresStorage) GetLiveDistribution(roomID int, questionIndex int) (models.LiveDistribution, error) {
+	d := models.LiveDistribution{QuestionIndex: questionIndex, Counts: make(map[string]int)}
+	rows, err := s.db.Query(`
+		SELECT answer, bool_or(correct), count(*)
+		  FROM live_answers
+		 WHERE room_id = $1 AND question_index = $2
+		 GROUP BY answer`, roomID, questionIndex)
+	if err != nil {
+		return d, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var answer string
+		var correct bool
+		var n int
+		if err := rows.Scan(&answer, &correct, &n); err != nil {
+			return d, err
+		}
+		d.Counts[answer] = n
+		d.Answered += n
+		if correct {
+			d.Correct += n
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return d, err
+	}
+	d.Participants, err = s.CountLiveRoomSessions(roomID)
+	return d, err
+}
+
+//
+// Webhooks
+//
+
+func (s *PostgresStorage) CreateWebhook(w models.Webhook) (models.Webhook, error) {
+	err := s.db.QueryRow(`
+		INSERT INTO webhooks(owner_id, url, secret, events, admin)
+		VALUES ($1,$2,$3,$4,$5)
+		RETURNING id, active, created_at`,
+		w.OwnerID, w.URL, w.Secret, pq.Array(w.Events), w.Admin,
+	).Scan(&w.ID, &w.Active, &w.CreatedAt)
+	return w, err
+}
+
+func (s *PostgresStorage) GetWebhook(id int) (*models.Webhook, error) {
+	var w models.Webhook
+	err := s.db.QueryRow(`
+		SELECT id, owner_id, url, events, admin, active, created_at
+		  FROM webhooks WHERE id = $1`, id,
+	).Scan(&w.ID, &w.OwnerID, &w.URL, pq.Array(&w.Events), &w.Admin, &w.Active, &w.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+func (s *PostgresStorage) ListWebhooksByOwner(ownerID int) ([]models.Webhook, error) {
+	rows, err := s.db.Query(`
+		SELECT id, owner_id, url, events, admin, active, created_at
+		  FROM webhooks WHERE owner_id = $1
+		 ORDER BY id`, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.Webhook, 0)
+	for rows.Next() {
+		var w models.Webhook
+		if err := rows.Scan(&w.ID, &w.OwnerID, &w.URL, pq.Array(&w.Events), &w.Admin, &w.Active, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, w)
+	}
+	return out, rows.Err()
+}
+
+func (s *PostgresStorage) DeleteWebhook(id int) error {
+	_, err := s.db.Exec(`DELETE FROM webhooks WHERE id = $1`, id)
+	return err
+}
+
+// EnqueueWebhookEvent queues a delivery of the event to every active webhook
+// subscribed to it: admin webhooks always, others only for events of a test
+// their owner can view. It returns the number of deliveries queued.
+func (s *PostgresStorage) EnqueueWebhookEvent(event string, payload []byte, testID *int) (int, error) {
+	res, err := s.db.Exec(`
+		INSERT INTO webhook_deliveries(webhook_id, event, payload)
+		SELECT w.id, $1, $2::jsonb
+		  FROM webhooks w
+		 WHERE w.active
+		   AND $1 = ANY(w.events)
+		   AND (w.admin
+		        OR EXISTS (SELECT 1 FROM tests t WHERE t.id = $3 AND t.created_by = w.owner_id)
+		        OR EXISTS (SELECT 1 FROM test_owners o WHERE o.test_id = $3 AND o.user_id = w.owner_id))`,
+		event, payload, testID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func (s *PostgresStorage) EnqueueWebhookDelivery(webhookID int, event string, payload []byte) error {
+	_, err := s.db.Exec(`INSERT INTO webhook_deliveries(webhook_id, event, payload) VALUES ($1,$2,$3)`,
+		webhookID, event, payload)
+	return err
+}
+
+// ClaimDueWebhookDeliveries returns up to limit pending deliveries that are
+// due and pushes their next attempt back by lease, so another worker does
+// not pick them up while they are being sent.
+func (s *PostgresStorage) ClaimDueWebhookDeliveries(limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	rows, err := s.db.Query(`
+		UPDATE webhook_deliveries d
+		   SET next_attempt_at = now() + make_interval(secs => $2)
+		  FROM webhooks w,
+		       (SELECT id FROM webhook_deliveries
+		         WHERE status = 'pending' AND next_attempt_at <= now()
+		         ORDER BY next_attempt_at
+		         LIMIT $1
+		         FOR UPDATE SKIP LOCKED) due
+		 WHERE d.id = due.id AND w.id = d.webhook_id
+		RETURNING d.id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.next_attempt_at,
+		          d.last_status, d.last_error, d.created_at, d.delivered_at, w.url, w.secret`,
+		limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.WebhookDelivery, 0)
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.Event, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&d.LastStatus, &d.LastError, &d.CreatedAt, &d.DeliveredAt, &d.URL, &d.Secret); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// RecordWebhookAttempt stores the outcome of one delivery attempt.
+func (s *PostgresStorage) RecordWebhookAttempt(id int64, status string, statusCode *int, lastError *string, nextAttemptAt time.Time) error {
+	_, err := s.db.Exec(`
+		UPDATE webhook_deliveries
+		   SET status = $2,
+		       attempts = attempts + 1,
+		       last_status = $3,
+		       last_error = $4,
+		       next_attempt_at = $5,
+		       delivered_at = CASE WHEN $2 = 'succeeded' THEN now() END
+		 WHERE id = $1`,
+		id, status, statusCode, lastError, nextAttemptAt)
+	return err
+}
+
+func (s *PostgresStorage) ListWebhookDeliveries(webhookID int, limit int) ([]models.WebhookDelivery, error) {
+	rows, err := s.db.Query(`
+		SELECT id, webhook_id, event, payload, status, attempts, next_attempt_at,
+		       last_status, last_error, created_at, delivered_at
+		  FROM webhook_deliveries
+		 WHERE webhook_id = $1
+		 ORDER BY created_at DESC, id DESC
+		 LIMIT $2`, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.WebhookDelivery, 0)
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.Event, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&d.LastStatus, &d.LastError, &d.CreatedAt, &d.DeliveredAt); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// MarkApprovalPending records that the user is waiting for manual approval.
+// It reports true the first time only, so the wait is announced once.
+func (s *PostgresStorage) MarkApprovalPending(userID int) (bool, error) {
+	res, err := s.db.Exec(`
+		INSERT INTO quiz_user_access(user_id, approved)
+		VALUES ($1, false)
+		ON CONFLICT (user_id) DO NOTHING`, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+//
+// LTI
+//
+
+const ltiPlatformColumns = `id, issuer, client_id, deployment_ids, auth_login_url, auth_token_url, jwks_url, created_at`
+
+func scanLTIPlatform(row interface{ Scan(...any) error }, extra ...any) (models.LTIPlatform, error) {
+	var p models.LTIPlatform
+	dest := append([]any{&p.ID, &p.Issuer, &p.ClientID, pq.Array(&p.DeploymentIDs), &p.AuthLoginURL,
+		&p.AuthTokenURL, &p.JWKSURL, &p.CreatedAt}, extra...)
+	err := row.Scan(dest...)
+	return p, err
+}
+
+func (s *PostgresStorage) CreateLTIPlatform(p models.LTIPlatform) (models.LTIPlatform, error) {
+	if p.DeploymentIDs == nil {
+		p.DeploymentIDs = []string{}
+	}
+	return scanLTIPlatform(s.db.QueryRow(`
+		INSERT INTO lti_platforms(issuer, client_id, deployment_ids, auth_login_url, auth_token_url, jwks_url)
+		VALUES ($1,$2,$3,$4,$5,$6)
+		RETURNING `+ltiPlatformColumns,
+		p.Issuer, p.ClientID, pq.Array(p.DeploymentIDs), p.AuthLoginURL, p.AuthTokenURL, p.JWKSURL,
+	))
+}
+
+func (s *PostgresStorage) ListLTIPlatforms() ([]models.LTIPlatform, error) {
+	rows, err := s.db.Query(`SELECT ` + ltiPlatformColumns + ` FROM lti_platforms ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.LTIPlatform, 0)
+	for rows.Next() {
+		p, err := scanLTIPlatform(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+func (s *PostgresStorage) GetLTIPlatform(id int) (*models.LTIPlatform, error) {
+	p, err := scanLTIPlatform(s.db.QueryRow(`SELECT `+ltiPlatformColumns+` FROM lti_platforms WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (s *PostgresStorage) DeleteLTIPlatform(id int) error {
+	res, err := s.db.Exec(`DELETE FROM lti_platforms WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CreateLTILoginState stores the state of a login initiation. Abandoned
+// states are cleared here, since nothing else reads them.
+func (s *PostgresStorage) CreateLTILoginState(state string, nonce string, platformID int) error {
+	if _, err := s.db.Exec(`DELETE FROM lti_login_states WHERE created_at < now() - interval '1 day'`); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`INSERT INTO lti_login_states(state, nonce, platform_id) VALUES ($1,$2,$3)`,
+		state, nonce, platformID)
+	return err
+}
+
+// ConsumeLTILoginState deletes the state and returns its nonce and platform.
+// Unknown states and states older than maxAge return sql.ErrNoRows.
+func (s *PostgresStorage) ConsumeLTILoginState(state string, maxAge time.Duration) (string, int, error) {
+	var nonce string
+	var platformID int
+	var fresh bool
+	err := s.db.QueryRow(`
+		DELETE FROM lti_login_states
+		 WHERE state = $1
+		RETURNING nonce, platform_id, created_at > now() - make_interval(secs => $2)`,
+		state, maxAge.Seconds(),
+	).Scan(&nonce, &platformID, &fresh)
+	if err != nil {
+		return "", 0, err
+	}
+	if !fresh {
+		return "", 0, sql.ErrNoRows
+	}
+	return nonce, platformID, nil
+}
+
+func (s *PostgresStorage) GetLTIResourceLink(platformID int, resourceLinkID string) (*models.LTIResourceLink, error) {
+	l := models.LTIResourceLink{PlatformID: platformID, ResourceLinkID: resourceLinkID}
+	err := s.db.QueryRow(`
+		SELECT deployment_id, test_id, line_item_url
+		  FROM lti_resource_links
+		 WHERE platform_id = $1 AND resource_link_id = $2`, platformID, resourceLinkID,
+	).Scan(&l.DeploymentID, &l.TestID, &l.LineItemURL)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// UpsertLTIResourceLink stores the link's test and line item. A launch
+// without a line item keeps the one stored before.
+func (s *PostgresStorage) UpsertLTIResourceLink(l models.LTIResourceLink) error {
+	_, err := s.db.Exec(`
+		INSERT INTO lti_resource_links(platform_id, resource_link_id, deployment_id, test_id, line_item_url)
+		VALUES ($1,$2,$3,$4,$5)
+		ON CONFLICT (platform_id, resource_link_id) DO UPDATE
+		   SET deployment_id = EXCLUDED.deployment_id,
+		       test_id = EXCLUDED.test_id,
+		       line_item_url = COALESCE(EXCLUDED.line_item_url, lti_resource_links.line_item_url),
+		       updated_at = now()`,
+		l.PlatformID, l.ResourceLinkID, l.DeploymentID, l.TestID, l.LineItemURL)
+	return err
+}
+
+func (s *PostgresStorage) RecordLTILaunch(platformID int, resourceLinkID string, userID int, subject string) error {
+	_, err := s.db.Exec(`
+		INSERT INTO lti_launch_users(platform_id, resource_link_id, user_id, subject)
+		VALUES ($1,$2,$3,$4)
+		ON CONFLICT (platform_id, resource_link_id, user_id) DO UPDATE
+		   SET subject = EXCLUDED.subject, launched_at = now()`,
+		platformID, resourceLinkID, userID, subject)
+	return err
+}
+
+// ReassignLTILaunches moves the launches of one user to another, when an
+// LMS identity is linked to an existing account, so that scores of its
+// links keep reaching the gradebook.
+func (s *PostgresStorage) ReassignLTILaunches(fromUserID int, toUserID int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO lti_launch_users(platform_id, resource_link_id, user_id, subject, launched_at)
+		SELECT platform_id, resource_link_id, $2, subject, launched_at
+		  FROM lti_launch_users
+		 WHERE user_id = $1
+		ON CONFLICT (platform_id, resource_link_id, user_id) DO NOTHING`, fromUserID, toUserID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM lti_launch_users WHERE user_id = $1`, fromUserID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListLTIGradeTargets returns the line items of the links to the test that
+// the user launched.
+func (s *PostgresStorage) ListLTIGradeTargets(userID int, testID int) ([]models.LTIGradeTarget, error) {
+	rows, err := s.db.Query(`
+		SELECT p.id, p.issuer, p.client_id, p.deployment_ids, p.auth_login_url, p.auth_token_url, p.jwks_url, p.created_at,
+		       l.line_item_url, u.subject
+		  FROM lti_launch_users u
+		  JOIN lti_resource_links l ON l.platform_id = u.platform_id AND l.resource_link_id = u.resource_link_id
+		  JOIN lti_platforms p ON p.id = u.platform_id
+		 WHERE u.user_id = $1 AND l.test_id = $2 AND l.line_item_url IS NOT NULL`, userID, testID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.LTIGradeTarget, 0)
+	for rows.Next() {
+		var t models.LTIGradeTarget
+		if t.Platform, err = scanLTIPlatform(rows, &t.LineItemURL, &t.Subject); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+//
+// xAPI
+//
+
+func (s *PostgresStorage) EnqueueXAPIStatement(id string, statement []byte) error {
+	_, err := s.db.Exec(`INSERT INTO xapi_statements(id, statement) VALUES ($1, $2::jsonb)`, id, string(statement))
+	return err
+}
+
+// ClaimDueXAPIStatements returns pending statements due for sending, oldest
+// first, and pushes their next attempt back by lease so that other instances
+// skip them meanwhile.
+func (s *PostgresStorage) ClaimDueXAPIStatements(limit int, lease time.Duration) ([]models.XAPIStatement, error) {
+	rows, err := s.db.Query(`
+		UPDATE xapi_statements x
+		   SET next_attempt_at = now() + make_interval(secs => $2)
+		  FROM (SELECT id FROM xapi_statements
+		         WHERE status = 'pending' AND next_attempt_at <= now()
+		         ORDER BY created_at
+		         LIMIT $1
+		         FOR UPDATE SKIP LOCKED) due
+		 WHERE x.id = due.id
+		RETURNING x.id, x.statement, x.attempts`,
+		limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.XAPIStatement, 0)
+	for rows.Next() {
+		var st models.XAPIStatement
+		if err := rows.Scan(&st.ID, &st.Statement, &st.Attempts); err != nil {
+			return nil, err
+		}
+		out = append(out, st)
+	}
+	return out, rows.Err()
+}
+
+// DeleteXAPIStatements drops statements the LRS accepted.
+func (s *PostgresStorage) DeleteXAPIStatements(ids []string) error {
+	_, err := s.db.Exec(`DELETE FROM xapi_statements WHERE id = ANY($1::uuid[])`, pq.Array(ids))
+	return err
+}
+
+// RecordXAPIFailure counts a failed attempt for each statement and schedules
+// the next one, or marks them failed when they are given up.
+func (s *PostgresStorage) RecordXAPIFailure(ids []string, lastError string, failed bool, nextAttemptAt time.Time) error {
+	_, err := s.db.Exec(`
+		UPDATE xapi_statements
+		   SET attempts = attempts + 1,
+		       last_error = $2,
+		       status = CASE WHEN $3 THEN 'failed' ELSE 'pending' END,
+		       next_attempt_at = $4
+		 WHERE id = ANY($1::uuid[])`,
+		pq.Array(ids), lastError, failed, nextAttemptAt)
+	return err
+}
+
+// GetCaseByCode returns the case without its parameters, or nil if there is
+// no case with the code.
+func (s *PostgresStorage) GetCaseByCode(code string) (*models.Case, error) {
+	var c models.Case
+	err := s.db.QueryRow(`
+		SELECT id, code, patient_gender, age1, age2
+		  FROM cases
+		 WHERE code = $1
+		 ORDER BY id
+		 LIMIT 1`, code).Scan(&c.ID, &c.Code, &c.Gender, &c.Age1, &c.Age2)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// ImportQuestions creates the questions with their options in one
+// transaction and returns their ids in order. Options are shared by text:
+// an existing option is reused, a new one is added.
+func (s *PostgresStorage) ImportQuestions(questions []models.QuestionImport) ([]int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	optionIDs := make(map[string]int)
+	ids := make([]int, 0, len(questions))
+	for _, q := range questions {
+		var id int
+		err := tx.QueryRow(`
+			INSERT INTO questions (question, prediction_age, case_id, group_number)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id`, q.Question, q.PredictionAge, q.CaseID, q.Group).Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		for _, option := range q.Options {
+			optionID, ok := optionIDs[option]
+			if !ok {
+				err := tx.QueryRow(`SELECT id FROM options WHERE option = $1 ORDER BY id LIMIT 1`, option).Scan(&optionID)
+				if err == sql.ErrNoRows {
+					err = tx.QueryRow(`INSERT INTO options (option) VALUES ($1) RETURNING id`, option).Scan(&optionID)
+				}
+				if err != nil {
+					return nil, err
+				}
+				optionIDs[option] = optionID
+			}
+			if _, err := tx.Exec(`
+				INSERT INTO question_options (question_id, option_id, is_correct)
+				VALUES ($1, $2, $3)`, id, optionID, option == q.Correct); err != nil {
+				return nil, err
+			}
+		}
+		ids = append(ids, id)
+	}
+	return ids, tx.Commit()
+}
+
+const certificateColumns = `id, code, user_id, kind, session_id, test_id, test_name, milestone,
+	recipient_name, correct, total, issued_at`
+
+func scanCertificate(row interface{ Scan(...any) error }) (models.Certificate, error) {
+	var c models.Certificate
+	err := row.Scan(&c.ID, &c.Code, &c.UserID, &c.Kind, &c.SessionID, &c.TestID, &c.TestName, &c.Milestone,
+		&c.RecipientName, &c.Correct, &c.Total, &c.IssuedAt)
+	return c, err
+}
+
+// CreateCertificate stores a certificate. It returns nil if the session or
+// the user's milestone already has one.
+func (s *PostgresStorage) CreateCertificate(c models.Certificate) (*models.Certificate, error) {
+	created, err := scanCertificate(s.db.QueryRow(`
+		INSERT INTO certificates (code, user_id, kind, session_id, test_id, test_name, milestone,
+		                          recipient_name, correct, total)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT DO NOTHING
+		RETURNING `+certificateColumns,
+		c.Code, c.UserID, c.Kind, c.SessionID, c.TestID, c.TestName, c.Milestone,
+		c.RecipientName, c.Correct, c.Total))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+func (s *PostgresStorage) GetCertificateByCode(code string) (*models.Certificate, error) {
+	c, err := scanCertificate(s.db.QueryRow(`SELECT `+certificateColumns+` FROM certificates WHERE code = $1`, code))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// ListCertificatesByUser returns the user's certificates, newest first.
+func (s *PostgresStorage) ListCertificatesByUser(userID int) ([]models.Certificate, error) {
+	rows, err := s.db.Query(`SELECT `+certificateColumns+` FROM certificates WHERE user_id = $1 ORDER BY issued_at DESC, id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.Certificate, 0)
+	for rows.Next() {
+		c, err := scanCertificate(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,273 @@
+// Package webhooks sends test events to the URLs teachers and admins
+// registered for them.
+//
+// Events are queued in the database, one delivery per webhook, and sent by a
+// background worker, so a slow or broken receiver never holds up a student.
+// Each request is a POST of the JSON envelope
+//
+//	{"event": "test.started", "created_at": "...", "data": {...}}
+//
+// with headers X-Webhook-Event, X-Webhook-Delivery, X-Webhook-Timestamp and
+// X-Webhook-Signature. The signature is "sha256=" followed by the hex
+// HMAC-SHA256, keyed with the webhook's secret, of the timestamp, a dot and
+// the body. Any 2xx response counts as delivered; anything else is retried
+// with exponential backoff, eight attempts in all. Redirects are not followed.
+//
+// Receivers must be on the public internet: the worker refuses to connect to
+// loopback, private and link-local addresses, so a webhook cannot reach the
+// other services or the cloud metadata endpoint. The delivery log keeps only
+// the status code or a fixed error, never what the receiver sent back.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+	"quiz/internal/models"
+	"quiz/internal/outbox"
+	"quiz/internal/storage"
+)
+
+const requestTimeout = 10 * time.Second
+
+// policy retries a delivery for about an hour. The lease covers a batch of
+// requests that all time out.
+var policy = outbox.Policy{
+	MaxAttempts:  8,
+	FirstBackoff: 30 * time.Second,
+	MaxBackoff:   time.Hour,
+	PollInterval: 15 * time.Second,
+	BatchSize:    20,
+	ClaimLease:   5 * time.Minute,
+}
+
+// ErrAddressNotAllowed is returned for receivers outside the public internet.
+var ErrAddressNotAllowed = errors.New("address not allowed")
+
+type Dispatcher struct {
+	store  storage.Store
+	logger *zap.Logger
+	client *http.Client
+	worker *outbox.Worker
+}
+
+func NewDispatcher(store storage.Store, logger *zap.Logger) *Dispatcher {
+	d := &Dispatcher{
+		store:  store,
+		logger: logger,
+		client: newClient(),
+	}
+	d.worker = outbox.NewWorker("webhook deliveries", policy, d.sendDue, logger)
+	return d
+}
+
+type envelope struct {
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// Emit queues the event for every webhook subscribed to it. Test events pass
+// the test's id so teachers only get events of their tests. Failures are
+// logged, never returned: a notification must not fail the request that
+// caused it.
+func (d *Dispatcher) Emit(event string, testID *int, data any) {
+	payload, err := json.Marshal(envelope{Event: event, CreatedAt: time.Now().UTC(), Data: data})
+	if err != nil {
+		d.logger.Error("failed to marshal webhook payload", zap.String("event", event), zap.Error(err))
+		return
+	}
+	n, err := d.store.EnqueueWebhookEvent(event, payload, testID)
+	if err != nil {
+		d.logger.Error("failed to queue webhook deliveries", zap.String("event", event), zap.Error(err))
+		return
+	}
+	if n > 0 {
+		d.worker.Notify()
+	}
+}
+
+// Ping queues a ping to one webhook, to check that its receiver works.
+func (d *Dispatcher) Ping(w models.Webhook) error {
+	payload, err := json.Marshal(envelope{
+		Event:     models.WebhookEventPing,
+		CreatedAt: time.Now().UTC(),
+		Data:      map[string]int{"webhook_id": w.ID},
+	})
+	if err != nil {
+		return err
+	}
+	if err := d.store.EnqueueWebhookDelivery(w.ID, models.WebhookEventPing, payload); err != nil {
+		return err
+	}
+	d.worker.Notify()
+	return nil
+}
+
+// TestStarted announces a new session of a test.
+func (d *Dispatcher) TestStarted(s models.QuizSession) {
+	if s.TestID == nil {
+		return
+	}
+	d.Emit(models.WebhookEventTestStarted, s.TestID, sessionData(s))
+}
+
+// TestFinished announces a finished session of a test.
+func (d *Dispatcher) TestFinished(s models.QuizSession) {
+	if s.TestID == nil {
+		return
+	}
+	d.Emit(models.WebhookEventTestFinished, s.TestID, sessionData(s))
+}
+
+func sessionData(s models.QuizSession) map[string]interface{} {
+	return map[string]interface{}{
+		"session_id":   s.ID,
+		"user_id":      s.UserID,
+		"test_id":      s.TestID,
+		"test_code":    s.TestCode,
+		"test_version": s.TestVersion,
+		"mode":         s.Mode,
+		"guest":        s.Guest,
+		"created_at":   s.CreatedAt,
+		"finished_at":  s.FinishedAt,
+	}
+}
+
+// Run sends due deliveries until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	d.worker.Run(ctx)
+}
+
+func (d *Dispatcher) sendDue(ctx context.Context, limit int, lease time.Duration) (int, error) {
+	due, err := d.store.ClaimDueWebhookDeliveries(limit, lease)
+	if err != nil {
+		return 0, err
+	}
+	for _, delivery := range due {
+		d.deliver(ctx, delivery)
+	}
+	return len(due), nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery models.WebhookDelivery) {
+	statusCode, err := d.send(ctx, delivery)
+	if err == nil {
+		if err := d.store.RecordWebhookAttempt(delivery.ID, models.WebhookDeliverySucceeded, &statusCode, nil, time.Now()); err != nil {
+			d.logger.Error("failed to record webhook delivery", zap.Int64("delivery_id", delivery.ID), zap.Error(err))
+		}
+		return
+	}
+
+	var code *int
+	if statusCode != 0 {
+		code = &statusCode
+	}
+	msg := err.Error()
+	status := models.WebhookDeliveryPending
+	attempts := delivery.Attempts + 1
+	if policy.Exhausted(attempts) {
+		status = models.WebhookDeliveryFailed
+	}
+	d.logger.Info("webhook delivery failed",
+		zap.Int64("delivery_id", delivery.ID), zap.Int("attempt", attempts), zap.String("error", msg))
+	if err := d.store.RecordWebhookAttempt(delivery.ID, status, code, &msg, time.Now().Add(policy.Backoff(attempts))); err != nil {
+		d.logger.Error("failed to record webhook delivery", zap.Int64("delivery_id", delivery.ID), zap.Error(err))
+	}
+}
+
+// send POSTs the delivery and returns the response status. The errors are
+// fixed messages, which the webhook's owner may see.
+func (d *Dispatcher) send(ctx context.Context, delivery models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "PrediGrowee-Webhooks/1")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", Sign(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	switch {
+	case errors.Is(err, ErrAddressNotAllowed):
+		return 0, ErrAddressNotAllowed
+	case errors.Is(err, context.DeadlineExceeded) || os.IsTimeout(err):
+		return 0, errors.New("request timed out")
+	case err != nil:
+		return 0, errors.New("could not connect")
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// newClient returns a client that only connects to public addresses, checked
+// after name resolution so that a host name cannot point inside, and does
+// not follow redirects, which could.
+func newClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: requestTimeout,
+		Control: func(network string, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip, err := netip.ParseAddr(host)
+			if err != nil || !publicAddr(ip) {
+				return ErrAddressNotAllowed
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: requestTimeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: requestTimeout,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, private in practice.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+func publicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
+
+// Sign returns the X-Webhook-Signature header for a body sent at timestamp.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,420 @@
+// Package xapi reports learning activity to a Learning Record Store as xAPI
+// statements: "attempted" when a session starts, "answered" for each answer,
+// "completed" when it finishes and "terminated" when it is left unfinished
+// and closed by the next start.
+//
+// Statements are written to an outbox table and sent by a background worker
+// in batches, so an LRS outage never holds up a student and no statement is
+// lost on restart. Failed batches are retried with exponential backoff, ten attempts
+// in all. Each statement has a fixed id, so a batch sent twice is
+// stored once by the LRS.
+//
+// Learners are identified by their PrediGrowee user id, never by name or
+// e-mail. Activity and extension IRIs live under the site's address.
+package xapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"quiz/internal/clients"
+	"quiz/internal/models"
+	"quiz/internal/outbox"
+	"quiz/internal/storage"
+)
+
+const (
+	xapiVersion = "1.0.3"
+
+	verbAttempted  = "http://adlnet.gov/expapi/verbs/attempted"
+	verbAnswered   = "http://adlnet.gov/expapi/verbs/answered"
+	verbCompleted  = "http://adlnet.gov/expapi/verbs/completed"
+	verbTerminated = "http://adlnet.gov/expapi/verbs/terminated"
+
+	activityAssessment  = "http://adlnet.gov/expapi/activities/assessment"
+	activityInteraction = "http://adlnet.gov/expapi/activities/cmi.interaction"
+
+	requestTimeout = 15 * time.Second
+	maxErrorLength = 500
+)
+
+// policy retries a batch for about three hours.
+var policy = outbox.Policy{
+	MaxAttempts:  10,
+	FirstBackoff: 30 * time.Second,
+	MaxBackoff:   time.Hour,
+	PollInterval: 15 * time.Second,
+	BatchSize:    50,
+	ClaimLease:   2 * time.Minute,
+}
+
+// Config is read from the environment by the API server. Endpoint is the
+// LRS's xAPI base URL, e.g. https://lrs.example.edu/xapi/; Username and
+// Password are its Basic credentials. HomePage is the public address of the
+// site, used for learner accounts and activity ids.
+type Config struct {
+	Endpoint string
+	Username string
+	Password string
+	HomePage string
+}
+
+// Emitter queues statements and sends them to the LRS. A nil Emitter, when
+// no LRS is configured, does nothing.
+type Emitter struct {
+	store       storage.Store
+	logger      *zap.Logger
+	statsClient *clients.StatsClient
+	cfg         Config
+	client      *http.Client
+	worker      *outbox.Worker
+}
+
+func NewEmitter(cfg Config, store storage.Store, logger *zap.Logger, statsClient *clients.StatsClient) *Emitter {
+	cfg.Endpoint = strings.TrimRight(cfg.Endpoint, "/")
+	cfg.HomePage = strings.TrimRight(cfg.HomePage, "/")
+	e := &Emitter{
+		store:       store,
+		logger:      logger,
+		statsClient: statsClient,
+		cfg:         cfg,
+		client:      &http.Client{Timeout: requestTimeout},
+	}
+	e.worker = outbox.NewWorker("xapi statements", policy, e.sendDue, logger)
+	return e
+}
+
+// Attempted records the start of a session.
+func (e *Emitter) Attempted(s models.QuizSession) {
+	if e == nil {
+		return
+	}
+	at := time.Now()
+	if s.CreatedAt != nil {
+		at = *s.CreatedAt
+	}
+	e.enqueue(e.statement(s, verbAttempted, "attempted", e.sessionActivity(s), nil, e.sessionContext(s, nil), at))
+}
+
+// Answered records an answer to a question of the session. correct is the
+// question's correct option; the case the question is about is the
+// statement's grouping activity.
+func (e *Emitter) Answered(s models.QuizSession, q models.Question, answer string, correct string, isCorrect bool, timeSpent time.Duration) {
+	if e == nil {
+		return
+	}
+	choices := make([]map[string]any, len(q.Options))
+	for i, o := range q.Options {
+		choices[i] = map[string]any{"id": o, "description": langMap(o)}
+	}
+	description := fmt.Sprintf("Case %s, prediction at age %d", q.Case.Code, q.PredictionAge)
+	object := map[string]any{
+		"objectType": "Activity",
+		"id":         e.iri("questions", strconv.Itoa(q.ID)),
+		"definition": map[string]any{
+			"type":                    activityInteraction,
+			"name":                    langMap(q.Question),
+			"description":             langMap(description),
+			"interactionType":         "choice",
+			"choices":                 choices,
+			"correctResponsesPattern": []string{correct},
+			"extensions": map[string]any{
+				e.iri("extensions", "prediction-age"): q.PredictionAge,
+				e.iri("extensions", "case-code"):      q.Case.Code,
+			},
+		},
+	}
+	result := map[string]any{
+		"response": answer,
+		"success":  isCorrect,
+		"duration": isoDuration(timeSpent),
+	}
+	caseActivity := map[string]any{
+		"objectType": "Activity",
+		"id":         e.iri("cases", strconv.Itoa(q.Case.ID)),
+		"definition": map[string]any{"name": langMap("Case " + q.Case.Code)},
+	}
+	e.enqueue(e.statement(s, verbAnswered, "answered", object, result, e.sessionContext(s, caseActivity), time.Now()))
+}
+
+// Completed records a finished session with its score, which is looked up
+// from the stats service in the background.
+func (e *Emitter) Completed(s models.QuizSession) {
+	e.ended(s, verbCompleted, "completed", map[string]any{"completion": true})
+}
+
+// Terminated records a session the student left without finishing, closed
+// when they started the next one. It carries the score so far but does not
+// claim completion.
+func (e *Emitter) Terminated(s models.QuizSession) {
+	e.ended(s, verbTerminated, "terminated", map[string]any{})
+}
+
+func (e *Emitter) ended(s models.QuizSession, verbID string, verbName string, result map[string]any) {
+	if e == nil {
+		return
+	}
+	go func() {
+		at := time.Now()
+		if s.FinishedAt != nil {
+			at = *s.FinishedAt
+		}
+		if s.CreatedAt != nil {
+			result["duration"] = isoDuration(at.Sub(*s.CreatedAt))
+		}
+		if score, ok := e.score(s); ok {
+			result["score"] = score
+		}
+		e.enqueue(e.statement(s, verbID, verbName, e.sessionActivity(s), result, e.sessionContext(s, nil), at))
+	}()
+}
+
+// score is the number of correct first answers out of the test's questions,
+// or out of the questions answered for practice sessions, which have no
+// fixed length. A failed lookup is logged and the statement sent without
+// one.
+func (e *Emitter) score(s models.QuizSession) (map[string]any, bool) {
+	answers, err := e.statsClient.GetSessionAnswers([]int{s.ID})
+	if err != nil {
+		e.logger.Error("failed to get session answers for xapi score", zap.Int("session_id", s.ID), zap.Error(err))
+		return nil, false
+	}
+	correct := 0
+	for _, a := range answers {
+		if a.Correct {
+			correct++
+		}
+	}
+	total := len(answers)
+	if s.TestID != nil {
+		total = len(s.GroupOrder)
+	}
+	if total == 0 {
+		return nil, false
+	}
+	return map[string]any{
+		"raw":    correct,
+		"min":    0,
+		"max":    total,
+		"scaled": math.Round(float64(correct)/float64(total)*10000) / 10000,
+	}, true
+}
+
+func (e *Emitter) statement(s models.QuizSession, verbID string, verbName string, object map[string]any, result map[string]any, context map[string]any, at time.Time) map[string]any {
+	st := map[string]any{
+		"id": newUUID(),
+		"actor": map[string]any{
+			"objectType": "Agent",
+			"account":    map[string]any{"homePage": e.cfg.HomePage, "name": strconv.Itoa(s.UserID)},
+		},
+		"verb":      map[string]any{"id": verbID, "display": langMap(verbName)},
+		"object":    object,
+		"context":   context,
+		"timestamp": at.UTC().Format(time.RFC3339Nano),
+	}
+	if result != nil {
+		st["result"] = result
+	}
+	return st
+}
+
+// sessionActivity is the test of the session, or the practice quiz of its
+// mode for sessions outside a test.
+func (e *Emitter) sessionActivity(s models.QuizSession) map[string]any {
+	id, name := e.iri("quiz", string(s.Mode)), "PrediGrowee "+string(s.Mode)+" quiz"
+	if s.TestCode != nil {
+		id, name = e.iri("tests", *s.TestCode), "PrediGrowee test "+*s.TestCode
+	}
+	return map[string]any{
+		"objectType": "Activity",
+		"id":         id,
+		"definition": map[string]any{"type": activityAssessment, "name": langMap(name)},
+	}
+}
+
+// sessionContext ties statements of one session together through the
+// registration, a UUID derived from the session id.
+func (e *Emitter) sessionContext(s models.QuizSession, grouping map[string]any) map[string]any {
+	extensions := map[string]any{
+		e.iri("extensions", "session-id"): s.ID,
+		e.iri("extensions", "mode"):       s.Mode,
+		e.iri("extensions", "guest"):      s.Guest,
+	}
+	if s.TestVersion != nil {
+		extensions[e.iri("extensions", "test-version")] = *s.TestVersion
+	}
+	if s.LiveRoomID != nil {
+		extensions[e.iri("extensions", "live-room-id")] = *s.LiveRoomID
+	}
+	activities := map[string]any{}
+	if grouping != nil {
+		activities["parent"] = []map[string]any{e.sessionActivity(s)}
+		activities["grouping"] = []map[string]any{grouping}
+	}
+	ctx := map[string]any{
+		"registration": sessionRegistration(s.ID),
+		"platform":     "PrediGrowee",
+		"extensions":   extensions,
+	}
+	if len(activities) > 0 {
+		ctx["contextActivities"] = activities
+	}
+	return ctx
+}
+
+func (e *Emitter) iri(parts ...string) string {
+	return e.cfg.HomePage + "/xapi/" + strings.Join(parts, "/")
+}
+
+func (e *Emitter) enqueue(statement map[string]any) {
+	payload, err := json.Marshal(statement)
+	if err != nil {
+		e.logger.Error("failed to marshal xapi statement", zap.Error(err))
+		return
+	}
+	if err := e.store.EnqueueXAPIStatement(statement["id"].(string), payload); err != nil {
+		e.logger.Error("failed to queue xapi statement", zap.Error(err))
+		return
+	}
+	e.worker.Notify()
+}
+
+// Run sends due statements until ctx is done.
+func (e *Emitter) Run(ctx context.Context) {
+	if e == nil {
+		return
+	}
+	e.worker.Run(ctx)
+}
+
+func (e *Emitter) sendDue(ctx context.Context, limit int, lease time.Duration) (int, error) {
+	due, err := e.store.ClaimDueXAPIStatements(limit, lease)
+	if err != nil || len(due) == 0 {
+		return 0, err
+	}
+	statusCode, err := e.send(ctx, due)
+	if err != nil && len(due) > 1 && statusCode == http.StatusBadRequest {
+		// one malformed statement rejects the whole batch; send them one by
+		// one so only it is held back
+		for _, st := range due {
+			_, err := e.send(ctx, []models.XAPIStatement{st})
+			e.record(ctx, []models.XAPIStatement{st}, err)
+		}
+	} else {
+		e.record(ctx, due, err)
+	}
+	return len(due), nil
+}
+
+func (e *Emitter) record(ctx context.Context, statements []models.XAPIStatement, sendErr error) {
+	ids := make([]string, len(statements))
+	// statements are rescheduled together with those that failed as often
+	byAttempts := make(map[int][]string)
+	for i, st := range statements {
+		ids[i] = st.ID
+		byAttempts[st.Attempts+1] = append(byAttempts[st.Attempts+1], st.ID)
+	}
+	if sendErr == nil {
+		if err := e.store.DeleteXAPIStatements(ids); err != nil {
+			e.logger.Error("failed to remove sent xapi statements", zap.Error(err))
+		}
+		return
+	}
+	if ctx.Err() != nil {
+		// shutting down; the claim lease expires and they are sent later
+		return
+	}
+	msg := sendErr.Error()
+	if len(msg) > maxErrorLength {
+		msg = msg[:maxErrorLength]
+	}
+	e.logger.Info("xapi statements not sent", zap.Int("count", len(ids)), zap.String("error", msg))
+	for attempts, group := range byAttempts {
+		next := time.Now().Add(policy.Backoff(attempts))
+		if err := e.store.RecordXAPIFailure(group, msg, policy.Exhausted(attempts), next); err != nil {
+			e.logger.Error("failed to record xapi failure", zap.Error(err))
+		}
+	}
+}
+
+// send POSTs the statements to the LRS and returns the response status.
+// Non-2xx responses are errors carrying the start of the response body.
+func (e *Emitter) send(ctx context.Context, statements []models.XAPIStatement) (int, error) {
+	body := make([]json.RawMessage, len(statements))
+	for i, st := range statements {
+		body[i] = st.Statement
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.cfg.Endpoint+"/statements", bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Experience-API-Version", xapiVersion)
+	if e.cfg.Username != "" {
+		req.SetBasicAuth(e.cfg.Username, e.cfg.Password)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorLength))
+		return resp.StatusCode, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	return resp.StatusCode, nil
+}
+
+func langMap(s string) map[string]string {
+	return map[string]string{"en-US": s}
+}
+
+// isoDuration formats d as an ISO 8601 duration in seconds, e.g. PT12.5S.
+func isoDuration(d time.Duration) string {
+	secs := math.Round(d.Seconds()*100) / 100
+	if secs < 0 {
+		secs = 0
+	}
+	return "PT" + strconv.FormatFloat(secs, 'f', -1, 64) + "S"
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return formatUUID(b)
+}
+
+// sessionRegistration returns a name-based (version 5) UUID for the session,
+// the same on every statement about it.
+func sessionRegistration(sessionID int) string {
+	h := sha1.Sum([]byte("predigrowee:quiz-session:" + strconv.Itoa(sessionID)))
+	var b [16]byte
+	copy(b[:], h[:16])
+	b[6] = b[6]&0x0f | 0x50
+	b[8] = b[8]&0x3f | 0x80
+	return formatUUID(b)
+}
+
+func formatUUID(b [16]byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,193 @@
+// Package xlsx writes single-sheet Office Open XML workbooks. It covers what
+// the quiz exports need: strings, numbers, booleans and date-times, with a
+// bold header row. Strings are stored inline, so there is no shared string
+// table to build.
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	styleDefault  = 0
+	styleHeader   = 1
+	styleDateTime = 2
+)
+
+// excelEpoch is day zero of the 1900 date system as Excel counts it, which
+// includes the non-existent 29 February 1900.
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// Write writes a workbook with one sheet. The first row is styled as a
+// header. Cells may be string, int, float64, bool, time.Time, *time.Time or
+// nil; nil and nil pointers produce empty cells. Times are written in UTC.
+func Write(w io.Writer, sheetName string, rows [][]any) error {
+	zw := zip.NewWriter(w)
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", contentTypes},
+		{"_rels/.rels", rootRels},
+		{"xl/workbook.xml", fmt.Sprintf(workbook, escape(sheetTitle(sheetName)))},
+		{"xl/_rels/workbook.xml.rels", workbookRels},
+		{"xl/styles.xml", styles},
+	}
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, f.content); err != nil {
+			return err
+		}
+	}
+	fw, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	if err := writeSheet(fw, rows); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func writeSheet(w io.Writer, rows [][]any) error {
+	var sb strings.Builder
+	sb.WriteString(xml.Header)
+	sb.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for r, row := range rows {
+		fmt.Fprintf(&sb, `<row r="%d">`, r+1)
+		style := styleDefault
+		if r == 0 {
+			style = styleHeader
+		}
+		for c, v := range row {
+			if err := writeCell(&sb, cellRef(c, r), v, style); err != nil {
+				return err
+			}
+		}
+		sb.WriteString(`</row>`)
+	}
+	sb.WriteString(`</sheetData></worksheet>`)
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func writeCell(sb *strings.Builder, ref string, v any, style int) error {
+	if t, ok := v.(*time.Time); ok {
+		if t == nil {
+			return nil
+		}
+		v = *t
+	}
+	switch v := v.(type) {
+	case nil:
+	case string:
+		fmt.Fprintf(sb, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, style, escape(EscapeFormula(v)))
+	case int:
+		fmt.Fprintf(sb, `<c r="%s" s="%d"><v>%d</v></c>`, ref, style, v)
+	case float64:
+		fmt.Fprintf(sb, `<c r="%s" s="%d"><v>%s</v></c>`, ref, style, strconv.FormatFloat(v, 'f', -1, 64))
+	case bool:
+		b := 0
+		if v {
+			b = 1
+		}
+		fmt.Fprintf(sb, `<c r="%s" s="%d" t="b"><v>%d</v></c>`, ref, style, b)
+	case time.Time:
+		days := v.UTC().Sub(excelEpoch).Hours() / 24
+		fmt.Fprintf(sb, `<c r="%s" s="%d"><v>%s</v></c>`, ref, styleDateTime, strconv.FormatFloat(days, 'f', -1, 64))
+	default:
+		return fmt.Errorf("xlsx: unsupported cell type %T", v)
+	}
+	return nil
+}
+
+// EscapeFormula makes a string cell that a spreadsheet would read as a
+// formula plain text by prefixing it with an apostrophe. Student names and
+// answers are untrusted, and a formula in them would run when the export is
+// opened.
+func EscapeFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// cellRef converts zero-based column and row indexes to an A1 reference.
+func cellRef(col, row int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name + strconv.Itoa(row+1)
+}
+
+// sheetTitle makes s a valid sheet name: at most 31 characters and none of
+// the characters Excel forbids.
+func sheetTitle(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, s)
+	if runes := []rune(s); len(runes) > 31 {
+		s = string(runes[:31])
+	}
+	if s == "" {
+		s = "Sheet1"
+	}
+	return s
+}
+
+func escape(s string) string {
+	var sb strings.Builder
+	// xml.EscapeText drops characters that are invalid in XML 1.0 by
+	// replacing them with U+FFFD, so the error is never non-nil here.
+	_ = xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
+
+const contentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`</Types>`
+
+const rootRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const workbook = xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+const workbookRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+	`</Relationships>`
+
+// styles defines the three cell formats used above: default, bold header and
+// date-time (custom format 164).
+const styles = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/></numFmts>` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="3">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
@@ -0,0 +1,10 @@
+-- Structured viewport dimensions next to the raw "WxH" screen_size string.
+
+ALTER TABLE public.quiz_sessions ADD COLUMN IF NOT EXISTS screen_width integer;
+ALTER TABLE public.quiz_sessions ADD COLUMN IF NOT EXISTS screen_height integer;
+
+UPDATE public.quiz_sessions
+   SET screen_width = NULLIF(split_part(screen_size, 'x', 1), '')::integer,
+       screen_height = NULLIF(split_part(screen_size, 'x', 2), '')::integer
+ WHERE screen_size ~ '^[0-9]{1,5}x[0-9]{1,5}$'
+   AND screen_width IS NULL;
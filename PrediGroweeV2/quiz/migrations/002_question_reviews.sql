@@ -0,0 +1,23 @@
+-- Review queue for questions flagged by the admin question-quality report.
+-- A question has at most one open review; closed reviews are kept so a
+-- dismissed flag is not raised again for the same reasons.
+
+CREATE TABLE IF NOT EXISTS public.question_reviews (
+    id          serial PRIMARY KEY,
+    question_id integer NOT NULL REFERENCES public.questions (id) ON DELETE CASCADE,
+    status      text NOT NULL DEFAULT 'open'
+                CHECK (status IN ('open', 'resolved', 'dismissed')),
+    reasons     text[] NOT NULL DEFAULT '{}',
+    note        text,
+    created_at  timestamptz NOT NULL DEFAULT now(),
+    updated_at  timestamptz NOT NULL DEFAULT now(),
+    closed_at   timestamptz,
+    closed_by   integer
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS question_reviews_open_idx
+    ON public.question_reviews (question_id) WHERE status = 'open';
+CREATE INDEX IF NOT EXISTS question_reviews_question_idx
+    ON public.question_reviews (question_id, id DESC);
+
+ALTER TABLE public.question_reviews OWNER TO quiz_user;
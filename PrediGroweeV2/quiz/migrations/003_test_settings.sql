@@ -0,0 +1,19 @@
+-- Scheduling, attempt limits and access control for teacher tests. Every
+-- column is optional; a test without settings behaves as before.
+
+ALTER TABLE public.tests ADD COLUMN IF NOT EXISTS opens_at timestamptz;
+ALTER TABLE public.tests ADD COLUMN IF NOT EXISTS closes_at timestamptz;
+ALTER TABLE public.tests ADD COLUMN IF NOT EXISTS max_attempts integer
+    CHECK (max_attempts > 0);
+ALTER TABLE public.tests ADD COLUMN IF NOT EXISTS password_hash text;
+ALTER TABLE public.tests ADD COLUMN IF NOT EXISTS mode public.quiz_mode
+    CHECK (mode IN ('classic', 'time_limited'));
+ALTER TABLE public.tests ADD COLUMN IF NOT EXISTS time_limit integer
+    CHECK (time_limit > 0);
+
+ALTER TABLE public.tests DROP CONSTRAINT IF EXISTS tests_schedule_check;
+ALTER TABLE public.tests ADD CONSTRAINT tests_schedule_check
+    CHECK (opens_at IS NULL OR closes_at IS NULL OR opens_at < closes_at);
+
+CREATE INDEX IF NOT EXISTS quiz_sessions_test_user_idx
+    ON public.quiz_sessions (test_id, user_id);
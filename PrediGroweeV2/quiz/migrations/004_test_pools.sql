@@ -0,0 +1,25 @@
+-- Question pools for teacher tests. A pooled test draws `draw` questions
+-- from each pool per session; the seed used is kept on the session so the
+-- draw can be reproduced. test_questions still lists every question of the
+-- test, pools in order, for reporting.
+
+CREATE TABLE IF NOT EXISTS public.test_pools (
+    id        serial PRIMARY KEY,
+    test_id   integer NOT NULL REFERENCES public.tests (id) ON DELETE CASCADE,
+    position  integer NOT NULL,
+    draw      integer NOT NULL CHECK (draw > 0),
+    stratify  boolean NOT NULL DEFAULT false,
+    UNIQUE (test_id, position)
+);
+
+CREATE TABLE IF NOT EXISTS public.test_pool_questions (
+    pool_id     integer NOT NULL REFERENCES public.test_pools (id) ON DELETE CASCADE,
+    question_id integer NOT NULL REFERENCES public.questions (id) ON DELETE CASCADE,
+    sort_order  integer NOT NULL,
+    PRIMARY KEY (pool_id, question_id)
+);
+
+ALTER TABLE public.test_pools OWNER TO quiz_user;
+ALTER TABLE public.test_pool_questions OWNER TO quiz_user;
+
+ALTER TABLE public.quiz_sessions ADD COLUMN IF NOT EXISTS question_seed bigint;
@@ -0,0 +1,49 @@
+-- Immutable versions of teacher tests. tests, test_questions and test_pools
+-- always hold the current version; every create or edit also writes a
+-- snapshot here, and sessions record the version they took.
+
+ALTER TABLE public.tests ADD COLUMN IF NOT EXISTS current_version integer NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS public.test_versions (
+    test_id       integer NOT NULL REFERENCES public.tests (id) ON DELETE CASCADE,
+    version       integer NOT NULL,
+    name          text NOT NULL,
+    question_ids  integer[] NOT NULL,
+    pools         jsonb NOT NULL DEFAULT '[]',
+    opens_at      timestamptz,
+    closes_at     timestamptz,
+    max_attempts  integer,
+    password_hash text,
+    mode          public.quiz_mode,
+    time_limit    integer,
+    created_by    integer NOT NULL,
+    created_at    timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (test_id, version)
+);
+
+ALTER TABLE public.test_versions OWNER TO quiz_user;
+
+-- Existing tests become version 1 as they are now.
+INSERT INTO public.test_versions (test_id, version, name, question_ids, pools,
+                                  opens_at, closes_at, max_attempts, password_hash,
+                                  mode, time_limit, created_by, created_at)
+SELECT t.id, 1, t.name,
+       COALESCE((SELECT array_agg(tq.question_id ORDER BY tq.sort_order)
+                   FROM public.test_questions tq WHERE tq.test_id = t.id), '{}'),
+       COALESCE((SELECT jsonb_agg(jsonb_build_object(
+                            'position', p.position,
+                            'draw', p.draw,
+                            'stratify', p.stratify,
+                            'question_ids', (SELECT COALESCE(jsonb_agg(pq.question_id ORDER BY pq.sort_order), '[]')
+                                               FROM public.test_pool_questions pq WHERE pq.pool_id = p.id))
+                            ORDER BY p.position)
+                   FROM public.test_pools p WHERE p.test_id = t.id), '[]'),
+       t.opens_at, t.closes_at, t.max_attempts, t.password_hash,
+       t.mode, t.time_limit, t.created_by, t.created_at
+  FROM public.tests t
+ON CONFLICT (test_id, version) DO NOTHING;
+
+ALTER TABLE public.quiz_sessions ADD COLUMN IF NOT EXISTS test_version integer;
+
+UPDATE public.quiz_sessions SET test_version = 1
+ WHERE test_id IS NOT NULL AND test_version IS NULL;
@@ -0,0 +1,34 @@
+-- Classes group students under a teacher. Students join with the class
+-- invite code; tests are assigned to a class with an optional due date.
+
+CREATE TABLE IF NOT EXISTS public.classes (
+    id          serial PRIMARY KEY,
+    name        text NOT NULL,
+    owner_id    integer NOT NULL,
+    invite_code text NOT NULL UNIQUE,
+    created_at  timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS classes_owner_idx ON public.classes (owner_id);
+
+CREATE TABLE IF NOT EXISTS public.class_members (
+    class_id  integer NOT NULL REFERENCES public.classes (id) ON DELETE CASCADE,
+    user_id   integer NOT NULL,
+    joined_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (class_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS class_members_user_idx ON public.class_members (user_id);
+
+CREATE TABLE IF NOT EXISTS public.class_assignments (
+    class_id    integer NOT NULL REFERENCES public.classes (id) ON DELETE CASCADE,
+    test_id     integer NOT NULL REFERENCES public.tests (id) ON DELETE CASCADE,
+    due_at      timestamptz,
+    assigned_by integer NOT NULL,
+    assigned_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (class_id, test_id)
+);
+
+ALTER TABLE public.classes OWNER TO quiz_user;
+ALTER TABLE public.class_members OWNER TO quiz_user;
+ALTER TABLE public.class_assignments OWNER TO quiz_user;
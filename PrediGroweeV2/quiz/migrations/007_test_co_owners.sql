@@ -0,0 +1,15 @@
+-- Co-owners of teacher tests. tests.created_by stays the single owner;
+-- viewers can see a test and its results, editors can also change it.
+
+CREATE TABLE IF NOT EXISTS public.test_owners (
+    test_id  integer NOT NULL REFERENCES public.tests (id) ON DELETE CASCADE,
+    user_id  integer NOT NULL,
+    role     text NOT NULL CHECK (role IN ('viewer', 'editor')),
+    added_by integer NOT NULL,
+    added_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (test_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS test_owners_user_idx ON public.test_owners (user_id);
+
+ALTER TABLE public.test_owners OWNER TO quiz_user;
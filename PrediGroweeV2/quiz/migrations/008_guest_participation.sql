@@ -0,0 +1,8 @@
+-- Guest participation: teachers opt a test in, then students can join it
+-- with a nickname instead of an account. The setting is versioned like the
+-- other test settings.
+
+ALTER TABLE public.tests ADD COLUMN IF NOT EXISTS allow_guests boolean NOT NULL DEFAULT false;
+ALTER TABLE public.test_versions ADD COLUMN IF NOT EXISTS allow_guests boolean NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS quiz_sessions_user_idx ON public.quiz_sessions (user_id);
@@ -0,0 +1,41 @@
+-- Live classroom rooms: the teacher runs a test for everyone at once and
+-- advances the questions. Every participant still gets a quiz session, so
+-- answers reach the stats service like any other; live_answers only backs
+-- the live answer distribution and guards against answering twice.
+
+CREATE TABLE IF NOT EXISTS public.live_rooms (
+    id                  serial PRIMARY KEY,
+    test_id             integer NOT NULL REFERENCES public.tests (id) ON DELETE CASCADE,
+    test_version        integer NOT NULL,
+    code                text NOT NULL,
+    host_id             integer NOT NULL,
+    question_ids        integer[] NOT NULL,
+    question_seed       bigint,
+    current_index       integer NOT NULL DEFAULT -1,
+    question_started_at timestamptz,
+    created_at          timestamptz NOT NULL DEFAULT now(),
+    ended_at            timestamptz
+);
+
+-- codes are only read out in class, so they need to be unique among open
+-- rooms only
+CREATE UNIQUE INDEX IF NOT EXISTS live_rooms_open_code_idx
+    ON public.live_rooms (code) WHERE ended_at IS NULL;
+
+ALTER TABLE public.quiz_sessions ADD COLUMN IF NOT EXISTS live_room_id integer
+    REFERENCES public.live_rooms (id) ON DELETE SET NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS quiz_sessions_live_room_user_idx
+    ON public.quiz_sessions (live_room_id, user_id) WHERE live_room_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS public.live_answers (
+    room_id        integer NOT NULL REFERENCES public.live_rooms (id) ON DELETE CASCADE,
+    question_index integer NOT NULL,
+    session_id     integer NOT NULL,
+    answer         text NOT NULL,
+    correct        boolean NOT NULL,
+    answered_at    timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (room_id, question_index, session_id)
+);
+
+ALTER TABLE public.live_rooms OWNER TO quiz_user;
+ALTER TABLE public.live_answers OWNER TO quiz_user;
@@ -0,0 +1,37 @@
+-- Webhooks notify outside tools of test events. Teachers receive events of
+-- the tests they can view; webhooks registered by an admin receive every
+-- event. Each event becomes one delivery row per webhook, retried with
+-- backoff until it succeeds or runs out of attempts.
+
+CREATE TABLE IF NOT EXISTS public.webhooks (
+    id         serial PRIMARY KEY,
+    owner_id   integer NOT NULL,
+    url        text NOT NULL,
+    secret     text NOT NULL,
+    events     text[] NOT NULL,
+    admin      boolean NOT NULL DEFAULT false,
+    active     boolean NOT NULL DEFAULT true,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS webhooks_owner_idx ON public.webhooks (owner_id);
+
+CREATE TABLE IF NOT EXISTS public.webhook_deliveries (
+    id              bigserial PRIMARY KEY,
+    webhook_id      integer NOT NULL REFERENCES public.webhooks (id) ON DELETE CASCADE,
+    event           text NOT NULL,
+    payload         jsonb NOT NULL,
+    status          text NOT NULL DEFAULT 'pending'
+                    CHECK (status IN ('pending', 'succeeded', 'failed')),
+    attempts        integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL DEFAULT now(),
+    last_status     integer,
+    last_error      text,
+    created_at      timestamptz NOT NULL DEFAULT now(),
+    delivered_at    timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_idx
+    ON public.webhook_deliveries (webhook_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx
+    ON public.webhook_deliveries (next_attempt_at) WHERE status = 'pending';
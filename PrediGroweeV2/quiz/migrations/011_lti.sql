@@ -0,0 +1,48 @@
+-- LTI 1.3: tests opened from a university LMS. An admin registers each LMS
+-- (platform) with the endpoints and client id it issued to us. Launches of
+-- a resource link are mapped to a test, and every student who launched a
+-- link is remembered so their score can be sent back to its grade column
+-- (line item) when they finish.
+
+CREATE TABLE IF NOT EXISTS public.lti_platforms (
+    id             serial PRIMARY KEY,
+    issuer         text NOT NULL,
+    client_id      text NOT NULL,
+    deployment_ids text[] NOT NULL DEFAULT '{}',
+    auth_login_url text NOT NULL,
+    auth_token_url text NOT NULL,
+    jwks_url       text NOT NULL,
+    created_at     timestamptz NOT NULL DEFAULT now(),
+    UNIQUE (issuer, client_id)
+);
+
+-- OIDC login state, consumed by the launch that follows it
+CREATE TABLE IF NOT EXISTS public.lti_login_states (
+    state       text PRIMARY KEY,
+    nonce       text NOT NULL,
+    platform_id integer NOT NULL REFERENCES public.lti_platforms (id) ON DELETE CASCADE,
+    created_at  timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS public.lti_resource_links (
+    platform_id      integer NOT NULL REFERENCES public.lti_platforms (id) ON DELETE CASCADE,
+    resource_link_id text NOT NULL,
+    deployment_id    text NOT NULL,
+    test_id          integer NOT NULL REFERENCES public.tests (id) ON DELETE CASCADE,
+    line_item_url    text,
+    updated_at       timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (platform_id, resource_link_id)
+);
+
+CREATE TABLE IF NOT EXISTS public.lti_launch_users (
+    platform_id      integer NOT NULL,
+    resource_link_id text NOT NULL,
+    user_id          integer NOT NULL,
+    subject          text NOT NULL,
+    launched_at      timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (platform_id, resource_link_id, user_id),
+    FOREIGN KEY (platform_id, resource_link_id)
+        REFERENCES public.lti_resource_links (platform_id, resource_link_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS lti_launch_users_user_idx ON public.lti_launch_users (user_id);
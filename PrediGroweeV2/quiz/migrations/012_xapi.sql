@@ -0,0 +1,17 @@
+-- Outbox of xAPI statements for the Learning Record Store. Statements are
+-- removed once the LRS accepts them; the ones it kept rejecting stay as
+-- 'failed' for inspection.
+
+CREATE TABLE IF NOT EXISTS public.xapi_statements (
+    id              uuid PRIMARY KEY,
+    statement       jsonb NOT NULL,
+    status          text NOT NULL DEFAULT 'pending'
+                    CHECK (status IN ('pending', 'failed')),
+    attempts        integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL DEFAULT now(),
+    last_error      text,
+    created_at      timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS xapi_statements_due_idx
+    ON public.xapi_statements (next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,26 @@
+-- Certificates of finished tests and of milestones such as 200 answers at
+-- 70% accuracy. The recipient's name and score are kept as issued, so a
+-- certificate verifies the same way later, whatever happens to the account
+-- or the session. A session or a milestone yields at most one certificate
+-- per user.
+
+CREATE TABLE IF NOT EXISTS public.certificates (
+    id             serial PRIMARY KEY,
+    code           text NOT NULL UNIQUE,
+    user_id        integer NOT NULL,
+    kind           text NOT NULL CHECK (kind IN ('test', 'milestone')),
+    session_id     integer UNIQUE,
+    test_id        integer,
+    test_name      text,
+    milestone      text,
+    recipient_name text NOT NULL,
+    correct        integer NOT NULL,
+    total          integer NOT NULL,
+    issued_at      timestamptz NOT NULL DEFAULT now(),
+    CHECK ((kind = 'test') = (session_id IS NOT NULL)),
+    CHECK ((kind = 'milestone') = (milestone IS NOT NULL))
+);
+
+CREATE INDEX IF NOT EXISTS certificates_user_idx ON public.certificates (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS certificates_user_milestone_idx
+    ON public.certificates (user_id, milestone) WHERE milestone IS NOT NULL;
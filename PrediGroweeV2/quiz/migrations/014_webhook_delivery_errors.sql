@@ -0,0 +1,11 @@
+-- Delivery errors used to hold the start of the receiver's response, which
+-- the webhook's owner can read; they are now fixed messages. Drop the bodies
+-- already logged.
+
+UPDATE public.webhook_deliveries
+   SET last_error = substring(last_error FROM '^unexpected status [0-9]+')
+ WHERE last_error LIKE 'unexpected status %';
+
+UPDATE public.webhook_deliveries
+   SET last_error = 'could not connect'
+ WHERE last_error IS NOT NULL AND last_error NOT LIKE 'unexpected status %';
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	_ "github.com/lib/pq"
@@ -9,13 +10,19 @@ import (
 	"os"
 	"stats/internal/api"
 	"stats/internal/clients"
+	"stats/internal/jobs"
 	"stats/internal/storage"
 
 	"time"
+	// Answer time zones are validated with time.LoadLocation; the container
+	// image has no system zoneinfo.
+	_ "time/tzdata"
 )
 
 const PingDbAttempts = 3
 
+const defaultIRTInterval = 24 * time.Hour
+
 func main() {
 	// Initialize logger
 	var err error
@@ -62,9 +69,28 @@ func main() {
 	}
 	postgresStorage := storage.NewPostgresStorage(db, logger)
 	authClient := clients.NewAuthClient("http://auth:8080/auth", logger)
-	apiServer := api.NewApiServer(":8080", postgresStorage, logger, authClient)
+	quizClient := clients.NewQuizClient("http://quiz:8080/quiz", os.Getenv("INTERNAL_API_KEY"), logger)
+	calibrator := jobs.NewIRTCalibrator(postgresStorage, logger, irtInterval(logger))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	calibrator.Start(ctx)
+	apiServer := api.NewApiServer(":8080", postgresStorage, logger, authClient, quizClient, calibrator)
 	apiServer.Run()
 }
+
+// irtInterval reads IRT_INTERVAL, e.g. "12h". "0" disables scheduled runs.
+func irtInterval(logger *zap.Logger) time.Duration {
+	v := os.Getenv("IRT_INTERVAL")
+	if v == "" {
+		return defaultIRTInterval
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		logger.Warn("invalid IRT_INTERVAL, using default", zap.String("value", v), zap.Error(err))
+		return defaultIRTInterval
+	}
+	return d
+}
 func connectToPostgres() (*sql.DB, error) {
 	//env := os.Getenv("ENV")
 	//sslMode := "require"
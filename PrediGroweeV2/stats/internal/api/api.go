@@ -9,6 +9,7 @@ import (
 	"os/signal"
 	"stats/internal/clients"
 	"stats/internal/handlers"
+	"stats/internal/jobs"
 	"stats/internal/middleware"
 	"stats/internal/storage"
 	"syscall"
@@ -18,16 +19,20 @@ import (
 type ApiServer struct {
 	addr       string
 	authClient *clients.AuthClient
+	quizClient *clients.QuizClient
 	storage    storage.Storage
 	logger     *zap.Logger
+	calibrator *jobs.IRTCalibrator
 }
 
-func NewApiServer(addr string, storage storage.Storage, logger *zap.Logger, authClient *clients.AuthClient) *ApiServer {
+func NewApiServer(addr string, storage storage.Storage, logger *zap.Logger, authClient *clients.AuthClient, quizClient *clients.QuizClient, calibrator *jobs.IRTCalibrator) *ApiServer {
 	return &ApiServer{
 		addr:       addr,
 		authClient: authClient,
+		quizClient: quizClient,
 		storage:    storage,
 		logger:     logger,
+		calibrator: calibrator,
 	}
 }
 
@@ -79,6 +84,7 @@ func (a *ApiServer) registerRoutes(mux *http.ServeMux) {
 	//internal
 	mux.HandleFunc("POST /stats/sessions/save", middleware.InternalAuth(handlers.NewQuizStatsHandler(a.storage, a.logger).SaveSession, a.logger, internalApiKey))
 	mux.HandleFunc("POST /stats/sessions/{quizSessionId}/respond", middleware.InternalAuth(handlers.NewQuizStatsHandler(a.storage, a.logger).SaveResponse, a.logger, internalApiKey))
+	mux.HandleFunc("POST /stats/sessions/reassign", middleware.InternalAuth(handlers.NewQuizStatsHandler(a.storage, a.logger).ReassignSessions, a.logger, internalApiKey))
 	mux.HandleFunc("POST /stats/sessions/{quizSessionId}/finish", middleware.InternalAuth(handlers.NewQuizStatsHandler(a.storage, a.logger).FinishSession, a.logger, internalApiKey))
 	// admin
 	allStatsHandler := handlers.NewGetAllStatsHandler(a.storage, a.logger)
@@ -87,22 +93,55 @@ func (a *ApiServer) registerRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /stats/responses", middleware.InternalAuth(allStatsHandler.GetResponses, a.logger, internalApiKey))
 	mux.HandleFunc("GET /stats/questions/{id}/stats", middleware.InternalAuth(allStatsHandler.GetStatsForQuestion, a.logger, internalApiKey))
 	mux.HandleFunc("GET /stats/activity", middleware.InternalAuth(allStatsHandler.GetActivity, a.logger, internalApiKey))
+	activityHandler := handlers.NewActivityHandler(a.storage, a.logger)
+	mux.HandleFunc("GET /stats/activity/heatmap", middleware.InternalAuth(activityHandler.GetHeatmap, a.logger, internalApiKey))
+	mux.HandleFunc("GET /stats/activity/countries", middleware.InternalAuth(activityHandler.GetByCountry, a.logger, internalApiKey))
+	deviceHandler := handlers.NewDeviceHandler(a.storage, a.logger)
+	mux.HandleFunc("GET /stats/devices", middleware.InternalAuth(deviceHandler.GetByClass, a.logger, internalApiKey))
+	mux.HandleFunc("GET /stats/devices/resolutions", middleware.InternalAuth(deviceHandler.GetByResolution, a.logger, internalApiKey))
 	mux.HandleFunc("GET /stats/summary", middleware.InternalAuth(allStatsHandler.GetSummary, a.logger, internalApiKey))
 	mux.HandleFunc("GET /stats/surveys/users/{id}", middleware.InternalAuth(handlers.NewSurveysHandler(a.storage, a.logger).GetSurvey, a.logger, internalApiKey))
 	mux.HandleFunc("GET /stats/grouped", middleware.InternalAuth(allStatsHandler.GetStatsGroupedBySurvey, a.logger, internalApiKey))
 	mux.HandleFunc("DELETE /stats/users/{id}/responses", middleware.InternalAuth(userStatsHandler.DeleteUserResponses, a.logger, internalApiKey))
 	mux.HandleFunc("DELETE /stats/responses/{id}", middleware.InternalAuth(allStatsHandler.DeleteResponse, a.logger, internalApiKey))
 	mux.HandleFunc("GET /stats/users/stats", middleware.InternalAuth(userStatsHandler.GetAllUsersStats, a.logger, internalApiKey))
+	researchHandler := handlers.NewResearchHandler(a.storage, a.logger, os.Getenv("RESEARCH_PSEUDONYM_KEY"))
+	mux.HandleFunc("GET /stats/research/rows", middleware.InternalAuth(researchHandler.GetRows, a.logger, internalApiKey))
+	timingHandler := handlers.NewTimingHandler(a.storage, a.logger)
+	mux.HandleFunc("GET /stats/timing/questions", middleware.InternalAuth(timingHandler.GetQuestions, a.logger, internalApiKey))
+	mux.HandleFunc("GET /stats/timing/users", middleware.InternalAuth(timingHandler.GetUsers, a.logger, internalApiKey))
+	mux.HandleFunc("GET /stats/timing/buckets", middleware.InternalAuth(timingHandler.GetBuckets, a.logger, internalApiKey))
+	mux.HandleFunc("GET /stats/flags", middleware.InternalAuth(timingHandler.GetFlags, a.logger, internalApiKey))
+	exclusionsHandler := handlers.NewExclusionsHandler(a.storage, a.logger)
+	mux.HandleFunc("GET /stats/exclusions", middleware.InternalAuth(exclusionsHandler.List, a.logger, internalApiKey))
+	mux.HandleFunc("POST /stats/exclusions", middleware.InternalAuth(exclusionsHandler.Create, a.logger, internalApiKey))
+	mux.HandleFunc("DELETE /stats/exclusions/{id}", middleware.InternalAuth(exclusionsHandler.Delete, a.logger, internalApiKey))
+	mux.HandleFunc("POST /stats/sessions/{quizSessionId}/exclude", middleware.InternalAuth(exclusionsHandler.ExcludeSession, a.logger, internalApiKey))
+	mux.HandleFunc("DELETE /stats/sessions/{quizSessionId}/exclude", middleware.InternalAuth(exclusionsHandler.RestoreSession, a.logger, internalApiKey))
+	mux.HandleFunc("GET /stats/participation", middleware.InternalAuth(handlers.NewParticipationHandler(a.storage, a.logger).Get, a.logger, internalApiKey))
+	irtHandler := handlers.NewIRTHandler(a.storage, a.logger, a.calibrator)
+	mux.HandleFunc("POST /stats/irt/runs", middleware.InternalAuth(irtHandler.TriggerRun, a.logger, internalApiKey))
+	mux.HandleFunc("GET /stats/irt/runs/latest", middleware.InternalAuth(irtHandler.GetLatestRun, a.logger, internalApiKey))
+	mux.HandleFunc("GET /stats/irt/questions", middleware.InternalAuth(irtHandler.GetQuestions, a.logger, internalApiKey))
+	mux.HandleFunc("GET /stats/irt/questions/{id}", middleware.InternalAuth(irtHandler.GetQuestion, a.logger, internalApiKey))
+	mux.HandleFunc("GET /stats/irt/users", middleware.InternalAuth(irtHandler.GetUsers, a.logger, internalApiKey))
+	mux.HandleFunc("GET /stats/irt/users/{id}", middleware.InternalAuth(irtHandler.GetUser, a.logger, internalApiKey))
+	mux.HandleFunc("GET /stats/questions/discrimination", middleware.InternalAuth(irtHandler.GetDiscrimination, a.logger, internalApiKey))
 
 	//external
 	mux.HandleFunc("GET /stats/userStats", middleware.VerifyToken(handlers.NewUserStatsHandler(a.storage, a.logger).Handle, a.authClient))
+	mux.HandleFunc("GET /stats/userStats/progress", middleware.VerifyToken(handlers.NewProgressHandler(a.storage, a.logger, a.quizClient).Handle, a.authClient))
 	mux.HandleFunc("GET /stats/quiz/{quizSessionId}", middleware.VerifyToken(handlers.NewQuizStatsHandler(a.storage, a.logger).GetStats, a.authClient))
 	mux.HandleFunc("GET /stats/sessions", middleware.VerifyToken(handlers.NewUserStatsHandler(a.storage, a.logger).GetUserSessions, a.authClient))
 	mux.HandleFunc("POST /stats/survey", middleware.VerifyToken(handlers.NewSurveysHandler(a.storage, a.logger).Save, a.authClient))
 	mux.HandleFunc("GET /stats/survey", middleware.VerifyToken(handlers.NewSurveysHandler(a.storage, a.logger).GetSurvey, a.authClient))
 
 	leaderboardHandler := handlers.NewLeaderboardHandler(a.storage, a.logger)
-    mux.HandleFunc("GET /stats/leaderboard", leaderboardHandler.Get)
+	mux.HandleFunc("GET /stats/leaderboard", leaderboardHandler.Get)
+	mux.HandleFunc("GET /stats/leaderboard/me", middleware.VerifyToken(leaderboardHandler.GetMe, a.authClient))
+	mux.HandleFunc("PUT /stats/leaderboard/me", middleware.VerifyToken(leaderboardHandler.OptIn, a.authClient))
+	mux.HandleFunc("DELETE /stats/leaderboard/me", middleware.VerifyToken(leaderboardHandler.OptOut, a.authClient))
 	
 	mux.HandleFunc("GET /stats/sessions/accuracy", middleware.InternalAuth(handlers.NewSessionsAccuracyHandler(a.storage, a.logger).Handle,a.logger,internalApiKey,),)
+	mux.HandleFunc("GET /stats/sessions/answers", middleware.InternalAuth(handlers.NewSessionsAccuracyHandler(a.storage, a.logger).Answers, a.logger, internalApiKey))
 }
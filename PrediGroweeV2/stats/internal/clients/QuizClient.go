@@ -0,0 +1,49 @@
+package clients
+
+import (
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"net/http"
+	"stats/internal/models"
+)
+
+type QuizClient struct {
+	addr   string
+	apiKey string
+	logger *zap.Logger
+}
+
+func NewQuizClient(addr string, apiKey string, logger *zap.Logger) *QuizClient {
+	return &QuizClient{
+		addr:   addr,
+		apiKey: apiKey,
+		logger: logger,
+	}
+}
+
+func (c *QuizClient) GetQuestionsMeta() ([]models.QuestionMeta, error) {
+	req, err := http.NewRequest("GET", c.addr+"/questions/meta", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-Api-Key", c.apiKey)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		c.logger.Error("unexpected status code", zap.Int("status_code", resp.StatusCode))
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var meta []models.QuestionMeta
+	if err := json.NewDecoder(resp.Body).Decode(&meta); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return meta, nil
+}
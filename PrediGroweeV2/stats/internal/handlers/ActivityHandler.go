@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"stats/internal/models"
+	"stats/internal/storage"
+)
+
+// localTimeZone asks for each answer to be placed in the respondent's own
+// time zone instead of a single requested one.
+const localTimeZone = "local"
+
+type ActivityHandler struct {
+	storage storage.Storage
+	logger  *zap.Logger
+}
+
+func NewActivityHandler(s storage.Storage, l *zap.Logger) *ActivityHandler {
+	return &ActivityHandler{storage: s, logger: l}
+}
+
+// GET /stats/activity/heatmap?tz=Europe/Warsaw|local&country=
+func (h *ActivityHandler) GetHeatmap(w http.ResponseWriter, r *http.Request) {
+	tz, ok := timeZoneParam(r, true)
+	if !ok {
+		http.Error(w, "invalid tz", http.StatusBadRequest)
+		return
+	}
+	country := strings.TrimSpace(r.URL.Query().Get("country"))
+	cells, err := h.storage.GetActivityHeatmap(tz, country)
+	if err != nil {
+		h.logger.Error("failed to get activity heatmap", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, h.logger, http.StatusOK, models.ActivityHeatmap{TimeZone: tz, Country: country, Cells: denseHeatmap(cells)})
+}
+
+// GET /stats/activity/countries?tz=Europe/Warsaw|local
+func (h *ActivityHandler) GetByCountry(w http.ResponseWriter, r *http.Request) {
+	tz, ok := timeZoneParam(r, true)
+	if !ok {
+		http.Error(w, "invalid tz", http.StatusBadRequest)
+		return
+	}
+	countries, err := h.storage.GetActivityByCountry(tz)
+	if err != nil {
+		h.logger.Error("failed to get activity by country", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, h.logger, http.StatusOK, countries)
+}
+
+// denseHeatmap returns all 7×24 cells, Monday 00:00 first, so clients do not
+// have to fill the gaps.
+func denseHeatmap(cells []models.ActivityHeatmapCell) []models.ActivityHeatmapCell {
+	out := make([]models.ActivityHeatmapCell, 7*24)
+	for d := 0; d < 7; d++ {
+		for hr := 0; hr < 24; hr++ {
+			out[d*24+hr] = models.ActivityHeatmapCell{Weekday: d + 1, Hour: hr}
+		}
+	}
+	for _, c := range cells {
+		if c.Weekday < 1 || c.Weekday > 7 || c.Hour < 0 || c.Hour > 23 {
+			continue
+		}
+		out[(c.Weekday-1)*24+c.Hour] = c
+	}
+	return out
+}
+
+// timeZoneParam reads the tz query parameter, defaulting to UTC. Zones are
+// checked here because Postgres rejects unknown names with a query error.
+func timeZoneParam(r *http.Request, allowLocal bool) (string, bool) {
+	tz := strings.TrimSpace(r.URL.Query().Get("tz"))
+	switch {
+	case tz == "":
+		return "UTC", true
+	case tz == localTimeZone:
+		return tz, allowLocal
+	}
+	return tz, isValidTimeZone(tz)
+}
+
+func isValidTimeZone(name string) bool {
+	if name == "" || strings.EqualFold(name, "local") {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
@@ -76,8 +76,14 @@ func (h *GetAllStatsHandler) GetStatsForQuestion(w http.ResponseWriter, r *http.
 	}
 }
 
+// GET /stats/activity?tz=Europe/Warsaw
 func (h *GetAllStatsHandler) GetActivity(w http.ResponseWriter, r *http.Request) {
-	stats, err := h.storage.GetActivityStats()
+	tz, ok := timeZoneParam(r, false)
+	if !ok {
+		http.Error(w, "invalid tz", http.StatusBadRequest)
+		return
+	}
+	stats, err := h.storage.GetActivityStats(tz)
 	if err != nil {
 		h.logger.Error("failed to get stats", zap.Error(err))
 		http.Error(w, "internal server error", http.StatusInternalServerError)
@@ -157,3 +163,12 @@ func (h *GetAllStatsHandler) DeleteResponse(w http.ResponseWriter, r *http.Reque
 		return
 	}
 }
+
+// writeJSON sends v as the JSON body of a response with the given status.
+func writeJSON(w http.ResponseWriter, logger *zap.Logger, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Error("failed to encode response", zap.Error(err))
+	}
+}
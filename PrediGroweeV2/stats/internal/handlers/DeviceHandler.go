@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"go.uber.org/zap"
+	"stats/internal/storage"
+)
+
+const defaultResolutionMinAnswers = 20
+
+type DeviceHandler struct {
+	storage storage.Storage
+	logger  *zap.Logger
+}
+
+func NewDeviceHandler(s storage.Storage, l *zap.Logger) *DeviceHandler {
+	return &DeviceHandler{storage: s, logger: l}
+}
+
+// GET /stats/devices
+func (h *DeviceHandler) GetByClass(w http.ResponseWriter, _ *http.Request) {
+	stats, err := h.storage.GetDeviceStats()
+	if err != nil {
+		h.logger.Error("failed to get device stats", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, h.logger, http.StatusOK, stats)
+}
+
+// GET /stats/devices/resolutions?minAnswers=20
+func (h *DeviceHandler) GetByResolution(w http.ResponseWriter, r *http.Request) {
+	minAnswers := defaultResolutionMinAnswers
+	if v := r.URL.Query().Get("minAnswers"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "invalid minAnswers", http.StatusBadRequest)
+			return
+		}
+		minAnswers = n
+	}
+	stats, err := h.storage.GetResolutionStats(minAnswers)
+	if err != nil {
+		h.logger.Error("failed to get resolution stats", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, h.logger, http.StatusOK, stats)
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"stats/internal/models"
+	"stats/internal/storage"
+)
+
+type ExclusionsHandler struct {
+	storage storage.Storage
+	logger  *zap.Logger
+}
+
+func NewExclusionsHandler(s storage.Storage, l *zap.Logger) *ExclusionsHandler {
+	return &ExclusionsHandler{storage: s, logger: l}
+}
+
+// GET /stats/exclusions?scope=response|session|user
+func (h *ExclusionsHandler) List(w http.ResponseWriter, r *http.Request) {
+	scope := r.URL.Query().Get("scope")
+	if scope != "" && !models.IsValidExclusionScope(scope) {
+		http.Error(w, "invalid scope", http.StatusBadRequest)
+		return
+	}
+	exclusions, err := h.storage.GetExclusions(scope)
+	if err != nil {
+		h.logger.Error("failed to get exclusions", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(exclusions); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+	}
+}
+
+// POST /stats/exclusions
+func (h *ExclusionsHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var exclusion models.Exclusion
+	if err := exclusion.FromJSON(r.Body); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	h.create(w, &exclusion)
+}
+
+// POST /stats/sessions/{quizSessionId}/exclude
+func (h *ExclusionsHandler) ExcludeSession(w http.ResponseWriter, r *http.Request) {
+	sessionID, err := strconv.Atoi(r.PathValue("quizSessionId"))
+	if err != nil {
+		http.Error(w, "invalid session id", http.StatusBadRequest)
+		return
+	}
+	var exclusion models.Exclusion
+	if err := exclusion.FromJSON(r.Body); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	exclusion.Scope = models.ExclusionScopeSession
+	exclusion.TargetID = sessionID
+	h.create(w, &exclusion)
+}
+
+// DELETE /stats/exclusions/{id}
+func (h *ExclusionsHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid exclusion id", http.StatusBadRequest)
+		return
+	}
+	if err := h.storage.DeleteExclusion(id); err != nil {
+		if errors.Is(err, storage.ErrExclusionNotFound) {
+			http.Error(w, "exclusion not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to delete exclusion", zap.Int("id", id), zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DELETE /stats/sessions/{quizSessionId}/exclude
+func (h *ExclusionsHandler) RestoreSession(w http.ResponseWriter, r *http.Request) {
+	sessionID, err := strconv.Atoi(r.PathValue("quizSessionId"))
+	if err != nil {
+		http.Error(w, "invalid session id", http.StatusBadRequest)
+		return
+	}
+	if err := h.storage.DeleteExclusionForTarget(models.ExclusionScopeSession, sessionID); err != nil {
+		h.logger.Error("failed to restore session", zap.Int("session_id", sessionID), zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ExclusionsHandler) create(w http.ResponseWriter, exclusion *models.Exclusion) {
+	exclusion.Reason = strings.TrimSpace(exclusion.Reason)
+	switch {
+	case !models.IsValidExclusionScope(exclusion.Scope):
+		http.Error(w, "scope must be response, session or user", http.StatusBadRequest)
+		return
+	case exclusion.TargetID <= 0:
+		http.Error(w, "target_id is required", http.StatusBadRequest)
+		return
+	case exclusion.Reason == "":
+		http.Error(w, "reason is required", http.StatusBadRequest)
+		return
+	case exclusion.ExcludedBy == 0:
+		http.Error(w, "excluded_by is required", http.StatusBadRequest)
+		return
+	}
+	if err := h.storage.CreateExclusion(exclusion); err != nil {
+		if errors.Is(err, storage.ErrExclusionTargetNotFound) {
+			http.Error(w, "target not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to create exclusion", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(exclusion); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+	}
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go.uber.org/zap"
+	"stats/internal/jobs"
+	"stats/internal/storage"
+)
+
+type IRTHandler struct {
+	storage    storage.Storage
+	logger     *zap.Logger
+	calibrator *jobs.IRTCalibrator
+}
+
+func NewIRTHandler(s storage.Storage, l *zap.Logger, calibrator *jobs.IRTCalibrator) *IRTHandler {
+	return &IRTHandler{storage: s, logger: l, calibrator: calibrator}
+}
+
+// POST /stats/irt/runs
+func (h *IRTHandler) TriggerRun(w http.ResponseWriter, _ *http.Request) {
+	run, err := h.calibrator.Trigger()
+	if errors.Is(err, jobs.ErrRunInProgress) {
+		http.Error(w, "calibration already running", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to start irt calibration", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, h.logger, http.StatusAccepted, run)
+}
+
+// GET /stats/irt/runs/latest
+func (h *IRTHandler) GetLatestRun(w http.ResponseWriter, _ *http.Request) {
+	run, err := h.storage.GetLatestIRTRun()
+	if errors.Is(err, storage.ErrIRTNotFound) {
+		http.Error(w, "no calibration runs", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to get latest irt run", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, h.logger, http.StatusOK, run)
+}
+
+// GET /stats/irt/questions
+func (h *IRTHandler) GetQuestions(w http.ResponseWriter, _ *http.Request) {
+	items, err := h.storage.GetIRTItemParams()
+	if err != nil {
+		h.logger.Error("failed to get irt item parameters", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, h.logger, http.StatusOK, items)
+}
+
+// GET /stats/irt/questions/{id}
+func (h *IRTHandler) GetQuestion(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid question id", http.StatusBadRequest)
+		return
+	}
+	item, err := h.storage.GetIRTItemParamsForQuestion(id)
+	if errors.Is(err, storage.ErrIRTNotFound) {
+		http.Error(w, "question not calibrated", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to get irt item parameters", zap.Int("question_id", id), zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, h.logger, http.StatusOK, item)
+}
+
+// GET /stats/irt/users
+func (h *IRTHandler) GetUsers(w http.ResponseWriter, _ *http.Request) {
+	abilities, err := h.storage.GetIRTAbilities()
+	if err != nil {
+		h.logger.Error("failed to get irt abilities", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, h.logger, http.StatusOK, abilities)
+}
+
+// GET /stats/irt/users/{id}
+func (h *IRTHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+	ability, err := h.storage.GetIRTUserAbility(id)
+	if errors.Is(err, storage.ErrIRTNotFound) {
+		http.Error(w, "user not calibrated", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to get irt ability", zap.Int("user_id", id), zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, h.logger, http.StatusOK, ability)
+}
+
+// GET /stats/questions/discrimination?minAnswers=5
+func (h *IRTHandler) GetDiscrimination(w http.ResponseWriter, r *http.Request) {
+	minAnswers := defaultMinAnswers
+	if v := r.URL.Query().Get("minAnswers"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 2 {
+			http.Error(w, "invalid minAnswers", http.StatusBadRequest)
+			return
+		}
+		minAnswers = n
+	}
+	stats, err := h.storage.GetQuestionDiscrimination(minAnswers)
+	if err != nil {
+		h.logger.Error("failed to get question discrimination", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, h.logger, http.StatusOK, stats)
+}
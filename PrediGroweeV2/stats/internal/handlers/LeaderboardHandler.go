@@ -2,13 +2,25 @@ package handlers
 
 import (
 	"encoding/json"
+	"math"
 	"net/http"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/lib/pq"
 	"go.uber.org/zap"
+	"stats/internal/models"
 	"stats/internal/storage"
 )
 
+// wilsonZ is the z-score for a 95% confidence interval.
+const wilsonZ = 1.96
+
+var displayNameRe = regexp.MustCompile(`^[\p{L}\p{N} _.\-]{3,32}$`)
+
 type LeaderboardHandler struct {
 	storage storage.Storage
 	logger  *zap.Logger
@@ -18,6 +30,7 @@ func NewLeaderboardHandler(s storage.Storage, l *zap.Logger) *LeaderboardHandler
 	return &LeaderboardHandler{storage: s, logger: l}
 }
 
+// GET /stats/leaderboard?scope=global|country|test&country=&testCode=&window=all|week|month
 func (h *LeaderboardHandler) Get(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
 
@@ -34,13 +47,147 @@ func (h *LeaderboardHandler) Get(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	rows, err := h.storage.GetLeaderboard(minAnswers, limit)
+	var filter models.LeaderboardFilter
+	switch scope := q.Get("scope"); scope {
+	case "", "global":
+	case "country":
+		filter.Country = strings.TrimSpace(q.Get("country"))
+		if filter.Country == "" {
+			http.Error(w, "country is required for country scope", http.StatusBadRequest)
+			return
+		}
+	case "test":
+		filter.TestCode = strings.ToUpper(strings.TrimSpace(q.Get("testCode")))
+		if filter.TestCode == "" {
+			http.Error(w, "testCode is required for test scope", http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "invalid scope", http.StatusBadRequest)
+		return
+	}
+	since, ok := windowStart(q.Get("window"), time.Now().UTC())
+	if !ok {
+		http.Error(w, "invalid window", http.StatusBadRequest)
+		return
+	}
+	filter.Since = since
+
+	rows, err := h.storage.GetLeaderboard(filter)
 	if err != nil {
 		h.logger.Error("get leaderboard failed", zap.Error(err))
 		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(rows)
+	_ = json.NewEncoder(w).Encode(rankLeaderboard(rows, minAnswers, limit))
+}
+
+// GET /stats/leaderboard/me
+func (h *LeaderboardHandler) GetMe(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	p, err := h.storage.GetLeaderboardParticipant(userID)
+	if err != nil {
+		h.logger.Error("get leaderboard participant failed", zap.Error(err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"opted_in":    p != nil,
+		"participant": p,
+	})
 }
 
+// PUT /stats/leaderboard/me
+func (h *LeaderboardHandler) OptIn(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	var req models.LeaderboardParticipant
+	if err := req.FromJSON(r.Body); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	name := strings.TrimSpace(req.DisplayName)
+	if !displayNameRe.MatchString(name) {
+		http.Error(w, "display name must be 3-32 letters, digits, spaces or . _ -", http.StatusBadRequest)
+		return
+	}
+	if err := h.storage.UpsertLeaderboardParticipant(userID, name); err != nil {
+		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
+			http.Error(w, "display name already taken", http.StatusConflict)
+			return
+		}
+		h.logger.Error("leaderboard opt-in failed", zap.Error(err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DELETE /stats/leaderboard/me
+func (h *LeaderboardHandler) OptOut(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	if err := h.storage.DeleteLeaderboardParticipant(userID); err != nil {
+		h.logger.Error("leaderboard opt-out failed", zap.Error(err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// windowStart returns the beginning of the current calendar week (Monday) or
+// month in UTC. An empty or "all" window means no lower bound.
+func windowStart(window string, now time.Time) (*time.Time, bool) {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch window {
+	case "", "all":
+		return nil, true
+	case "week":
+		offset := (int(day.Weekday()) + 6) % 7
+		start := day.AddDate(0, 0, -offset)
+		return &start, true
+	case "month":
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return &start, true
+	}
+	return nil, false
+}
+
+// wilsonLowerBound is the lower end of the Wilson score interval for a
+// binomial proportion. It favours users whose accuracy is backed by more
+// answers, so 9/10 does not outrank 180/200.
+func wilsonLowerBound(correct, total int, z float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	n := float64(total)
+	p := float64(correct) / n
+	z2 := z * z
+	centre := p + z2/(2*n)
+	margin := z * math.Sqrt((p*(1-p)+z2/(4*n))/n)
+	return (centre - margin) / (1 + z2/n)
+}
+
+func rankLeaderboard(rows []models.LeaderboardRow, minAnswers, limit int) []models.LeaderboardRow {
+	out := make([]models.LeaderboardRow, 0, len(rows))
+	for _, r := range rows {
+		if r.TotalAnswers < minAnswers {
+			continue
+		}
+		r.Score = wilsonLowerBound(r.CorrectAnswers, r.TotalAnswers, wilsonZ)
+		out = append(out, r)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].TotalAnswers > out[j].TotalAnswers
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	for i := range out {
+		out[i].Rank = i + 1
+	}
+	return out
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+	"stats/internal/models"
+	"stats/internal/storage"
+)
+
+type ParticipationHandler struct {
+	storage storage.Storage
+	logger  *zap.Logger
+}
+
+func NewParticipationHandler(s storage.Storage, l *zap.Logger) *ParticipationHandler {
+	return &ParticipationHandler{storage: s, logger: l}
+}
+
+// GET /stats/participation
+func (h *ParticipationHandler) Get(w http.ResponseWriter, _ *http.Request) {
+	users, err := h.storage.GetParticipation()
+	if err != nil {
+		h.logger.Error("failed to get participation", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	excluded, err := h.storage.GetExcludedUserIDs()
+	if err != nil {
+		h.logger.Error("failed to get excluded users", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(models.ParticipationReport{Users: users, ExcludedUserIDs: excluded}); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+	}
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+	"stats/internal/clients"
+	"stats/internal/models"
+	"stats/internal/storage"
+)
+
+const (
+	// progressRollingWindow is the number of most recent answers the rolling
+	// accuracy of each timeline point is computed over.
+	progressRollingWindow = 20
+	// progressPopulationMinAnswers keeps casual users with a handful of answers
+	// out of the reference population.
+	progressPopulationMinAnswers = 20
+)
+
+type ProgressHandler struct {
+	storage    storage.Storage
+	logger     *zap.Logger
+	quizClient *clients.QuizClient
+}
+
+func NewProgressHandler(s storage.Storage, l *zap.Logger, quizClient *clients.QuizClient) *ProgressHandler {
+	return &ProgressHandler{storage: s, logger: l, quizClient: quizClient}
+}
+
+// GET /stats/userStats/progress
+func (h *ProgressHandler) Handle(rw http.ResponseWriter, r *http.Request) {
+	userID, statusOverride, err := resolveTargetUser(r, readRole(r))
+	if err != nil {
+		code := statusOverride
+		if code == 0 {
+			code = http.StatusInternalServerError
+		}
+		http.Error(rw, err.Error(), code)
+		return
+	}
+
+	history, err := h.storage.GetUserAnswerHistory(userID)
+	if err != nil {
+		h.logger.Error("failed to get answer history", zap.Int("user_id", userID), zap.Error(err))
+		http.Error(rw, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Question metadata only enriches the breakdowns, so the curve is still
+	// served when the quiz service is unavailable.
+	metaByID := make(map[int]models.QuestionMeta)
+	if meta, err := h.quizClient.GetQuestionsMeta(); err != nil {
+		h.logger.Warn("failed to get questions meta", zap.Error(err))
+	} else {
+		for _, m := range meta {
+			metaByID[m.QuestionID] = m
+		}
+	}
+
+	progress := buildProgress(history, metaByID)
+
+	if progress.TotalAnswers > 0 {
+		population, err := h.storage.GetUsersAccuracy(progressPopulationMinAnswers)
+		if err != nil {
+			h.logger.Error("failed to get population accuracy", zap.Error(err))
+		} else if len(population) > 0 {
+			progress.Population = &models.PopulationComparison{
+				Users:      len(population),
+				Percentile: percentileRank(population, progress.Accuracy),
+			}
+		}
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	if err := progress.ToJSON(rw); err != nil {
+		h.logger.Error("failed to encode progress", zap.Error(err))
+	}
+}
+
+func buildProgress(history []models.AnswerRecord, metaByID map[int]models.QuestionMeta) *models.UserProgress {
+	p := &models.UserProgress{
+		RollingWindow: progressRollingWindow,
+		Timeline:      make([]models.ProgressPoint, 0),
+		ByMode:        make(map[models.QuizMode]*models.ProgressBreakdown),
+		ByOption:      make(map[string]*models.ProgressBreakdown),
+		ByCategory:    make(map[string]*models.ProgressBreakdown),
+	}
+
+	byMode := make(map[models.QuizMode][]bool)
+	byOption := make(map[string][]bool)
+	byCategory := make(map[string][]bool)
+	outcomes := make([]bool, 0, len(history))
+	run := 0
+
+	for i, rec := range history {
+		outcomes = append(outcomes, rec.IsCorrect)
+		p.TotalAnswers++
+		if rec.IsCorrect {
+			p.CorrectAnswers++
+			run++
+			if run > p.Streaks.Longest {
+				p.Streaks.Longest = run
+			}
+		} else {
+			run = 0
+		}
+
+		byMode[rec.Mode] = append(byMode[rec.Mode], rec.IsCorrect)
+		if m, ok := metaByID[rec.QuestionID]; ok {
+			if m.Correct != "" {
+				byOption[m.Correct] = append(byOption[m.Correct], rec.IsCorrect)
+			}
+			byCategory[m.Category] = append(byCategory[m.Category], rec.IsCorrect)
+		}
+
+		if rec.Time == nil {
+			continue
+		}
+		day := time.Date(rec.Time.Year(), rec.Time.Month(), rec.Time.Day(), 0, 0, 0, 0, rec.Time.Location())
+		if n := len(p.Timeline); n == 0 || !p.Timeline[n-1].Date.Equal(day) {
+			p.Timeline = append(p.Timeline, models.ProgressPoint{Date: day})
+		}
+		pt := &p.Timeline[len(p.Timeline)-1]
+		pt.Answers++
+		if rec.IsCorrect {
+			pt.Correct++
+		}
+		pt.Accuracy = ratio(pt.Correct, pt.Answers)
+		pt.CumulativeAccuracy = ratio(p.CorrectAnswers, p.TotalAnswers)
+		pt.RollingAccuracy = accuracyOf(outcomes[max(0, i+1-progressRollingWindow):])
+	}
+	p.Streaks.Current = run
+	p.Accuracy = ratio(p.CorrectAnswers, p.TotalAnswers)
+
+	for k, v := range byMode {
+		p.ByMode[k] = breakdown(v)
+	}
+	for k, v := range byOption {
+		p.ByOption[k] = breakdown(v)
+	}
+	for k, v := range byCategory {
+		p.ByCategory[k] = breakdown(v)
+	}
+	return p
+}
+
+func breakdown(outcomes []bool) *models.ProgressBreakdown {
+	b := &models.ProgressBreakdown{Total: len(outcomes)}
+	for _, ok := range outcomes {
+		if ok {
+			b.Correct++
+		}
+	}
+	b.Accuracy = ratio(b.Correct, b.Total)
+	if b.Total < 2 {
+		b.EarlyAccuracy, b.RecentAccuracy = b.Accuracy, b.Accuracy
+		return b
+	}
+	half := b.Total / 2
+	b.EarlyAccuracy = accuracyOf(outcomes[:half])
+	b.RecentAccuracy = accuracyOf(outcomes[half:])
+	b.Change = b.RecentAccuracy - b.EarlyAccuracy
+	return b
+}
+
+func accuracyOf(outcomes []bool) float64 {
+	correct := 0
+	for _, ok := range outcomes {
+		if ok {
+			correct++
+		}
+	}
+	return ratio(correct, len(outcomes))
+}
+
+func ratio(correct, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(correct) / float64(total)
+}
+
+// percentileRank is the share of the population scoring below value, counting
+// ties as half, in percent.
+func percentileRank(population []float64, value float64) float64 {
+	below, equal := 0, 0
+	for _, v := range population {
+		switch {
+		case v < value:
+			below++
+		case v == value:
+			equal++
+		}
+	}
+	return 100 * (float64(below) + 0.5*float64(equal)) / float64(len(population))
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"encoding/json"
 	"fmt"
 	"go.uber.org/zap"
 	"net/http"
@@ -66,6 +67,29 @@ func (h *QuizStatsHandler) SaveSession(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// POST /stats/sessions/reassign  (internal)
+// Body: {"from_user_id": 1, "to_user_id": 2}
+//
+// Used when a guest claims their sessions into a full account.
+func (h *QuizStatsHandler) ReassignSessions(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		FromUserID int `json:"from_user_id"`
+		ToUserID   int `json:"to_user_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.FromUserID <= 0 || req.ToUserID <= 0 {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	n, err := h.storage.ReassignUserSessions(req.FromUserID, req.ToUserID)
+	if err != nil {
+		h.logger.Error("failed to reassign sessions", zap.Error(err))
+		http.Error(w, "failed to reassign sessions", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]int{"sessions": n})
+}
+
 func (h *QuizStatsHandler) FinishSession(w http.ResponseWriter, r *http.Request) {
 	quizId := r.PathValue("quizSessionId")
 	if quizId == "" {
@@ -101,6 +125,11 @@ func (h *QuizStatsHandler) SaveResponse(w http.ResponseWriter, r *http.Request)
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	// An unknown zone must not cost us the answer itself.
+	if response.TimeZone != "" && !isValidTimeZone(response.TimeZone) {
+		h.logger.Warn("dropping invalid answer time zone", zap.String("time_zone", response.TimeZone))
+		response.TimeZone = ""
+	}
 	session, err := h.storage.GetQuizSessionByID(sessionID)
 	if err == storage.ErrSessionNotFound {
 		err = h.storage.SaveSession(&models.QuizSession{
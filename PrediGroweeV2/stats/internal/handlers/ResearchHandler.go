@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"stats/internal/models"
+	"stats/internal/storage"
+)
+
+// researchExportTimeout replaces the server's write timeout, too short for
+// the whole dataset.
+const researchExportTimeout = 10 * time.Minute
+
+type ResearchHandler struct {
+	storage      storage.Storage
+	logger       *zap.Logger
+	pseudonymKey string
+}
+
+func NewResearchHandler(s storage.Storage, l *zap.Logger, pseudonymKey string) *ResearchHandler {
+	return &ResearchHandler{storage: s, logger: l, pseudonymKey: pseudonymKey}
+}
+
+// Pseudonym maps a user id to a stable, non-reversible participant id.
+// The same key always yields the same pseudonym, so exports taken at
+// different times can be joined without ever exposing the user id.
+func Pseudonym(key string, userID int) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(strconv.Itoa(userID)))
+	return "P-" + hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// SessionPseudonym maps a session id to a stable, non-reversible id in the
+// same way, kept apart from the participant ids by its prefix.
+func SessionPseudonym(key string, sessionID int) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte("session:" + strconv.Itoa(sessionID)))
+	return "S-" + hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// GET /stats/research/rows (internal)
+//
+// Streams the dataset as JSON lines. A failure part way aborts the response,
+// so the caller never takes a truncated dataset for a whole one.
+func (h *ResearchHandler) GetRows(w http.ResponseWriter, _ *http.Request) {
+	if h.pseudonymKey == "" {
+		h.logger.Error("research export requested but RESEARCH_PSEUDONYM_KEY is not set")
+		http.Error(w, "research export not configured", http.StatusServiceUnavailable)
+		return
+	}
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(researchExportTimeout))
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+	written := false
+	err := h.storage.EachResearchRow(func(row models.ResearchRow) error {
+		row.ParticipantID = Pseudonym(h.pseudonymKey, row.UserID)
+		row.SessionPseudonym = SessionPseudonym(h.pseudonymKey, row.SessionID)
+		written = true
+		return enc.Encode(row)
+	})
+	if err != nil {
+		h.logger.Error("failed to stream research rows", zap.Error(err))
+		if !written {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		panic(http.ErrAbortHandler)
+	}
+}
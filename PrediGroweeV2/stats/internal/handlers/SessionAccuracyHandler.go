@@ -20,10 +20,46 @@ func NewSessionsAccuracyHandler(s storage.Storage, l *zap.Logger) *SessionsAccur
 }
 
 func (h *SessionsAccuracyHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	ids, ok := sessionIDsParam(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := h.store.GetAccuracyBatch(ids)
+	if err != nil {
+		h.logger.Error("GetAccuracyBatch failed", zap.Error(err))
+		http.Error(w, "failed", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+
+// GET /stats/sessions/answers?ids=1,2,3
+func (h *SessionsAccuracyHandler) Answers(w http.ResponseWriter, r *http.Request) {
+	ids, ok := sessionIDsParam(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := h.store.GetSessionAnswers(ids)
+	if err != nil {
+		h.logger.Error("GetSessionAnswers failed", zap.Error(err))
+		http.Error(w, "failed", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+// sessionIDsParam parses the comma-separated ids query parameter and writes
+// a 400 response if it is missing or malformed.
+func sessionIDsParam(w http.ResponseWriter, r *http.Request) ([]int, bool) {
 	q := r.URL.Query().Get("ids")
 	if q == "" {
 		http.Error(w, "missing ids", http.StatusBadRequest)
-		return
+		return nil, false
 	}
 	parts := strings.Split(q, ",")
 	ids := make([]int, 0, len(parts))
@@ -34,18 +70,9 @@ func (h *SessionsAccuracyHandler) Handle(w http.ResponseWriter, r *http.Request)
 		n, err := strconv.Atoi(p)
 		if err != nil {
 			http.Error(w, "bad ids", http.StatusBadRequest)
-			return
+			return nil, false
 		}
 		ids = append(ids, n)
 	}
-
-	data, err := h.store.GetAccuracyBatch(ids)
-	if err != nil {
-		h.logger.Error("GetAccuracyBatch failed", zap.Error(err))
-		http.Error(w, "failed", http.StatusInternalServerError)
-		return
-	}
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(data)
+	return ids, true
 }
-
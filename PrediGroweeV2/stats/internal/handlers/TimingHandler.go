@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"stats/internal/models"
+	"stats/internal/storage"
+)
+
+// timeBucketBounds are the lower edges, in seconds, of the time buckets used
+// for accuracy-by-time. time_spent is truncated to whole seconds, so the
+// first bucket holds answers given in under two seconds.
+var timeBucketBounds = []int{0, 2, 5, 10, 20, 40, 60}
+
+const (
+	defaultFastSeconds = 2
+	defaultFastShare   = 0.5
+	defaultMinAnswers  = 5
+	// constantAnswersMin is the session length from which always picking the
+	// same option is treated as guessing rather than coincidence.
+	constantAnswersMin = 8
+	// duplicateMinWrong avoids flagging strong users who simply answered
+	// everything correctly: identical sequences are only suspicious when they
+	// share several identical mistakes.
+	duplicateMinWrong = 3
+)
+
+type TimingHandler struct {
+	storage storage.Storage
+	logger  *zap.Logger
+}
+
+func NewTimingHandler(s storage.Storage, l *zap.Logger) *TimingHandler {
+	return &TimingHandler{storage: s, logger: l}
+}
+
+// GET /stats/timing/questions
+func (h *TimingHandler) GetQuestions(w http.ResponseWriter, _ *http.Request) {
+	stats, err := h.storage.GetQuestionTimingStats()
+	if err != nil {
+		h.logger.Error("failed to get question timing stats", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, h.logger, http.StatusOK, stats)
+}
+
+// GET /stats/timing/users
+func (h *TimingHandler) GetUsers(w http.ResponseWriter, _ *http.Request) {
+	stats, err := h.storage.GetUserTimingStats()
+	if err != nil {
+		h.logger.Error("failed to get user timing stats", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, h.logger, http.StatusOK, stats)
+}
+
+// GET /stats/timing/buckets
+func (h *TimingHandler) GetBuckets(w http.ResponseWriter, _ *http.Request) {
+	counts, err := h.storage.GetTimeBucketStats(timeBucketBounds)
+	if err != nil {
+		h.logger.Error("failed to get time bucket stats", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, h.logger, http.StatusOK, buildTimeBuckets(timeBucketBounds, counts))
+}
+
+// GET /stats/flags?fastSeconds=&fastShare=&minAnswers=
+func (h *TimingHandler) GetFlags(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	fastSeconds := defaultFastSeconds
+	if v := q.Get("fastSeconds"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid fastSeconds", http.StatusBadRequest)
+			return
+		}
+		fastSeconds = n
+	}
+	fastShare := defaultFastShare
+	if v := q.Get("fastShare"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f <= 0 || f > 1 {
+			http.Error(w, "invalid fastShare", http.StatusBadRequest)
+			return
+		}
+		fastShare = f
+	}
+	minAnswers := defaultMinAnswers
+	if v := q.Get("minAnswers"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid minAnswers", http.StatusBadRequest)
+			return
+		}
+		minAnswers = n
+	}
+
+	patterns, err := h.storage.GetSessionAnswerPatterns(fastSeconds, minAnswers)
+	if err != nil {
+		h.logger.Error("failed to get session answer patterns", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, h.logger, http.StatusOK, flagSessions(patterns, fastShare))
+}
+
+func buildTimeBuckets(bounds []int, counts map[int]models.TimeBucketStats) []models.TimeBucketStats {
+	out := make([]models.TimeBucketStats, len(bounds))
+	for i, from := range bounds {
+		b := counts[i+1]
+		b.From = from
+		if i+1 < len(bounds) {
+			to := bounds[i+1]
+			b.To = &to
+			b.Label = fmt.Sprintf("%d-%ds", from, to)
+		} else {
+			b.Label = fmt.Sprintf("%ds+", from)
+		}
+		b.Accuracy = ratio(b.Correct, b.Total)
+		out[i] = b
+	}
+	return out
+}
+
+// flagSessions applies the guessing rules to each session and returns only
+// the flagged ones:
+//   - fast_answers: at least fastShare of the answers were faster than the threshold
+//   - constant_answers: a long session where every answer is the same option
+//   - duplicate_sequence: another user's session has exactly the same answers
+//     to the same questions, including several identical mistakes
+func flagSessions(patterns []models.SessionAnswerPattern, fastShare float64) []models.SuspiciousSession {
+	bySignature := make(map[string][]int)
+	for i, p := range patterns {
+		if sig, ok := sequenceSignature(p); ok {
+			bySignature[sig] = append(bySignature[sig], i)
+		}
+	}
+
+	out := make([]models.SuspiciousSession, 0)
+	for i, p := range patterns {
+		s := models.SuspiciousSession{
+			SessionID:   p.SessionID,
+			UserID:      p.UserID,
+			Answers:     p.Answers,
+			FastAnswers: p.FastAnswers,
+			FastShare:   ratio(p.FastAnswers, p.Answers),
+			Accuracy:    ratio(p.Correct, p.Answers),
+			Reasons:     make([]string, 0, 3),
+			Excluded:    p.ExcludedAt != nil,
+			ExcludedAt:  p.ExcludedAt,
+		}
+		if s.FastShare >= fastShare {
+			s.Reasons = append(s.Reasons, models.FlagFastAnswers)
+		}
+		if p.Answers >= constantAnswersMin && isConstant(p.Sequence) {
+			s.Reasons = append(s.Reasons, models.FlagConstantAnswers)
+		}
+		if sig, ok := sequenceSignature(p); ok {
+			for _, j := range bySignature[sig] {
+				if j != i && patterns[j].UserID != p.UserID {
+					s.DuplicateOf = append(s.DuplicateOf, patterns[j].SessionID)
+				}
+			}
+			if len(s.DuplicateOf) > 0 {
+				s.Reasons = append(s.Reasons, models.FlagDuplicateSequence)
+			}
+		}
+		if len(s.Reasons) > 0 {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// sequenceSignature identifies a session by its question/answer pairs. It
+// reports false for sessions with too few mistakes to be compared.
+func sequenceSignature(p models.SessionAnswerPattern) (string, bool) {
+	wrong := 0
+	for _, ok := range p.Corrects {
+		if !ok {
+			wrong++
+		}
+	}
+	if wrong < duplicateMinWrong {
+		return "", false
+	}
+	var sb strings.Builder
+	for i, id := range p.QuestionIDs {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(strconv.Itoa(id))
+		sb.WriteByte(':')
+		if i < len(p.Sequence) {
+			sb.WriteString(p.Sequence[i])
+		}
+	}
+	return sb.String(), true
+}
+
+func isConstant(sequence []string) bool {
+	if len(sequence) == 0 || sequence[0] == "" {
+		return false
+	}
+	for _, a := range sequence[1:] {
+		if a != sequence[0] {
+			return false
+		}
+	}
+	return true
+}
@@ -42,7 +42,9 @@ func hasElevatedAccess(role string) bool {
 	return strings.EqualFold(role, "admin") || strings.EqualFold(role, "teacher")
 }
 
-func (h *UserStatsHandler) resolveTargetUser(r *http.Request, role string) (int, int, error) {
+// resolveTargetUser picks the user a stats request is about: the path id, the
+// userId query for teachers and admins, or the caller.
+func resolveTargetUser(r *http.Request, role string) (int, int, error) {
 	if idStr := r.PathValue("id"); idStr != "" {
 		uid, err := strconv.Atoi(strings.TrimSpace(idStr))
 		if err != nil {
@@ -72,7 +74,7 @@ func (h *UserStatsHandler) resolveTargetUser(r *http.Request, role string) (int,
 func (h *UserStatsHandler) Handle(rw http.ResponseWriter, r *http.Request) {
 	role := readRole(r)
 
-	userID, statusOverride, err := h.resolveTargetUser(r, role)
+	userID, statusOverride, err := resolveTargetUser(r, role)
 	if err != nil {
 		code := statusOverride
 		if code == 0 {
@@ -122,7 +124,7 @@ func (h *UserStatsHandler) Handle(rw http.ResponseWriter, r *http.Request) {
 func (h *UserStatsHandler) GetUserSessions(rw http.ResponseWriter, r *http.Request) {
 	role := readRole(r)
 
-	userID, statusOverride, err := h.resolveTargetUser(r, role)
+	userID, statusOverride, err := resolveTargetUser(r, role)
 	if err != nil {
 		code := statusOverride
 		if code == 0 {
@@ -0,0 +1,342 @@
+// Package irt calibrates a two-parameter logistic (2PL) item response model.
+//
+// The probability that a user of ability θ answers question j correctly is
+//
+//	P(θ, aj, bj) = 1 / (1 + exp(-aj (θ - bj)))
+//
+// where b is the question's difficulty and a its discrimination. Item
+// parameters are estimated by marginal maximum likelihood with the EM
+// algorithm of Bock and Aitkin: abilities are integrated out over a N(0, 1)
+// population on a fixed quadrature grid. Weak priors (b ~ N(0, 2²),
+// ln a ~ N(0, 0.5²)) keep estimates finite for questions everybody gets right
+// or wrong. Abilities are expected a posteriori (EAP) estimates and their
+// standard errors are posterior standard deviations. Item standard errors
+// come from the information matrix of the final M-step, which ignores the
+// uncertainty of the abilities and so slightly understates them.
+package irt
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"stats/internal/models"
+)
+
+const (
+	quadraturePoints  = 41
+	quadratureRange   = 4.0
+	difficultyPrior   = 4.0
+	logDiscrimPrior   = 0.25
+	maxNewtonStep     = 1.0
+	mStepIterations   = 10
+	maxAbsDifficulty  = 6.0
+	minDiscrimination = 0.05
+	maxDiscrimination = 6.0
+)
+
+var ErrNotEnoughData = errors.New("not enough responses to calibrate")
+
+type Options struct {
+	MaxIterations    int
+	Tolerance        float64
+	MinItemResponses int
+	MinUserResponses int
+}
+
+func DefaultOptions() Options {
+	return Options{
+		MaxIterations:    500,
+		Tolerance:        1e-4,
+		MinItemResponses: 10,
+		MinUserResponses: 5,
+	}
+}
+
+type Result struct {
+	Items         []models.IRTItemParams
+	Abilities     []models.IRTAbility
+	Responses     int
+	Iterations    int
+	Converged     bool
+	LogLikelihood float64
+}
+
+type observation struct {
+	idx     int
+	correct bool
+}
+
+// Fit calibrates the model. Questions and users with fewer responses than
+// the configured minimums are left out, repeatedly, until both hold.
+func Fit(responses []models.IRTResponse, opts Options) (*Result, error) {
+	kept := filterSparse(responses, opts.MinItemResponses, opts.MinUserResponses)
+
+	userIdx, itemIdx := map[int]int{}, map[int]int{}
+	var userIDs, itemIDs []int
+	for _, r := range kept {
+		if _, ok := userIdx[r.UserID]; !ok {
+			userIdx[r.UserID] = len(userIDs)
+			userIDs = append(userIDs, r.UserID)
+		}
+		if _, ok := itemIdx[r.QuestionID]; !ok {
+			itemIdx[r.QuestionID] = len(itemIDs)
+			itemIDs = append(itemIDs, r.QuestionID)
+		}
+	}
+	if len(userIDs) < 2 || len(itemIDs) < 2 {
+		return nil, ErrNotEnoughData
+	}
+
+	byUser := make([][]observation, len(userIDs))
+	byItem := make([][]observation, len(itemIDs))
+	for _, r := range kept {
+		u, it := userIdx[r.UserID], itemIdx[r.QuestionID]
+		byUser[u] = append(byUser[u], observation{idx: it, correct: r.Correct})
+		byItem[it] = append(byItem[it], observation{idx: u, correct: r.Correct})
+	}
+
+	nodes, prior := quadrature()
+	logA := make([]float64, len(itemIDs))
+	b := make([]float64, len(itemIDs))
+	for it, obs := range byItem {
+		b[it] = clamp(-logit(shrunkProportion(obs)), maxAbsDifficulty)
+	}
+
+	res := &Result{Responses: len(kept)}
+	// expected[j][q] is the expected number of respondents to question j at
+	// node q, correct[j][q] how many of them answered correctly.
+	expected := make([][]float64, len(itemIDs))
+	correct := make([][]float64, len(itemIDs))
+	for j := range itemIDs {
+		expected[j] = make([]float64, quadraturePoints)
+		correct[j] = make([]float64, quadraturePoints)
+	}
+	posterior := make([]float64, quadraturePoints)
+
+	for iter := 1; iter <= opts.MaxIterations; iter++ {
+		for j := range itemIDs {
+			clear(expected[j])
+			clear(correct[j])
+		}
+		res.LogLikelihood = 0
+		for _, obs := range byUser {
+			res.LogLikelihood += userPosterior(obs, nodes, prior, logA, b, posterior)
+			for _, o := range obs {
+				for q, w := range posterior {
+					expected[o.idx][q] += w
+					if o.correct {
+						correct[o.idx][q] += w
+					}
+				}
+			}
+		}
+
+		change := 0.0
+		for j := range itemIDs {
+			newLogA, newB := maximizeItem(nodes, expected[j], correct[j], logA[j], b[j])
+			change = math.Max(change, math.Max(math.Abs(newLogA-logA[j]), math.Abs(newB-b[j])))
+			logA[j], b[j] = newLogA, newB
+		}
+		res.Iterations = iter
+		if change < opts.Tolerance {
+			res.Converged = true
+			break
+		}
+	}
+
+	res.Items = make([]models.IRTItemParams, len(itemIDs))
+	for j, obs := range byItem {
+		_, _, iAA, iBB, iAB := itemDerivatives(nodes, expected[j], correct[j], logA[j], b[j])
+		a := math.Exp(logA[j])
+		params := models.IRTItemParams{
+			QuestionID:     itemIDs[j],
+			Difficulty:     b[j],
+			Discrimination: a,
+			Responses:      len(obs),
+			PValue:         proportion(obs),
+		}
+		if det := iAA*iBB - iAB*iAB; det > 0 {
+			params.DiscriminationSE = a * math.Sqrt(iBB/det)
+			params.DifficultySE = math.Sqrt(iAA / det)
+		}
+		res.Items[j] = params
+	}
+
+	res.Abilities = make([]models.IRTAbility, len(userIDs))
+	for u, obs := range byUser {
+		userPosterior(obs, nodes, prior, logA, b, posterior)
+		mean, variance := 0.0, 0.0
+		for q, w := range posterior {
+			mean += w * nodes[q]
+		}
+		for q, w := range posterior {
+			variance += w * (nodes[q] - mean) * (nodes[q] - mean)
+		}
+		nCorrect := 0
+		for _, o := range obs {
+			if o.correct {
+				nCorrect++
+			}
+		}
+		res.Abilities[u] = models.IRTAbility{
+			UserID:    userIDs[u],
+			Ability:   mean,
+			AbilitySE: math.Sqrt(variance),
+			Responses: len(obs),
+			Correct:   nCorrect,
+		}
+	}
+
+	sort.Slice(res.Items, func(i, j int) bool { return res.Items[i].QuestionID < res.Items[j].QuestionID })
+	sort.Slice(res.Abilities, func(i, j int) bool { return res.Abilities[i].UserID < res.Abilities[j].UserID })
+	return res, nil
+}
+
+// quadrature returns equally spaced nodes over ±quadratureRange with
+// normalised N(0, 1) weights.
+func quadrature() (nodes, weights []float64) {
+	nodes = make([]float64, quadraturePoints)
+	weights = make([]float64, quadraturePoints)
+	step := 2 * quadratureRange / float64(quadraturePoints-1)
+	total := 0.0
+	for q := range nodes {
+		nodes[q] = -quadratureRange + float64(q)*step
+		weights[q] = math.Exp(-nodes[q] * nodes[q] / 2)
+		total += weights[q]
+	}
+	for q := range weights {
+		weights[q] /= total
+	}
+	return nodes, weights
+}
+
+// userPosterior fills out with the posterior distribution of the user's
+// ability over the nodes and returns the log marginal likelihood.
+func userPosterior(obs []observation, nodes, prior, logA, b []float64, out []float64) float64 {
+	maxLog := math.Inf(-1)
+	for q, theta := range nodes {
+		l := math.Log(prior[q])
+		for _, o := range obs {
+			p := prob(theta, math.Exp(logA[o.idx]), b[o.idx])
+			if o.correct {
+				l += math.Log(math.Max(p, 1e-300))
+			} else {
+				l += math.Log(math.Max(1-p, 1e-300))
+			}
+		}
+		out[q] = l
+		maxLog = math.Max(maxLog, l)
+	}
+	total := 0.0
+	for q := range out {
+		out[q] = math.Exp(out[q] - maxLog)
+		total += out[q]
+	}
+	for q := range out {
+		out[q] /= total
+	}
+	return maxLog + math.Log(total)
+}
+
+// maximizeItem runs a few damped Newton steps on the expected complete-data
+// log posterior of one question.
+func maximizeItem(nodes, expected, correct []float64, logA, b float64) (float64, float64) {
+	for k := 0; k < mStepIterations; k++ {
+		dAlpha, dB, iAA, iBB, iAB := itemDerivatives(nodes, expected, correct, logA, b)
+		det := iAA*iBB - iAB*iAB
+		if det <= 0 {
+			break
+		}
+		stepAlpha := clamp((iBB*dAlpha-iAB*dB)/det, maxNewtonStep)
+		stepB := clamp((iAA*dB-iAB*dAlpha)/det, maxNewtonStep)
+		logA = math.Min(math.Max(logA+stepAlpha, math.Log(minDiscrimination)), math.Log(maxDiscrimination))
+		b = clamp(b+stepB, maxAbsDifficulty)
+		if math.Abs(stepAlpha) < 1e-8 && math.Abs(stepB) < 1e-8 {
+			break
+		}
+	}
+	return logA, b
+}
+
+// itemDerivatives returns the gradient of the expected log posterior with
+// respect to (ln a, b) and the expected information matrix, priors included.
+func itemDerivatives(nodes, expected, correct []float64, logA, b float64) (dAlpha, dB, iAA, iBB, iAB float64) {
+	a := math.Exp(logA)
+	for q, theta := range nodes {
+		n := expected[q]
+		if n == 0 {
+			continue
+		}
+		d := theta - b
+		p := prob(theta, a, b)
+		w := n * p * (1 - p)
+		r := correct[q] - n*p
+		dAlpha += a * r * d
+		dB -= a * r
+		iAA += a * a * w * d * d
+		iBB += a * a * w
+		iAB -= a * a * w * d
+	}
+	dAlpha -= logA / logDiscrimPrior
+	dB -= b / difficultyPrior
+	iAA += 1 / logDiscrimPrior
+	iBB += 1 / difficultyPrior
+	return
+}
+
+func filterSparse(responses []models.IRTResponse, minItem, minUser int) []models.IRTResponse {
+	kept := responses
+	for {
+		perItem, perUser := map[int]int{}, map[int]int{}
+		for _, r := range kept {
+			perItem[r.QuestionID]++
+			perUser[r.UserID]++
+		}
+		next := make([]models.IRTResponse, 0, len(kept))
+		for _, r := range kept {
+			if perItem[r.QuestionID] >= minItem && perUser[r.UserID] >= minUser {
+				next = append(next, r)
+			}
+		}
+		if len(next) == len(kept) {
+			return next
+		}
+		kept = next
+	}
+}
+
+func prob(theta, a, b float64) float64 {
+	return 1 / (1 + math.Exp(-a*(theta-b)))
+}
+
+func logit(p float64) float64 {
+	return math.Log(p / (1 - p))
+}
+
+// shrunkProportion is the share of correct answers with one pseudo-correct
+// and one pseudo-wrong answer added, so it is never exactly 0 or 1.
+func shrunkProportion(obs []observation) float64 {
+	return (countCorrect(obs) + 1) / (float64(len(obs)) + 2)
+}
+
+func proportion(obs []observation) float64 {
+	if len(obs) == 0 {
+		return 0
+	}
+	return countCorrect(obs) / float64(len(obs))
+}
+
+func countCorrect(obs []observation) float64 {
+	n := 0.0
+	for _, o := range obs {
+		if o.correct {
+			n++
+		}
+	}
+	return n
+}
+
+func clamp(v, limit float64) float64 {
+	return math.Max(-limit, math.Min(limit, v))
+}
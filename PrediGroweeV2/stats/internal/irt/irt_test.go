@@ -0,0 +1,139 @@
+package irt
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"stats/internal/models"
+)
+
+// simulate answers questions with the given parameters for users of N(0, 1)
+// ability; user and question ids start at 1.
+func simulate(rng *rand.Rand, users int, a, b []float64) ([]models.IRTResponse, []float64) {
+	thetas := make([]float64, users)
+	responses := make([]models.IRTResponse, 0, users*len(b))
+	for u := range thetas {
+		thetas[u] = rng.NormFloat64()
+		for j := range b {
+			responses = append(responses, models.IRTResponse{
+				UserID:     u + 1,
+				QuestionID: j + 1,
+				Correct:    rng.Float64() < prob(thetas[u], a[j], b[j]),
+			})
+		}
+	}
+	return responses, thetas
+}
+
+// TestFitSimulatedData calibrates data shaped like the answers table: most
+// users answered every question, a few only tried one or two and must be
+// left out.
+func TestFitSimulatedData(t *testing.T) {
+	a := []float64{0.6, 1.8, 1, 2.2, 0.8, 1.4, 1.2, 1}
+	b := []float64{0.5, -1.5, 0, 1.5, -0.5, 1, -1, -2}
+	rng := rand.New(rand.NewSource(1))
+	responses, thetas := simulate(rng, 1500, a, b)
+	for u := 2001; u <= 2020; u++ {
+		responses = append(responses,
+			models.IRTResponse{UserID: u, QuestionID: 1, Correct: true},
+			models.IRTResponse{UserID: u, QuestionID: 2, Correct: false})
+	}
+
+	res, err := Fit(responses, DefaultOptions())
+	if err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+	if !res.Converged {
+		t.Errorf("Fit() did not converge in %d iterations", res.Iterations)
+	}
+	if res.Responses != 1500*len(b) || len(res.Abilities) != 1500 || len(res.Items) != len(b) {
+		t.Fatalf("Fit() used %d responses of %d users for %d questions", res.Responses, len(res.Abilities), len(res.Items))
+	}
+
+	for j, item := range res.Items {
+		if item.QuestionID != j+1 {
+			t.Fatalf("question %d at %d, want items sorted by id", item.QuestionID, j)
+		}
+		if math.Abs(item.Difficulty-b[j]) > 3*item.DifficultySE {
+			t.Errorf("question %d: difficulty %.2f ± %.2f, want %.2f", item.QuestionID, item.Difficulty, item.DifficultySE, b[j])
+		}
+		if r := item.Discrimination / a[j]; r < 0.7 || r > 1.4 {
+			t.Errorf("question %d: discrimination %.2f, want %.2f", item.QuestionID, item.Discrimination, a[j])
+		}
+		if item.DifficultySE <= 0 || item.DiscriminationSE <= 0 {
+			t.Errorf("question %d: standard errors %.3f and %.3f", item.QuestionID, item.DifficultySE, item.DiscriminationSE)
+		}
+	}
+
+	// eight questions measure a user only roughly, which caps how closely
+	// the estimates can follow the true abilities
+	var sx, sy, sxx, syy, sxy float64
+	for u, ability := range res.Abilities {
+		x, y := ability.Ability, thetas[u]
+		sx, sy, sxx, syy, sxy = sx+x, sy+y, sxx+x*x, syy+y*y, sxy+x*y
+	}
+	n := float64(len(thetas))
+	if r := (n*sxy - sx*sy) / math.Sqrt((n*sxx-sx*sx)*(n*syy-sy*sy)); r < 0.7 {
+		t.Errorf("abilities correlate %.2f with the true ones", r)
+	}
+}
+
+// TestFitExtremeQuestions checks that questions everybody gets right or
+// wrong get finite difficulties at the ends of the scale.
+func TestFitExtremeQuestions(t *testing.T) {
+	responses, _ := simulate(rand.New(rand.NewSource(2)), 300, []float64{1, 1, 1}, []float64{-1, 0, 1})
+	for u := 1; u <= 300; u++ {
+		responses = append(responses,
+			models.IRTResponse{UserID: u, QuestionID: 4, Correct: true},
+			models.IRTResponse{UserID: u, QuestionID: 5, Correct: false})
+	}
+	res, err := Fit(responses, DefaultOptions())
+	if err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+	for _, item := range res.Items {
+		if math.IsNaN(item.Difficulty) || math.Abs(item.Difficulty) > maxAbsDifficulty {
+			t.Errorf("question %d: difficulty %v", item.QuestionID, item.Difficulty)
+		}
+	}
+	easy, hard := res.Items[3], res.Items[4]
+	if easy.Difficulty >= res.Items[0].Difficulty || hard.Difficulty <= res.Items[2].Difficulty {
+		t.Errorf("difficulties %.2f and %.2f do not bracket the others", easy.Difficulty, hard.Difficulty)
+	}
+	if easy.PValue != 1 || hard.PValue != 0 {
+		t.Errorf("p values %.2f and %.2f, want 1 and 0", easy.PValue, hard.PValue)
+	}
+}
+
+// TestFitNotEnoughData is the first calibration of a new deployment, with
+// fewer answers per question than the default minimum.
+func TestFitNotEnoughData(t *testing.T) {
+	responses, _ := simulate(rand.New(rand.NewSource(3)), 9, []float64{1, 1, 1, 1, 1}, []float64{-1, 0, 1, 0, 0})
+	if _, err := Fit(responses, DefaultOptions()); !errors.Is(err, ErrNotEnoughData) {
+		t.Errorf("Fit() error = %v, want ErrNotEnoughData", err)
+	}
+	if _, err := Fit(nil, DefaultOptions()); !errors.Is(err, ErrNotEnoughData) {
+		t.Errorf("Fit(nil) error = %v, want ErrNotEnoughData", err)
+	}
+}
+
+// TestFilterSparse drops question 3, answered once; user 3 is then left
+// with one answer and dropped, which leaves question 1 with one answer too.
+func TestFilterSparse(t *testing.T) {
+	r := func(user, question int) models.IRTResponse {
+		return models.IRTResponse{UserID: user, QuestionID: question, Correct: true}
+	}
+	responses := []models.IRTResponse{
+		r(1, 2), r(1, 4),
+		r(2, 2), r(2, 4),
+		r(3, 1), r(3, 3),
+		r(4, 1), r(4, 2), r(4, 4),
+	}
+	want := []models.IRTResponse{r(1, 2), r(1, 4), r(2, 2), r(2, 4), r(4, 2), r(4, 4)}
+	if got := filterSparse(responses, 2, 2); !reflect.DeepEqual(got, want) {
+		t.Errorf("filterSparse() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,128 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"stats/internal/irt"
+	"stats/internal/models"
+	"stats/internal/storage"
+)
+
+var ErrRunInProgress = errors.New("irt calibration already running")
+
+// IRTCalibrator periodically refits the IRT model over all included answers.
+// Runs can also be triggered on demand; only one runs at a time.
+type IRTCalibrator struct {
+	storage  storage.Storage
+	logger   *zap.Logger
+	interval time.Duration
+	options  irt.Options
+	running  sync.Mutex
+}
+
+func NewIRTCalibrator(s storage.Storage, l *zap.Logger, interval time.Duration) *IRTCalibrator {
+	return &IRTCalibrator{
+		storage:  s,
+		logger:   l,
+		interval: interval,
+		options:  irt.DefaultOptions(),
+	}
+}
+
+// Start schedules calibration every interval until ctx is cancelled. A zero
+// interval disables scheduling; runs can still be triggered. If the last run
+// is older than the interval, or there is none, one is started immediately.
+func (c *IRTCalibrator) Start(ctx context.Context) {
+	if c.interval <= 0 {
+		c.logger.Info("scheduled irt calibration disabled")
+		return
+	}
+	go func() {
+		last, err := c.storage.GetLatestIRTRun()
+		if err != nil && !errors.Is(err, storage.ErrIRTNotFound) {
+			c.logger.Error("failed to get latest irt run", zap.Error(err))
+		}
+		if last == nil || time.Since(last.StartedAt) >= c.interval {
+			c.trigger()
+		}
+
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.trigger()
+			}
+		}
+	}()
+}
+
+// Trigger starts a run in the background and returns it straight away.
+func (c *IRTCalibrator) Trigger() (*models.IRTRun, error) {
+	if !c.running.TryLock() {
+		return nil, ErrRunInProgress
+	}
+	run, err := c.storage.CreateIRTRun()
+	if err != nil {
+		c.running.Unlock()
+		return nil, err
+	}
+	go func() {
+		defer c.running.Unlock()
+		c.calibrate(run)
+	}()
+	return run, nil
+}
+
+func (c *IRTCalibrator) trigger() {
+	if _, err := c.Trigger(); err != nil && !errors.Is(err, ErrRunInProgress) {
+		c.logger.Error("failed to start irt calibration", zap.Error(err))
+	}
+}
+
+func (c *IRTCalibrator) calibrate(run *models.IRTRun) {
+	started := time.Now()
+	responses, err := c.storage.GetIRTResponses()
+	if err != nil {
+		c.fail(run, err)
+		return
+	}
+	res, err := irt.Fit(responses, c.options)
+	if err != nil {
+		c.fail(run, err)
+		return
+	}
+
+	run.Users = len(res.Abilities)
+	run.Items = len(res.Items)
+	run.Responses = res.Responses
+	run.Iterations = res.Iterations
+	run.Converged = res.Converged
+	run.LogLikelihood = res.LogLikelihood
+	if err := c.storage.FinishIRTRun(run, res.Items, res.Abilities); err != nil {
+		c.fail(run, err)
+		return
+	}
+	if !res.Converged {
+		c.logger.Warn("irt calibration did not converge", zap.Int("run_id", run.ID), zap.Int("iterations", res.Iterations))
+	}
+	c.logger.Info("irt calibration finished",
+		zap.Int("run_id", run.ID),
+		zap.Int("users", run.Users),
+		zap.Int("items", run.Items),
+		zap.Int("responses", run.Responses),
+		zap.Duration("took", time.Since(started)))
+}
+
+func (c *IRTCalibrator) fail(run *models.IRTRun, cause error) {
+	c.logger.Error("irt calibration failed", zap.Int("run_id", run.ID), zap.Error(cause))
+	if err := c.storage.FailIRTRun(run.ID, cause.Error()); err != nil {
+		c.logger.Error("failed to record irt run failure", zap.Int("run_id", run.ID), zap.Error(err))
+	}
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	DeviceClassPhone   = "phone"
+	DeviceClassTablet  = "tablet"
+	DeviceClassDesktop = "desktop"
+	DeviceClassUnknown = "unknown"
+)
+
+// MaxScreenDimension is the largest viewport dimension accepted from a
+// client. Sizes are reported by the browser unchecked; larger values are
+// implausible and are rejected rather than counted as a device.
+const MaxScreenDimension = 100000
+
+// ParseScreenSize parses the "WxH" viewport size sent by the client. Sizes
+// outside 1..MaxScreenDimension are not ok.
+func ParseScreenSize(s string) (width, height int, ok bool) {
+	w, h, found := strings.Cut(strings.TrimSpace(strings.ToLower(s)), "x")
+	if !found {
+		return 0, 0, false
+	}
+	width, err := strconv.Atoi(w)
+	if err != nil || width <= 0 || width > MaxScreenDimension {
+		return 0, 0, false
+	}
+	height, err = strconv.Atoi(h)
+	if err != nil || height <= 0 || height > MaxScreenDimension {
+		return 0, 0, false
+	}
+	return width, height, true
+}
+
+// DeviceStats aggregates answers by device class, and by exact viewport
+// resolution when Resolution is set.
+type DeviceStats struct {
+	DeviceClass     string  `json:"device_class"`
+	Resolution      string  `json:"resolution,omitempty"`
+	Width           int     `json:"width,omitempty"`
+	Height          int     `json:"height,omitempty"`
+	Users           int     `json:"users"`
+	Answers         int     `json:"answers"`
+	Correct         int     `json:"correct"`
+	Accuracy        float64 `json:"accuracy"`
+	MeanTimeSpent   float64 `json:"mean_time_spent"`
+	MedianTimeSpent float64 `json:"median_time_spent"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+type ExclusionScope = string
+
+const (
+	ExclusionScopeResponse ExclusionScope = "response"
+	ExclusionScopeSession  ExclusionScope = "session"
+	ExclusionScopeUser     ExclusionScope = "user"
+)
+
+func IsValidExclusionScope(scope string) bool {
+	switch scope {
+	case ExclusionScopeResponse, ExclusionScopeSession, ExclusionScopeUser:
+		return true
+	}
+	return false
+}
+
+// Exclusion hides a response, a session or all sessions of a user from
+// aggregate statistics without deleting anything.
+type Exclusion struct {
+	ID         int            `json:"id"`
+	Scope      ExclusionScope `json:"scope"`
+	TargetID   int            `json:"target_id"`
+	Reason     string         `json:"reason"`
+	ExcludedBy int            `json:"excluded_by"`
+	ExcludedAt time.Time      `json:"excluded_at"`
+}
+
+func (e *Exclusion) FromJSON(r io.Reader) error {
+	return json.NewDecoder(r).Decode(e)
+}
@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+const (
+	IRTRunStatusRunning  = "running"
+	IRTRunStatusFinished = "finished"
+	IRTRunStatusFailed   = "failed"
+)
+
+// IRTResponse is a single scored first attempt of a user at a question.
+type IRTResponse struct {
+	UserID     int
+	QuestionID int
+	Correct    bool
+}
+
+// IRTItemParams are the 2PL parameters of a question. Difficulty is on the
+// ability scale, where the calibrated population has mean 0 and standard
+// deviation 1. PValue is the raw share of correct answers, kept for
+// comparison.
+type IRTItemParams struct {
+	QuestionID       int     `json:"question_id"`
+	Difficulty       float64 `json:"difficulty"`
+	DifficultySE     float64 `json:"difficulty_se"`
+	Discrimination   float64 `json:"discrimination"`
+	DiscriminationSE float64 `json:"discrimination_se"`
+	Responses        int     `json:"responses"`
+	PValue           float64 `json:"p_value"`
+}
+
+type IRTAbility struct {
+	UserID    int     `json:"user_id"`
+	Ability   float64 `json:"ability"`
+	AbilitySE float64 `json:"ability_se"`
+	Responses int     `json:"responses"`
+	Correct   int     `json:"correct"`
+}
+
+type IRTRun struct {
+	ID            int        `json:"id"`
+	Status        string     `json:"status"`
+	StartedAt     time.Time  `json:"started_at"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`
+	Users         int        `json:"users"`
+	Items         int        `json:"items"`
+	Responses     int        `json:"responses"`
+	Iterations    int        `json:"iterations"`
+	Converged     bool       `json:"converged"`
+	LogLikelihood float64    `json:"log_likelihood"`
+	Error         string     `json:"error,omitempty"`
+}
+
+// QuestionDiscrimination is the classical-test-theory counterpart of the IRT
+// discrimination. PointBiserial is nil when it is undefined, e.g. when every
+// respondent answered the question correctly.
+type QuestionDiscrimination struct {
+	QuestionID    int      `json:"question_id"`
+	Responses     int      `json:"responses"`
+	PointBiserial *float64 `json:"point_biserial"`
+}
@@ -1,11 +1,37 @@
 package models
 
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// LeaderboardRow is a public leaderboard entry. Only users who opted in are
+// listed and they are identified solely by the display name they chose.
 type LeaderboardRow struct {
-	UserID         int     `json:"user_id"`
-	Education      *string `json:"education,omitempty"`
-	Country        *string `json:"country,omitempty"`
+	Rank           int     `json:"rank"`
+	UserID         int     `json:"-"`
+	DisplayName    string  `json:"display_name"`
 	TotalAnswers   int     `json:"total_answers"`
 	CorrectAnswers int     `json:"correct_answers"`
 	Accuracy       float64 `json:"accuracy"`
+	Score          float64 `json:"score"`
+}
+
+// LeaderboardFilter scopes the leaderboard aggregation. Empty fields mean no
+// restriction.
+type LeaderboardFilter struct {
+	Country  string
+	TestCode string
+	Since    *time.Time
 }
 
+type LeaderboardParticipant struct {
+	UserID      int       `json:"-"`
+	DisplayName string    `json:"display_name"`
+	OptedInAt   time.Time `json:"opted_in_at"`
+}
+
+func (p *LeaderboardParticipant) FromJSON(r io.Reader) error {
+	return json.NewDecoder(r).Decode(p)
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// UserParticipation is what stats knows about one user's progress through
+// the study. ActiveWeeks are the Mondays (YYYY-MM-DD) of weeks with answers.
+type UserParticipation struct {
+	UserID          int        `json:"user_id"`
+	SurveyCompleted bool       `json:"survey_completed"`
+	QuizStarted     bool       `json:"quiz_started"`
+	FirstAnswerAt   *time.Time `json:"first_answer_at,omitempty"`
+	Answers         int        `json:"answers"`
+	FinishedTests   int        `json:"finished_tests"`
+	ActiveWeeks     []string   `json:"active_weeks"`
+}
+
+type ParticipationReport struct {
+	Users           []UserParticipation `json:"users"`
+	ExcludedUserIDs []int               `json:"excluded_user_ids"`
+}
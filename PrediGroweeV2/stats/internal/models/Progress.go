@@ -0,0 +1,64 @@
+package models
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// AnswerRecord is one answer of a user in chronological order, the raw input
+// for learning-curve analytics.
+type AnswerRecord struct {
+	QuestionID int        `json:"question_id"`
+	IsCorrect  bool       `json:"is_correct"`
+	Time       *time.Time `json:"time"`
+	Mode       QuizMode   `json:"mode"`
+	TimeSpent  int        `json:"time_spent"`
+}
+
+type ProgressPoint struct {
+	Date               time.Time `json:"date"`
+	Answers            int       `json:"answers"`
+	Correct            int       `json:"correct"`
+	Accuracy           float64   `json:"accuracy"`
+	RollingAccuracy    float64   `json:"rolling_accuracy"`
+	CumulativeAccuracy float64   `json:"cumulative_accuracy"`
+}
+
+// ProgressBreakdown compares the earlier and the more recent half of a
+// user's answers within one slice (mode, option class, case category).
+type ProgressBreakdown struct {
+	Total          int     `json:"total"`
+	Correct        int     `json:"correct"`
+	Accuracy       float64 `json:"accuracy"`
+	EarlyAccuracy  float64 `json:"early_accuracy"`
+	RecentAccuracy float64 `json:"recent_accuracy"`
+	Change         float64 `json:"change"`
+}
+
+type Streaks struct {
+	Current int `json:"current"`
+	Longest int `json:"longest"`
+}
+
+type PopulationComparison struct {
+	Users      int     `json:"users"`
+	Percentile float64 `json:"percentile"`
+}
+
+type UserProgress struct {
+	TotalAnswers   int                             `json:"total_answers"`
+	CorrectAnswers int                             `json:"correct_answers"`
+	Accuracy       float64                         `json:"accuracy"`
+	RollingWindow  int                             `json:"rolling_window"`
+	Timeline       []ProgressPoint                 `json:"timeline"`
+	ByMode         map[QuizMode]*ProgressBreakdown `json:"by_mode"`
+	ByOption       map[string]*ProgressBreakdown   `json:"by_option"`
+	ByCategory     map[string]*ProgressBreakdown   `json:"by_category"`
+	Streaks        Streaks                         `json:"streaks"`
+	Population     *PopulationComparison           `json:"population,omitempty"`
+}
+
+func (p *UserProgress) ToJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(p)
+}
@@ -0,0 +1,13 @@
+package models
+
+// QuestionMeta mirrors the quiz service's flat question description.
+type QuestionMeta struct {
+	QuestionID    int      `json:"question_id"`
+	CaseID        int      `json:"case_id"`
+	CaseCode      string   `json:"case_code"`
+	CaseGender    string   `json:"case_gender"`
+	PredictionAge int      `json:"prediction_age"`
+	Correct       string   `json:"correct"`
+	SNMP          *float64 `json:"sn_mp,omitempty"`
+	Category      string   `json:"category"`
+}
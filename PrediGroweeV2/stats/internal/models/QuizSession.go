@@ -11,6 +11,10 @@ type QuizSession struct {
 	UserID     int        `json:"user_id"`
 	FinishTime *time.Time `json:"finish_time"`
 	QuizMode   string     `json:"quiz_mode"`
+	TestCode   *string    `json:"test_code,omitempty"`
+	// Guest is set for sessions taken without an account. It stays set when
+	// the guest later claims the session into an account.
+	Guest bool `json:"guest"`
 }
 
 func (q *QuizSession) FromJSON(r io.Reader) error {
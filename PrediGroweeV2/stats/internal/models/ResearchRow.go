@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// ResearchRow is a single answer joined with its session and the respondent's
+// survey. It never carries identifiers that other endpoints tie to a user:
+// the user and the session are represented only by ParticipantID and
+// SessionPseudonym, stable pseudonyms derived from their ids.
+type ResearchRow struct {
+	ParticipantID    string     `json:"participant_id"`
+	UserID           int        `json:"-"`
+	SessionID        int        `json:"-"`
+	SessionPseudonym string     `json:"session_id"`
+	QuizMode         string     `json:"quiz_mode"`
+	SessionFinished  *time.Time `json:"session_finished_at,omitempty"`
+	Guest            bool       `json:"guest"`
+	QuestionID       int        `json:"question_id"`
+	CaseCode         string     `json:"case_code"`
+	Answer           string     `json:"answer"`
+	IsCorrect        bool       `json:"is_correct"`
+	AnsweredAt       *time.Time `json:"answered_at,omitempty"`
+	TimeSpent        int        `json:"time_spent"`
+	ScreenSize       string     `json:"screen_size"`
+	Gender           string     `json:"gender"`
+	Age              string     `json:"age"`
+	VisionDefect     string     `json:"vision_defect"`
+	Education        string     `json:"education"`
+	Experience       string     `json:"experience"`
+	Country          string     `json:"country"`
+}
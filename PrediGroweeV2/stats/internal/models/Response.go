@@ -16,6 +16,8 @@ type QuestionResponse struct {
 	UserID     *int       `json:"user_id,omitempty"`
 	ScreenSize string     `json:"screen_size"`
 	TimeSpent  int        `json:"time_spent"`
+	Excluded   bool       `json:"excluded"`
+	TimeZone   string     `json:"time_zone,omitempty"`
 }
 
 func (q *QuestionResponse) FromJSON(r io.Reader) error {
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type SessionAccuracy struct {
 	SessionID int     `json:"session_id"`
 	Correct   int     `json:"correct"`
@@ -7,3 +9,13 @@ type SessionAccuracy struct {
 	Accuracy  float64 `json:"accuracy"`
 }
 
+
+// SessionAnswer is the first answer given to a question within a session.
+type SessionAnswer struct {
+	SessionID  int       `json:"session_id"`
+	QuestionID int       `json:"question_id"`
+	Answer     string    `json:"answer"`
+	Correct    bool      `json:"correct"`
+	TimeSpent  int       `json:"time_spent"`
+	AnswerTime time.Time `json:"answer_time"`
+}
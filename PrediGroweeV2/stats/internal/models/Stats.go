@@ -70,3 +70,27 @@ type SurveyGroupedStats struct {
 	Correct  int     `json:"correct"`
 	Accuracy float64 `json:"accuracy"`
 }
+
+// ActivityHeatmapCell counts answers given on an ISO weekday (1 = Monday)
+// during an hour of the day, both in the requested time zone.
+type ActivityHeatmapCell struct {
+	Weekday int `json:"weekday"`
+	Hour    int `json:"hour"`
+	Total   int `json:"total"`
+	Correct int `json:"correct"`
+}
+
+type ActivityHeatmap struct {
+	TimeZone string                `json:"time_zone"`
+	Country  string                `json:"country,omitempty"`
+	Cells    []ActivityHeatmapCell `json:"cells"`
+}
+
+type CountryActivity struct {
+	Country  string  `json:"country"`
+	Users    int     `json:"users"`
+	Answers  int     `json:"answers"`
+	Correct  int     `json:"correct"`
+	Accuracy float64 `json:"accuracy"`
+	ByHour   []int   `json:"by_hour"`
+}
@@ -8,11 +8,16 @@ import (
 	"github.com/lib/pq"
 )
 
+// Aggregate statistics read from the included_answers and included_sessions
+// views, which hide excluded responses, sessions and users. A participant's
+// own results and per-session scores read answers directly, so an exclusion
+// never changes what the participant or their teacher was shown.
 type Storage interface {
 	Ping() error
 	Close() error
 	SaveResponse(sessionID int, response *models.QuestionResponse) error
 	SaveSession(session *models.QuizSession) error
+	ReassignUserSessions(fromUserID int, toUserID int) (int, error)
 	GetUserStatsForMode(userID int, mode models.QuizMode) (correctCount int, wrongCount int, err error)
 	GetQuizSessionByID(quizSessionID int) (*models.QuizSession, error)
 	GetQuizQuestionsStats(quizSessionID int) ([]models.QuestionStat, error)
@@ -28,21 +33,65 @@ type Storage interface {
 	GetAllResponses() ([]models.QuestionResponse, error)
 	GetStatsForQuestion(id int) (models.QuestionAllStats, error)
 	GetStatsForAllQuestions() ([]models.QuestionAllStats, error)
-	GetActivityStats() ([]models.ActivityStats, error)
+	GetActivityStats(tz string) ([]models.ActivityStats, error)
+	GetActivityHeatmap(tz, country string) ([]models.ActivityHeatmapCell, error)
+	GetActivityByCountry(tz string) ([]models.CountryActivity, error)
+	GetDeviceStats() ([]models.DeviceStats, error)
+	GetResolutionStats(minAnswers int) ([]models.DeviceStats, error)
 	CountQuizSessions() (int, error)
 	CountAnswers() (int, error)
 	CountCorrectAnswers() (int, error)
 	GetUserQuizSessionsStats(userID int) ([]*models.QuizStats, error)
+	GetUserAnswerHistory(userID int) ([]models.AnswerRecord, error)
+	GetUsersAccuracy(minAnswers int) ([]float64, error)
 	GetStatsGroupedBySurveyField(field string) ([]models.SurveyGroupedStats, error)
 	DeleteUserResponses(userId int) error
 	DeleteResponse(id int) error
 	GetAllUsersStats() ([]models.UserQuizStats, error)
-    GetLeaderboard(minAnswers, limit int) ([]models.LeaderboardRow, error)
+	GetLeaderboard(filter models.LeaderboardFilter) ([]models.LeaderboardRow, error)
+	GetLeaderboardParticipant(userID int) (*models.LeaderboardParticipant, error)
+	UpsertLeaderboardParticipant(userID int, displayName string) error
+	DeleteLeaderboardParticipant(userID int) error
 	GetAccuracyBatch(sessionIDs []int) ([]models.SessionAccuracy, error)
+	GetSessionAnswers(sessionIDs []int) ([]models.SessionAnswer, error)
+
+	// research
+	EachResearchRow(fn func(models.ResearchRow) error) error
+
+	// timing and guessing detection
+	GetQuestionTimingStats() ([]models.TimingStats, error)
+	GetUserTimingStats() ([]models.TimingStats, error)
+	GetTimeBucketStats(bounds []int) (map[int]models.TimeBucketStats, error)
+	GetSessionAnswerPatterns(fastSeconds, minAnswers int) ([]models.SessionAnswerPattern, error)
+
+	// exclusions
+	CreateExclusion(exclusion *models.Exclusion) error
+	GetExclusions(scope string) ([]models.Exclusion, error)
+	DeleteExclusion(id int) error
+	DeleteExclusionForTarget(scope string, targetID int) error
+	GetExcludedUserIDs() ([]int, error)
+
+	// participation
+	GetParticipation() ([]models.UserParticipation, error)
+
+	// item response theory
+	GetIRTResponses() ([]models.IRTResponse, error)
+	CreateIRTRun() (*models.IRTRun, error)
+	FinishIRTRun(run *models.IRTRun, items []models.IRTItemParams, abilities []models.IRTAbility) error
+	FailIRTRun(id int, reason string) error
+	GetLatestIRTRun() (*models.IRTRun, error)
+	GetIRTItemParams() ([]models.IRTItemParams, error)
+	GetIRTItemParamsForQuestion(questionID int) (*models.IRTItemParams, error)
+	GetIRTAbilities() ([]models.IRTAbility, error)
+	GetIRTUserAbility(userID int) (*models.IRTAbility, error)
+	GetQuestionDiscrimination(minUserAnswers int) ([]models.QuestionDiscrimination, error)
 }
 
 var ErrSessionNotFound = fmt.Errorf("session not found")
 var ErrStatsNotFound = fmt.Errorf("stats not found")
+var ErrExclusionNotFound = fmt.Errorf("exclusion not found")
+var ErrExclusionTargetNotFound = fmt.Errorf("exclusion target not found")
+var ErrIRTNotFound = fmt.Errorf("irt calibration not found")
 
 type PostgresStorage struct {
 	db     *sql.DB
@@ -64,7 +113,12 @@ func (p *PostgresStorage) Close() error {
 	return p.db.Close()
 }
 func (p *PostgresStorage) SaveResponse(sessionID int, response *models.QuestionResponse) error {
-	_, err := p.db.Exec(`INSERT INTO answers (session_id, question_id, answer, correct, screen_size, time_spent, case_code) values ($1, $2, $3, $4, $5, $6, $7)`, sessionID, response.QuestionID, response.Answer, response.IsCorrect, response.ScreenSize, response.TimeSpent, response.CaseCode)
+	var width, height sql.NullInt64
+	if w, h, ok := models.ParseScreenSize(response.ScreenSize); ok {
+		width = sql.NullInt64{Int64: int64(w), Valid: true}
+		height = sql.NullInt64{Int64: int64(h), Valid: true}
+	}
+	_, err := p.db.Exec(`INSERT INTO answers (session_id, question_id, answer, correct, screen_size, time_spent, case_code, time_zone, screen_width, screen_height) values ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9, $10)`, sessionID, response.QuestionID, response.Answer, response.IsCorrect, response.ScreenSize, response.TimeSpent, response.CaseCode, response.TimeZone, width, height)
 	if err != nil {
 		return err
 	}
@@ -72,12 +126,23 @@ func (p *PostgresStorage) SaveResponse(sessionID int, response *models.QuestionR
 }
 
 func (p *PostgresStorage) SaveSession(session *models.QuizSession) error {
-	_, err := p.db.Exec(`INSERT INTO quiz_sessions (user_id, quiz_mode, session_id) values ($1, $2, $3)`, session.UserID, session.QuizMode, session.SessionID)
+	_, err := p.db.Exec(`INSERT INTO quiz_sessions (user_id, quiz_mode, session_id, test_code, guest) values ($1, $2, $3, $4, $5)`, session.UserID, session.QuizMode, session.SessionID, session.TestCode, session.Guest)
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+// ReassignUserSessions moves every session of one user, and so their
+// answers, to another user. It returns the number of sessions moved.
+func (p *PostgresStorage) ReassignUserSessions(fromUserID int, toUserID int) (int, error) {
+	res, err := p.db.Exec(`UPDATE quiz_sessions SET user_id = $2 WHERE user_id = $1`, fromUserID, toUserID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
 func (p *PostgresStorage) GetUserStatsForMode(userID int, mode models.QuizMode) (correctCount int, wrongCount int, err error) {
 	rows, err := p.db.Query(`select correct, count(*) from answers a
     join quiz_sessions s on a.session_id = s.session_id
@@ -142,6 +207,54 @@ func (p *PostgresStorage) GetUserQuizSessionsStats(userID int) ([]*models.QuizSt
 	return stats, nil
 }
 
+func (p *PostgresStorage) GetUserAnswerHistory(userID int) ([]models.AnswerRecord, error) {
+	rows, err := p.db.Query(`
+		SELECT a.question_id, a.correct, a.answer_time, s.quiz_mode, COALESCE(a.time_spent, 0)
+		  FROM answers a
+		  JOIN quiz_sessions s ON a.session_id = s.session_id
+		 WHERE s.user_id = $1
+		 ORDER BY a.answer_time, a.id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.AnswerRecord, 0)
+	for rows.Next() {
+		var rec models.AnswerRecord
+		if err := rows.Scan(&rec.QuestionID, &rec.IsCorrect, &rec.Time, &rec.Mode, &rec.TimeSpent); err != nil {
+			return nil, err
+		}
+		out = append(out, rec)
+	}
+	return out, rows.Err()
+}
+
+// GetUsersAccuracy returns the overall accuracy of every user with at least
+// minAnswers answers, used as the reference population for percentiles.
+func (p *PostgresStorage) GetUsersAccuracy(minAnswers int) ([]float64, error) {
+	rows, err := p.db.Query(`
+		SELECT SUM(CASE WHEN a.correct THEN 1 ELSE 0 END)::float8 / COUNT(*)
+		  FROM included_answers a
+		  JOIN quiz_sessions s ON a.session_id = s.session_id
+		 GROUP BY s.user_id
+		HAVING COUNT(*) >= $1`, minAnswers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]float64, 0)
+	for rows.Next() {
+		var acc float64
+		if err := rows.Scan(&acc); err != nil {
+			return nil, err
+		}
+		out = append(out, acc)
+	}
+	return out, rows.Err()
+}
+
 func (p *PostgresStorage) GetQuizSessionByID(quizSessionID int) (*models.QuizSession, error) {
 	var session models.QuizSession
 	err := p.db.QueryRow(`SELECT user_id, session_id, finish_time, quiz_mode FROM quiz_sessions WHERE session_id = $1`, quizSessionID).Scan(&session.UserID, &session.SessionID, &session.FinishTime, &session.QuizMode)
@@ -266,7 +379,8 @@ func (p *PostgresStorage) GetAllSurveyResponses() ([]models.SurveyResponse, erro
 	return surveys, nil
 }
 func (p *PostgresStorage) GetAllResponses() ([]models.QuestionResponse, error) {
-	query := `SELECT id, user_id, question_id, answer, correct, answer_time, answers.screen_size, answers.time_spent, answers.case_code FROM answers
+	query := `SELECT id, user_id, question_id, answer, correct, answer_time, answers.screen_size, answers.time_spent, answers.case_code,
+       NOT EXISTS (SELECT 1 FROM included_answers i WHERE i.id = answers.id) FROM answers
     			join quiz_sessions on answers.session_id = quiz_sessions.session_id
                 order by answer_time desc;`
 	rows, err := p.db.Query(query)
@@ -276,7 +390,7 @@ func (p *PostgresStorage) GetAllResponses() ([]models.QuestionResponse, error) {
 	var stats []models.QuestionResponse
 	for rows.Next() {
 		var stat models.QuestionResponse
-		err = rows.Scan(&stat.ID, &stat.UserID, &stat.QuestionID, &stat.Answer, &stat.IsCorrect, &stat.Time, &stat.ScreenSize, &stat.TimeSpent, &stat.CaseCode)
+		err = rows.Scan(&stat.ID, &stat.UserID, &stat.QuestionID, &stat.Answer, &stat.IsCorrect, &stat.Time, &stat.ScreenSize, &stat.TimeSpent, &stat.CaseCode, &stat.Excluded)
 		if err != nil {
 			return nil, err
 		}
@@ -285,7 +399,7 @@ func (p *PostgresStorage) GetAllResponses() ([]models.QuestionResponse, error) {
 	return stats, nil
 }
 func (p *PostgresStorage) GetStatsForQuestion(id int) (models.QuestionAllStats, error) {
-	query := `SELECT question_id, count(*), sum(CASE WHEN correct THEN 1 ELSE 0 END) FROM answers
+	query := `SELECT question_id, count(*), sum(CASE WHEN correct THEN 1 ELSE 0 END) FROM included_answers
 				WHERE question_id = $1
 				group by question_id`
 	var stats models.QuestionAllStats
@@ -300,7 +414,7 @@ func (p *PostgresStorage) GetStatsForQuestion(id int) (models.QuestionAllStats,
 }
 
 func (p *PostgresStorage) GetStatsForAllQuestions() ([]models.QuestionAllStats, error) {
-	query := `SELECT question_id, count(*), sum(CASE WHEN correct THEN 1 ELSE 0 END) FROM answers
+	query := `SELECT question_id, count(*), sum(CASE WHEN correct THEN 1 ELSE 0 END) FROM included_answers
 				group by question_id`
 	rows, err := p.db.Query(query)
 	if err != nil {
@@ -321,13 +435,15 @@ func (p *PostgresStorage) GetStatsForAllQuestions() ([]models.QuestionAllStats,
 	return stats, nil
 }
 
-func (p *PostgresStorage) GetActivityStats() ([]models.ActivityStats, error) {
-	query := `SELECT * FROM (SELECT date_trunc('day', answer_time) as date, count(*), sum(CASE WHEN correct THEN 1 ELSE 0 END) FROM answers
-				group by date_trunc('day', answer_time)
-				order by date_trunc('day', answer_time) desc
+// GetActivityStats returns the last ten active days, with days taken in the
+// tz time zone.
+func (p *PostgresStorage) GetActivityStats(tz string) ([]models.ActivityStats, error) {
+	query := `SELECT * FROM (SELECT date_trunc('day', answer_time AT TIME ZONE $1) as date, count(*), sum(CASE WHEN correct THEN 1 ELSE 0 END) FROM included_answers
+				group by 1
+				order by 1 desc
 				limit 10) as dcs ORDER BY date ASC`
 	var stats []models.ActivityStats
-	rows, err := p.db.Query(query)
+	rows, err := p.db.Query(query, tz)
 	if err != nil {
 		return []models.ActivityStats{}, err
 	}
@@ -344,7 +460,7 @@ func (p *PostgresStorage) GetActivityStats() ([]models.ActivityStats, error) {
 
 func (p *PostgresStorage) CountQuizSessions() (int, error) {
 	var count int
-	err := p.db.QueryRow(`SELECT count(*) FROM quiz_sessions`).Scan(&count)
+	err := p.db.QueryRow(`SELECT count(*) FROM included_sessions`).Scan(&count)
 	if err != nil {
 		return 0, err
 	}
@@ -353,7 +469,7 @@ func (p *PostgresStorage) CountQuizSessions() (int, error) {
 
 func (p *PostgresStorage) CountAnswers() (int, error) {
 	var count int
-	err := p.db.QueryRow(`SELECT count(*) FROM answers`).Scan(&count)
+	err := p.db.QueryRow(`SELECT count(*) FROM included_answers`).Scan(&count)
 	if err != nil {
 		return 0, err
 	}
@@ -362,7 +478,7 @@ func (p *PostgresStorage) CountAnswers() (int, error) {
 
 func (p *PostgresStorage) CountCorrectAnswers() (int, error) {
 	var count int
-	err := p.db.QueryRow(`SELECT count(*) FROM answers WHERE correct = true`).Scan(&count)
+	err := p.db.QueryRow(`SELECT count(*) FROM included_answers WHERE correct = true`).Scan(&count)
 	if err != nil {
 		return 0, err
 	}
@@ -380,7 +496,7 @@ func (p *PostgresStorage) GetStatsGroupedBySurveyField(field string) ([]models.S
                SUM(CASE WHEN a.correct THEN 1 ELSE 0 END) as correct
            FROM users_surveys us
            LEFT JOIN quiz_sessions qs ON us.user_id = qs.user_id
-           LEFT JOIN answers a ON qs.session_id = a.session_id
+           LEFT JOIN included_answers a ON qs.session_id = a.session_id
            WHERE us.gender IS NOT NULL
            GROUP BY us.gender`
 	case "age":
@@ -391,7 +507,7 @@ func (p *PostgresStorage) GetStatsGroupedBySurveyField(field string) ([]models.S
                SUM(CASE WHEN a.correct THEN 1 ELSE 0 END) as correct
            FROM users_surveys us
            LEFT JOIN quiz_sessions qs ON us.user_id = qs.user_id
-           LEFT JOIN answers a ON qs.session_id = a.session_id
+           LEFT JOIN included_answers a ON qs.session_id = a.session_id
            WHERE us.age IS NOT NULL
            GROUP BY us.age`
 	case "vision_defect":
@@ -402,7 +518,7 @@ func (p *PostgresStorage) GetStatsGroupedBySurveyField(field string) ([]models.S
                SUM(CASE WHEN a.correct THEN 1 ELSE 0 END) as correct
            FROM users_surveys us
            LEFT JOIN quiz_sessions qs ON us.user_id = qs.user_id
-           LEFT JOIN answers a ON qs.session_id = a.session_id
+           LEFT JOIN included_answers a ON qs.session_id = a.session_id
            WHERE us.vision_defect IS NOT NULL
            GROUP BY us.vision_defect`
 	case "education":
@@ -413,7 +529,7 @@ func (p *PostgresStorage) GetStatsGroupedBySurveyField(field string) ([]models.S
                SUM(CASE WHEN a.correct THEN 1 ELSE 0 END) as correct
            FROM users_surveys us
            LEFT JOIN quiz_sessions qs ON us.user_id = qs.user_id
-           LEFT JOIN answers a ON qs.session_id = a.session_id
+           LEFT JOIN included_answers a ON qs.session_id = a.session_id
            WHERE us.education IS NOT NULL
            GROUP BY us.education`
 	case "experience":
@@ -424,7 +540,7 @@ func (p *PostgresStorage) GetStatsGroupedBySurveyField(field string) ([]models.S
                SUM(CASE WHEN a.correct THEN 1 ELSE 0 END) as correct
            FROM users_surveys us
            LEFT JOIN quiz_sessions qs ON us.user_id = qs.user_id
-           LEFT JOIN answers a ON qs.session_id = a.session_id
+           LEFT JOIN included_answers a ON qs.session_id = a.session_id
            WHERE us.experience IS NOT NULL
            GROUP BY us.experience`
 	case "country":
@@ -435,7 +551,7 @@ func (p *PostgresStorage) GetStatsGroupedBySurveyField(field string) ([]models.S
                SUM(CASE WHEN a.correct THEN 1 ELSE 0 END) as correct
            FROM users_surveys us
            LEFT JOIN quiz_sessions qs ON us.user_id = qs.user_id
-           LEFT JOIN answers a ON qs.session_id = a.session_id
+           LEFT JOIN included_answers a ON qs.session_id = a.session_id
            WHERE us.country IS NOT NULL
            GROUP BY us.country`
 	default:
@@ -485,7 +601,7 @@ func (p *PostgresStorage) GetAllUsersStats() ([]models.UserQuizStats, error) {
                SUM(CASE WHEN correct THEN 1 ELSE 0 END) as correct_answers,
                us.experience,
                us.education
-        FROM answers a
+        FROM included_answers a
         JOIN quiz_sessions s ON a.session_id = s.session_id
         LEFT JOIN users_surveys us ON s.user_id = us.user_id
         GROUP BY s.user_id, us.experience, us.education`
@@ -510,49 +626,85 @@ func (p *PostgresStorage) GetAllUsersStats() ([]models.UserQuizStats, error) {
 	}
 	return stats, nil
 }
-func (p *PostgresStorage) GetLeaderboard(minAnswers, limit int) ([]models.LeaderboardRow, error) {
-	const q = `
-WITH agg AS (
-  SELECT
-    qs.user_id,
-    COALESCE(SUM(CASE WHEN a.correct THEN 1 ELSE 0 END),0) AS correct_answers,
-    COUNT(a.id) AS total_answers
-  FROM quiz_sessions qs
-  JOIN answers a ON a.session_id = qs.session_id
-  GROUP BY qs.user_id
-)
+// GetLeaderboard aggregates answers of opted-in users only. Ranking is left to
+// the caller, which needs the raw counts to compute a confidence bound.
+func (p *PostgresStorage) GetLeaderboard(filter models.LeaderboardFilter) ([]models.LeaderboardRow, error) {
+	q := `
 SELECT
-  agg.user_id,
-  us.education,
-  us.country,
-  agg.total_answers,
-  agg.correct_answers,
-  CASE WHEN agg.total_answers = 0 THEN 0
-       ELSE agg.correct_answers::decimal / agg.total_answers::decimal
-  END AS accuracy
-FROM agg
-LEFT JOIN users_surveys us ON us.user_id = agg.user_id
-WHERE agg.total_answers >= $1
-ORDER BY accuracy DESC, total_answers DESC
-LIMIT $2;
-`
-	rows, err := p.db.Query(q, minAnswers, limit)
+  qs.user_id,
+  lp.display_name,
+  COUNT(a.id) AS total_answers,
+  COALESCE(SUM(CASE WHEN a.correct THEN 1 ELSE 0 END),0) AS correct_answers
+FROM quiz_sessions qs
+JOIN included_answers a ON a.session_id = qs.session_id
+JOIN leaderboard_participants lp ON lp.user_id = qs.user_id
+LEFT JOIN users_surveys us ON us.user_id = qs.user_id
+WHERE 1=1`
+	args := make([]interface{}, 0, 3)
+	if filter.Country != "" {
+		args = append(args, filter.Country)
+		q += fmt.Sprintf(" AND lower(us.country) = lower($%d)", len(args))
+	}
+	if filter.TestCode != "" {
+		args = append(args, filter.TestCode)
+		q += fmt.Sprintf(" AND qs.test_code = $%d", len(args))
+	}
+	if filter.Since != nil {
+		args = append(args, *filter.Since)
+		q += fmt.Sprintf(" AND a.answer_time >= $%d", len(args))
+	}
+	q += `
+GROUP BY qs.user_id, lp.display_name`
+
+	rows, err := p.db.Query(q, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	res := make([]models.LeaderboardRow, 0, limit)
+	res := make([]models.LeaderboardRow, 0)
 	for rows.Next() {
 		var r models.LeaderboardRow
-		if err := rows.Scan(&r.UserID, &r.Education, &r.Country, &r.TotalAnswers, &r.CorrectAnswers, &r.Accuracy); err != nil {
+		if err := rows.Scan(&r.UserID, &r.DisplayName, &r.TotalAnswers, &r.CorrectAnswers); err != nil {
 			return nil, err
 		}
+		if r.TotalAnswers > 0 {
+			r.Accuracy = float64(r.CorrectAnswers) / float64(r.TotalAnswers)
+		}
 		res = append(res, r)
 	}
 	return res, rows.Err()
 }
 
+func (p *PostgresStorage) GetLeaderboardParticipant(userID int) (*models.LeaderboardParticipant, error) {
+	var lp models.LeaderboardParticipant
+	err := p.db.QueryRow(`SELECT user_id, display_name, opted_in_at FROM leaderboard_participants WHERE user_id = $1`, userID).
+		Scan(&lp.UserID, &lp.DisplayName, &lp.OptedInAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &lp, nil
+}
+
+func (p *PostgresStorage) UpsertLeaderboardParticipant(userID int, displayName string) error {
+	_, err := p.db.Exec(`
+		INSERT INTO leaderboard_participants (user_id, display_name)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		   SET display_name = EXCLUDED.display_name,
+		       updated_at = now()
+	`, userID, displayName)
+	return err
+}
+
+func (p *PostgresStorage) DeleteLeaderboardParticipant(userID int) error {
+	_, err := p.db.Exec(`DELETE FROM leaderboard_participants WHERE user_id = $1`, userID)
+	return err
+}
+
 func (p *PostgresStorage) GetAccuracyBatch(sessionIDs []int) ([]models.SessionAccuracy, error) {
 	out := make([]models.SessionAccuracy, 0, len(sessionIDs))
 	if len(sessionIDs) == 0 {
@@ -596,3 +748,754 @@ func (p *PostgresStorage) GetAccuracyBatch(sessionIDs []int) ([]models.SessionAc
 	}
 	return out, rows.Err()
 }
+
+// GetSessionAnswers returns the first answer to each question in the given
+// sessions, ordered by session and answer time. Like GetAccuracyBatch it
+// reads answers directly, so teachers see what their students answered even
+// if a session is excluded from aggregate statistics.
+func (p *PostgresStorage) GetSessionAnswers(sessionIDs []int) ([]models.SessionAnswer, error) {
+	out := make([]models.SessionAnswer, 0)
+	if len(sessionIDs) == 0 {
+		return out, nil
+	}
+	rows, err := p.db.Query(`
+		SELECT session_id, question_id, answer, correct, time_spent, answer_time
+		  FROM (
+			SELECT DISTINCT ON (session_id, question_id)
+			       session_id, question_id, COALESCE(answer, '') AS answer, correct, COALESCE(time_spent, 0) AS time_spent, answer_time
+			  FROM answers
+			 WHERE session_id = ANY($1)
+			 ORDER BY session_id, question_id, answer_time, id
+		  ) first
+		 ORDER BY session_id, answer_time, question_id
+	`, pq.Array(sessionIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a models.SessionAnswer
+		if err := rows.Scan(&a.SessionID, &a.QuestionID, &a.Answer, &a.Correct, &a.TimeSpent, &a.AnswerTime); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// EachResearchRow calls fn with every answer of the research dataset in
+// turn, without holding the dataset in memory, and stops at fn's first
+// error.
+func (p *PostgresStorage) EachResearchRow(fn func(models.ResearchRow) error) error {
+	rows, err := p.db.Query(`
+		SELECT qs.user_id, a.session_id, qs.quiz_mode, qs.finish_time, qs.guest,
+		       a.question_id, a.case_code, a.answer, a.correct, a.answer_time,
+		       a.time_spent, a.screen_size,
+		       us.gender, us.age, us.vision_defect, us.education, us.experience, us.country
+		  FROM included_answers a
+		  JOIN quiz_sessions qs ON qs.session_id = a.session_id
+		  LEFT JOIN users_surveys us ON us.user_id = qs.user_id
+		 ORDER BY qs.user_id, a.session_id, a.answer_time, a.id
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			r                                          models.ResearchRow
+			caseCode, answer, screenSize               sql.NullString
+			timeSpent                                  sql.NullInt64
+			gender, age, vision, education, exp, cntry sql.NullString
+		)
+		if err := rows.Scan(
+			&r.UserID, &r.SessionID, &r.QuizMode, &r.SessionFinished, &r.Guest,
+			&r.QuestionID, &caseCode, &answer, &r.IsCorrect, &r.AnsweredAt,
+			&timeSpent, &screenSize,
+			&gender, &age, &vision, &education, &exp, &cntry,
+		); err != nil {
+			return err
+		}
+		r.CaseCode = caseCode.String
+		r.Answer = answer.String
+		r.TimeSpent = int(timeSpent.Int64)
+		r.ScreenSize = screenSize.String
+		r.Gender = gender.String
+		r.Age = age.String
+		r.VisionDefect = vision.String
+		r.Education = education.String
+		r.Experience = exp.String
+		r.Country = cntry.String
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+
+const timingAggregates = `
+		       COUNT(*),
+		       SUM(CASE WHEN a.correct THEN 1 ELSE 0 END),
+		       AVG(a.time_spent)::float8,
+		       MIN(a.time_spent),
+		       percentile_cont(0.25) WITHIN GROUP (ORDER BY a.time_spent),
+		       percentile_cont(0.5) WITHIN GROUP (ORDER BY a.time_spent),
+		       percentile_cont(0.75) WITHIN GROUP (ORDER BY a.time_spent),
+		       percentile_cont(0.9) WITHIN GROUP (ORDER BY a.time_spent),
+		       MAX(a.time_spent)`
+
+func (p *PostgresStorage) GetQuestionTimingStats() ([]models.TimingStats, error) {
+	return p.queryTimingStats(`
+		SELECT a.question_id,` + timingAggregates + `
+		  FROM included_answers a
+		 WHERE a.time_spent IS NOT NULL
+		 GROUP BY a.question_id
+		 ORDER BY a.question_id`, func(s *models.TimingStats, id int) { s.QuestionID = &id })
+}
+
+func (p *PostgresStorage) GetUserTimingStats() ([]models.TimingStats, error) {
+	return p.queryTimingStats(`
+		SELECT s.user_id,` + timingAggregates + `
+		  FROM included_answers a
+		  JOIN quiz_sessions s ON a.session_id = s.session_id
+		 WHERE a.time_spent IS NOT NULL
+		 GROUP BY s.user_id
+		 ORDER BY s.user_id`, func(s *models.TimingStats, id int) { s.UserID = &id })
+}
+
+func (p *PostgresStorage) queryTimingStats(query string, setKey func(*models.TimingStats, int)) ([]models.TimingStats, error) {
+	rows, err := p.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.TimingStats, 0)
+	for rows.Next() {
+		var (
+			key int
+			st  models.TimingStats
+		)
+		if err := rows.Scan(&key, &st.Answers, &st.Correct, &st.Mean, &st.Min, &st.P25, &st.Median, &st.P75, &st.P90, &st.Max); err != nil {
+			return nil, err
+		}
+		setKey(&st, key)
+		if st.Answers > 0 {
+			st.Accuracy = float64(st.Correct) / float64(st.Answers)
+		}
+		out = append(out, st)
+	}
+	return out, rows.Err()
+}
+
+// GetTimeBucketStats counts answers per time bucket. Buckets are keyed by
+// the width_bucket index over bounds: key i covers [bounds[i-1], bounds[i]).
+func (p *PostgresStorage) GetTimeBucketStats(bounds []int) (map[int]models.TimeBucketStats, error) {
+	rows, err := p.db.Query(`
+		SELECT width_bucket(a.time_spent, $1::int[]) AS bucket,
+		       COUNT(*),
+		       SUM(CASE WHEN a.correct THEN 1 ELSE 0 END)
+		  FROM included_answers a
+		 WHERE a.time_spent IS NOT NULL
+		 GROUP BY bucket`, pq.Array(bounds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[int]models.TimeBucketStats)
+	for rows.Next() {
+		var (
+			bucket int
+			st     models.TimeBucketStats
+		)
+		if err := rows.Scan(&bucket, &st.Total, &st.Correct); err != nil {
+			return nil, err
+		}
+		out[bucket] = st
+	}
+	return out, rows.Err()
+}
+
+// GetSessionAnswerPatterns returns every session with at least minAnswers
+// answers, including excluded ones so that reviewers see their status.
+func (p *PostgresStorage) GetSessionAnswerPatterns(fastSeconds, minAnswers int) ([]models.SessionAnswerPattern, error) {
+	rows, err := p.db.Query(`
+		SELECT a.session_id, s.user_id,
+		       COUNT(*),
+		       SUM(CASE WHEN COALESCE(a.time_spent, 0) < $1 THEN 1 ELSE 0 END),
+		       SUM(CASE WHEN a.correct THEN 1 ELSE 0 END),
+		       array_agg(a.question_id ORDER BY a.question_id),
+		       array_agg(COALESCE(a.answer, '') ORDER BY a.question_id),
+		       array_agg(a.correct ORDER BY a.question_id),
+		       e.excluded_at
+		  FROM answers a
+		  JOIN quiz_sessions s ON a.session_id = s.session_id
+		  LEFT JOIN exclusions e ON e.scope = 'session' AND e.target_id = a.session_id
+		 GROUP BY a.session_id, s.user_id, e.excluded_at
+		HAVING COUNT(*) >= $2
+		 ORDER BY a.session_id`, fastSeconds, minAnswers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.SessionAnswerPattern, 0)
+	for rows.Next() {
+		var (
+			sp          models.SessionAnswerPattern
+			questionIDs pq.Int64Array
+			answers     pq.StringArray
+			corrects    pq.BoolArray
+		)
+		if err := rows.Scan(&sp.SessionID, &sp.UserID, &sp.Answers, &sp.FastAnswers, &sp.Correct,
+			&questionIDs, &answers, &corrects, &sp.ExcludedAt); err != nil {
+			return nil, err
+		}
+		sp.QuestionIDs = make([]int, len(questionIDs))
+		for i, id := range questionIDs {
+			sp.QuestionIDs[i] = int(id)
+		}
+		sp.Sequence = answers
+		sp.Corrects = corrects
+		out = append(out, sp)
+	}
+	return out, rows.Err()
+}
+
+func (p *PostgresStorage) CreateExclusion(exclusion *models.Exclusion) error {
+	var exists bool
+	var err error
+	switch exclusion.Scope {
+	case models.ExclusionScopeResponse:
+		err = p.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM answers WHERE id = $1)`, exclusion.TargetID).Scan(&exists)
+	case models.ExclusionScopeSession:
+		err = p.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM quiz_sessions WHERE session_id = $1)`, exclusion.TargetID).Scan(&exists)
+	default:
+		// Users may be excluded before they answer anything, e.g. a staff
+		// account created for testing.
+		exists = true
+	}
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrExclusionTargetNotFound
+	}
+	return p.db.QueryRow(`
+		INSERT INTO exclusions (scope, target_id, reason, excluded_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (scope, target_id) DO UPDATE
+		   SET reason = EXCLUDED.reason,
+		       excluded_by = EXCLUDED.excluded_by,
+		       excluded_at = now()
+		RETURNING id, excluded_at
+	`, exclusion.Scope, exclusion.TargetID, exclusion.Reason, exclusion.ExcludedBy).Scan(&exclusion.ID, &exclusion.ExcludedAt)
+}
+
+// GetExclusions lists exclusions, newest first. An empty scope lists all.
+func (p *PostgresStorage) GetExclusions(scope string) ([]models.Exclusion, error) {
+	rows, err := p.db.Query(`
+		SELECT id, scope, target_id, reason, excluded_by, excluded_at
+		  FROM exclusions
+		 WHERE $1 = '' OR scope = $1
+		 ORDER BY excluded_at DESC, id DESC`, scope)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.Exclusion, 0)
+	for rows.Next() {
+		var e models.Exclusion
+		if err := rows.Scan(&e.ID, &e.Scope, &e.TargetID, &e.Reason, &e.ExcludedBy, &e.ExcludedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+func (p *PostgresStorage) DeleteExclusion(id int) error {
+	res, err := p.db.Exec(`DELETE FROM exclusions WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrExclusionNotFound
+	}
+	return nil
+}
+
+func (p *PostgresStorage) DeleteExclusionForTarget(scope string, targetID int) error {
+	_, err := p.db.Exec(`DELETE FROM exclusions WHERE scope = $1 AND target_id = $2`, scope, targetID)
+	return err
+}
+
+func (p *PostgresStorage) GetExcludedUserIDs() ([]int, error) {
+	rows, err := p.db.Query(`SELECT target_id FROM exclusions WHERE scope = 'user' ORDER BY target_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
+
+// GetParticipation returns one row per user who completed the survey or
+// started a quiz. A test counts as finished when its session has a finish time.
+func (p *PostgresStorage) GetParticipation() ([]models.UserParticipation, error) {
+	rows, err := p.db.Query(`
+		WITH activity AS (
+			SELECT s.user_id,
+			       MIN(a.answer_time) AS first_answer_at,
+			       COUNT(a.id) AS answers,
+			       COUNT(DISTINCT s.session_id) FILTER (WHERE s.test_code IS NOT NULL AND s.finish_time IS NOT NULL) AS finished_tests,
+			       array_remove(array_agg(DISTINCT to_char(date_trunc('week', a.answer_time), 'YYYY-MM-DD')), NULL) AS active_weeks
+			  FROM included_sessions s
+			  LEFT JOIN included_answers a ON a.session_id = s.session_id
+			 WHERE s.user_id IS NOT NULL
+			 GROUP BY s.user_id
+		), surveyed AS (
+			SELECT DISTINCT user_id FROM users_surveys
+		)
+		SELECT COALESCE(act.user_id, sv.user_id),
+		       sv.user_id IS NOT NULL,
+		       act.user_id IS NOT NULL,
+		       act.first_answer_at,
+		       COALESCE(act.answers, 0),
+		       COALESCE(act.finished_tests, 0),
+		       COALESCE(act.active_weeks, '{}')
+		  FROM activity act
+		  FULL JOIN surveyed sv ON sv.user_id = act.user_id
+		 ORDER BY 1`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.UserParticipation, 0)
+	for rows.Next() {
+		var (
+			up    models.UserParticipation
+			weeks pq.StringArray
+		)
+		if err := rows.Scan(&up.UserID, &up.SurveyCompleted, &up.QuizStarted, &up.FirstAnswerAt,
+			&up.Answers, &up.FinishedTests, &weeks); err != nil {
+			return nil, err
+		}
+		up.ActiveWeeks = weeks
+		out = append(out, up)
+	}
+	return out, rows.Err()
+}
+
+// localAnswerTime converts answer_time to wall-clock time in the zone given
+// as $1, or in each respondent's own zone when $1 is 'local'. Answers saved
+// before time zones were recorded fall back to UTC.
+const localAnswerTime = `(a.answer_time AT TIME ZONE CASE WHEN $1 = 'local' THEN COALESCE(a.time_zone, 'UTC') ELSE $1 END)`
+
+func (p *PostgresStorage) GetActivityHeatmap(tz, country string) ([]models.ActivityHeatmapCell, error) {
+	rows, err := p.db.Query(`
+		SELECT EXTRACT(ISODOW FROM `+localAnswerTime+`)::int AS weekday,
+		       EXTRACT(HOUR FROM `+localAnswerTime+`)::int AS hour,
+		       COUNT(*),
+		       SUM(CASE WHEN a.correct THEN 1 ELSE 0 END)
+		  FROM included_answers a
+		  JOIN quiz_sessions s ON s.session_id = a.session_id
+		  LEFT JOIN users_surveys us ON us.user_id = s.user_id
+		 WHERE a.answer_time IS NOT NULL
+		   AND ($2 = '' OR lower(us.country) = lower($2))
+		 GROUP BY weekday, hour`, tz, country)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.ActivityHeatmapCell, 0)
+	for rows.Next() {
+		var c models.ActivityHeatmapCell
+		if err := rows.Scan(&c.Weekday, &c.Hour, &c.Total, &c.Correct); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// GetActivityByCountry aggregates answers by the respondent's survey country.
+// Respondents without a survey are grouped under an empty country.
+func (p *PostgresStorage) GetActivityByCountry(tz string) ([]models.CountryActivity, error) {
+	rows, err := p.db.Query(`
+		SELECT COALESCE(us.country, '') AS country,
+		       EXTRACT(HOUR FROM `+localAnswerTime+`)::int AS hour,
+		       COUNT(*),
+		       SUM(CASE WHEN a.correct THEN 1 ELSE 0 END),
+		       array_agg(DISTINCT s.user_id)
+		  FROM included_answers a
+		  JOIN quiz_sessions s ON s.session_id = a.session_id
+		  LEFT JOIN users_surveys us ON us.user_id = s.user_id
+		 WHERE a.answer_time IS NOT NULL
+		 GROUP BY 1, 2
+		 ORDER BY 1, 2`, tz)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.CountryActivity, 0)
+	users := make(map[int]bool)
+	for rows.Next() {
+		var (
+			country        string
+			hour           int
+			total, correct int
+			userIDs        pq.Int64Array
+		)
+		if err := rows.Scan(&country, &hour, &total, &correct, &userIDs); err != nil {
+			return nil, err
+		}
+		if n := len(out); n == 0 || out[n-1].Country != country {
+			out = append(out, models.CountryActivity{Country: country, ByHour: make([]int, 24)})
+			users = make(map[int]bool)
+		}
+		c := &out[len(out)-1]
+		c.Answers += total
+		c.Correct += correct
+		if hour >= 0 && hour < 24 {
+			c.ByHour[hour] += total
+		}
+		for _, id := range userIDs {
+			users[int(id)] = true
+		}
+		c.Users = len(users)
+	}
+	for i := range out {
+		if out[i].Answers > 0 {
+			out[i].Accuracy = float64(out[i].Correct) / float64(out[i].Answers)
+		}
+	}
+	return out, rows.Err()
+}
+
+func (p *PostgresStorage) GetDeviceStats() ([]models.DeviceStats, error) {
+	rows, err := p.db.Query(`
+		SELECT device_class(a.screen_width, a.screen_height) AS class,
+		       COUNT(DISTINCT s.user_id),
+		       COUNT(*),
+		       SUM(CASE WHEN a.correct THEN 1 ELSE 0 END),
+		       COALESCE(AVG(a.time_spent), 0)::float8,
+		       COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY a.time_spent), 0)
+		  FROM included_answers a
+		  JOIN quiz_sessions s ON s.session_id = a.session_id
+		 GROUP BY class
+		 ORDER BY class`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.DeviceStats, 0)
+	for rows.Next() {
+		var d models.DeviceStats
+		if err := rows.Scan(&d.DeviceClass, &d.Users, &d.Answers, &d.Correct, &d.MeanTimeSpent, &d.MedianTimeSpent); err != nil {
+			return nil, err
+		}
+		if d.Answers > 0 {
+			d.Accuracy = float64(d.Correct) / float64(d.Answers)
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// GetResolutionStats groups answers by exact viewport resolution. Rare
+// resolutions are dropped, their accuracy is mostly noise.
+func (p *PostgresStorage) GetResolutionStats(minAnswers int) ([]models.DeviceStats, error) {
+	rows, err := p.db.Query(`
+		SELECT a.screen_width, a.screen_height,
+		       device_class(a.screen_width, a.screen_height),
+		       COUNT(DISTINCT s.user_id),
+		       COUNT(*),
+		       SUM(CASE WHEN a.correct THEN 1 ELSE 0 END),
+		       COALESCE(AVG(a.time_spent), 0)::float8,
+		       COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY a.time_spent), 0)
+		  FROM included_answers a
+		  JOIN quiz_sessions s ON s.session_id = a.session_id
+		 WHERE a.screen_width IS NOT NULL AND a.screen_height IS NOT NULL
+		 GROUP BY a.screen_width, a.screen_height
+		HAVING COUNT(*) >= $1
+		 ORDER BY COUNT(*) DESC, a.screen_width, a.screen_height`, minAnswers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.DeviceStats, 0)
+	for rows.Next() {
+		var d models.DeviceStats
+		if err := rows.Scan(&d.Width, &d.Height, &d.DeviceClass, &d.Users, &d.Answers, &d.Correct, &d.MeanTimeSpent, &d.MedianTimeSpent); err != nil {
+			return nil, err
+		}
+		d.Resolution = fmt.Sprintf("%dx%d", d.Width, d.Height)
+		if d.Answers > 0 {
+			d.Accuracy = float64(d.Correct) / float64(d.Answers)
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// firstAttempts selects each user's first attempt at each question. Later
+// attempts are practice on a question the user has already seen, so they say
+// more about memory than ability. Psychometric estimates use only these.
+const firstAttempts = `
+		SELECT DISTINCT ON (s.user_id, a.question_id) s.user_id, a.question_id, a.correct
+		  FROM included_answers a
+		  JOIN quiz_sessions s ON s.session_id = a.session_id
+		 WHERE s.user_id IS NOT NULL
+		 ORDER BY s.user_id, a.question_id, a.answer_time, a.id`
+
+func (p *PostgresStorage) GetIRTResponses() ([]models.IRTResponse, error) {
+	rows, err := p.db.Query(firstAttempts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.IRTResponse, 0)
+	for rows.Next() {
+		var r models.IRTResponse
+		if err := rows.Scan(&r.UserID, &r.QuestionID, &r.Correct); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// CreateIRTRun starts a new run. Runs still marked as running were
+// interrupted by a restart and are failed first.
+func (p *PostgresStorage) CreateIRTRun() (*models.IRTRun, error) {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE irt_runs SET status = $1, finished_at = now(), error = 'interrupted' WHERE status = $2`,
+		models.IRTRunStatusFailed, models.IRTRunStatusRunning); err != nil {
+		return nil, err
+	}
+	run := &models.IRTRun{Status: models.IRTRunStatusRunning}
+	if err := tx.QueryRow(`INSERT INTO irt_runs (status) VALUES ($1) RETURNING id, started_at`, run.Status).Scan(&run.ID, &run.StartedAt); err != nil {
+		return nil, err
+	}
+	return run, tx.Commit()
+}
+
+func (p *PostgresStorage) FinishIRTRun(run *models.IRTRun, items []models.IRTItemParams, abilities []models.IRTAbility) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	itemStmt, err := tx.Prepare(pq.CopyIn("irt_item_params", "run_id", "question_id", "difficulty", "difficulty_se",
+		"discrimination", "discrimination_se", "responses", "p_value"))
+	if err != nil {
+		return err
+	}
+	for _, it := range items {
+		if _, err := itemStmt.Exec(run.ID, it.QuestionID, it.Difficulty, it.DifficultySE,
+			it.Discrimination, it.DiscriminationSE, it.Responses, it.PValue); err != nil {
+			return err
+		}
+	}
+	if _, err := itemStmt.Exec(); err != nil {
+		return err
+	}
+	if err := itemStmt.Close(); err != nil {
+		return err
+	}
+
+	abilityStmt, err := tx.Prepare(pq.CopyIn("irt_user_abilities", "run_id", "user_id", "ability", "ability_se", "responses", "correct"))
+	if err != nil {
+		return err
+	}
+	for _, ab := range abilities {
+		if _, err := abilityStmt.Exec(run.ID, ab.UserID, ab.Ability, ab.AbilitySE, ab.Responses, ab.Correct); err != nil {
+			return err
+		}
+	}
+	if _, err := abilityStmt.Exec(); err != nil {
+		return err
+	}
+	if err := abilityStmt.Close(); err != nil {
+		return err
+	}
+
+	run.Status = models.IRTRunStatusFinished
+	err = tx.QueryRow(`
+		UPDATE irt_runs
+		   SET status = $2, finished_at = now(), users = $3, items = $4, responses = $5,
+		       iterations = $6, converged = $7, log_likelihood = $8
+		 WHERE id = $1
+		RETURNING finished_at`,
+		run.ID, run.Status, run.Users, run.Items, run.Responses, run.Iterations, run.Converged, run.LogLikelihood).Scan(&run.FinishedAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (p *PostgresStorage) FailIRTRun(id int, reason string) error {
+	_, err := p.db.Exec(`UPDATE irt_runs SET status = $2, finished_at = now(), error = $3 WHERE id = $1`,
+		id, models.IRTRunStatusFailed, reason)
+	return err
+}
+
+// GetLatestIRTRun returns the most recently started run, whatever its status.
+func (p *PostgresStorage) GetLatestIRTRun() (*models.IRTRun, error) {
+	var (
+		run     models.IRTRun
+		errText sql.NullString
+	)
+	err := p.db.QueryRow(`
+		SELECT id, status, started_at, finished_at, users, items, responses,
+		       iterations, converged, log_likelihood, error
+		  FROM irt_runs
+		 ORDER BY started_at DESC, id DESC
+		 LIMIT 1`).Scan(&run.ID, &run.Status, &run.StartedAt, &run.FinishedAt, &run.Users, &run.Items, &run.Responses,
+		&run.Iterations, &run.Converged, &run.LogLikelihood, &errText)
+	if err == sql.ErrNoRows {
+		return nil, ErrIRTNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	run.Error = errText.String
+	return &run, nil
+}
+
+// latestFinishedIRTRun selects the run whose parameters are served.
+const latestFinishedIRTRun = `(SELECT id FROM irt_runs WHERE status = 'finished' ORDER BY finished_at DESC, id DESC LIMIT 1)`
+
+func (p *PostgresStorage) GetIRTItemParams() ([]models.IRTItemParams, error) {
+	return p.queryIRTItemParams(`WHERE run_id = `+latestFinishedIRTRun+` ORDER BY question_id`)
+}
+
+func (p *PostgresStorage) GetIRTItemParamsForQuestion(questionID int) (*models.IRTItemParams, error) {
+	items, err := p.queryIRTItemParams(`WHERE run_id = `+latestFinishedIRTRun+` AND question_id = $1`, questionID)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, ErrIRTNotFound
+	}
+	return &items[0], nil
+}
+
+func (p *PostgresStorage) queryIRTItemParams(where string, args ...any) ([]models.IRTItemParams, error) {
+	rows, err := p.db.Query(`
+		SELECT question_id, difficulty, difficulty_se, discrimination, discrimination_se, responses, p_value
+		  FROM irt_item_params `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.IRTItemParams, 0)
+	for rows.Next() {
+		var it models.IRTItemParams
+		if err := rows.Scan(&it.QuestionID, &it.Difficulty, &it.DifficultySE, &it.Discrimination, &it.DiscriminationSE,
+			&it.Responses, &it.PValue); err != nil {
+			return nil, err
+		}
+		out = append(out, it)
+	}
+	return out, rows.Err()
+}
+
+func (p *PostgresStorage) GetIRTAbilities() ([]models.IRTAbility, error) {
+	return p.queryIRTAbilities(`WHERE run_id = ` + latestFinishedIRTRun + ` ORDER BY user_id`)
+}
+
+func (p *PostgresStorage) GetIRTUserAbility(userID int) (*models.IRTAbility, error) {
+	abilities, err := p.queryIRTAbilities(`WHERE run_id = `+latestFinishedIRTRun+` AND user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(abilities) == 0 {
+		return nil, ErrIRTNotFound
+	}
+	return &abilities[0], nil
+}
+
+func (p *PostgresStorage) queryIRTAbilities(where string, args ...any) ([]models.IRTAbility, error) {
+	rows, err := p.db.Query(`
+		SELECT user_id, ability, ability_se, responses, correct
+		  FROM irt_user_abilities `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.IRTAbility, 0)
+	for rows.Next() {
+		var ab models.IRTAbility
+		if err := rows.Scan(&ab.UserID, &ab.Ability, &ab.AbilitySE, &ab.Responses, &ab.Correct); err != nil {
+			return nil, err
+		}
+		out = append(out, ab)
+	}
+	return out, rows.Err()
+}
+
+// GetQuestionDiscrimination computes the corrected point-biserial correlation
+// of each question: the correlation between answering it correctly and the
+// user's accuracy on the other questions they answered. Users answer
+// different subsets, so the rest score is a share rather than a count. Users
+// with fewer than minUserAnswers first attempts are left out.
+func (p *PostgresStorage) GetQuestionDiscrimination(minUserAnswers int) ([]models.QuestionDiscrimination, error) {
+	rows, err := p.db.Query(`
+		WITH first AS (`+firstAttempts+`
+		), totals AS (
+			SELECT user_id, SUM(correct::int) AS correct, COUNT(*) AS answered
+			  FROM first
+			 GROUP BY user_id
+			HAVING COUNT(*) >= GREATEST($1, 2)
+		)
+		SELECT f.question_id,
+		       COUNT(*),
+		       corr(f.correct::int, (t.correct - f.correct::int)::float8 / (t.answered - 1))
+		  FROM first f
+		  JOIN totals t ON t.user_id = f.user_id
+		 GROUP BY f.question_id
+		 ORDER BY f.question_id`, minUserAnswers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.QuestionDiscrimination, 0)
+	for rows.Next() {
+		var d models.QuestionDiscrimination
+		if err := rows.Scan(&d.QuestionID, &d.Responses, &d.PointBiserial); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
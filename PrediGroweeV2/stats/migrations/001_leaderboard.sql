@@ -0,0 +1,15 @@
+-- Opt-in leaderboard participation and per-test scoping.
+
+ALTER TABLE public.quiz_sessions ADD COLUMN IF NOT EXISTS test_code text;
+
+CREATE TABLE IF NOT EXISTS public.leaderboard_participants (
+    user_id integer PRIMARY KEY,
+    display_name text NOT NULL,
+    opted_in_at timestamp with time zone DEFAULT now() NOT NULL,
+    updated_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS leaderboard_participants_display_name_uq
+    ON public.leaderboard_participants (lower(display_name));
+
+ALTER TABLE public.leaderboard_participants OWNER TO stats_user;
@@ -0,0 +1,20 @@
+-- Sessions flagged as guessing can be excluded from aggregates without
+-- deleting their answers. Aggregate queries read from included_answers.
+
+CREATE TABLE IF NOT EXISTS public.excluded_sessions (
+    session_id integer PRIMARY KEY,
+    reason text NOT NULL,
+    excluded_by integer NOT NULL,
+    excluded_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+ALTER TABLE public.excluded_sessions OWNER TO stats_user;
+
+CREATE OR REPLACE VIEW public.included_answers AS
+SELECT a.*
+  FROM public.answers a
+ WHERE NOT EXISTS (
+        SELECT 1 FROM public.excluded_sessions e WHERE e.session_id = a.session_id
+       );
+
+ALTER VIEW public.included_answers OWNER TO stats_user;
@@ -0,0 +1,50 @@
+-- Generalises excluded_sessions: single responses, whole sessions or whole
+-- users (e.g. staff test accounts) can be excluded from aggregates. Nothing
+-- is deleted; removing the exclusion row restores the data.
+
+CREATE TABLE IF NOT EXISTS public.exclusions (
+    id serial PRIMARY KEY,
+    scope text NOT NULL CHECK (scope IN ('response', 'session', 'user')),
+    target_id integer NOT NULL,
+    reason text NOT NULL,
+    excluded_by integer NOT NULL,
+    excluded_at timestamp with time zone DEFAULT now() NOT NULL,
+    UNIQUE (scope, target_id)
+);
+
+ALTER TABLE public.exclusions OWNER TO stats_user;
+
+INSERT INTO public.exclusions (scope, target_id, reason, excluded_by, excluded_at)
+SELECT 'session', session_id, reason, excluded_by, excluded_at
+  FROM public.excluded_sessions
+ON CONFLICT (scope, target_id) DO NOTHING;
+
+CREATE OR REPLACE VIEW public.included_answers AS
+SELECT a.*
+  FROM public.answers a
+ WHERE NOT EXISTS (
+        SELECT 1 FROM public.exclusions e
+         WHERE e.scope = 'response' AND e.target_id = a.id
+       )
+   AND NOT EXISTS (
+        SELECT 1 FROM public.exclusions e
+         WHERE e.scope = 'session' AND e.target_id = a.session_id
+       )
+   AND NOT EXISTS (
+        SELECT 1 FROM public.quiz_sessions s
+          JOIN public.exclusions e ON e.scope = 'user' AND e.target_id = s.user_id
+         WHERE s.session_id = a.session_id
+       );
+
+CREATE OR REPLACE VIEW public.included_sessions AS
+SELECT s.*
+  FROM public.quiz_sessions s
+ WHERE NOT EXISTS (
+        SELECT 1 FROM public.exclusions e
+         WHERE (e.scope = 'session' AND e.target_id = s.session_id)
+            OR (e.scope = 'user' AND e.target_id = s.user_id)
+       );
+
+ALTER VIEW public.included_sessions OWNER TO stats_user;
+
+DROP TABLE IF EXISTS public.excluded_sessions;
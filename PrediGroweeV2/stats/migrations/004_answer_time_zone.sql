@@ -0,0 +1,47 @@
+-- Store answer times as absolute instants and keep the respondent's IANA
+-- time zone. Existing values were written by now() in the server's session
+-- time zone, so they are interpreted in that zone. The exclusion views
+-- depend on answers.* and have to be recreated around the type change.
+
+BEGIN;
+
+DROP VIEW IF EXISTS public.included_answers;
+DROP VIEW IF EXISTS public.included_sessions;
+
+ALTER TABLE public.answers
+    ALTER COLUMN answer_time TYPE timestamp with time zone
+        USING answer_time AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN answer_time SET DEFAULT now();
+
+ALTER TABLE public.answers ADD COLUMN IF NOT EXISTS time_zone text;
+
+CREATE VIEW public.included_answers AS
+SELECT a.*
+  FROM public.answers a
+ WHERE NOT EXISTS (
+        SELECT 1 FROM public.exclusions e
+         WHERE e.scope = 'response' AND e.target_id = a.id
+       )
+   AND NOT EXISTS (
+        SELECT 1 FROM public.exclusions e
+         WHERE e.scope = 'session' AND e.target_id = a.session_id
+       )
+   AND NOT EXISTS (
+        SELECT 1 FROM public.quiz_sessions s
+          JOIN public.exclusions e ON e.scope = 'user' AND e.target_id = s.user_id
+         WHERE s.session_id = a.session_id
+       );
+
+CREATE VIEW public.included_sessions AS
+SELECT s.*
+  FROM public.quiz_sessions s
+ WHERE NOT EXISTS (
+        SELECT 1 FROM public.exclusions e
+         WHERE (e.scope = 'session' AND e.target_id = s.session_id)
+            OR (e.scope = 'user' AND e.target_id = s.user_id)
+       );
+
+ALTER VIEW public.included_answers OWNER TO stats_user;
+ALTER VIEW public.included_sessions OWNER TO stats_user;
+
+COMMIT;
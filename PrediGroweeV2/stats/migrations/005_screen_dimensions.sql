@@ -0,0 +1,51 @@
+-- Structured viewport dimensions for answers and a device classification
+-- shared by all device queries.
+
+BEGIN;
+
+ALTER TABLE public.answers ADD COLUMN IF NOT EXISTS screen_width integer;
+ALTER TABLE public.answers ADD COLUMN IF NOT EXISTS screen_height integer;
+
+UPDATE public.answers
+   SET screen_width = split_part(screen_size, 'x', 1)::integer,
+       screen_height = split_part(screen_size, 'x', 2)::integer
+ WHERE screen_size ~ '^[0-9]{1,5}x[0-9]{1,5}$'
+   AND screen_width IS NULL;
+
+-- Viewport sizes are CSS pixels with browser chrome removed, so the short
+-- side is the most reliable signal: phones stay under 600 in either
+-- orientation. Tablets have a squarer aspect ratio than laptop viewports of
+-- similar size.
+CREATE OR REPLACE FUNCTION public.device_class(width integer, height integer)
+RETURNS text
+LANGUAGE sql IMMUTABLE AS $$
+    SELECT CASE
+        WHEN width IS NULL OR height IS NULL OR width <= 0 OR height <= 0 THEN 'unknown'
+        WHEN LEAST(width, height) < 600 THEN 'phone'
+        WHEN GREATEST(width, height) <= 1400
+             AND LEAST(width, height)::float8 / GREATEST(width, height) >= 0.6 THEN 'tablet'
+        ELSE 'desktop'
+    END
+$$;
+
+ALTER FUNCTION public.device_class(integer, integer) OWNER TO stats_user;
+
+-- Pick up the new answers columns.
+CREATE OR REPLACE VIEW public.included_answers AS
+SELECT a.*
+  FROM public.answers a
+ WHERE NOT EXISTS (
+        SELECT 1 FROM public.exclusions e
+         WHERE e.scope = 'response' AND e.target_id = a.id
+       )
+   AND NOT EXISTS (
+        SELECT 1 FROM public.exclusions e
+         WHERE e.scope = 'session' AND e.target_id = a.session_id
+       )
+   AND NOT EXISTS (
+        SELECT 1 FROM public.quiz_sessions s
+          JOIN public.exclusions e ON e.scope = 'user' AND e.target_id = s.user_id
+         WHERE s.session_id = a.session_id
+       );
+
+COMMIT;
@@ -0,0 +1,49 @@
+-- Item response theory calibration runs. Each run keeps its own parameters so
+-- a failed or partial run never overwrites the last good calibration.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS public.irt_runs (
+    id             serial PRIMARY KEY,
+    status         text NOT NULL DEFAULT 'running'
+                   CHECK (status IN ('running', 'finished', 'failed')),
+    started_at     timestamptz NOT NULL DEFAULT now(),
+    finished_at    timestamptz,
+    users          integer NOT NULL DEFAULT 0,
+    items          integer NOT NULL DEFAULT 0,
+    responses      integer NOT NULL DEFAULT 0,
+    iterations     integer NOT NULL DEFAULT 0,
+    converged      boolean NOT NULL DEFAULT false,
+    log_likelihood double precision NOT NULL DEFAULT 0,
+    error          text
+);
+
+CREATE TABLE IF NOT EXISTS public.irt_item_params (
+    run_id            integer NOT NULL REFERENCES public.irt_runs (id) ON DELETE CASCADE,
+    question_id       integer NOT NULL,
+    difficulty        double precision NOT NULL,
+    difficulty_se     double precision NOT NULL,
+    discrimination    double precision NOT NULL,
+    discrimination_se double precision NOT NULL,
+    responses         integer NOT NULL,
+    p_value           double precision NOT NULL,
+    PRIMARY KEY (run_id, question_id)
+);
+
+CREATE TABLE IF NOT EXISTS public.irt_user_abilities (
+    run_id     integer NOT NULL REFERENCES public.irt_runs (id) ON DELETE CASCADE,
+    user_id    integer NOT NULL,
+    ability    double precision NOT NULL,
+    ability_se double precision NOT NULL,
+    responses  integer NOT NULL,
+    correct    integer NOT NULL,
+    PRIMARY KEY (run_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS irt_runs_status_idx ON public.irt_runs (status, finished_at DESC);
+
+ALTER TABLE public.irt_runs OWNER TO stats_user;
+ALTER TABLE public.irt_item_params OWNER TO stats_user;
+ALTER TABLE public.irt_user_abilities OWNER TO stats_user;
+
+COMMIT;
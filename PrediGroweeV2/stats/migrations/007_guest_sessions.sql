@@ -0,0 +1,18 @@
+-- Sessions taken by guests (students without an account). The flag stays set
+-- after a guest claims the session into a full account.
+
+BEGIN;
+
+ALTER TABLE public.quiz_sessions ADD COLUMN IF NOT EXISTS guest boolean NOT NULL DEFAULT false;
+
+-- Pick up the new quiz_sessions column.
+CREATE OR REPLACE VIEW public.included_sessions AS
+SELECT s.*
+  FROM public.quiz_sessions s
+ WHERE NOT EXISTS (
+        SELECT 1 FROM public.exclusions e
+         WHERE (e.scope = 'session' AND e.target_id = s.session_id)
+            OR (e.scope = 'user' AND e.target_id = s.user_id)
+       );
+
+COMMIT;